@@ -23,13 +23,17 @@ import (
 	"github.com/percona/pmm/version"
 	"golang.org/x/text/language"
 	"golang.org/x/text/message"
+	"google.golang.org/grpc"
 	"google.golang.org/grpc/grpclog"
 	"gopkg.in/alecthomas/kingpin.v2"
 
 	_ "github.com/percona-platform/dbaas-controller/catalog" // load messages.
 	"github.com/percona-platform/dbaas-controller/service/cluster"
+	"github.com/percona-platform/dbaas-controller/service/k8sclient"
 	"github.com/percona-platform/dbaas-controller/service/logs"
+	"github.com/percona-platform/dbaas-controller/service/monitoring"
 	"github.com/percona-platform/dbaas-controller/service/operator"
+	"github.com/percona-platform/dbaas-controller/service/registry"
 	"github.com/percona-platform/dbaas-controller/utils/app"
 	"github.com/percona-platform/dbaas-controller/utils/logger"
 	"github.com/percona-platform/dbaas-controller/utils/servers"
@@ -41,7 +45,8 @@ func main() {
 	}
 
 	flags, err := app.Setup(&app.SetupOpts{
-		Name: "dbaas-controller",
+		Name:              "dbaas-controller",
+		DefaultLogsLimits: app.LogsLimits(logs.DefaultLimits()),
 	})
 	if err != nil {
 		log.Fatal(err)
@@ -62,20 +67,96 @@ func main() {
 	// Setup grpc server
 	grpclog.SetLoggerV2(l.GRPCLogger())
 
+	i18nPrinter := message.NewPrinter(language.English)
+
+	var registryStore *registry.Store
+	if flags.RegistryPath != "" {
+		if registryStore, err = registry.Open(flags.RegistryPath, flags.RegistryKeyPath); err != nil {
+			l.Fatalf("Failed to open Kubernetes clusters registry: %s.", err)
+		}
+	}
+	registryService := registry.NewService(i18nPrinter, registryStore)
+
 	gRPCServer := servers.NewGRPCServer(ctx, &servers.NewGRPCServerOpts{
-		Addr: flags.GRPCAddr,
+		Addr:               flags.GRPCAddr,
+		UnaryInterceptors:  []grpc.UnaryServerInterceptor{registryService.UnaryServerInterceptor()},
+		StreamInterceptors: []grpc.StreamServerInterceptor{registryService.StreamServerInterceptor()},
 	})
 	if err != nil {
 		l.Fatalf("Failed to create gRPC server: %s.", err)
 	}
 
-	i18nPrinter := message.NewPrinter(language.English)
-	controllerv1beta1.RegisterPXCClusterAPIServer(gRPCServer.GetUnderlyingServer(), cluster.NewPXCClusterService(i18nPrinter))
-	controllerv1beta1.RegisterPSMDBClusterAPIServer(gRPCServer.GetUnderlyingServer(), cluster.NewPSMDBClusterService(i18nPrinter))
-	controllerv1beta1.RegisterKubernetesClusterAPIServer(gRPCServer.GetUnderlyingServer(), cluster.NewKubernetesClusterService(i18nPrinter))
-	controllerv1beta1.RegisterLogsAPIServer(gRPCServer.GetUnderlyingServer(), logs.NewService(i18nPrinter))
-	controllerv1beta1.RegisterPXCOperatorAPIServer(gRPCServer.GetUnderlyingServer(), operator.NewPXCOperatorService(i18nPrinter, flags.PXCOperatorURLTemplate))
-	controllerv1beta1.RegisterPSMDBOperatorAPIServer(gRPCServer.GetUnderlyingServer(), operator.NewPSMDBOperatorService(i18nPrinter, flags.PSMDBOperatorURLTemplate))
+	passwordPolicy := &k8sclient.PasswordPolicy{
+		Length:    flags.PasswordLength,
+		Lowercase: flags.PasswordLowercase,
+		Uppercase: flags.PasswordUppercase,
+		Digits:    flags.PasswordDigits,
+		Symbols: map[k8sclient.Engine]string{
+			k8sclient.EnginePXC:   flags.PXCPasswordSymbols,
+			k8sclient.EnginePSMDB: flags.PSMDBPasswordSymbols,
+		},
+	}
+	if err := passwordPolicy.Check(); err != nil {
+		l.Fatalf("Invalid password policy: %s.", err)
+	}
+
+	pxcManifests, err := k8sclient.NewManifestSource(
+		flags.OperatorManifests, k8sclient.EnginePXC, flags.PXCOperatorURLTemplate, flags.OperatorManifestsRequireChecksums,
+	)
+	if err != nil {
+		l.Fatalf("Failed to load PXC operator manifests: %s.", err)
+	}
+	psmdbManifests, err := k8sclient.NewManifestSource(
+		flags.OperatorManifests, k8sclient.EnginePSMDB, flags.PSMDBOperatorURLTemplate, flags.OperatorManifestsRequireChecksums,
+	)
+	if err != nil {
+		l.Fatalf("Failed to load PSMDB operator manifests: %s.", err)
+	}
+
+	rolloutParams := k8sclient.RolloutParams{
+		BatchSize:    flags.RolloutBatchSize,
+		ReadyTimeout: flags.RolloutReadyTimeout,
+	}
+
+	logsLimits := logs.Limits{
+		Lines:         flags.LogsLinesLimit,
+		Bytes:         flags.LogsBytesLimit,
+		FailingWeight: flags.LogsFailingWeight,
+		EventsWeight:  flags.LogsEventsWeight,
+		OtherWeight:   flags.LogsOtherWeight,
+	}
+	if err := logsLimits.Check(); err != nil {
+		l.Fatalf("Invalid logs limits: %s.", err)
+	}
+
+	monitoringService := monitoring.NewService(i18nPrinter, monitoring.Params{
+		ScrapeInterval: flags.MonitoringScrapeInterval,
+		TLSVerify:      flags.MonitoringTLSVerify,
+	})
+	pxcOperatorService := operator.NewPXCOperatorService(ctx, i18nPrinter, pxcManifests, rolloutParams)
+	psmdbOperatorService := operator.NewPSMDBOperatorService(ctx, i18nPrinter, psmdbManifests, rolloutParams)
+	pxcClusterService := cluster.NewPXCClusterService(i18nPrinter, passwordPolicy)
+	psmdbClusterService := cluster.NewPSMDBClusterService(i18nPrinter, passwordPolicy)
+	controllerv1beta1.RegisterPXCClusterAPIServer(gRPCServer.GetUnderlyingServer(), pxcClusterService)
+	controllerv1beta1.RegisterPSMDBClusterAPIServer(gRPCServer.GetUnderlyingServer(), psmdbClusterService)
+	kubernetesClusterService := cluster.NewKubernetesClusterService(i18nPrinter, monitoringService)
+	controllerv1beta1.RegisterKubernetesClusterAPIServer(gRPCServer.GetUnderlyingServer(), kubernetesClusterService)
+	cluster.RegisterAPIServer(gRPCServer.GetUnderlyingServer(), &cluster.APIServer{
+		PXC:        pxcClusterService,
+		PSMDB:      psmdbClusterService,
+		Kubernetes: kubernetesClusterService,
+	})
+	monitoring.RegisterAPIServer(gRPCServer.GetUnderlyingServer(), monitoringService)
+	registry.RegisterAPIServer(gRPCServer.GetUnderlyingServer(), registryService)
+	logsService := logs.NewService(i18nPrinter, logsLimits)
+	controllerv1beta1.RegisterLogsAPIServer(gRPCServer.GetUnderlyingServer(), logsService)
+	logs.RegisterAPIServer(gRPCServer.GetUnderlyingServer(), logsService)
+	controllerv1beta1.RegisterPXCOperatorAPIServer(gRPCServer.GetUnderlyingServer(), pxcOperatorService)
+	controllerv1beta1.RegisterPSMDBOperatorAPIServer(gRPCServer.GetUnderlyingServer(), psmdbOperatorService)
+	operator.RegisterAPIServer(gRPCServer.GetUnderlyingServer(), &operator.APIServer{
+		PXC:   pxcOperatorService,
+		PSMDB: psmdbOperatorService,
+	})
 
 	go servers.RunDebugServer(ctx, &servers.RunDebugServerOpts{
 		Addr: flags.DebugAddr,
@@ -86,4 +167,8 @@ func main() {
 	})
 
 	gRPCServer.Run(ctx)
+
+	// Rollouts are canceled with ctx, wait for them to save their state.
+	pxcOperatorService.WaitRollouts()
+	psmdbOperatorService.WaitRollouts()
 }
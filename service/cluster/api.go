@@ -0,0 +1,185 @@
+// dbaas-controller
+// Copyright (C) 2020 Percona LLC
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package cluster
+
+import (
+	"context"
+
+	controllerv1beta1 "github.com/percona-platform/dbaas-api/gen/controller"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/types/known/emptypb"
+
+	"github.com/percona-platform/dbaas-controller/service/k8sclient"
+	"github.com/percona-platform/dbaas-controller/utils/jsonapi"
+)
+
+// APIServiceName is the name of gRPC service with cluster methods which are not part of controller API.
+// Its messages are JSON objects, see jsonapi package.
+const APIServiceName = "percona.platform.dbaas.controller.cluster.v1.ClusterAPI"
+
+// APIServer serves cluster methods which are not part of controller API.
+type APIServer struct {
+	PXC        *PXCClusterService
+	PSMDB      *PSMDBClusterService
+	Kubernetes *KubernetesClusterService
+}
+
+// APIServiceDesc describes gRPC service with cluster methods which are not part of controller API.
+var APIServiceDesc = grpc.ServiceDesc{ //nolint:gochecknoglobals
+	ServiceName: APIServiceName,
+	HandlerType: (*interface{})(nil), // handlers require *APIServer
+	Methods: []grpc.MethodDesc{
+		unaryMethod("ClonePXCCluster", func() interface{} { return new(CloneClusterRequest) },
+			func(ctx context.Context, s *APIServer, req interface{}) (interface{}, error) {
+				if err := s.PXC.ClonePXCCluster(ctx, req.(*CloneClusterRequest)); err != nil {
+					return nil, err
+				}
+				return new(emptypb.Empty), nil
+			}),
+		unaryMethod("GetPXCCloneOperation", func() interface{} { return new(GetCloneOperationRequest) },
+			func(ctx context.Context, s *APIServer, req interface{}) (interface{}, error) {
+				return s.PXC.GetPXCCloneOperation(ctx, req.(*GetCloneOperationRequest))
+			}),
+		unaryMethod("ClonePSMDBCluster", func() interface{} { return new(CloneClusterRequest) },
+			func(ctx context.Context, s *APIServer, req interface{}) (interface{}, error) {
+				if err := s.PSMDB.ClonePSMDBCluster(ctx, req.(*CloneClusterRequest)); err != nil {
+					return nil, err
+				}
+				return new(emptypb.Empty), nil
+			}),
+		unaryMethod("GetPSMDBCloneOperation", func() interface{} { return new(GetCloneOperationRequest) },
+			func(ctx context.Context, s *APIServer, req interface{}) (interface{}, error) {
+				return s.PSMDB.GetPSMDBCloneOperation(ctx, req.(*GetCloneOperationRequest))
+			}),
+		unaryMethod("CheckPXCClusterCapacity", func() interface{} { return new(controllerv1beta1.CreatePXCClusterRequest) },
+			func(ctx context.Context, s *APIServer, req interface{}) (interface{}, error) {
+				if err := s.PXC.CheckPXCClusterCapacity(ctx, req.(*controllerv1beta1.CreatePXCClusterRequest)); err != nil {
+					return nil, err
+				}
+				return new(emptypb.Empty), nil
+			}),
+		unaryMethod("CheckPSMDBClusterCapacity", func() interface{} { return new(controllerv1beta1.CreatePSMDBClusterRequest) },
+			func(ctx context.Context, s *APIServer, req interface{}) (interface{}, error) {
+				if err := s.PSMDB.CheckPSMDBClusterCapacity(ctx, req.(*controllerv1beta1.CreatePSMDBClusterRequest)); err != nil {
+					return nil, err
+				}
+				return new(emptypb.Empty), nil
+			}),
+		unaryMethod("GetNodesResources", func() interface{} { return new(GetNodesResourcesRequest) },
+			func(ctx context.Context, s *APIServer, req interface{}) (interface{}, error) {
+				return s.Kubernetes.GetNodesResources(ctx, req.(*GetNodesResourcesRequest))
+			}),
+		unaryMethod("DiagnoseKubernetesClusterConnection", func() interface{} { return new(DiagnoseKubernetesClusterConnectionRequest) },
+			func(ctx context.Context, s *APIServer, req interface{}) (interface{}, error) {
+				return s.Kubernetes.DiagnoseKubernetesClusterConnection(ctx, req.(*DiagnoseKubernetesClusterConnectionRequest))
+			}),
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "service/cluster/api.go",
+}
+
+// unaryMethod returns description of the API method.
+func unaryMethod(
+	name string, newRequest func() interface{}, call func(ctx context.Context, s *APIServer, req interface{}) (interface{}, error),
+) grpc.MethodDesc {
+	return jsonapi.UnaryMethod(APIServiceName, name, newRequest, func(ctx context.Context, srv interface{}, req interface{}) (interface{}, error) {
+		return call(ctx, srv.(*APIServer), req)
+	})
+}
+
+// RegisterAPIServer registers gRPC service with cluster methods which are not part of controller API.
+func RegisterAPIServer(s *grpc.Server, srv *APIServer) {
+	s.RegisterService(&APIServiceDesc, srv)
+}
+
+// APIClient is the client of gRPC service with cluster methods which are not part of controller API.
+type APIClient struct {
+	cc grpc.ClientConnInterface
+}
+
+// NewAPIClient returns new APIClient instance.
+func NewAPIClient(cc grpc.ClientConnInterface) *APIClient {
+	return &APIClient{cc: cc}
+}
+
+// ClonePXCCluster creates a new PXC cluster seeded with data from the source cluster's backup.
+func (c *APIClient) ClonePXCCluster(ctx context.Context, req *CloneClusterRequest, opts ...grpc.CallOption) error {
+	return jsonapi.Invoke(ctx, c.cc, "/"+APIServiceName+"/ClonePXCCluster", req, new(emptypb.Empty), opts...)
+}
+
+// GetPXCCloneOperation returns progress of cloning into PXC cluster.
+func (c *APIClient) GetPXCCloneOperation(
+	ctx context.Context, req *GetCloneOperationRequest, opts ...grpc.CallOption,
+) (*k8sclient.CloneOperation, error) {
+	res := new(k8sclient.CloneOperation)
+	if err := jsonapi.Invoke(ctx, c.cc, "/"+APIServiceName+"/GetPXCCloneOperation", req, res, opts...); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// ClonePSMDBCluster creates a new PSMDB cluster seeded with data from the source cluster's backup.
+func (c *APIClient) ClonePSMDBCluster(ctx context.Context, req *CloneClusterRequest, opts ...grpc.CallOption) error {
+	return jsonapi.Invoke(ctx, c.cc, "/"+APIServiceName+"/ClonePSMDBCluster", req, new(emptypb.Empty), opts...)
+}
+
+// GetPSMDBCloneOperation returns progress of cloning into PSMDB cluster.
+func (c *APIClient) GetPSMDBCloneOperation(
+	ctx context.Context, req *GetCloneOperationRequest, opts ...grpc.CallOption,
+) (*k8sclient.CloneOperation, error) {
+	res := new(k8sclient.CloneOperation)
+	if err := jsonapi.Invoke(ctx, c.cc, "/"+APIServiceName+"/GetPSMDBCloneOperation", req, res, opts...); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// CheckPXCClusterCapacity checks that PXC cluster from the creation request fits into Kubernetes cluster.
+func (c *APIClient) CheckPXCClusterCapacity(
+	ctx context.Context, req *controllerv1beta1.CreatePXCClusterRequest, opts ...grpc.CallOption,
+) error {
+	return jsonapi.Invoke(ctx, c.cc, "/"+APIServiceName+"/CheckPXCClusterCapacity", req, new(emptypb.Empty), opts...)
+}
+
+// CheckPSMDBClusterCapacity checks that PSMDB cluster from the creation request fits into Kubernetes cluster.
+func (c *APIClient) CheckPSMDBClusterCapacity(
+	ctx context.Context, req *controllerv1beta1.CreatePSMDBClusterRequest, opts ...grpc.CallOption,
+) error {
+	return jsonapi.Invoke(ctx, c.cc, "/"+APIServiceName+"/CheckPSMDBClusterCapacity", req, new(emptypb.Empty), opts...)
+}
+
+// GetNodesResources returns resources of each worker node of Kubernetes cluster.
+func (c *APIClient) GetNodesResources(
+	ctx context.Context, req *GetNodesResourcesRequest, opts ...grpc.CallOption,
+) (*GetNodesResourcesResponse, error) {
+	res := new(GetNodesResourcesResponse)
+	if err := jsonapi.Invoke(ctx, c.cc, "/"+APIServiceName+"/GetNodesResources", req, res, opts...); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// DiagnoseKubernetesClusterConnection returns a checklist of connectivity to Kubernetes cluster.
+func (c *APIClient) DiagnoseKubernetesClusterConnection(
+	ctx context.Context, req *DiagnoseKubernetesClusterConnectionRequest, opts ...grpc.CallOption,
+) (*k8sclient.ConnectionDiagnostics, error) {
+	res := new(k8sclient.ConnectionDiagnostics)
+	if err := jsonapi.Invoke(ctx, c.cc, "/"+APIServiceName+"/DiagnoseKubernetesClusterConnection", req, res, opts...); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
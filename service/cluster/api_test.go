@@ -0,0 +1,74 @@
+// dbaas-controller
+// Copyright (C) 2020 Percona LLC
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package cluster
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	grpc_validator "github.com/grpc-ecosystem/go-grpc-middleware/validator"
+	controllerv1beta1 "github.com/percona-platform/dbaas-api/gen/controller"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/percona-platform/dbaas-controller/service/k8sclient"
+)
+
+func TestAPI(t *testing.T) {
+	t.Parallel()
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	p := message.NewPrinter(language.English)
+	server := grpc.NewServer(grpc.UnaryInterceptor(grpc_validator.UnaryServerInterceptor()))
+	RegisterAPIServer(server, &APIServer{
+		PXC:        NewPXCClusterService(p, nil),
+		PSMDB:      NewPSMDBClusterService(p, nil),
+		Kubernetes: NewKubernetesClusterService(p, nil),
+	})
+	go server.Serve(lis) //nolint:errcheck
+	defer server.Stop()
+
+	ctx := context.Background()
+	conn, err := grpc.DialContext(ctx, lis.Addr().String(), grpc.WithInsecure())
+	require.NoError(t, err)
+	defer conn.Close() //nolint:errcheck
+
+	client := NewAPIClient(conn)
+	err = client.ClonePXCCluster(ctx, &CloneClusterRequest{Kubeconfig: "{}", SourceName: "source"})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	_, err = client.GetPSMDBCloneOperation(ctx, &GetCloneOperationRequest{Name: "clone"})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	_, err = client.GetNodesResources(ctx, &GetNodesResourcesRequest{CPUMillis: 500})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	err = client.CheckPXCClusterCapacity(ctx, &controllerv1beta1.CreatePXCClusterRequest{Name: "cluster"})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	_, err = client.DiagnoseKubernetesClusterConnection(ctx, &DiagnoseKubernetesClusterConnectionRequest{})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	// Invalid kubeconfig is reported in the checklist, not as an error.
+	diagnostics, err := client.DiagnoseKubernetesClusterConnection(ctx, &DiagnoseKubernetesClusterConnectionRequest{Kubeconfig: "not a kubeconfig"})
+	require.NoError(t, err)
+	require.NotNil(t, diagnostics.FirstFailure())
+	assert.Equal(t, k8sclient.CheckKubeconfig, diagnostics.FirstFailure().Name)
+}
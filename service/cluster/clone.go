@@ -0,0 +1,169 @@
+// dbaas-controller
+// Copyright (C) 2020 Percona LLC
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package cluster
+
+import (
+	"context"
+
+	controllerv1beta1 "github.com/percona-platform/dbaas-api/gen/controller"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/percona-platform/dbaas-controller/service/k8sclient"
+)
+
+// CloneClusterRequest contains parameters of cloning a database cluster.
+type CloneClusterRequest struct {
+	Kubeconfig string `json:"kubeconfig"`
+	// SourceName is the name of the cluster to clone.
+	SourceName string `json:"sourceName"`
+	// Name is the name of the new cluster.
+	Name string `json:"name"`
+	// BackupName is the name of the source cluster's backup the new cluster is seeded with.
+	// The latest successful backup is used if empty.
+	BackupName string `json:"backupName,omitempty"`
+	// Size overrides number of database pods of the new cluster if greater than zero.
+	Size int32 `json:"size,omitempty"`
+	// ComputeResources overrides compute resources of database containers if not nil.
+	ComputeResources *controllerv1beta1.ComputeResources `json:"computeResources,omitempty"`
+}
+
+// Validate returns an error if required parameters are missing or invalid.
+func (req *CloneClusterRequest) Validate() error {
+	if req.Kubeconfig == "" {
+		return errors.New("kubeconfig is required")
+	}
+	if req.SourceName == "" || req.Name == "" {
+		return errors.New("source cluster name and cluster name are required")
+	}
+	if req.Size < 0 {
+		return errors.New("cluster size can't be negative")
+	}
+	return nil
+}
+
+// GetCloneOperationRequest contains parameters of getting progress of cloning a database cluster.
+type GetCloneOperationRequest struct {
+	Kubeconfig string `json:"kubeconfig"`
+	// Name is the name of the new cluster.
+	Name string `json:"name"`
+}
+
+// Validate returns an error if required parameters are missing.
+func (req *GetCloneOperationRequest) Validate() error {
+	if req.Kubeconfig == "" || req.Name == "" {
+		return errors.New("kubeconfig and cluster name are required")
+	}
+	return nil
+}
+
+// cloneParams validates the request and converts it to clone parameters.
+func cloneParams(req *CloneClusterRequest) (*k8sclient.CloneParams, error) {
+	if err := req.Validate(); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	return &k8sclient.CloneParams{
+		SourceName:       req.SourceName,
+		Name:             req.Name,
+		BackupName:       req.BackupName,
+		Size:             req.Size,
+		ComputeResources: computeResources(req.ComputeResources),
+	}, nil
+}
+
+// cloneError converts clone error to gRPC status.
+func cloneError(err error) error {
+	switch {
+	case errors.Is(err, k8sclient.ErrNotFound):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, k8sclient.ErrInvalidCloneSource):
+		return status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, k8sclient.ErrNoBackupToClone):
+		return status.Error(codes.FailedPrecondition, err.Error())
+	default:
+		return status.Error(codes.Internal, err.Error())
+	}
+}
+
+// ClonePXCCluster creates a new PXC cluster with the spec of the source cluster and seeds it with
+// data from the source cluster's backup. Progress is returned by GetPXCCloneOperation.
+func (s *PXCClusterService) ClonePXCCluster(ctx context.Context, req *CloneClusterRequest) error {
+	params, err := cloneParams(req)
+	if err != nil {
+		return err
+	}
+	client, err := k8sclient.New(ctx, req.Kubeconfig)
+	if err != nil {
+		return status.Error(codes.Internal, s.p.Sprintf("Cannot initialize K8s client: %s", err))
+	}
+	defer client.Cleanup() //nolint:errcheck
+
+	if err = client.ClonePXCCluster(ctx, params); err != nil {
+		return cloneError(err)
+	}
+	return nil
+}
+
+// GetPXCCloneOperation returns progress of cloning into PXC cluster with given name.
+func (s *PXCClusterService) GetPXCCloneOperation(ctx context.Context, req *GetCloneOperationRequest) (*k8sclient.CloneOperation, error) {
+	client, err := k8sclient.New(ctx, req.Kubeconfig)
+	if err != nil {
+		return nil, status.Error(codes.Internal, s.p.Sprintf("Cannot initialize K8s client: %s", err))
+	}
+	defer client.Cleanup() //nolint:errcheck
+
+	op, err := client.GetPXCCloneOperation(ctx, req.Name)
+	if err != nil {
+		return nil, cloneError(err)
+	}
+	return op, nil
+}
+
+// ClonePSMDBCluster creates a new PSMDB cluster with the spec of the source cluster and seeds it with
+// data from the source cluster's backup. Progress is returned by GetPSMDBCloneOperation.
+func (s *PSMDBClusterService) ClonePSMDBCluster(ctx context.Context, req *CloneClusterRequest) error {
+	params, err := cloneParams(req)
+	if err != nil {
+		return err
+	}
+	client, err := k8sclient.New(ctx, req.Kubeconfig)
+	if err != nil {
+		return status.Error(codes.Internal, s.p.Sprintf("Cannot initialize K8s client: %s", err))
+	}
+	defer client.Cleanup() //nolint:errcheck
+
+	if err = client.ClonePSMDBCluster(ctx, params); err != nil {
+		return cloneError(err)
+	}
+	return nil
+}
+
+// GetPSMDBCloneOperation returns progress of cloning into PSMDB cluster with given name.
+func (s *PSMDBClusterService) GetPSMDBCloneOperation(ctx context.Context, req *GetCloneOperationRequest) (*k8sclient.CloneOperation, error) {
+	client, err := k8sclient.New(ctx, req.Kubeconfig)
+	if err != nil {
+		return nil, status.Error(codes.Internal, s.p.Sprintf("Cannot initialize K8s client: %s", err))
+	}
+	defer client.Cleanup() //nolint:errcheck
+
+	op, err := client.GetPSMDBCloneOperation(ctx, req.Name)
+	if err != nil {
+		return nil, cloneError(err)
+	}
+	return op, nil
+}
@@ -0,0 +1,53 @@
+// dbaas-controller
+// Copyright (C) 2020 Percona LLC
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package cluster
+
+import (
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/percona-platform/dbaas-controller/service/k8sclient"
+)
+
+func TestCloneError(t *testing.T) {
+	t.Parallel()
+	for _, tc := range []struct {
+		err  error
+		code codes.Code
+	}{
+		{errors.Wrap(k8sclient.ErrNotFound, "cannot get source cluster"), codes.NotFound},
+		{errors.Wrap(k8sclient.ErrInvalidCloneSource, "no replica sets"), codes.InvalidArgument},
+		{errors.Wrap(k8sclient.ErrNoBackupToClone, "cluster \"test\""), codes.FailedPrecondition},
+		{errors.New("kubectl failed"), codes.Internal},
+	} {
+		assert.Equal(t, tc.code, status.Code(cloneError(tc.err)), tc.err.Error())
+	}
+}
+
+func TestCloneParams(t *testing.T) {
+	t.Parallel()
+	_, err := cloneParams(&CloneClusterRequest{SourceName: "source"})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	params, err := cloneParams(&CloneClusterRequest{Kubeconfig: "{}", SourceName: "source", Name: "clone", Size: 3})
+	assert.NoError(t, err)
+	assert.Equal(t, &k8sclient.CloneParams{SourceName: "source", Name: "clone", Size: 3}, params)
+}
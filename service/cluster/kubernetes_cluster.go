@@ -20,29 +20,36 @@ import (
 	"context"
 
 	controllerv1beta1 "github.com/percona-platform/dbaas-api/gen/controller"
+	"github.com/pkg/errors"
 	"golang.org/x/text/message"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 
 	"github.com/percona-platform/dbaas-controller/service/k8sclient"
 	"github.com/percona-platform/dbaas-controller/service/k8sclient/common"
+	"github.com/percona-platform/dbaas-controller/service/monitoring"
 	"github.com/percona-platform/dbaas-controller/utils/logger"
 )
 
 // KubernetesClusterService implements methods of gRPC server and other business logic related to kubernetes clusters.
 type KubernetesClusterService struct {
-	p *message.Printer
+	p          *message.Printer
+	monitoring *monitoring.Service
 }
 
 // NewKubernetesClusterService returns new KubernetesClusterService instance.
-func NewKubernetesClusterService(p *message.Printer) *KubernetesClusterService {
-	return &KubernetesClusterService{p: p}
+func NewKubernetesClusterService(p *message.Printer, monitoringService *monitoring.Service) *KubernetesClusterService {
+	return &KubernetesClusterService{p: p, monitoring: monitoringService}
 }
 
 // CheckKubernetesClusterConnection checks connection with kubernetes cluster.
 func (k KubernetesClusterService) CheckKubernetesClusterConnection(ctx context.Context, req *controllerv1beta1.CheckKubernetesClusterConnectionRequest) (*controllerv1beta1.CheckKubernetesClusterConnectionResponse, error) {
 	k8Client, err := k8sclient.New(ctx, req.KubeAuth.Kubeconfig)
 	if err != nil {
+		// Find out which step of connection failed to return more specific error.
+		if failure := k8sclient.DiagnoseConnection(ctx, req.KubeAuth.Kubeconfig).FirstFailure(); failure != nil {
+			return nil, status.Error(codes.FailedPrecondition, k.p.Sprintf("Unable to connect to Kubernetes cluster: %s check failed: %s", failure.Name, failure.Message))
+		}
 		return nil, status.Error(codes.FailedPrecondition, k.p.Sprintf("Unable to connect to Kubernetes cluster: %s", err))
 	}
 	defer k8Client.Cleanup() //nolint:errcheck
@@ -55,9 +62,11 @@ func (k KubernetesClusterService) CheckKubernetesClusterConnection(ctx context.C
 	l := logger.Get(ctx)
 	l = l.WithField("component", "kubernetesClusterService")
 
+	// Cluster which doesn't list its API versions can't be used by the controller.
 	operators, err := k8Client.CheckOperators(ctx)
 	if err != nil {
 		l.Error(err)
+		resp.Status = controllerv1beta1.KubernetesClusterStatus_KUBERNETES_CLUSTER_STATUS_UNAVAILABLE
 		return resp, nil
 	}
 
@@ -67,6 +76,29 @@ func (k KubernetesClusterService) CheckKubernetesClusterConnection(ctx context.C
 	return resp, nil
 }
 
+// DiagnoseKubernetesClusterConnectionRequest contains parameters of connectivity diagnostics.
+type DiagnoseKubernetesClusterConnectionRequest struct {
+	Kubeconfig string `json:"kubeconfig"`
+}
+
+// Validate checks that the request has kubeconfig.
+func (req *DiagnoseKubernetesClusterConnectionRequest) Validate() error {
+	if req.Kubeconfig == "" {
+		return errors.New("kubeconfig is required")
+	}
+	return nil
+}
+
+// DiagnoseKubernetesClusterConnection returns a checklist of kubeconfig validity, network connectivity,
+// authentication and permissions required by the controller. Failed checks are reported in the checklist,
+// so it returns an error only for invalid request.
+func (k KubernetesClusterService) DiagnoseKubernetesClusterConnection(ctx context.Context, req *DiagnoseKubernetesClusterConnectionRequest) (*k8sclient.ConnectionDiagnostics, error) {
+	if err := req.Validate(); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	return k8sclient.DiagnoseConnection(ctx, req.Kubeconfig), nil
+}
+
 // GetResources returns total and available amounts of resources of certain k8s cluster.
 func (k KubernetesClusterService) GetResources(ctx context.Context, req *controllerv1beta1.GetResourcesRequest) (*controllerv1beta1.GetResourcesResponse, error) {
 	k8sClient, err := k8sclient.New(ctx, req.KubeAuth.Kubeconfig)
@@ -78,7 +110,7 @@ func (k KubernetesClusterService) GetResources(ctx context.Context, req *control
 	// Get cluster type
 	clusterType := k8sClient.GetKubernetesClusterType(ctx)
 	var volumes *common.PersistentVolumeList
-	if clusterType == k8sclient.AmazonEKSClusterType {
+	if clusterType.UsesAttachableVolumes() {
 		volumes, err = k8sClient.GetPersistentVolumes(ctx)
 		if err != nil {
 			return nil, status.Error(codes.Internal, err.Error())
@@ -129,22 +161,57 @@ func (k KubernetesClusterService) GetResources(ctx context.Context, req *control
 	}, nil
 }
 
-// StartMonitoring sets up victoria metrics operator to monitor kubernetes cluster.
-func (k KubernetesClusterService) StartMonitoring(ctx context.Context, req *controllerv1beta1.StartMonitoringRequest) (*controllerv1beta1.StartMonitoringResponse, error) {
-	k8sClient, err := k8sclient.New(ctx, req.KubeAuth.Kubeconfig)
+// GetNodesResourcesRequest contains parameters of getting per-node resources.
+type GetNodesResourcesRequest struct {
+	Kubeconfig string `json:"kubeconfig"`
+	// CPUMillis and MemoryBytes are requests of a pod to estimate how many such pods could still be scheduled.
+	// Estimation is skipped if both are zero.
+	CPUMillis   uint64 `json:"cpuMillis,omitempty"`
+	MemoryBytes uint64 `json:"memoryBytes,omitempty"`
+	// AntiAffinity requires estimated pods to run on different nodes.
+	AntiAffinity bool `json:"antiAffinity,omitempty"`
+}
+
+// Validate checks that the request has kubeconfig.
+func (req *GetNodesResourcesRequest) Validate() error {
+	if req.Kubeconfig == "" {
+		return errors.New("kubeconfig is required")
+	}
+	return nil
+}
+
+// GetNodesResourcesResponse contains resources of worker nodes.
+type GetNodesResourcesResponse struct {
+	Nodes []k8sclient.NodeResources `json:"nodes"`
+	// SchedulablePods is the estimated number of pods with requested resources that could still be scheduled.
+	SchedulablePods uint64 `json:"schedulablePods"`
+}
+
+// GetNodesResources returns allocatable, requested and free resources of each worker node,
+// which GetResources only reports summed up for the whole Kubernetes cluster.
+func (k KubernetesClusterService) GetNodesResources(ctx context.Context, req *GetNodesResourcesRequest) (*GetNodesResourcesResponse, error) {
+	if err := req.Validate(); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	k8sClient, err := k8sclient.New(ctx, req.Kubeconfig)
 	if err != nil {
 		return nil, status.Error(codes.FailedPrecondition, k.p.Sprintf("Unable to connect to Kubernetes cluster: %s", err))
 	}
 	defer k8sClient.Cleanup() //nolint:errcheck
 
-	err = k8sClient.CreateVMOperator(ctx, &k8sclient.PMM{
-		PublicAddress: req.Pmm.PublicAddress,
-		Login:         req.Pmm.Login,
-		Password:      req.Pmm.Password,
-	})
+	nodes, err := k8sClient.GetNodesResources(ctx)
 	if err != nil {
-		return nil, err
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	res := &GetNodesResourcesResponse{Nodes: nodes}
+	if req.CPUMillis > 0 || req.MemoryBytes > 0 {
+		res.SchedulablePods = k8sclient.EstimateSchedulablePods(nodes, req.CPUMillis, req.MemoryBytes, req.AntiAffinity)
 	}
+	return res, nil
+}
 
-	return new(controllerv1beta1.StartMonitoringResponse), nil
+// StartMonitoring sets up victoria metrics operator to monitor kubernetes cluster.
+func (k KubernetesClusterService) StartMonitoring(ctx context.Context, req *controllerv1beta1.StartMonitoringRequest) (*controllerv1beta1.StartMonitoringResponse, error) {
+	return k.monitoring.StartMonitoring(ctx, req)
 }
@@ -26,6 +26,7 @@ import (
 	"golang.org/x/text/message"
 	"google.golang.org/grpc/codes"
 
+	"github.com/percona-platform/dbaas-controller/service/monitoring"
 	"github.com/percona-platform/dbaas-controller/utils/testutil"
 )
 
@@ -34,7 +35,7 @@ func TestKubernetesClusterServiceCheckConnection(t *testing.T) {
 	t.Run("Wrong kube config", func(t *testing.T) {
 		t.Parallel()
 		i18nPrinter := message.NewPrinter(language.English)
-		k := NewKubernetesClusterService(i18nPrinter)
+		k := NewKubernetesClusterService(i18nPrinter, monitoring.NewService(i18nPrinter, monitoring.Params{}))
 		kubeConfig := `{
 			"kind": "Config",
 			"apiVersion": "v1",
@@ -31,12 +31,13 @@ import (
 
 // PSMDBClusterService implements methods of gRPC server and other business logic related to PSMDB clusters.
 type PSMDBClusterService struct {
-	p *message.Printer
+	p              *message.Printer
+	passwordPolicy *k8sclient.PasswordPolicy
 }
 
 // NewPSMDBClusterService returns new PSMDBClusterService instance.
-func NewPSMDBClusterService(p *message.Printer) *PSMDBClusterService {
-	return &PSMDBClusterService{p: p}
+func NewPSMDBClusterService(p *message.Printer, passwordPolicy *k8sclient.PasswordPolicy) *PSMDBClusterService {
+	return &PSMDBClusterService{p: p, passwordPolicy: passwordPolicy}
 }
 
 // ListPSMDBClusters returns a list of PSMDB clusters.
@@ -105,6 +106,21 @@ func (s *PSMDBClusterService) CreatePSMDBCluster(ctx context.Context, req *contr
 	}
 	defer client.Cleanup() //nolint:errcheck
 
+	params := s.psmdbParams(req)
+
+	err = client.CreatePSMDBCluster(ctx, params)
+	if err != nil {
+		if errors.Is(err, k8sclient.ErrInsufficientCapacity) {
+			return nil, status.Error(codes.FailedPrecondition, err.Error())
+		}
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	return new(controllerv1beta1.CreatePSMDBClusterResponse), nil
+}
+
+// psmdbParams converts cluster creation request to cluster parameters.
+func (s *PSMDBClusterService) psmdbParams(req *controllerv1beta1.CreatePSMDBClusterRequest) *k8sclient.PSMDBParams {
 	params := &k8sclient.PSMDBParams{
 		Name:  req.Name,
 		Image: req.Params.Image,
@@ -114,6 +130,7 @@ func (s *PSMDBClusterService) CreatePSMDBCluster(ctx context.Context, req *contr
 		},
 		Expose:            req.Expose,
 		VersionServiceURL: req.Params.VersionServiceUrl,
+		PasswordPolicy:    s.passwordPolicy,
 	}
 
 	if req.Pmm != nil {
@@ -127,13 +144,26 @@ func (s *PSMDBClusterService) CreatePSMDBCluster(ctx context.Context, req *contr
 	if req.Params.Replicaset.ComputeResources != nil {
 		params.Replicaset.ComputeResources = computeResources(req.Params.Replicaset.ComputeResources)
 	}
+	return params
+}
 
-	err = client.CreatePSMDBCluster(ctx, params)
+// CheckPSMDBClusterCapacity checks that PSMDB cluster with parameters of the creation request fits into
+// Kubernetes cluster without creating anything. It returns FailedPrecondition error with the reason if it doesn't.
+func (s *PSMDBClusterService) CheckPSMDBClusterCapacity(ctx context.Context, req *controllerv1beta1.CreatePSMDBClusterRequest) error {
+	client, err := k8sclient.New(ctx, req.KubeAuth.Kubeconfig)
 	if err != nil {
-		return nil, status.Error(codes.Internal, err.Error())
+		return status.Error(codes.Internal, err.Error())
 	}
+	defer client.Cleanup() //nolint:errcheck
 
-	return new(controllerv1beta1.CreatePSMDBClusterResponse), nil
+	err = client.CheckPSMDBClusterCapacity(ctx, s.psmdbParams(req))
+	if err != nil {
+		if errors.Is(err, k8sclient.ErrInsufficientCapacity) {
+			return status.Error(codes.FailedPrecondition, err.Error())
+		}
+		return status.Error(codes.Internal, err.Error())
+	}
+	return nil
 }
 
 // UpdatePSMDBCluster updates existing PSMDB cluster.
@@ -171,6 +201,9 @@ func (s *PSMDBClusterService) UpdatePSMDBCluster(ctx context.Context, req *contr
 
 	err = client.UpdatePSMDBCluster(ctx, params)
 	if err != nil {
+		if errors.Is(err, k8sclient.ErrInsufficientCapacity) {
+			return nil, status.Error(codes.FailedPrecondition, err.Error())
+		}
 		return nil, status.Error(codes.Internal, err.Error())
 	}
 
@@ -44,12 +44,13 @@ var dbClusterStatesMap = map[k8sclient.ClusterState]controllerv1beta1.DBClusterS
 
 // PXCClusterService implements methods of gRPC server and other business logic related to PXC clusters.
 type PXCClusterService struct {
-	p *message.Printer
+	p              *message.Printer
+	passwordPolicy *k8sclient.PasswordPolicy
 }
 
 // NewPXCClusterService returns new PXCClusterService instance.
-func NewPXCClusterService(p *message.Printer) *PXCClusterService {
-	return &PXCClusterService{p: p}
+func NewPXCClusterService(p *message.Printer, passwordPolicy *k8sclient.PasswordPolicy) *PXCClusterService {
+	return &PXCClusterService{p: p, passwordPolicy: passwordPolicy}
 }
 
 // setComputeResources converts input resources and sets them to output compute resources.
@@ -157,6 +158,19 @@ func (s *PXCClusterService) CreatePXCCluster(ctx context.Context, req *controlle
 	}
 	defer client.Cleanup() //nolint:errcheck
 
+	params := s.pxcParams(req)
+	err = client.CreatePXCCluster(ctx, params)
+	if err != nil {
+		if errors.Is(err, k8sclient.ErrInsufficientCapacity) {
+			return nil, status.Error(codes.FailedPrecondition, err.Error())
+		}
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	return new(controllerv1beta1.CreatePXCClusterResponse), nil
+}
+
+// pxcParams converts cluster creation request to cluster parameters.
+func (s *PXCClusterService) pxcParams(req *controllerv1beta1.CreatePXCClusterRequest) *k8sclient.PXCParams {
 	params := &k8sclient.PXCParams{
 		Name: req.Name,
 		Size: req.Params.ClusterSize,
@@ -167,6 +181,7 @@ func (s *PXCClusterService) CreatePXCCluster(ctx context.Context, req *controlle
 		},
 		Expose:            req.Expose,
 		VersionServiceURL: req.Params.VersionServiceUrl,
+		PasswordPolicy:    s.passwordPolicy,
 	}
 	if req.Params.Proxysql != nil {
 		params.ProxySQL = &k8sclient.ProxySQL{
@@ -188,11 +203,26 @@ func (s *PXCClusterService) CreatePXCCluster(ctx context.Context, req *controlle
 			Password:      req.Pmm.Password,
 		}
 	}
-	err = client.CreatePXCCluster(ctx, params)
+	return params
+}
+
+// CheckPXCClusterCapacity checks that PXC cluster with parameters of the creation request fits into
+// Kubernetes cluster without creating anything. It returns FailedPrecondition error with the reason if it doesn't.
+func (s *PXCClusterService) CheckPXCClusterCapacity(ctx context.Context, req *controllerv1beta1.CreatePXCClusterRequest) error {
+	client, err := k8sclient.New(ctx, req.KubeAuth.Kubeconfig)
 	if err != nil {
-		return nil, status.Error(codes.Internal, err.Error())
+		return status.Error(codes.Internal, err.Error())
 	}
-	return new(controllerv1beta1.CreatePXCClusterResponse), nil
+	defer client.Cleanup() //nolint:errcheck
+
+	err = client.CheckPXCClusterCapacity(ctx, s.pxcParams(req))
+	if err != nil {
+		if errors.Is(err, k8sclient.ErrInsufficientCapacity) {
+			return status.Error(codes.FailedPrecondition, err.Error())
+		}
+		return status.Error(codes.Internal, err.Error())
+	}
+	return nil
 }
 
 func computeResources(pxcRes *controllerv1beta1.ComputeResources) *k8sclient.ComputeResources {
@@ -245,6 +275,9 @@ func (s *PXCClusterService) UpdatePXCCluster(ctx context.Context, req *controlle
 
 	err = client.UpdatePXCCluster(ctx, params)
 	if err != nil {
+		if errors.Is(err, k8sclient.ErrInsufficientCapacity) {
+			return nil, status.Error(codes.FailedPrecondition, err.Error())
+		}
 		return nil, status.Error(codes.Internal, err.Error())
 	}
 
@@ -0,0 +1,635 @@
+// dbaas-controller
+// Copyright (C) 2020 Percona LLC
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package k8sclient
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/percona-platform/dbaas-controller/service/k8sclient/common"
+	"github.com/percona-platform/dbaas-controller/service/k8sclient/internal/psmdb"
+	"github.com/percona-platform/dbaas-controller/service/k8sclient/internal/pxc"
+	"github.com/percona-platform/dbaas-controller/utils/convertors"
+)
+
+// ErrInsufficientCapacity is returned when Kubernetes cluster does not have enough
+// resources to run a database cluster.
+var ErrInsufficientCapacity = errors.New("not enough resources in Kubernetes cluster")
+
+// podGroup describes a group of identical pods of a database cluster.
+type podGroup struct {
+	name        string
+	count       int32
+	cpuMillis   uint64
+	memoryBytes uint64
+	// antiAffinity requires pods of the group to run on different nodes.
+	antiAffinity bool
+	// selector contains labels of running pods of the group.
+	selector map[string]string
+	// replacing is true for pods which replace running pods of the group and request only the increase
+	// of resources. Each of them is placed on the node of the pod it replaces, see nodes.
+	replacing bool
+	// nodes are names of nodes running pods of the group.
+	nodes []string
+}
+
+// Footprint is the amount of resources a database cluster requests.
+type Footprint struct {
+	CPUMillis   uint64
+	MemoryBytes uint64
+	DiskBytes   uint64
+	pods        []podGroup
+}
+
+// NodeResources holds resources of a single worker node.
+type NodeResources struct {
+	Name   string         `json:"name"`
+	Zone   string         `json:"zone,omitempty"`
+	Taints []common.Taint `json:"taints,omitempty"`
+
+	AllocatableCPUMillis   uint64 `json:"allocatableCpuMillis"`
+	AllocatableMemoryBytes uint64 `json:"allocatableMemoryBytes"`
+	RequestedCPUMillis     uint64 `json:"requestedCpuMillis"`
+	RequestedMemoryBytes   uint64 `json:"requestedMemoryBytes"`
+	FreeCPUMillis          uint64 `json:"freeCpuMillis"`
+	FreeMemoryBytes        uint64 `json:"freeMemoryBytes"`
+}
+
+// Schedulable returns false if node has a taint that prevents database pods from being scheduled
+// or executed on it.
+func (n *NodeResources) Schedulable() bool {
+	for _, taint := range n.Taints {
+		if taint.Effect == common.TaintEffectNoSchedule || taint.Effect == common.TaintEffectNoExecute {
+			return false
+		}
+	}
+	return true
+}
+
+func (f *Footprint) addPods(group podGroup) {
+	if group.count <= 0 {
+		return
+	}
+	f.pods = append(f.pods, group)
+	f.CPUMillis += uint64(group.count) * group.cpuMillis
+	f.MemoryBytes += uint64(group.count) * group.memoryBytes
+}
+
+func (f *Footprint) addVolumes(count int32, volumeSpec *common.VolumeSpec) error {
+	if count <= 0 || volumeSpec == nil || volumeSpec.PersistentVolumeClaim == nil {
+		return nil
+	}
+	bytes, err := convertors.StrToBytes(volumeSpec.PersistentVolumeClaim.Resources.Requests[common.ResourceStorage])
+	if err != nil {
+		return errors.Wrap(err, "failed to get volume size")
+	}
+	f.DiskBytes += uint64(count) * bytes
+	return nil
+}
+
+// growth returns footprint of pods and volumes that have to be added to get from old footprint to f.
+// Added pods request full resources. Pods that are kept but request more resources are replaced
+// one by one, so each of them needs only the increase on the node the replaced pod frees;
+// such increases are counted as a separate group of replacing pods.
+func (f *Footprint) growth(old *Footprint) *Footprint {
+	oldGroups := make(map[string]podGroup, len(old.pods))
+	for _, group := range old.pods {
+		oldGroups[group.name] = group
+	}
+	res := new(Footprint)
+	for _, group := range f.pods {
+		oldGroup, ok := oldGroups[group.name]
+		if !ok {
+			res.addPods(group)
+			continue
+		}
+		kept := group.count
+		if oldGroup.count < kept {
+			kept = oldGroup.count
+		}
+		resized := podGroup{
+			name:         group.name,
+			count:        kept,
+			cpuMillis:    subtractFloor(group.cpuMillis, oldGroup.cpuMillis),
+			memoryBytes:  subtractFloor(group.memoryBytes, oldGroup.memoryBytes),
+			antiAffinity: group.antiAffinity,
+			selector:     group.selector,
+			replacing:    true,
+		}
+		if resized.cpuMillis > 0 || resized.memoryBytes > 0 {
+			res.addPods(resized)
+		}
+		group.count -= oldGroup.count
+		res.addPods(group)
+	}
+	if f.DiskBytes > old.DiskBytes {
+		res.DiskBytes = f.DiskBytes - old.DiskBytes
+	}
+	return res
+}
+
+// podResourcesRequests returns requested CPU and memory. Limits are used for resources
+// without requests the same way Kubernetes does.
+func podResourcesRequests(resources *common.PodResources) (cpuMillis uint64, memoryBytes uint64, err error) {
+	if resources == nil {
+		return 0, 0, nil
+	}
+	var cpu, memory string
+	for _, list := range []*common.ResourcesList{resources.Limits, resources.Requests} {
+		if list == nil {
+			continue
+		}
+		if list.CPU != "" {
+			cpu = list.CPU
+		}
+		if list.Memory != "" {
+			memory = list.Memory
+		}
+	}
+	return getResources(common.ResourceList{
+		common.ResourceCPU:    cpu,
+		common.ResourceMemory: memory,
+	})
+}
+
+// newPodGroup returns pod group with resources of the main container and optional sidecar.
+// Selector contains labels of the group's pods.
+func newPodGroup(
+	name string, count int32, resources, sidecar *common.PodResources, antiAffinity bool, selector map[string]string,
+) (podGroup, error) {
+	cpu, memory, err := podResourcesRequests(resources)
+	if err != nil {
+		return podGroup{}, errors.Wrapf(err, "failed to get resources of %s pods", name)
+	}
+	sidecarCPU, sidecarMemory, err := podResourcesRequests(sidecar)
+	if err != nil {
+		return podGroup{}, errors.Wrapf(err, "failed to get sidecar resources of %s pods", name)
+	}
+	return podGroup{
+		name:         name,
+		count:        count,
+		cpuMillis:    cpu + sidecarCPU,
+		memoryBytes:  memory + sidecarMemory,
+		antiAffinity: antiAffinity,
+		selector:     selector,
+	}, nil
+}
+
+// pxcFootprint returns resources requested by Percona XtraDB cluster.
+// Paused cluster keeps its volumes, but runs no pods.
+func pxcFootprint(cluster *pxc.PerconaXtraDBCluster) (*Footprint, error) {
+	var pmm *common.PodResources
+	if cluster.Spec.PMM != nil && cluster.Spec.PMM.Enabled {
+		pmm = cluster.Spec.PMM.Resources
+	}
+	footprint := new(Footprint)
+	components := []struct {
+		name    string
+		spec    *pxc.PodSpec
+		enabled bool
+	}{
+		{name: "pxc", spec: cluster.Spec.PXC, enabled: true},
+		{name: "proxysql", spec: cluster.Spec.ProxySQL, enabled: cluster.Spec.ProxySQL != nil && cluster.Spec.ProxySQL.Enabled},
+		{name: "haproxy", spec: cluster.Spec.HAProxy, enabled: cluster.Spec.HAProxy != nil && cluster.Spec.HAProxy.Enabled},
+	}
+	for _, component := range components {
+		if !component.enabled || component.spec == nil || component.spec.Size == nil {
+			continue
+		}
+		antiAffinity := component.spec.Affinity != nil && component.spec.Affinity.TopologyKey != nil &&
+			*component.spec.Affinity.TopologyKey != pxc.AffinityTopologyKeyOff
+		selector := map[string]string{
+			common.LabelInstance:  cluster.Name,
+			common.LabelComponent: component.name,
+		}
+		group, err := newPodGroup(component.name, *component.spec.Size, component.spec.Resources, pmm, antiAffinity, selector)
+		if err != nil {
+			return nil, err
+		}
+		if !cluster.Spec.Pause {
+			footprint.addPods(group)
+		}
+		if err := footprint.addVolumes(*component.spec.Size, component.spec.VolumeSpec); err != nil {
+			return nil, err
+		}
+	}
+	if cluster.Spec.Backup != nil {
+		for _, storage := range cluster.Spec.Backup.Storages {
+			if storage == nil || storage.Type != pxc.BackupStorageFilesystem {
+				continue
+			}
+			if err := footprint.addVolumes(1, storage.Volume); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return footprint, nil
+}
+
+// psmdbFootprint returns resources requested by Percona Server for MongoDB cluster.
+// Paused cluster keeps its volumes, but runs no pods.
+func psmdbFootprint(cluster *psmdb.PerconaServerMongoDB) (*Footprint, error) {
+	var pmm *common.PodResources
+	if cluster.Spec.PMM != nil && cluster.Spec.PMM.Enabled {
+		pmm = cluster.Spec.PMM.Resources
+	}
+	replsets := make(map[string]*psmdb.ReplsetSpec, len(cluster.Spec.Replsets)+2)
+	for _, rs := range cluster.Spec.Replsets {
+		replsets[rs.Name] = rs
+	}
+	if cluster.Spec.Sharding != nil && cluster.Spec.Sharding.Enabled {
+		replsets["cfg"] = cluster.Spec.Sharding.ConfigsvrReplSet
+		replsets["mongos"] = cluster.Spec.Sharding.Mongos
+	}
+
+	names := make([]string, 0, len(replsets))
+	for name := range replsets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	footprint := new(Footprint)
+	for _, name := range names {
+		rs := replsets[name]
+		if rs == nil {
+			continue
+		}
+		antiAffinity := rs.Affinity != nil && rs.Affinity.TopologyKey != nil && *rs.Affinity.TopologyKey != psmdb.AffinityOff
+		selector := map[string]string{common.LabelInstance: cluster.Name, common.LabelReplset: name}
+		if name == "mongos" {
+			selector = map[string]string{common.LabelInstance: cluster.Name, common.LabelComponent: name}
+		}
+		group, err := newPodGroup(name, rs.Size, rs.Resources, pmm, antiAffinity, selector)
+		if err != nil {
+			return nil, err
+		}
+		if !cluster.Spec.Pause {
+			footprint.addPods(group)
+		}
+		if name == "mongos" {
+			continue
+		}
+		if err := footprint.addVolumes(rs.Size, rs.VolumeSpec); err != nil {
+			return nil, err
+		}
+	}
+	return footprint, nil
+}
+
+// podRequests returns CPU and memory requested by pod's containers and init containers that did not terminate yet.
+func podRequests(pod common.Pod) (cpuMillis uint64, memoryBytes uint64, err error) {
+	nonTerminatedInitContainers := make([]common.ContainerSpec, 0, len(pod.Spec.InitContainers))
+	for _, container := range pod.Spec.InitContainers {
+		if !common.IsContainerInState(
+			pod.Status.InitContainerStatuses, common.ContainerStateTerminated, container.Name,
+		) {
+			nonTerminatedInitContainers = append(nonTerminatedInitContainers, container)
+		}
+	}
+	for _, container := range append(pod.Spec.Containers, nonTerminatedInitContainers...) {
+		cpu, memory, err := getResources(container.Resources.Requests)
+		if err != nil {
+			return 0, 0, err
+		}
+		cpuMillis += cpu
+		memoryBytes += memory
+	}
+	return cpuMillis, memoryBytes, nil
+}
+
+// GetNodesResources returns allocatable, requested and free resources of worker nodes.
+// Resources requested by pods scheduled to the node are subtracted from node's allocatable resources.
+func (c *K8sClient) GetNodesResources(ctx context.Context) ([]NodeResources, error) {
+	nodes, err := c.getWorkerNodes(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not get a list of nodes")
+	}
+	pods, err := c.getActivePods(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return nodesResources(nodes, pods)
+}
+
+// getActivePods returns pods of all namespaces which did not terminate yet.
+func (c *K8sClient) getActivePods(ctx context.Context) ([]common.Pod, error) {
+	pods, err := c.GetPods(ctx, "--all-namespaces", "--field-selector=status.phase!=Succeeded,status.phase!=Failed")
+	if err != nil {
+		return nil, errors.Wrap(err, "could not get a list of pods")
+	}
+	return pods.Items, nil
+}
+
+// nodesResources returns resources of given nodes with resources requested by given pods subtracted.
+func nodesResources(nodes []common.Node, pods []common.Pod) ([]NodeResources, error) {
+	type requests struct {
+		cpuMillis   uint64
+		memoryBytes uint64
+	}
+	requested := make(map[string]requests, len(nodes))
+	for _, pod := range pods {
+		if pod.Spec.NodeName == "" || pod.Status.Phase == common.PodPhaseSucceded || pod.Status.Phase == common.PodPhaseFailed {
+			continue
+		}
+		cpu, memory, err := podRequests(pod)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to get resources requested by pod %q", pod.Name)
+		}
+		r := requested[pod.Spec.NodeName]
+		r.cpuMillis += cpu
+		r.memoryBytes += memory
+		requested[pod.Spec.NodeName] = r
+	}
+
+	res := make([]NodeResources, 0, len(nodes))
+	for _, node := range nodes {
+		cpu, memory, err := getResources(node.Status.Allocatable)
+		if err != nil {
+			return nil, errors.Wrap(err, "could not get allocatable resources of the node")
+		}
+		r := requested[node.Name]
+		res = append(res, NodeResources{
+			Name:                   node.Name,
+			Zone:                   nodeZone(node),
+			Taints:                 node.Spec.Taints,
+			AllocatableCPUMillis:   cpu,
+			AllocatableMemoryBytes: memory,
+			RequestedCPUMillis:     r.cpuMillis,
+			RequestedMemoryBytes:   r.memoryBytes,
+			FreeCPUMillis:          subtractFloor(cpu, r.cpuMillis),
+			FreeMemoryBytes:        subtractFloor(memory, r.memoryBytes),
+		})
+	}
+	return res, nil
+}
+
+// podNodes returns names of nodes running pods which have all labels of the selector, ordered by pod names.
+func podNodes(pods []common.Pod, selector map[string]string) []string {
+	matched := make([]common.Pod, 0, len(pods))
+	for _, pod := range pods {
+		if pod.Spec.NodeName == "" || len(selector) == 0 {
+			continue
+		}
+		match := true
+		for k, v := range selector {
+			if pod.Labels[k] != v {
+				match = false
+				break
+			}
+		}
+		if match {
+			matched = append(matched, pod)
+		}
+	}
+	sort.Slice(matched, func(i, j int) bool { return matched[i].Name < matched[j].Name })
+	nodes := make([]string, len(matched))
+	for i, pod := range matched {
+		nodes[i] = pod.Spec.NodeName
+	}
+	return nodes
+}
+
+// nodeZone returns availability zone of the node or an empty string if it's unknown.
+func nodeZone(node common.Node) string {
+	if zone := node.Labels[common.LabelTopologyZone]; zone != "" {
+		return zone
+	}
+	return node.Labels[common.LabelFailureDomainBetaZone]
+}
+
+// EstimateSchedulablePods returns how many pods requesting given resources could still
+// be scheduled on the nodes. If antiAffinity is true, at most one pod is placed on each node.
+func EstimateSchedulablePods(nodes []NodeResources, cpuMillis, memoryBytes uint64, antiAffinity bool) uint64 {
+	var count uint64
+	for _, node := range nodes {
+		if !node.Schedulable() {
+			continue
+		}
+		fit := uint64(math.MaxUint64)
+		if cpuMillis > 0 {
+			fit = node.FreeCPUMillis / cpuMillis
+		}
+		if memoryBytes > 0 && node.FreeMemoryBytes/memoryBytes < fit {
+			fit = node.FreeMemoryBytes / memoryBytes
+		}
+		if fit == math.MaxUint64 {
+			// Pods don't request anything, scheduling is not limited by resources.
+			return math.MaxUint64
+		}
+		if antiAffinity && fit > 1 {
+			fit = 1
+		}
+		count += fit
+	}
+	return count
+}
+
+// EstimateSchedulablePods returns how many pods requesting given resources could still
+// be scheduled in the Kubernetes cluster.
+func (c *K8sClient) EstimateSchedulablePods(ctx context.Context, cpuMillis, memoryBytes uint64, antiAffinity bool) (uint64, error) {
+	nodes, err := c.GetNodesResources(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return EstimateSchedulablePods(nodes, cpuMillis, memoryBytes, antiAffinity), nil
+}
+
+// subtractFloor returns a - b or zero if b is greater than a.
+func subtractFloor(a, b uint64) uint64 {
+	if b > a {
+		return 0
+	}
+	return a - b
+}
+
+// fitPods tries to place all pods of the footprint on given nodes. It returns
+// the reason why they don't fit or an empty string.
+func fitPods(nodes []NodeResources, footprint *Footprint) string {
+	free := make([]NodeResources, 0, len(nodes))
+	for _, node := range nodes {
+		if node.Schedulable() {
+			free = append(free, node)
+		}
+	}
+
+	var availableCPU, availableMemory uint64
+	for _, node := range free {
+		availableCPU += node.FreeCPUMillis
+		availableMemory += node.FreeMemoryBytes
+	}
+	if footprint.CPUMillis > availableCPU {
+		return fmt.Sprintf("%d millicpus are required, but only %d are available", footprint.CPUMillis, availableCPU)
+	}
+	if footprint.MemoryBytes > availableMemory {
+		return fmt.Sprintf("%d bytes of memory are required, but only %d are available", footprint.MemoryBytes, availableMemory)
+	}
+
+	// Place the biggest pods first.
+	groups := make([]podGroup, len(footprint.pods))
+	copy(groups, footprint.pods)
+	sort.SliceStable(groups, func(i, j int) bool {
+		if groups[i].cpuMillis != groups[j].cpuMillis {
+			return groups[i].cpuMillis > groups[j].cpuMillis
+		}
+		return groups[i].memoryBytes > groups[j].memoryBytes
+	})
+
+	nodeIndexes := make(map[string]int, len(free))
+	for i, node := range free {
+		nodeIndexes[node.Name] = i
+	}
+
+	for _, group := range groups {
+		used := make(map[int]struct{}, group.count)
+		for i := int32(0); i < group.count; i++ {
+			// Replacing pod needs the increase of resources on the node of the pod it replaces.
+			if j, ok := replacedPodNode(group, i, nodeIndexes); ok {
+				if free[j].FreeCPUMillis < group.cpuMillis || free[j].FreeMemoryBytes < group.memoryBytes {
+					return fmt.Sprintf(
+						"node %s can't run resized %s pod requesting %d more millicpus and %d more bytes of memory",
+						free[j].Name, group.name, group.cpuMillis, group.memoryBytes,
+					)
+				}
+				used[j] = struct{}{}
+				free[j].FreeCPUMillis -= group.cpuMillis
+				free[j].FreeMemoryBytes -= group.memoryBytes
+				continue
+			}
+
+			// Best fit: choose the node with the least free CPU that can still run the pod.
+			best := -1
+			for j, node := range free {
+				if _, ok := used[j]; ok && group.antiAffinity {
+					continue
+				}
+				if node.FreeCPUMillis < group.cpuMillis || node.FreeMemoryBytes < group.memoryBytes {
+					continue
+				}
+				if best < 0 || node.FreeCPUMillis < free[best].FreeCPUMillis {
+					best = j
+				}
+			}
+			if best < 0 {
+				reason := fmt.Sprintf(
+					"no node can run %s pod %d of %d requesting %d millicpus and %d bytes of memory",
+					group.name, i+1, group.count, group.cpuMillis, group.memoryBytes,
+				)
+				if group.antiAffinity {
+					reason += ", pods have to run on different nodes"
+				}
+				return reason
+			}
+			used[best] = struct{}{}
+			free[best].FreeCPUMillis -= group.cpuMillis
+			free[best].FreeMemoryBytes -= group.memoryBytes
+		}
+	}
+	return ""
+}
+
+// replacedPodNode returns index of the node running the pod which i-th pod of the group replaces.
+// It returns false if the pod doesn't replace a running pod on a schedulable node.
+func replacedPodNode(group podGroup, i int32, nodeIndexes map[string]int) (int, bool) {
+	if !group.replacing || int(i) >= len(group.nodes) {
+		return 0, false
+	}
+	j, ok := nodeIndexes[group.nodes[i]]
+	return j, ok
+}
+
+// checkCapacity checks that the footprint fits into the Kubernetes cluster.
+// It returns error wrapping ErrInsufficientCapacity with the reason if it doesn't.
+func (c *K8sClient) checkCapacity(ctx context.Context, footprint *Footprint) error {
+	if len(footprint.pods) == 0 && footprint.DiskBytes == 0 {
+		return nil
+	}
+	// Nodes are fetched once for both pods and disk checks.
+	workers, err := c.getWorkerNodes(ctx)
+	if err != nil {
+		return errors.Wrap(err, "failed to check capacity")
+	}
+	var reasons []string
+	if len(footprint.pods) > 0 {
+		pods, err := c.getActivePods(ctx)
+		if err != nil {
+			return errors.Wrap(err, "failed to check capacity")
+		}
+		nodes, err := nodesResources(workers, pods)
+		if err != nil {
+			return errors.Wrap(err, "failed to check capacity")
+		}
+		for i, group := range footprint.pods {
+			if group.replacing {
+				footprint.pods[i].nodes = podNodes(pods, group.selector)
+			}
+		}
+		if reason := fitPods(nodes, footprint); reason != "" {
+			reasons = append(reasons, reason)
+		}
+	}
+
+	if footprint.DiskBytes > 0 {
+		availableDisk, known, err := c.getAvailableDiskBytes(ctx, workers)
+		if err != nil {
+			// Disk accounting is best effort, don't block cluster creation because of it.
+			c.l.Warnf("failed to get available disk size: %v", err)
+		} else if known && footprint.DiskBytes > availableDisk {
+			reasons = append(reasons, fmt.Sprintf(
+				"%d bytes of disk are required, but only %d are available", footprint.DiskBytes, availableDisk,
+			))
+		}
+	}
+
+	if len(reasons) > 0 {
+		return errors.Wrap(ErrInsufficientCapacity, strings.Join(reasons, "; "))
+	}
+	return nil
+}
+
+// getAvailableDiskBytes returns disk size available for new volumes on given worker nodes. Known is false
+// if disk size can't be determined for the Kubernetes cluster type.
+func (c *K8sClient) getAvailableDiskBytes(ctx context.Context, nodes []common.Node) (availableBytes uint64, known bool, err error) {
+	clusterType := c.GetKubernetesClusterType(ctx)
+	if !clusterType.IsLocal() && !clusterType.UsesAttachableVolumes() {
+		return 0, false, nil
+	}
+	var volumes *common.PersistentVolumeList
+	if clusterType.UsesAttachableVolumes() {
+		volumes, err = c.GetPersistentVolumes(ctx)
+		if err != nil {
+			return 0, false, err
+		}
+	}
+	_, _, allBytes, err := c.allClusterResources(ctx, nodes, clusterType, volumes)
+	if err != nil {
+		return 0, false, err
+	}
+	if allBytes == 0 && !clusterType.IsLocal() {
+		// Volume attach limits of nodes are not known.
+		return 0, false, nil
+	}
+	consumedBytes, err := c.GetConsumedDiskBytes(ctx, clusterType, volumes)
+	if err != nil {
+		return 0, false, err
+	}
+	return subtractFloor(allBytes, consumedBytes), true, nil
+}
@@ -0,0 +1,251 @@
+// dbaas-controller
+// Copyright (C) 2020 Percona LLC
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package k8sclient
+
+import (
+	"testing"
+
+	"github.com/AlekSi/pointer"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/percona-platform/dbaas-controller/service/k8sclient/common"
+	"github.com/percona-platform/dbaas-controller/service/k8sclient/internal/psmdb"
+	"github.com/percona-platform/dbaas-controller/service/k8sclient/internal/pxc"
+)
+
+func volume(size string) *common.VolumeSpec {
+	return &common.VolumeSpec{
+		PersistentVolumeClaim: &common.PersistentVolumeClaimSpec{
+			Resources: common.ResourceRequirements{
+				Requests: common.ResourceList{common.ResourceStorage: size},
+			},
+		},
+	}
+}
+
+func TestPXCFootprint(t *testing.T) {
+	t.Parallel()
+	cluster := &pxc.PerconaXtraDBCluster{
+		Spec: &pxc.PerconaXtraDBClusterSpec{
+			PXC: &pxc.PodSpec{
+				Size:       pointer.ToInt32(3),
+				Resources:  &common.PodResources{Limits: &common.ResourcesList{CPU: "1", Memory: "2G"}},
+				VolumeSpec: volume("10G"),
+			},
+			HAProxy: &pxc.PodSpec{
+				Enabled:   true,
+				Size:      pointer.ToInt32(3),
+				Resources: &common.PodResources{Limits: &common.ResourcesList{CPU: "500m", Memory: "1G"}},
+			},
+			PMM: &pxc.PMMSpec{
+				Enabled:   true,
+				Resources: &common.PodResources{Requests: &common.ResourcesList{CPU: "500m", Memory: "300M"}},
+			},
+			Backup: &pxc.PXCScheduledBackup{
+				Storages: map[string]*pxc.BackupStorageSpec{
+					"backup": {Type: pxc.BackupStorageFilesystem, Volume: volume("10G")},
+				},
+			},
+		},
+	}
+	footprint, err := pxcFootprint(cluster)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(3*1500+3*1000), footprint.CPUMillis)
+	assert.Equal(t, uint64(3*2300000000+3*1300000000), footprint.MemoryBytes)
+	assert.Equal(t, uint64(4*10000000000), footprint.DiskBytes)
+
+	cluster.Spec.PXC.Size = pointer.ToInt32(5)
+	cluster.Spec.HAProxy.Size = pointer.ToInt32(5)
+	grown, err := pxcFootprint(cluster)
+	require.NoError(t, err)
+	growth := grown.growth(footprint)
+	assert.Equal(t, uint64(2*1500+2*1000), growth.CPUMillis)
+	assert.Equal(t, uint64(2*10000000000), growth.DiskBytes)
+
+	cluster.Spec.PXC.Resources = &common.PodResources{Limits: &common.ResourcesList{CPU: "2", Memory: "2G"}}
+	resized, err := pxcFootprint(cluster)
+	require.NoError(t, err)
+	growth = resized.growth(grown)
+	assert.Equal(t, uint64(5*1000), growth.CPUMillis, "each PXC pod requests 1 more CPU")
+	require.Len(t, growth.pods, 1)
+	assert.True(t, growth.pods[0].replacing)
+	assert.Zero(t, growth.MemoryBytes)
+	assert.Zero(t, growth.DiskBytes)
+
+	cluster.Spec.Pause = true
+	paused, err := pxcFootprint(cluster)
+	require.NoError(t, err)
+	assert.Zero(t, paused.CPUMillis)
+	assert.Equal(t, resized.DiskBytes, paused.DiskBytes)
+	assert.Zero(t, paused.growth(resized).CPUMillis)
+	growth = resized.growth(paused)
+	assert.Equal(t, resized.CPUMillis, growth.CPUMillis, "resumed cluster needs all its pods")
+	assert.Zero(t, growth.DiskBytes)
+}
+
+func TestPSMDBFootprint(t *testing.T) {
+	t.Parallel()
+	affinity := &psmdb.PodAffinity{TopologyKey: pointer.ToString("kubernetes.io/hostname")}
+	resources := &common.PodResources{Limits: &common.ResourcesList{CPU: "1", Memory: "1G"}}
+	cluster := &psmdb.PerconaServerMongoDB{
+		Spec: &psmdb.PerconaServerMongoDBSpec{
+			Replsets: []*psmdb.ReplsetSpec{{
+				Name:       "rs0",
+				Size:       3,
+				Resources:  resources,
+				VolumeSpec: volume("1G"),
+				MultiAZ:    psmdb.MultiAZ{Affinity: affinity},
+			}},
+			Sharding: &psmdb.ShardingSpec{
+				Enabled: true,
+				ConfigsvrReplSet: &psmdb.ReplsetSpec{
+					Size:       3,
+					VolumeSpec: volume("1G"),
+					MultiAZ:    psmdb.MultiAZ{Affinity: affinity},
+				},
+				Mongos: &psmdb.ReplsetSpec{
+					Size:      3,
+					Resources: resources,
+					MultiAZ:   psmdb.MultiAZ{Affinity: affinity},
+				},
+			},
+		},
+	}
+	footprint, err := psmdbFootprint(cluster)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(6000), footprint.CPUMillis)
+	assert.Equal(t, uint64(6*1000000000), footprint.DiskBytes)
+	assert.Len(t, footprint.pods, 3)
+	for _, group := range footprint.pods {
+		assert.True(t, group.antiAffinity, group.name)
+	}
+}
+
+func TestFitPods(t *testing.T) {
+	t.Parallel()
+	nodes := []NodeResources{
+		{Name: "a", FreeCPUMillis: 1000, FreeMemoryBytes: 4000},
+		{Name: "b", FreeCPUMillis: 1000, FreeMemoryBytes: 4000},
+		{Name: "c", FreeCPUMillis: 1000, FreeMemoryBytes: 4000},
+	}
+
+	t.Run("Fits", func(t *testing.T) {
+		t.Parallel()
+		footprint := new(Footprint)
+		footprint.addPods(podGroup{name: "pxc", count: 3, cpuMillis: 1000, memoryBytes: 1000, antiAffinity: true})
+		assert.Empty(t, fitPods(nodes, footprint))
+	})
+
+	t.Run("Fragmented", func(t *testing.T) {
+		t.Parallel()
+		footprint := new(Footprint)
+		footprint.addPods(podGroup{name: "pxc", count: 1, cpuMillis: 2000, memoryBytes: 1000})
+		assert.Equal(t, "no node can run pxc pod 1 of 1 requesting 2000 millicpus and 1000 bytes of memory", fitPods(nodes, footprint))
+	})
+
+	t.Run("Anti-affinity", func(t *testing.T) {
+		t.Parallel()
+		footprint := new(Footprint)
+		footprint.addPods(podGroup{name: "rs0", count: 4, cpuMillis: 100, memoryBytes: 100, antiAffinity: true})
+		assert.Contains(t, fitPods(nodes, footprint), "pods have to run on different nodes")
+	})
+
+	t.Run("Replacing pods", func(t *testing.T) {
+		t.Parallel()
+		nodes := []NodeResources{
+			{Name: "a", FreeCPUMillis: 500, FreeMemoryBytes: 4000},
+			{Name: "b", FreeCPUMillis: 2000, FreeMemoryBytes: 4000},
+		}
+		footprint := new(Footprint)
+		footprint.addPods(podGroup{name: "pxc", count: 2, cpuMillis: 500, memoryBytes: 100, replacing: true, nodes: []string{"a", "b"}})
+		assert.Empty(t, fitPods(nodes, footprint))
+
+		footprint = new(Footprint)
+		footprint.addPods(podGroup{name: "pxc", count: 2, cpuMillis: 1000, memoryBytes: 100, replacing: true, nodes: []string{"a", "b"}})
+		assert.Equal(t, "node a can't run resized pxc pod requesting 1000 more millicpus and 100 more bytes of memory", fitPods(nodes, footprint))
+	})
+
+	t.Run("Not enough memory", func(t *testing.T) {
+		t.Parallel()
+		footprint := new(Footprint)
+		footprint.addPods(podGroup{name: "pxc", count: 3, cpuMillis: 100, memoryBytes: 5000})
+		assert.Equal(t, "15000 bytes of memory are required, but only 12000 are available", fitPods(nodes, footprint))
+	})
+}
+
+func TestEstimateSchedulablePods(t *testing.T) {
+	t.Parallel()
+	nodes := []NodeResources{
+		{Name: "a", FreeCPUMillis: 1000, FreeMemoryBytes: 4000},
+		{Name: "b", FreeCPUMillis: 1000, FreeMemoryBytes: 4000},
+		{Name: "c", FreeCPUMillis: 1000, FreeMemoryBytes: 4000},
+		{Name: "d", FreeCPUMillis: 8000, FreeMemoryBytes: 8000, Taints: []common.Taint{
+			{Key: "dedicated", Value: "gpu", Effect: common.TaintEffectNoSchedule},
+		}},
+	}
+
+	for _, tt := range []struct {
+		name         string
+		cpuMillis    uint64
+		memoryBytes  uint64
+		antiAffinity bool
+		expected     uint64
+	}{
+		{name: "fragmented", cpuMillis: 2000, memoryBytes: 1000, expected: 0},
+		{name: "limited by CPU", cpuMillis: 500, memoryBytes: 1000, expected: 6},
+		{name: "limited by memory", cpuMillis: 100, memoryBytes: 3000, expected: 3},
+		{name: "anti-affinity", cpuMillis: 100, memoryBytes: 100, antiAffinity: true, expected: 3},
+	} {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, tt.expected, EstimateSchedulablePods(nodes, tt.cpuMillis, tt.memoryBytes, tt.antiAffinity))
+		})
+	}
+}
+
+func TestNodeZone(t *testing.T) {
+	t.Parallel()
+	node := common.Node{}
+	assert.Empty(t, nodeZone(node))
+
+	node.Labels = map[string]string{common.LabelFailureDomainBetaZone: "us-east-1a"}
+	assert.Equal(t, "us-east-1a", nodeZone(node))
+
+	node.Labels[common.LabelTopologyZone] = "us-east-1b"
+	assert.Equal(t, "us-east-1b", nodeZone(node))
+}
+
+func TestPodNodes(t *testing.T) {
+	t.Parallel()
+	pod := func(name, node string, labels map[string]string) common.Pod {
+		var p common.Pod
+		p.Name = name
+		p.Labels = labels
+		p.Spec.NodeName = node
+		return p
+	}
+	selector := map[string]string{common.LabelInstance: "test", common.LabelComponent: "pxc"}
+	pods := []common.Pod{
+		pod("test-pxc-1", "b", selector),
+		pod("test-pxc-0", "a", selector),
+		pod("test-pxc-2", "", selector),
+		pod("test-haproxy-0", "c", map[string]string{common.LabelInstance: "test", common.LabelComponent: "haproxy"}),
+	}
+	assert.Equal(t, []string{"a", "b"}, podNodes(pods, selector))
+}
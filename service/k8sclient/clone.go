@@ -0,0 +1,451 @@
+// dbaas-controller
+// Copyright (C) 2020 Percona LLC
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package k8sclient
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/percona-platform/dbaas-controller/service/k8sclient/common"
+	"github.com/percona-platform/dbaas-controller/service/k8sclient/internal/kubectl"
+	"github.com/percona-platform/dbaas-controller/service/k8sclient/internal/psmdb"
+	"github.com/percona-platform/dbaas-controller/service/k8sclient/internal/pxc"
+)
+
+const (
+	cloneRestoreNameTmpl = "clone-%s"
+	// pxcDefaultSecretNameTmpl is the name of secret PXC operator uses if the cluster doesn't set secretsName.
+	pxcDefaultSecretNameTmpl = "%s-secrets"
+	// cloneTotalSteps is a number of steps of clone operation: cluster created,
+	// data restored and cluster ready.
+	cloneTotalSteps = 3
+)
+
+// ErrNoBackupToClone is returned when source cluster has no successful backup to seed a clone with.
+var ErrNoBackupToClone = errors.New("no successful backup to clone the cluster from")
+
+// ErrInvalidCloneSource is returned when spec of the source cluster can't be used for a clone.
+var ErrInvalidCloneSource = errors.New("source cluster can't be cloned")
+
+// CloneParams contains parameters required to clone a database cluster.
+type CloneParams struct {
+	// SourceName is the name of the cluster to clone.
+	SourceName string
+	// Name is the name of the new cluster.
+	Name string
+	// BackupName is the name of the source cluster's backup the new cluster is seeded with.
+	// The latest successful backup is used if empty.
+	BackupName string
+	// Size overrides number of database pods of the new cluster if greater than zero.
+	// Proxies and routers keep the size of the source cluster.
+	Size int32
+	// ComputeResources overrides compute resources of database containers if not nil.
+	ComputeResources *ComputeResources
+}
+
+// CloneOperation represents progress of cloning a database cluster.
+type CloneOperation struct {
+	FinishedSteps int32  `json:"finishedSteps"`
+	TotalSteps    int32  `json:"totalSteps"`
+	Message       string `json:"message"`
+	// Failed is true if data could not be restored into the new cluster.
+	Failed bool `json:"failed"`
+}
+
+// deepCopy copies src into dst through its JSON representation.
+func deepCopy(src, dst interface{}) error {
+	b, err := json.Marshal(src)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, dst)
+}
+
+// clonePXCBackup returns backup configuration of a clone: storages are kept, so the clone could be
+// backed up on demand, but schedules are cleared, so the clone does not write its backups next to
+// the source cluster's ones. Point-in-time recovery is not part of the spec we copy.
+func clonePXCBackup(backup *pxc.PXCScheduledBackup) *pxc.PXCScheduledBackup {
+	if backup == nil {
+		return nil
+	}
+	res := *backup
+	res.Schedule = nil
+	return &res
+}
+
+// clonePSMDBBackup returns backup configuration of a clone. Backup agent has to stay enabled,
+// since it restores the data, but scheduled tasks are cleared.
+func clonePSMDBBackup(backup *psmdb.BackupSpec) *psmdb.BackupSpec {
+	if backup == nil {
+		return nil
+	}
+	res := *backup
+	res.Tasks = nil
+	return &res
+}
+
+// completedAfter returns true if completion time a is after b. Unknown time is treated as the oldest.
+func completedAfter(a, b *time.Time) bool {
+	if a == nil {
+		return false
+	}
+	return b == nil || a.After(*b)
+}
+
+// copySecret copies data of the secret with sourceName into a new secret with given name.
+func (c *K8sClient) copySecret(ctx context.Context, sourceName, name string) error {
+	var secret common.Secret
+	err := c.kubeCtl.Get(ctx, k8sMetaKindSecret, sourceName, &secret)
+	if err != nil {
+		return errors.Wrapf(err, "cannot get secret %q", sourceName)
+	}
+	return c.CreateSecret(ctx, name, secret.Data)
+}
+
+// createClone copies the secret of the source cluster and creates the clone. The copied secret
+// is deleted if the clone can't be created, so a failed attempt doesn't leave it behind.
+func (c *K8sClient) createClone(ctx context.Context, sourceSecretName, secretName string, clone interface{}) error {
+	// Restored data contains system users of the source cluster, so the clone has to use their passwords.
+	if err := c.copySecret(ctx, sourceSecretName, secretName); err != nil {
+		return err
+	}
+	if err := c.kubeCtl.Apply(ctx, clone); err != nil {
+		if delErr := c.deleteSecret(ctx, secretName); delErr != nil {
+			c.l.Warnf("failed to delete secret %q of the clone: %v", secretName, delErr)
+		}
+		return err
+	}
+	return nil
+}
+
+// ClonePXCCluster creates a new Percona XtraDB cluster with the spec of the source cluster
+// and seeds it with data from the source cluster's backup.
+func (c *K8sClient) ClonePXCCluster(ctx context.Context, params *CloneParams) error {
+	var source pxc.PerconaXtraDBCluster
+	err := c.kubeCtl.Get(ctx, pxc.PerconaXtraDBClusterKind, params.SourceName, &source)
+	if err != nil {
+		if errors.Is(err, kubectl.ErrNotFound) {
+			return errors.Wrapf(ErrNotFound, "cannot get source XtraDB cluster %q", params.SourceName)
+		}
+		return errors.Wrap(err, "cannot get source XtraDB cluster")
+	}
+
+	var cluster pxc.PerconaXtraDBCluster
+	err = c.kubeCtl.Get(ctx, pxc.PerconaXtraDBClusterKind, params.Name, &cluster)
+	if err == nil {
+		return fmt.Errorf(clusterWithSameNameExistsErrTemplate, params.Name)
+	}
+
+	backup, err := c.getPXCBackupToClone(ctx, params.SourceName, params.BackupName)
+	if err != nil {
+		return err
+	}
+
+	if source.Spec == nil || source.Spec.PXC == nil {
+		return errors.Wrapf(ErrInvalidCloneSource, "XtraDB cluster %q has no PXC spec", params.SourceName)
+	}
+	spec := new(pxc.PerconaXtraDBClusterSpec)
+	if err := deepCopy(source.Spec, spec); err != nil {
+		return errors.Wrap(err, "cannot copy source XtraDB cluster spec")
+	}
+	// Let the operator generate new certificates for the clone.
+	spec.SSLSecretName = ""
+	spec.SSLInternalSecretName = ""
+	spec.SecretsName = fmt.Sprintf(pxcSecretNameTmpl, params.Name)
+	spec.Pause = false
+	spec.Backup = clonePXCBackup(spec.Backup)
+	if params.Size > 0 {
+		spec.PXC.Size = &params.Size
+	}
+	spec.PXC.Resources = c.updateComputeResources(params.ComputeResources, spec.PXC.Resources)
+
+	res := &pxc.PerconaXtraDBCluster{
+		TypeMeta: source.TypeMeta,
+		ObjectMeta: common.ObjectMeta{
+			Name:       params.Name,
+			Finalizers: source.Finalizers,
+		},
+		Spec: spec,
+	}
+
+	sourceSecretName := source.Spec.SecretsName
+	if sourceSecretName == "" {
+		sourceSecretName = fmt.Sprintf(pxcDefaultSecretNameTmpl, params.SourceName)
+	}
+	err = c.createClone(ctx, sourceSecretName, spec.SecretsName, res)
+	if err != nil {
+		return errors.Wrap(err, "cannot create XtraDB cluster")
+	}
+
+	restore := &pxc.PerconaXtraDBClusterRestore{
+		TypeMeta: common.TypeMeta{
+			APIVersion: pxcAPINamespace + "/v1",
+			Kind:       pxc.PerconaXtraDBClusterRestoreKind,
+		},
+		ObjectMeta: common.ObjectMeta{
+			Name: fmt.Sprintf(cloneRestoreNameTmpl, params.Name),
+		},
+		Spec: pxc.PerconaXtraDBClusterRestoreSpec{
+			PXCCluster:   params.Name,
+			BackupSource: &backup.Status,
+		},
+	}
+	return c.kubeCtl.Apply(ctx, restore)
+}
+
+// getPXCBackupToClone returns backup with given name or the latest successful backup of the cluster.
+func (c *K8sClient) getPXCBackupToClone(ctx context.Context, clusterName, backupName string) (*pxc.PerconaXtraDBClusterBackup, error) {
+	if backupName != "" {
+		var backup pxc.PerconaXtraDBClusterBackup
+		err := c.kubeCtl.Get(ctx, pxc.PerconaXtraDBClusterBackupKind, backupName, &backup)
+		if err != nil {
+			return nil, errors.Wrapf(err, "cannot get backup %q", backupName)
+		}
+		if backup.Spec.PXCCluster != clusterName {
+			return nil, errors.Errorf("backup %q does not belong to cluster %q", backupName, clusterName)
+		}
+		if backup.Status.State != pxc.PXCBackupStateSucceeded {
+			return nil, errors.Wrapf(ErrNoBackupToClone, "backup %q is in state %q", backupName, backup.Status.State)
+		}
+		return &backup, nil
+	}
+
+	var list pxc.PerconaXtraDBClusterBackupList
+	err := c.kubeCtl.Get(ctx, pxc.PerconaXtraDBClusterBackupKind, "", &list)
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot get XtraDB cluster backups")
+	}
+	var latest *pxc.PerconaXtraDBClusterBackup
+	for i, backup := range list.Items {
+		if backup.Spec.PXCCluster != clusterName || backup.Status.State != pxc.PXCBackupStateSucceeded {
+			continue
+		}
+		if latest == nil || completedAfter(backup.Status.Completed, latest.Status.Completed) {
+			latest = &list.Items[i]
+		}
+	}
+	if latest == nil {
+		return nil, errors.Wrapf(ErrNoBackupToClone, "cluster %q", clusterName)
+	}
+	return latest, nil
+}
+
+// GetPXCCloneOperation returns progress of cloning into Percona XtraDB cluster with given name.
+func (c *K8sClient) GetPXCCloneOperation(ctx context.Context, name string) (*CloneOperation, error) {
+	var restore pxc.PerconaXtraDBClusterRestore
+	err := c.kubeCtl.Get(ctx, pxc.PerconaXtraDBClusterRestoreKind, fmt.Sprintf(cloneRestoreNameTmpl, name), &restore)
+	if err != nil {
+		if errors.Is(err, kubectl.ErrNotFound) {
+			return nil, errors.Wrapf(ErrNotFound, "cluster %q is not a clone", name)
+		}
+		return nil, errors.Wrap(err, "cannot get clone restore")
+	}
+	var cluster pxc.PerconaXtraDBCluster
+	err = c.kubeCtl.Get(ctx, pxc.PerconaXtraDBClusterKind, name, &cluster)
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot get XtraDB cluster")
+	}
+
+	switch restore.Status.State {
+	case pxc.RestoreStateFailed:
+		return cloneFailed(restore.Status.Comments), nil
+	case pxc.RestoreStateSucceeded:
+		return c.cloneRestored(ctx, &cluster), nil
+	default:
+		return cloneRestoring(string(restore.Status.State)), nil
+	}
+}
+
+// ClonePSMDBCluster creates a new Percona Server for MongoDB cluster with the spec of the
+// source cluster and seeds it with data from the source cluster's backup.
+func (c *K8sClient) ClonePSMDBCluster(ctx context.Context, params *CloneParams) error {
+	var source psmdb.PerconaServerMongoDB
+	err := c.kubeCtl.Get(ctx, psmdb.PerconaServerMongoDBKind, params.SourceName, &source)
+	if err != nil {
+		if errors.Is(err, kubectl.ErrNotFound) {
+			return errors.Wrapf(ErrNotFound, "cannot get source PSMDB cluster %q", params.SourceName)
+		}
+		return errors.Wrap(err, "cannot get source PSMDB cluster")
+	}
+
+	var cluster psmdb.PerconaServerMongoDB
+	err = c.kubeCtl.Get(ctx, psmdb.PerconaServerMongoDBKind, params.Name, &cluster)
+	if err == nil {
+		return fmt.Errorf(clusterWithSameNameExistsErrTemplate, params.Name)
+	}
+
+	backup, err := c.getPSMDBBackupToClone(ctx, params.SourceName, params.BackupName)
+	if err != nil {
+		return err
+	}
+
+	if source.Spec == nil || len(source.Spec.Replsets) == 0 || source.Spec.Replsets[0] == nil {
+		return errors.Wrapf(ErrInvalidCloneSource, "PSMDB cluster %q has no replica sets", params.SourceName)
+	}
+	spec := new(psmdb.PerconaServerMongoDBSpec)
+	if err := deepCopy(source.Spec, spec); err != nil {
+		return errors.Wrap(err, "cannot copy source PSMDB cluster spec")
+	}
+	spec.Pause = false
+	spec.Backup = clonePSMDBBackup(spec.Backup)
+	spec.Secrets = &psmdb.SecretsSpec{
+		Users: fmt.Sprintf(psmdbSecretNameTmpl, params.Name),
+	}
+	if spec.Mongod != nil && spec.Mongod.Security != nil {
+		spec.Mongod.Security.EncryptionKeySecret = fmt.Sprintf("%s-mongodb-encryption-key", params.Name)
+	}
+	if params.Size > 0 {
+		spec.Replsets[0].Size = params.Size
+	}
+	spec.Replsets[0].Resources = c.updateComputeResources(params.ComputeResources, spec.Replsets[0].Resources)
+
+	res := &psmdb.PerconaServerMongoDB{
+		TypeMeta: source.TypeMeta,
+		ObjectMeta: common.ObjectMeta{
+			Name:       params.Name,
+			Finalizers: source.Finalizers,
+		},
+		Spec: spec,
+	}
+
+	sourceSecretName := fmt.Sprintf(psmdbSecretNameTmpl, params.SourceName)
+	if source.Spec.Secrets != nil && source.Spec.Secrets.Users != "" {
+		sourceSecretName = source.Spec.Secrets.Users
+	}
+	err = c.createClone(ctx, sourceSecretName, spec.Secrets.Users, res)
+	if err != nil {
+		return errors.Wrap(err, "cannot create PSMDB cluster")
+	}
+
+	restore := &psmdb.PerconaServerMongoDBRestore{
+		TypeMeta: common.TypeMeta{
+			APIVersion: psmdbAPINamespace + "/v1",
+			Kind:       psmdb.PerconaServerMongoDBRestoreKind,
+		},
+		ObjectMeta: common.ObjectMeta{
+			Name: fmt.Sprintf(cloneRestoreNameTmpl, params.Name),
+		},
+		Spec: psmdb.PerconaServerMongoDBRestoreSpec{
+			ClusterName:  params.Name,
+			BackupSource: &backup.Status,
+		},
+	}
+	return c.kubeCtl.Apply(ctx, restore)
+}
+
+// getPSMDBBackupToClone returns backup with given name or the latest successful backup of the cluster.
+func (c *K8sClient) getPSMDBBackupToClone(ctx context.Context, clusterName, backupName string) (*psmdb.PerconaServerMongoDBBackup, error) {
+	if backupName != "" {
+		var backup psmdb.PerconaServerMongoDBBackup
+		err := c.kubeCtl.Get(ctx, psmdb.PerconaServerMongoDBBackupKind, backupName, &backup)
+		if err != nil {
+			return nil, errors.Wrapf(err, "cannot get backup %q", backupName)
+		}
+		if backup.Spec.PSMDBCluster != clusterName {
+			return nil, errors.Errorf("backup %q does not belong to cluster %q", backupName, clusterName)
+		}
+		if backup.Status.State != psmdb.BackupStateReady {
+			return nil, errors.Wrapf(ErrNoBackupToClone, "backup %q is in state %q", backupName, backup.Status.State)
+		}
+		return &backup, nil
+	}
+
+	var list psmdb.PerconaServerMongoDBBackupList
+	err := c.kubeCtl.Get(ctx, psmdb.PerconaServerMongoDBBackupKind, "", &list)
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot get PSMDB cluster backups")
+	}
+	var latest *psmdb.PerconaServerMongoDBBackup
+	for i, backup := range list.Items {
+		if backup.Spec.PSMDBCluster != clusterName || backup.Status.State != psmdb.BackupStateReady {
+			continue
+		}
+		if latest == nil || completedAfter(backup.Status.CompletedAt, latest.Status.CompletedAt) {
+			latest = &list.Items[i]
+		}
+	}
+	if latest == nil {
+		return nil, errors.Wrapf(ErrNoBackupToClone, "cluster %q", clusterName)
+	}
+	return latest, nil
+}
+
+// GetPSMDBCloneOperation returns progress of cloning into PSMDB cluster with given name.
+func (c *K8sClient) GetPSMDBCloneOperation(ctx context.Context, name string) (*CloneOperation, error) {
+	var restore psmdb.PerconaServerMongoDBRestore
+	err := c.kubeCtl.Get(ctx, psmdb.PerconaServerMongoDBRestoreKind, fmt.Sprintf(cloneRestoreNameTmpl, name), &restore)
+	if err != nil {
+		if errors.Is(err, kubectl.ErrNotFound) {
+			return nil, errors.Wrapf(ErrNotFound, "cluster %q is not a clone", name)
+		}
+		return nil, errors.Wrap(err, "cannot get clone restore")
+	}
+	var cluster psmdb.PerconaServerMongoDB
+	err = c.kubeCtl.Get(ctx, psmdb.PerconaServerMongoDBKind, name, &cluster)
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot get PSMDB cluster")
+	}
+
+	switch restore.Status.State {
+	case psmdb.RestoreStateError, psmdb.RestoreStateRejected:
+		return cloneFailed(restore.Status.Error), nil
+	case psmdb.RestoreStateReady:
+		return c.cloneRestored(ctx, &cluster), nil
+	default:
+		return cloneRestoring(string(restore.Status.State)), nil
+	}
+}
+
+func cloneFailed(message string) *CloneOperation {
+	return &CloneOperation{
+		FinishedSteps: 1,
+		TotalSteps:    cloneTotalSteps,
+		Message:       "Failed to restore data: " + message,
+		Failed:        true,
+	}
+}
+
+func cloneRestoring(state string) *CloneOperation {
+	message := "Restoring data"
+	if state != "" {
+		message += ": " + state
+	}
+	return &CloneOperation{
+		FinishedSteps: 1,
+		TotalSteps:    cloneTotalSteps,
+		Message:       message,
+	}
+}
+
+func (c *K8sClient) cloneRestored(ctx context.Context, cluster common.DatabaseCluster) *CloneOperation {
+	if c.getClusterState(ctx, cluster, c.crVersionMatchesPodsVersion) != ClusterStateReady {
+		return &CloneOperation{
+			FinishedSteps: 2,
+			TotalSteps:    cloneTotalSteps,
+			Message:       "Data restored, waiting for cluster to become ready",
+		}
+	}
+	return &CloneOperation{
+		FinishedSteps: cloneTotalSteps,
+		TotalSteps:    cloneTotalSteps,
+		Message:       "Cluster cloned",
+	}
+}
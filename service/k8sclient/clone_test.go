@@ -0,0 +1,70 @@
+// dbaas-controller
+// Copyright (C) 2020 Percona LLC
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package k8sclient
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/percona-platform/dbaas-controller/service/k8sclient/internal/psmdb"
+	"github.com/percona-platform/dbaas-controller/service/k8sclient/internal/pxc"
+)
+
+func TestCompletedAfter(t *testing.T) {
+	t.Parallel()
+	older := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	newer := older.Add(time.Hour)
+
+	assert.True(t, completedAfter(&newer, &older))
+	assert.False(t, completedAfter(&older, &newer))
+	assert.True(t, completedAfter(&older, nil))
+	assert.False(t, completedAfter(nil, &older))
+	assert.False(t, completedAfter(nil, nil))
+}
+
+func TestCloneOperation(t *testing.T) {
+	t.Parallel()
+	op := cloneRestoring("Restoring")
+	assert.Equal(t, &CloneOperation{FinishedSteps: 1, TotalSteps: cloneTotalSteps, Message: "Restoring data: Restoring"}, op)
+
+	op = cloneFailed("backup not found")
+	assert.True(t, op.Failed)
+	assert.Equal(t, "Failed to restore data: backup not found", op.Message)
+}
+
+func TestCloneBackup(t *testing.T) {
+	t.Parallel()
+
+	pxcBackup := &pxc.PXCScheduledBackup{
+		Image:    "percona/percona-xtradb-cluster-operator:1.7.0-pxc8.0-backup",
+		Schedule: []pxc.PXCScheduledBackupSchedule{{Name: "daily", Schedule: "0 0 * * *", StorageName: "s3"}},
+		Storages: map[string]*pxc.BackupStorageSpec{"s3": {}},
+	}
+	clone := clonePXCBackup(pxcBackup)
+	assert.Empty(t, clone.Schedule)
+	assert.Equal(t, pxcBackup.Storages, clone.Storages)
+	assert.Len(t, pxcBackup.Schedule, 1, "source spec is not changed")
+	assert.Nil(t, clonePXCBackup(nil))
+
+	psmdbBackup := &psmdb.BackupSpec{Enabled: true, Image: "percona/percona-server-mongodb-operator:1.7.0-backup"}
+	cloned := clonePSMDBBackup(psmdbBackup)
+	assert.True(t, cloned.Enabled)
+	assert.Empty(t, cloned.Tasks)
+	assert.Nil(t, clonePSMDBBackup(nil))
+}
@@ -0,0 +1,180 @@
+// dbaas-controller
+// Copyright (C) 2020 Percona LLC
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package k8sclient
+
+import (
+	"strings"
+
+	"github.com/percona-platform/dbaas-controller/service/k8sclient/internal/psmdb"
+	"github.com/percona-platform/dbaas-controller/service/k8sclient/internal/pxc"
+)
+
+// Components of database clusters having their own images.
+const (
+	componentPXC      = "pxc"
+	componentProxySQL = "proxysql"
+	componentHAProxy  = "haproxy"
+	componentMongod   = "mongod"
+	componentBackup   = "backup"
+	componentPMM      = "pmm"
+)
+
+// pxcImagePaths returns paths of PXC cluster components images inside the cluster spec.
+func pxcImagePaths() map[string][]string {
+	return map[string][]string{
+		componentPXC:      {"pxc", "image"},
+		componentProxySQL: {"proxysql", "image"},
+		componentHAProxy:  {"haproxy", "image"},
+		componentBackup:   {"backup", "image"},
+		componentPMM:      {"pmm", "image"},
+	}
+}
+
+// psmdbImagePaths returns paths of PSMDB cluster components images inside the cluster spec.
+func psmdbImagePaths() map[string][]string {
+	return map[string][]string{
+		componentMongod: {"image"},
+		componentBackup: {"backup", "image"},
+		componentPMM:    {"pmm", "image"},
+	}
+}
+
+// imageResolver resolves images of cluster components matching new version of the operator.
+type imageResolver struct {
+	oldVersion string
+	newVersion string
+	// pmmImage is PMM client image used by new clusters, PMM images are not changed if it's empty.
+	pmmImage string
+}
+
+// newImageResolver returns resolver of images for operator upgrade from oldVersion to newVersion.
+func newImageResolver(oldVersion, newVersion, pmmImage string) *imageResolver {
+	return &imageResolver{oldVersion: oldVersion, newVersion: newVersion, pmmImage: pmmImage}
+}
+
+// resolve returns image of the component for the new operator version.
+// Images built with the operator, like "percona/percona-xtradb-cluster-operator:1.7.0-haproxy",
+// are tagged with the operator version, so the version in the tag is replaced.
+// Images released independently, like database images, stay as they are. PMM client image is set to pmmImage.
+func (r *imageResolver) resolve(component, image string) string {
+	if component == componentPMM {
+		if r.pmmImage == "" {
+			return image
+		}
+		return r.pmmImage
+	}
+	return replaceImageTagVersion(image, r.oldVersion, r.newVersion)
+}
+
+// resolveAll returns changed images of components.
+func (r *imageResolver) resolveAll(images map[string]string) map[string]string {
+	changed := make(map[string]string)
+	for component, image := range images {
+		if resolved := r.resolve(component, image); resolved != image {
+			changed[component] = resolved
+		}
+	}
+	return changed
+}
+
+// replaceImageTagVersion replaces oldVersion with newVersion if image tag is oldVersion or starts with "<oldVersion>-".
+func replaceImageTagVersion(image, oldVersion, newVersion string) string {
+	i := strings.LastIndex(image, ":")
+	if i < 0 || strings.Contains(image[i:], "/") || strings.Contains(image, "@") {
+		return image
+	}
+	name, tag := image[:i], image[i+1:]
+	if tag != oldVersion && !strings.HasPrefix(tag, oldVersion+"-") {
+		return image
+	}
+	return name + ":" + newVersion + strings.TrimPrefix(tag, oldVersion)
+}
+
+// imagesPatch returns merge patch of the cluster setting crVersion and given images of components.
+// Typed clusters are not used for patches as they always include fields like spec.pause.
+func imagesPatch(crVersion string, images map[string]string, paths map[string][]string) map[string]interface{} {
+	spec := map[string]interface{}{"crVersion": crVersion}
+	for component, image := range images {
+		path, ok := paths[component]
+		if !ok {
+			continue
+		}
+		m := spec
+		for _, key := range path[:len(path)-1] {
+			if _, ok := m[key]; !ok {
+				m[key] = make(map[string]interface{})
+			}
+			m = m[key].(map[string]interface{})
+		}
+		m[path[len(path)-1]] = image
+	}
+	return map[string]interface{}{"spec": spec}
+}
+
+// pxcClusterImages returns images of PXC cluster components.
+func pxcClusterImages(cluster *pxc.PerconaXtraDBCluster) map[string]string {
+	images := make(map[string]string)
+	if cluster.Spec == nil {
+		return images
+	}
+	for component, spec := range map[string]*pxc.PodSpec{
+		componentPXC:      cluster.Spec.PXC,
+		componentHAProxy:  cluster.Spec.HAProxy,
+		componentProxySQL: cluster.Spec.ProxySQL,
+	} {
+		if spec != nil && spec.Image != "" {
+			images[component] = spec.Image
+		}
+	}
+	if cluster.Spec.Backup != nil && cluster.Spec.Backup.Image != "" {
+		images[componentBackup] = cluster.Spec.Backup.Image
+	}
+	if cluster.Spec.PMM != nil && cluster.Spec.PMM.Image != "" {
+		images[componentPMM] = cluster.Spec.PMM.Image
+	}
+	return images
+}
+
+// psmdbClusterImages returns images of PSMDB cluster components.
+func psmdbClusterImages(cluster *psmdb.PerconaServerMongoDB) map[string]string {
+	images := make(map[string]string)
+	if cluster.Spec == nil {
+		return images
+	}
+	if cluster.Spec.Image != "" {
+		images[componentMongod] = cluster.Spec.Image
+	}
+	if cluster.Spec.Backup != nil && cluster.Spec.Backup.Image != "" {
+		images[componentBackup] = cluster.Spec.Backup.Image
+	}
+	if cluster.Spec.PMM != nil && cluster.Spec.PMM.Image != "" {
+		images[componentPMM] = cluster.Spec.PMM.Image
+	}
+	return images
+}
+
+// pxcUpgradePatch returns merge patch of the cluster replacing the images and crVersion
+// to match new version of the operator.
+func pxcUpgradePatch(cluster *pxc.PerconaXtraDBCluster, resolver *imageResolver) map[string]interface{} {
+	return imagesPatch(resolver.newVersion, resolver.resolveAll(pxcClusterImages(cluster)), pxcImagePaths())
+}
+
+// psmdbUpgradePatch returns merge patch of the cluster replacing the images and crVersion
+// to match new version of the operator.
+func psmdbUpgradePatch(cluster *psmdb.PerconaServerMongoDB, resolver *imageResolver) map[string]interface{} {
+	return imagesPatch(resolver.newVersion, resolver.resolveAll(psmdbClusterImages(cluster)), psmdbImagePaths())
+}
@@ -0,0 +1,140 @@
+// dbaas-controller
+// Copyright (C) 2020 Percona LLC
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package k8sclient
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/percona-platform/dbaas-controller/service/k8sclient/internal/psmdb"
+	"github.com/percona-platform/dbaas-controller/service/k8sclient/internal/pxc"
+)
+
+func TestReplaceImageTagVersion(t *testing.T) {
+	t.Parallel()
+	for _, tt := range []struct {
+		image    string
+		expected string
+	}{
+		{"percona/percona-xtradb-cluster-operator:1.7.0-haproxy", "percona/percona-xtradb-cluster-operator:1.8.0-haproxy"},
+		{"percona/percona-xtradb-cluster-operator:1.7.0", "percona/percona-xtradb-cluster-operator:1.8.0"},
+		{"registry:5000/percona/percona-xtradb-cluster-operator:1.7.0-pxc8.0-backup", "registry:5000/percona/percona-xtradb-cluster-operator:1.8.0-pxc8.0-backup"},
+		{"percona/percona-xtradb-cluster:8.0.21-1.7.0", "percona/percona-xtradb-cluster:8.0.21-1.7.0"},
+		{"percona/percona-xtradb-cluster-operator:1.7.01-haproxy", "percona/percona-xtradb-cluster-operator:1.7.01-haproxy"},
+		{"registry:5000/percona/percona-xtradb-cluster-operator", "registry:5000/percona/percona-xtradb-cluster-operator"},
+		{"percona/percona-xtradb-cluster-operator@sha256:1.7.0", "percona/percona-xtradb-cluster-operator@sha256:1.7.0"},
+	} {
+		tt := tt
+		t.Run(tt.image, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, tt.expected, replaceImageTagVersion(tt.image, "1.7.0", "1.8.0"))
+		})
+	}
+}
+
+func TestUpgradePatches(t *testing.T) {
+	t.Parallel()
+	resolver := newImageResolver("1.7.0", "1.8.0", "percona/pmm-client:2.18.0")
+
+	for _, tt := range []struct {
+		name     string
+		cluster  *pxc.PerconaXtraDBCluster
+		expected string
+	}{{
+		name: "PXC with HAProxy",
+		cluster: &pxc.PerconaXtraDBCluster{Spec: &pxc.PerconaXtraDBClusterSpec{
+			CRVersion: "1.7.0",
+			PXC:       &pxc.PodSpec{Image: "percona/percona-xtradb-cluster:8.0.20-11.1"},
+			HAProxy:   &pxc.PodSpec{Image: "percona/percona-xtradb-cluster-operator:1.7.0-haproxy"},
+			Backup:    &pxc.PXCScheduledBackup{Image: "percona/percona-xtradb-cluster-operator:1.7.0-pxc8.0-backup"},
+		}},
+		expected: `{"spec": {
+			"crVersion": "1.8.0",
+			"haproxy": {"image": "percona/percona-xtradb-cluster-operator:1.8.0-haproxy"},
+			"backup": {"image": "percona/percona-xtradb-cluster-operator:1.8.0-pxc8.0-backup"}
+		}}`,
+	}, {
+		name: "PXC with ProxySQL",
+		cluster: &pxc.PerconaXtraDBCluster{Spec: &pxc.PerconaXtraDBClusterSpec{
+			CRVersion: "1.7.0",
+			PXC:       &pxc.PodSpec{Image: "percona/percona-xtradb-cluster:8.0.20-11.1"},
+			ProxySQL:  &pxc.PodSpec{Image: "percona/percona-xtradb-cluster-operator:1.7.0-proxysql"},
+			Backup:    &pxc.PXCScheduledBackup{Image: "percona/percona-xtradb-cluster-operator:1.7.0-pxc8.0-backup"},
+		}},
+		expected: `{"spec": {
+			"crVersion": "1.8.0",
+			"proxysql": {"image": "percona/percona-xtradb-cluster-operator:1.8.0-proxysql"},
+			"backup": {"image": "percona/percona-xtradb-cluster-operator:1.8.0-pxc8.0-backup"}
+		}}`,
+	}, {
+		name: "PXC with operator built database image and PMM",
+		cluster: &pxc.PerconaXtraDBCluster{Spec: &pxc.PerconaXtraDBClusterSpec{
+			CRVersion: "1.7.0",
+			PXC:       &pxc.PodSpec{Image: "percona/percona-xtradb-cluster-operator:1.7.0-pxc8.0"},
+			ProxySQL:  &pxc.PodSpec{Image: "percona/percona-xtradb-cluster-operator:1.7.0-proxysql"},
+			PMM:       &pxc.PMMSpec{Enabled: true, Image: "percona/pmm-client:2.12.0"},
+		}},
+		expected: `{"spec": {
+			"crVersion": "1.8.0",
+			"pxc": {"image": "percona/percona-xtradb-cluster-operator:1.8.0-pxc8.0"},
+			"proxysql": {"image": "percona/percona-xtradb-cluster-operator:1.8.0-proxysql"},
+			"pmm": {"image": "percona/pmm-client:2.18.0"}
+		}}`,
+	}} {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			b, err := json.Marshal(pxcUpgradePatch(tt.cluster, resolver))
+			require.NoError(t, err)
+			assert.JSONEq(t, tt.expected, string(b))
+		})
+	}
+
+	t.Run("PSMDB with PMM", func(t *testing.T) {
+		t.Parallel()
+		cluster := &psmdb.PerconaServerMongoDB{Spec: &psmdb.PerconaServerMongoDBSpec{
+			CRVersion: "1.7.0",
+			Image:     "percona/percona-server-mongodb:4.4.3-5",
+			Backup:    &psmdb.BackupSpec{Enabled: true, Image: "percona/percona-server-mongodb-operator:1.7.0-backup"},
+			PMM:       &psmdb.PmmSpec{Enabled: true, Image: "percona/pmm-client:2.18.0"},
+		}}
+		b, err := json.Marshal(psmdbUpgradePatch(cluster, resolver))
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"spec": {
+			"crVersion": "1.8.0",
+			"backup": {"image": "percona/percona-server-mongodb-operator:1.8.0-backup"}
+		}}`, string(b))
+	})
+
+	t.Run("PMM image is kept without PMM client image", func(t *testing.T) {
+		t.Parallel()
+		cluster := &psmdb.PerconaServerMongoDB{Spec: &psmdb.PerconaServerMongoDBSpec{
+			CRVersion: "1.7.0",
+			Image:     "percona/percona-server-mongodb-operator:1.7.0-mongod4.4",
+			PMM:       &psmdb.PmmSpec{Enabled: true, Image: "percona/pmm-client:2.12.0"},
+		}}
+		b, err := json.Marshal(psmdbUpgradePatch(cluster, newImageResolver("1.7.0", "1.8.0", "")))
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"spec": {
+			"crVersion": "1.8.0",
+			"image": "percona/percona-server-mongodb-operator:1.8.0-mongod4.4"
+		}}`, string(b))
+	})
+}
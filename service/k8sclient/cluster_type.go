@@ -0,0 +1,359 @@
+// dbaas-controller
+// Copyright (C) 2020 Percona LLC
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package k8sclient
+
+import (
+	"context"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/percona-platform/dbaas-controller/service/k8sclient/common"
+	"github.com/percona-platform/dbaas-controller/utils/convertors"
+)
+
+// KubernetesClusterType represents kubernetes cluster type(eg: EKS, Minikube).
+type KubernetesClusterType uint8
+
+const (
+	clusterTypeUnknown KubernetesClusterType = iota
+	// AmazonEKSClusterType represents EKS cluster type.
+	AmazonEKSClusterType
+	// MinikubeClusterType represents minikube Kubernetes cluster.
+	MinikubeClusterType
+	// GKEClusterType represents Google Kubernetes Engine cluster.
+	GKEClusterType
+	// AKSClusterType represents Azure Kubernetes Service cluster.
+	AKSClusterType
+	// OpenShiftClusterType represents OpenShift cluster.
+	OpenShiftClusterType
+	// K3sClusterType represents k3s Kubernetes cluster.
+	K3sClusterType
+	// KindClusterType represents kind (Kubernetes in Docker) cluster.
+	KindClusterType
+	// GenericClusterType represents Kubernetes cluster that doesn't match any known type.
+	GenericClusterType
+)
+
+// String returns human readable name of the cluster type.
+func (t KubernetesClusterType) String() string {
+	switch t {
+	case AmazonEKSClusterType:
+		return "eks"
+	case MinikubeClusterType:
+		return "minikube"
+	case GKEClusterType:
+		return "gke"
+	case AKSClusterType:
+		return "aks"
+	case OpenShiftClusterType:
+		return "openshift"
+	case K3sClusterType:
+		return "k3s"
+	case KindClusterType:
+		return "kind"
+	case GenericClusterType:
+		return "generic"
+	default:
+		return "unknown"
+	}
+}
+
+// IsLocal returns true for development clusters that store volumes on nodes' local disks.
+func (t KubernetesClusterType) IsLocal() bool {
+	switch t {
+	case MinikubeClusterType, K3sClusterType, KindClusterType:
+		return true
+	default:
+		return false
+	}
+}
+
+// UsesAttachableVolumes returns true for clusters that store volumes on network disks
+// with a limited number of disks attached to a single node. For OpenShift and generic clusters
+// the limit is known only if CSI drivers report it.
+func (t KubernetesClusterType) UsesAttachableVolumes() bool {
+	switch t {
+	case AmazonEKSClusterType, GKEClusterType, AKSClusterType, OpenShiftClusterType, GenericClusterType:
+		return true
+	default:
+		return false
+	}
+}
+
+const (
+	// Max size of volume for AWS Elastic Block Storage service is 16TiB.
+	maxVolumeSizeEBS uint64 = 16 * 1024 * 1024 * 1024 * 1024
+	// Max size of Google Compute Engine persistent disk is 64TiB.
+	maxVolumeSizeGCEPD uint64 = 64 * 1024 * 1024 * 1024 * 1024
+	// Max size of Azure managed disk is 32TiB.
+	maxVolumeSizeAzureDisk uint64 = 32 * 1024 * 1024 * 1024 * 1024
+)
+
+// clusterTypeSignals holds information used to detect Kubernetes cluster type.
+type clusterTypeSignals struct {
+	provisioners []string
+	nodes        []common.Node
+	apiGroups    []string
+}
+
+// detectClusterType returns cluster type based on given signals. Distributions are
+// detected first because they can run on top of any cloud provider.
+func detectClusterType(signals clusterTypeSignals) KubernetesClusterType {
+	for _, group := range signals.apiGroups {
+		if strings.HasSuffix(group, ".openshift.io") {
+			return OpenShiftClusterType
+		}
+	}
+
+	for _, node := range signals.nodes {
+		switch {
+		case strings.HasPrefix(node.Spec.ProviderID, "kind://"):
+			return KindClusterType
+		case strings.HasPrefix(node.Spec.ProviderID, "k3s://"), node.Labels["node.kubernetes.io/instance-type"] == "k3s":
+			return K3sClusterType
+		case node.Labels["minikube.k8s.io/name"] != "":
+			return MinikubeClusterType
+		case strings.HasPrefix(node.Spec.ProviderID, "aws://"), node.Labels["eks.amazonaws.com/nodegroup"] != "":
+			return AmazonEKSClusterType
+		case strings.HasPrefix(node.Spec.ProviderID, "gce://"), node.Labels["cloud.google.com/gke-nodepool"] != "":
+			return GKEClusterType
+		case strings.HasPrefix(node.Spec.ProviderID, "azure://"), node.Labels["kubernetes.azure.com/cluster"] != "":
+			return AKSClusterType
+		}
+	}
+
+	for _, provisioner := range signals.provisioners {
+		switch {
+		case strings.Contains(provisioner, "minikube"):
+			return MinikubeClusterType
+		case strings.Contains(provisioner, "aws"):
+			return AmazonEKSClusterType
+		case provisioner == "kubernetes.io/gce-pd", provisioner == "pd.csi.storage.gke.io":
+			return GKEClusterType
+		case provisioner == "kubernetes.io/azure-disk", provisioner == "disk.csi.azure.com":
+			return AKSClusterType
+		}
+	}
+
+	if len(signals.nodes) == 0 && len(signals.provisioners) == 0 {
+		return clusterTypeUnknown
+	}
+	return GenericClusterType
+}
+
+// getAPIGroups returns API groups served by Kubernetes cluster.
+func (c *K8sClient) getAPIGroups(ctx context.Context) ([]string, error) {
+	out, err := c.kubeCtl.Run(ctx, []string{"api-versions"}, nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "cannot get API versions")
+	}
+	var groups []string
+	for _, line := range strings.Split(string(out), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		group := line
+		if i := strings.LastIndex(line, "/"); i >= 0 {
+			group = line[:i]
+		}
+		groups = append(groups, group)
+	}
+	return groups, nil
+}
+
+// GetKubernetesClusterType returns k8s cluster type based on API groups, node labels and storage class provisioners.
+// Detected type is cached for the lifetime of the client, detection is retried if the type is unknown.
+func (c *K8sClient) GetKubernetesClusterType(ctx context.Context) KubernetesClusterType {
+	c.clusterTypeM.Lock()
+	defer c.clusterTypeM.Unlock()
+
+	if c.clusterType == clusterTypeUnknown {
+		c.clusterType = c.detectKubernetesClusterType(ctx)
+	}
+	return c.clusterType
+}
+
+// detectKubernetesClusterType collects signals and detects k8s cluster type.
+func (c *K8sClient) detectKubernetesClusterType(ctx context.Context) KubernetesClusterType {
+	var signals clusterTypeSignals
+	var err error
+	signals.apiGroups, err = c.getAPIGroups(ctx)
+	if err != nil {
+		c.l.Warn(errors.Wrap(err, "failed to get API groups for detecting k8s cluster type"))
+	}
+
+	var nodes common.NodeList
+	if err := c.kubeCtl.Get(ctx, "nodes", "", &nodes); err != nil {
+		c.l.Warn(errors.Wrap(err, "failed to get nodes for detecting k8s cluster type"))
+	}
+	signals.nodes = nodes.Items
+
+	sc, err := c.getStorageClass(ctx)
+	if err != nil {
+		c.l.Warn(errors.Wrap(err, "failed to get storage classes for detecting k8s cluster type"))
+	} else {
+		for _, class := range sc.Items {
+			signals.provisioners = append(signals.provisioners, class.Provisioner)
+		}
+	}
+
+	clusterType := detectClusterType(signals)
+	if clusterType == clusterTypeUnknown {
+		c.l.Error("failed to get k8s cluster type")
+	}
+	return clusterType
+}
+
+// nodeInstanceType returns cloud instance type of the node.
+func nodeInstanceType(node common.Node) (string, bool) {
+	if instanceType, ok := node.Labels["node.kubernetes.io/instance-type"]; ok {
+		return instanceType, true
+	}
+	instanceType, ok := node.Labels["beta.kubernetes.io/instance-type"]
+	return instanceType, ok
+}
+
+// maxVolumeSize returns max size of a single volume for given cluster type.
+// EBS limit, the smallest one of the known providers, is used for other clusters.
+func maxVolumeSize(clusterType KubernetesClusterType) uint64 {
+	switch clusterType {
+	case GKEClusterType:
+		return maxVolumeSizeGCEPD
+	case AKSClusterType:
+		return maxVolumeSizeAzureDisk
+	default:
+		return maxVolumeSizeEBS
+	}
+}
+
+// csiDrivers maps cluster types to CSI drivers of their network disks.
+var csiDrivers = map[KubernetesClusterType]string{ //nolint:gochecknoglobals
+	AmazonEKSClusterType: "ebs.csi.aws.com",
+	GKEClusterType:       "pd.csi.storage.gke.io",
+	AKSClusterType:       "disk.csi.azure.com",
+}
+
+// inTreeAttachableVolumes maps cluster types to node's allocatable resource reported by in-tree volume plugins.
+var inTreeAttachableVolumes = map[KubernetesClusterType]common.ResourceName{ //nolint:gochecknoglobals
+	AmazonEKSClusterType: "attachable-volumes-aws-ebs",
+	GKEClusterType:       "attachable-volumes-gce-pd",
+	AKSClusterType:       "attachable-volumes-azure-disk",
+}
+
+// errVolumeAttachLimitUnknown is returned when the volume attach limit of a node can't be determined.
+var errVolumeAttachLimitUnknown = errors.New("volume attach limit is not known")
+
+// csiVolumeAttachLimit returns volume attach limit reported by CSI driver of the node. Cluster types
+// without known CSI driver use the smallest limit of all drivers, since the driver of database volumes is not known.
+func csiVolumeAttachLimit(clusterType KubernetesClusterType, csiNode *common.CSINode) (uint64, bool) {
+	if csiNode == nil {
+		return 0, false
+	}
+	name, known := csiDrivers[clusterType]
+	var limit uint64
+	var found bool
+	for _, driver := range csiNode.Spec.Drivers {
+		if (known && driver.Name != name) || driver.Allocatable == nil || driver.Allocatable.Count == nil {
+			continue
+		}
+		count := uint64(*driver.Allocatable.Count)
+		if !found || count < limit {
+			limit, found = count, true
+		}
+	}
+	return limit, found
+}
+
+// volumeAttachLimit returns how many volumes could be attached to the node. Limits reported
+// by CSI driver and by the node in allocatable resources take precedence over provider specific defaults.
+// Exact is false if the limit is a guess because node does not provide enough information.
+// It returns errVolumeAttachLimitUnknown for cluster types without defaults if the node reports no limit.
+func volumeAttachLimit(clusterType KubernetesClusterType, node common.Node, csiNode *common.CSINode) (limit uint64, exact bool, err error) {
+	if limit, ok := csiVolumeAttachLimit(clusterType, csiNode); ok {
+		return limit, true, nil
+	}
+	if count, ok := node.Status.Allocatable[inTreeAttachableVolumes[clusterType]]; ok {
+		if limit, err := strconv.ParseUint(count, 10, 64); err == nil {
+			return limit, true, nil
+		}
+	}
+
+	switch clusterType {
+	case AmazonEKSClusterType:
+		limit, exact = volumeAttachLimitEKS(node)
+		return limit, exact, nil
+	case GKEClusterType:
+		return volumeAttachLimitGKE(node), true, nil
+	case AKSClusterType:
+		limit, err = volumeAttachLimitAKS(node)
+		return limit, true, err
+	default:
+		return 0, false, errors.Wrapf(errVolumeAttachLimitUnknown, "node %q of %s cluster", node.Name, clusterType)
+	}
+}
+
+// volumeAttachLimitEKS returns EBS volume attach limit of the EKS node based on its instance type.
+// Exact is false if the node does not have instance type label or the type is not known.
+func volumeAttachLimitEKS(node common.Node) (limit uint64, exact bool) {
+	instanceType, ok := nodeInstanceType(node)
+	if !ok {
+		return defaultEBSAttachLimits.Default, false
+	}
+	return defaultEBSAttachLimits.limit(instanceType)
+}
+
+// volumeAttachLimitGKE returns persistent disk attach limit of the GKE node.
+// See https://cloud.google.com/compute/docs/disks#pdnumberlimits.
+func volumeAttachLimitGKE(node common.Node) uint64 {
+	instanceType, _ := nodeInstanceType(node)
+	switch instanceType {
+	case "e2-micro", "e2-small", "e2-medium", "f1-micro", "g1-small":
+		// Shared-core machine types.
+		return 15
+	default:
+		return 127
+	}
+}
+
+// volumeAttachLimitAKS returns data disk attach limit of the AKS node. Azure limit depends
+// on VM size, it's approximated as two disks per vCPU, but not more than 64.
+// See https://docs.microsoft.com/en-us/azure/virtual-machines/sizes.
+func volumeAttachLimitAKS(node common.Node) (uint64, error) {
+	cpu, ok := node.Status.Capacity[common.ResourceCPU]
+	if !ok {
+		cpu, ok = node.Status.Allocatable[common.ResourceCPU]
+	}
+	if !ok {
+		return 0, errors.New("dealing with AKS cluster but the node does not report its CPU count")
+	}
+	cpuMillis, err := convertors.StrToMilliCPU(cpu)
+	if err != nil {
+		return 0, errors.Wrapf(err, "failed to convert '%s' to millicpus", cpu)
+	}
+	limit := 2 * ((cpuMillis + 999) / 1000)
+	switch {
+	case limit < 4:
+		return 4, nil
+	case limit > 64:
+		return 64, nil
+	default:
+		return limit, nil
+	}
+}
@@ -0,0 +1,204 @@
+// dbaas-controller
+// Copyright (C) 2020 Percona LLC
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package k8sclient
+
+import (
+	"testing"
+
+	"github.com/AlekSi/pointer"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/percona-platform/dbaas-controller/service/k8sclient/common"
+)
+
+func TestDetectClusterType(t *testing.T) {
+	t.Parallel()
+	node := func(providerID string, labels map[string]string) []common.Node {
+		return []common.Node{{
+			ObjectMeta: common.ObjectMeta{Labels: labels},
+			Spec:       common.NodeSpec{ProviderID: providerID},
+		}}
+	}
+
+	for _, tt := range []struct {
+		name     string
+		signals  clusterTypeSignals
+		expected KubernetesClusterType
+	}{
+		{name: "no signals", expected: clusterTypeUnknown},
+		{
+			name:     "OpenShift on AWS",
+			signals:  clusterTypeSignals{apiGroups: []string{"apps", "route.openshift.io"}, nodes: node("aws:///us-east-1a/i-1", nil)},
+			expected: OpenShiftClusterType,
+		},
+		{name: "kind", signals: clusterTypeSignals{nodes: node("kind://docker/kind/kind-control-plane", nil)}, expected: KindClusterType},
+		{
+			name:     "k3s",
+			signals:  clusterTypeSignals{nodes: node("", map[string]string{"node.kubernetes.io/instance-type": "k3s"})},
+			expected: K3sClusterType,
+		},
+		{
+			name:     "minikube",
+			signals:  clusterTypeSignals{nodes: node("", map[string]string{"minikube.k8s.io/name": "minikube"})},
+			expected: MinikubeClusterType,
+		},
+		{name: "EKS", signals: clusterTypeSignals{nodes: node("aws:///us-east-1a/i-1", nil)}, expected: AmazonEKSClusterType},
+		{name: "GKE", signals: clusterTypeSignals{nodes: node("gce://project/zone/node", nil)}, expected: GKEClusterType},
+		{
+			name:     "AKS",
+			signals:  clusterTypeSignals{nodes: node("", map[string]string{"kubernetes.azure.com/cluster": "rg"})},
+			expected: AKSClusterType,
+		},
+		{name: "provisioner", signals: clusterTypeSignals{provisioners: []string{"pd.csi.storage.gke.io"}}, expected: GKEClusterType},
+		{
+			name:     "generic",
+			signals:  clusterTypeSignals{provisioners: []string{"rook-ceph.rbd.csi.ceph.com"}, nodes: node("", nil)},
+			expected: GenericClusterType,
+		},
+	} {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, tt.expected, detectClusterType(tt.signals))
+		})
+	}
+}
+
+func TestVolumeAttachLimit(t *testing.T) {
+	t.Parallel()
+	for _, tt := range []struct {
+		name        string
+		clusterType KubernetesClusterType
+		node        common.Node
+		csiNode     *common.CSINode
+		expected    uint64
+		exact       bool
+	}{
+		{
+			name:        "allocatable",
+			clusterType: AmazonEKSClusterType,
+			node: common.Node{Status: common.NodeStatus{Allocatable: common.ResourceList{
+				"attachable-volumes-aws-ebs": "10",
+			}}},
+			expected: 10,
+			exact:    true,
+		},
+		{
+			name:        "CSI",
+			clusterType: AmazonEKSClusterType,
+			node: common.Node{Status: common.NodeStatus{Allocatable: common.ResourceList{
+				"attachable-volumes-aws-ebs": "10",
+			}}},
+			csiNode: &common.CSINode{Spec: common.CSINodeSpec{Drivers: []common.CSINodeDriver{
+				{Name: "efs.csi.aws.com"},
+				{Name: "ebs.csi.aws.com", Allocatable: &common.VolumeNodeResources{Count: pointer.ToInt32(24)}},
+			}}},
+			expected: 24,
+			exact:    true,
+		},
+		{
+			name:        "EKS",
+			clusterType: AmazonEKSClusterType,
+			node:        common.Node{ObjectMeta: common.ObjectMeta{Labels: map[string]string{"beta.kubernetes.io/instance-type": "m5.large"}}},
+			expected:    25,
+			exact:       true,
+		},
+		{
+			name:        "EKS z1d",
+			clusterType: AmazonEKSClusterType,
+			node:        common.Node{ObjectMeta: common.ObjectMeta{Labels: map[string]string{"node.kubernetes.io/instance-type": "z1d.large"}}},
+			expected:    25,
+			exact:       true,
+		},
+		{
+			name:        "EKS unknown family",
+			clusterType: AmazonEKSClusterType,
+			node:        common.Node{ObjectMeta: common.ObjectMeta{Labels: map[string]string{"node.kubernetes.io/instance-type": "x9zz.large"}}},
+			expected:    39,
+		},
+		{
+			name:        "EKS without instance type",
+			clusterType: AmazonEKSClusterType,
+			expected:    39,
+		},
+		{
+			name:        "GKE shared-core",
+			clusterType: GKEClusterType,
+			node:        common.Node{ObjectMeta: common.ObjectMeta{Labels: map[string]string{"node.kubernetes.io/instance-type": "e2-small"}}},
+			expected:    15,
+			exact:       true,
+		},
+		{
+			name:        "GKE",
+			clusterType: GKEClusterType,
+			node:        common.Node{ObjectMeta: common.ObjectMeta{Labels: map[string]string{"node.kubernetes.io/instance-type": "n2-standard-4"}}},
+			expected:    127,
+			exact:       true,
+		},
+		{
+			name:        "AKS",
+			clusterType: AKSClusterType,
+			node:        common.Node{Status: common.NodeStatus{Capacity: common.ResourceList{common.ResourceCPU: "8"}}},
+			expected:    16,
+			exact:       true,
+		},
+	} {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			limit, exact, err := volumeAttachLimit(tt.clusterType, tt.node, tt.csiNode)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, limit)
+			assert.Equal(t, tt.exact, exact)
+		})
+	}
+
+	_, _, err := volumeAttachLimit(GenericClusterType, common.Node{}, nil)
+	assert.True(t, errors.Is(err, errVolumeAttachLimitUnknown))
+
+	csiNode := &common.CSINode{Spec: common.CSINodeSpec{Drivers: []common.CSINodeDriver{
+		{Name: "csi.vsphere.vmware.com", Allocatable: &common.VolumeNodeResources{Count: pointer.ToInt32(59)}},
+		{Name: "rbd.csi.ceph.com", Allocatable: &common.VolumeNodeResources{Count: pointer.ToInt32(32)}},
+		{Name: "nfs.csi.k8s.io"},
+	}}}
+	for _, clusterType := range []KubernetesClusterType{GenericClusterType, OpenShiftClusterType} {
+		limit, exact, err := volumeAttachLimit(clusterType, common.Node{}, csiNode)
+		require.NoError(t, err)
+		assert.Equal(t, uint64(32), limit, clusterType.String())
+		assert.True(t, exact)
+	}
+}
+
+func TestEBSAttachLimits(t *testing.T) {
+	t.Parallel()
+	limits, err := parseEBSAttachLimits(ebsAttachLimitsJSON)
+	require.NoError(t, err)
+	assert.NotEmpty(t, limits.Version)
+
+	limit, found := limits.limit("T3.Medium")
+	assert.True(t, found)
+	assert.Equal(t, uint64(25), limit)
+
+	limit, found = limits.limit("m4.xlarge")
+	assert.False(t, found)
+	assert.Equal(t, uint64(39), limit)
+
+	_, err = parseEBSAttachLimits([]byte(`{"version": "1", "families": {}}`))
+	assert.Error(t, err)
+}
@@ -0,0 +1,68 @@
+// dbaas-controller
+// Copyright (C) 2020 Percona LLC
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package common
+
+// Extracted from https://pkg.go.dev/k8s.io/api/authorization/v1
+
+const (
+	// SelfSubjectAccessReviewKind is the kind of SelfSubjectAccessReview objects.
+	SelfSubjectAccessReviewKind = "SelfSubjectAccessReview"
+	// AuthorizationAPIVersion is the API version of authorization objects.
+	AuthorizationAPIVersion = "authorization.k8s.io/v1"
+)
+
+// ResourceAttributes includes the authorization attributes available for resource requests.
+type ResourceAttributes struct {
+	// Namespace is the namespace of the action being requested, "" means all namespaces.
+	Namespace string `json:"namespace,omitempty"`
+	// Verb is a Kubernetes resource API verb, like: get, list, watch, create, update, delete, proxy.
+	Verb string `json:"verb,omitempty"`
+	// Group is the API Group of the Resource.
+	Group string `json:"group,omitempty"`
+	// Resource is one of the existing resource types.
+	Resource string `json:"resource,omitempty"`
+	// Subresource is one of the existing resource subtypes, like "log" of pods.
+	Subresource string `json:"subresource,omitempty"`
+	// Name is the name of the resource being requested, "" means all.
+	Name string `json:"name,omitempty"`
+}
+
+// SelfSubjectAccessReviewSpec is a description of the access request.
+type SelfSubjectAccessReviewSpec struct {
+	ResourceAttributes *ResourceAttributes `json:"resourceAttributes,omitempty"`
+}
+
+// SubjectAccessReviewStatus holds result of the access review.
+type SubjectAccessReviewStatus struct {
+	// Allowed is required. True if the action would be allowed, false otherwise.
+	Allowed bool `json:"allowed"`
+	// Denied is optional. True if the action would be denied, otherwise false.
+	Denied bool `json:"denied,omitempty"`
+	// Reason is optional. It indicates why a request was allowed or denied.
+	Reason string `json:"reason,omitempty"`
+	// EvaluationError is an indication that some error occurred during the authorization check.
+	EvaluationError string `json:"evaluationError,omitempty"`
+}
+
+// SelfSubjectAccessReview checks whether the current user can perform an action.
+type SelfSubjectAccessReview struct {
+	TypeMeta
+	ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   SelfSubjectAccessReviewSpec `json:"spec"`
+	Status SubjectAccessReviewStatus   `json:"status,omitempty"`
+}
@@ -17,6 +17,8 @@
 package common
 
 import (
+	"time"
+
 	"github.com/pkg/errors"
 )
 
@@ -39,17 +41,56 @@ type HostPathVolumeSource struct {
 // https://pkg.go.dev/k8s.io/api/core/v1#EmptyDirVolumeSource
 type EmptyDirVolumeSource struct{}
 
+// ContainerStateDetails holds details of container's state.
+// Not all fields are set for every state, see https://pkg.go.dev/k8s.io/api/core/v1#ContainerState.
+type ContainerStateDetails struct {
+	// Reason of the state, like CrashLoopBackOff or OOMKilled.
+	Reason string `json:"reason,omitempty"`
+	// Message regarding the state.
+	Message string `json:"message,omitempty"`
+	// ExitCode of the terminated container.
+	ExitCode int32 `json:"exitCode,omitempty"`
+	// StartedAt is the time the container started.
+	StartedAt *time.Time `json:"startedAt,omitempty"`
+	// FinishedAt is the time the container terminated.
+	FinishedAt *time.Time `json:"finishedAt,omitempty"`
+}
+
 // ContainerStatus contains container's status.
 type ContainerStatus struct {
-	Name  string              `json:"name,omitempty"`
-	Ready bool                `json:"ready,omitempty"`
-	State map[string]struct{} `json:"state,omitempty"`
+	Name  string `json:"name,omitempty"`
+	Ready bool   `json:"ready,omitempty"`
+	// State is keyed by container state: waiting, running or terminated.
+	State map[string]ContainerStateDetails `json:"state,omitempty"`
+	// LastTerminationState holds the last termination state of the container.
+	LastTerminationState map[string]ContainerStateDetails `json:"lastState,omitempty"`
+	// RestartCount is the number of times the container has been restarted.
+	RestartCount int32 `json:"restartCount,omitempty"`
+}
+
+// ObjectFieldSelector selects a field of the object.
+type ObjectFieldSelector struct {
+	// Path of the field to select, like metadata.namespace.
+	FieldPath string `json:"fieldPath"`
+}
+
+// EnvVarSource represents a source for the value of an EnvVar.
+type EnvVarSource struct {
+	FieldRef *ObjectFieldSelector `json:"fieldRef,omitempty"`
+}
+
+// EnvVar represents an environment variable present in a container.
+type EnvVar struct {
+	Name      string        `json:"name"`
+	Value     string        `json:"value,omitempty"`
+	ValueFrom *EnvVarSource `json:"valueFrom,omitempty"`
 }
 
 // ContainerSpec represents a container definition.
 type ContainerSpec struct {
 	Name      string               `json:"name,omitempty"`
 	Image     string               `json:"image,omitempty"`
+	Env       []EnvVar             `json:"env,omitempty"`
 	Resources ResourceRequirements `json:"resources,omitempty"`
 }
 
@@ -113,6 +154,8 @@ const (
 	// ContainerStateWaiting represents a state when container requires some
 	// operations being done in order to complete start up.
 	ContainerStateWaiting ContainerState = "waiting"
+	// ContainerStateRunning indicates that container is executing without issues.
+	ContainerStateRunning ContainerState = "running"
 	// ContainerStateTerminated indicates that container began execution and
 	// then either ran to completion or failed for some reason.
 	ContainerStateTerminated ContainerState = "terminated"
@@ -138,15 +181,29 @@ type DeploymentTemplate struct {
 
 // DeploymentSpec details deployment specification.
 type DeploymentSpec struct {
+	Replicas *int32             `json:"replicas,omitempty"`
 	Selector LabelSelector      `json:"selector,omitempty"`
 	Template DeploymentTemplate `json:"template,omitempty"`
 }
 
+// DeploymentStatus is the most recently observed status of the deployment.
+type DeploymentStatus struct {
+	// Total number of pods targeted by the deployment.
+	Replicas int32 `json:"replicas,omitempty"`
+	// Total number of pods targeted by the deployment that have the desired template spec.
+	UpdatedReplicas int32 `json:"updatedReplicas,omitempty"`
+	// Total number of ready pods targeted by the deployment.
+	ReadyReplicas int32 `json:"readyReplicas,omitempty"`
+	// Total number of available pods (ready for at least minReadySeconds) targeted by the deployment.
+	AvailableReplicas int32 `json:"availableReplicas,omitempty"`
+}
+
 // Deployment is a higher abstraction based on pods. It's basically a group of pods.
 type Deployment struct {
 	TypeMeta
 	ObjectMeta `json:"metadata,omitempty"`
-	Spec       DeploymentSpec `json:"spec,omitempty"`
+	Spec       DeploymentSpec    `json:"spec,omitempty"`
+	Status     *DeploymentStatus `json:"status,omitempty"`
 }
 
 // PodStatus holds pod status.
@@ -159,6 +216,36 @@ type PodStatus struct {
 
 	// Phase holds pod's phase.
 	Phase PodPhase `json:"phase,omitempty"`
+
+	// Conditions holds current service state of pod.
+	Conditions []PodCondition `json:"conditions,omitempty"`
+
+	// StartTime is the time when the pod was acknowledged by the kubelet.
+	StartTime *time.Time `json:"startTime,omitempty"`
+}
+
+// PodConditionType is a valid value for PodCondition.Type.
+type PodConditionType string
+
+const (
+	// PodConditionReady means the pod is able to service requests.
+	PodConditionReady PodConditionType = "Ready"
+	// PodConditionScheduled represents status of the scheduling process for the pod.
+	PodConditionScheduled PodConditionType = "PodScheduled"
+)
+
+// ConditionStatus is a status of the condition: True, False or Unknown.
+type ConditionStatus string
+
+// ConditionTrue means the resource is in the condition.
+const ConditionTrue ConditionStatus = "True"
+
+// PodCondition contains details for the current condition of the pod.
+type PodCondition struct {
+	Type    PodConditionType `json:"type"`
+	Status  ConditionStatus  `json:"status"`
+	Reason  string           `json:"reason,omitempty"`
+	Message string           `json:"message,omitempty"`
 }
 
 // Pod is a collection of containers that can run on a host. This resource is created
@@ -215,6 +302,16 @@ type Secret struct {
 
 type SecretType string
 
+// ConfigMap holds configuration data for pods to consume.
+type ConfigMap struct {
+	TypeMeta
+	// Standard object's metadata.
+	ObjectMeta `json:"metadata,omitempty"`
+
+	// Data contains the configuration data.
+	Data map[string]string `json:"data,omitempty"`
+}
+
 const (
 	// SecretTypeOpaque is the default. Arbitrary user-defined data.
 	SecretTypeOpaque SecretType = "Opaque"
@@ -258,6 +355,8 @@ func IsNodeInCondition(node Node, conditionType NodeConditionType) bool {
 
 // NodeStatus holds Kubernetes node status.
 type NodeStatus struct {
+	// Capacity represents the total resources of the node.
+	Capacity ResourceList `json:"capacity,omitempty"`
 	// Allocatable is amount of recources from node's capacity that is available
 	// for allocation by pods. The difference between capacity and allocatable of
 	// the node is reserved for Kubernetes overhead and non-Kubernetes processes.
@@ -275,8 +374,32 @@ type NodeStatus struct {
 type Taint struct {
 	Effect string `json:"effect,omitempty"`
 	Key    string `json:"key,omitempty"`
+	Value  string `json:"value,omitempty"`
 }
 
+const (
+	// TaintEffectNoSchedule forbids scheduling of pods that don't tolerate the taint.
+	TaintEffectNoSchedule = "NoSchedule"
+	// TaintEffectPreferNoSchedule tries to avoid scheduling of pods that don't tolerate the taint.
+	TaintEffectPreferNoSchedule = "PreferNoSchedule"
+	// TaintEffectNoExecute evicts running pods that don't tolerate the taint.
+	TaintEffectNoExecute = "NoExecute"
+)
+
+const (
+	// LabelTopologyZone is a node label holding availability zone of the node.
+	LabelTopologyZone = "topology.kubernetes.io/zone"
+	// LabelFailureDomainBetaZone is a deprecated node label holding availability zone of the node.
+	LabelFailureDomainBetaZone = "failure-domain.beta.kubernetes.io/zone"
+
+	// LabelInstance is a label operators set to the name of database cluster the pod belongs to.
+	LabelInstance = "app.kubernetes.io/instance"
+	// LabelComponent is a label operators set to the component of database cluster, like "pxc" or "mongos".
+	LabelComponent = "app.kubernetes.io/component"
+	// LabelReplset is a label PSMDB operator sets to the name of replica set the pod belongs to.
+	LabelReplset = "app.kubernetes.io/replset"
+)
+
 // Image holds continaer image names and image size.
 type Image struct {
 	Names     []string `json:"names,omitempty"`
@@ -285,7 +408,9 @@ type Image struct {
 
 // NodeSpec holds Kubernetes node specification.
 type NodeSpec struct {
-	Taints []Taint `json:"taints,omitempty"`
+	// ProviderID is the ID of the node assigned by the cloud provider in the format <ProviderName>://<ProviderSpecificNodeID>.
+	ProviderID string  `json:"providerID,omitempty"`
+	Taints     []Taint `json:"taints,omitempty"`
 }
 
 // Node holds information about Kubernetes node.
@@ -298,6 +423,34 @@ type Node struct {
 	Status NodeStatus `json:"status,omitempty"`
 }
 
+// VolumeNodeResources is a set of resource limits for scheduling of volumes.
+type VolumeNodeResources struct {
+	// Count is the maximum number of unique volumes managed by the CSI driver that can be used on a node.
+	Count *int32 `json:"count,omitempty"`
+}
+
+// CSINodeDriver holds information about the specification of one CSI driver installed on a node.
+type CSINodeDriver struct {
+	// Name of the CSI driver.
+	Name string `json:"name"`
+	// NodeID of the node from the driver point of view.
+	NodeID string `json:"nodeID"`
+	// Allocatable represents the volume resources of a node that are available for scheduling.
+	Allocatable *VolumeNodeResources `json:"allocatable,omitempty"`
+}
+
+// CSINodeSpec holds information about the specification of all CSI drivers installed on a node.
+type CSINodeSpec struct {
+	Drivers []CSINodeDriver `json:"drivers"`
+}
+
+// CSINode holds information about all CSI drivers installed on a node. Its name is the name of the node.
+type CSINode struct {
+	TypeMeta
+	ObjectMeta `json:"metadata,omitempty"`
+	Spec       CSINodeSpec `json:"spec"`
+}
+
 // PersistentVolumeCapacity holds string representation of storage size.
 type PersistentVolumeCapacity struct {
 	// Storage size as string.
@@ -316,3 +469,77 @@ type PersistentVolume struct {
 	// Specification of the volume.
 	Spec PersistentVolumeSpec `json:"spec,omitempty"`
 }
+
+// EventType is a type of the event.
+type EventType string
+
+const (
+	// EventTypeNormal is used for information events.
+	EventTypeNormal EventType = "Normal"
+	// EventTypeWarning is used for events that need attention.
+	EventTypeWarning EventType = "Warning"
+)
+
+// ObjectReference contains enough information to let you inspect or modify the referred object.
+type ObjectReference struct {
+	Kind       string `json:"kind,omitempty"`
+	Namespace  string `json:"namespace,omitempty"`
+	Name       string `json:"name,omitempty"`
+	APIVersion string `json:"apiVersion,omitempty"`
+	// FieldPath refers to a piece of an object, for example a container within a pod.
+	FieldPath string `json:"fieldPath,omitempty"`
+}
+
+// EventSource contains information for an event.
+type EventSource struct {
+	// Component from which the event is generated.
+	Component string `json:"component,omitempty"`
+	// Node name on which the event is generated.
+	Host string `json:"host,omitempty"`
+}
+
+// Event is a report of an event somewhere in the cluster.
+type Event struct {
+	TypeMeta
+	ObjectMeta `json:"metadata,omitempty"`
+
+	// The object that this event is about.
+	InvolvedObject ObjectReference `json:"involvedObject"`
+	// This should be a short, machine understandable string that gives the reason
+	// for the transition into the object's current status.
+	Reason string `json:"reason,omitempty"`
+	// A human-readable description of the status of this operation.
+	Message string `json:"message,omitempty"`
+	// The component reporting this event.
+	Source EventSource `json:"source,omitempty"`
+	// The time at which the event was first recorded.
+	FirstTimestamp *time.Time `json:"firstTimestamp,omitempty"`
+	// The time at which the most recent occurrence of this event was recorded.
+	LastTimestamp *time.Time `json:"lastTimestamp,omitempty"`
+	// Time when this event was first observed, set by newer reporters instead of timestamps above.
+	EventTime *time.Time `json:"eventTime,omitempty"`
+	// The number of times this event has occurred.
+	Count int32 `json:"count,omitempty"`
+	// Type of this event (Normal, Warning).
+	Type EventType `json:"type,omitempty"`
+}
+
+// LastSeen returns the time of the most recent occurrence of the event or zero time if it's unknown.
+func (e *Event) LastSeen() time.Time {
+	for _, t := range []*time.Time{e.LastTimestamp, e.EventTime, e.FirstTimestamp} {
+		if t != nil && !t.IsZero() {
+			return *t
+		}
+	}
+	return time.Time{}
+}
+
+// FirstSeen returns the time of the first occurrence of the event or zero time if it's unknown.
+func (e *Event) FirstSeen() time.Time {
+	for _, t := range []*time.Time{e.FirstTimestamp, e.EventTime, e.LastTimestamp} {
+		if t != nil && !t.IsZero() {
+			return *t
+		}
+	}
+	return time.Time{}
+}
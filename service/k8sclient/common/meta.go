@@ -16,6 +16,8 @@
 
 package common
 
+import "time"
+
 // Extracted from https://pkg.go.dev/k8s.io/apimachinery/pkg/apis/meta/v1
 
 // TypeMeta describes an individual object in an API response or request
@@ -47,6 +49,11 @@ type ObjectMeta struct {
 	// More info: http://kubernetes.io/docs/user-guide/identifiers#names
 	Name string `json:"name,omitempty"`
 
+	// Namespace defines the space within which each name must be unique.
+	// Not all objects are required to be scoped to a namespace.
+	// More info: http://kubernetes.io/docs/user-guide/namespaces
+	Namespace string `json:"namespace,omitempty"`
+
 	// Map of string keys and values that can be used to organize and categorize
 	// (scope and select) objects. May match selectors of replication controllers
 	// and services.
@@ -67,6 +74,14 @@ type ObjectMeta struct {
 	// Without enforced ordering finalizers are free to order amongst themselves and
 	// are not vulnerable to ordering changes in the list.
 	Finalizers []string `json:"finalizers,omitempty"`
+
+	// CreationTimestamp is a timestamp representing the server time when this object was created.
+	// Populated by the system. Read-only.
+	CreationTimestamp *time.Time `json:"creationTimestamp,omitempty"`
+
+	// A sequence number representing a specific generation of the desired state.
+	// Populated by the system. Read-only.
+	Generation int64 `json:"generation,omitempty"`
 }
 
 // Note:
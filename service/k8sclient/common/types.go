@@ -109,6 +109,13 @@ type PodList struct {
 	Items []Pod `json:"items"`
 }
 
+// EventList holds a list of event objects.
+type EventList struct {
+	TypeMeta // anonymous for embedding
+
+	Items []Event `json:"items"`
+}
+
 // PodDisruptionBudgetSpec POD disruption budget specs.
 type PodDisruptionBudgetSpec struct {
 	MinAvailable   *int `json:"minAvailable,omitempty"`
@@ -145,6 +152,13 @@ type NodeList struct {
 	Items []Node `json:"items,omitempty"`
 }
 
+// CSINodeList holds a list of CSI node objects.
+type CSINodeList struct {
+	TypeMeta // anonymous for embedding
+
+	Items []CSINode `json:"items,omitempty"`
+}
+
 // PersistentVolumeList holds a list of persistent volume objects.
 type PersistentVolumeList struct {
 	TypeMeta // anonymous for embedding
@@ -0,0 +1,566 @@
+// dbaas-controller
+// Copyright (C) 2020 Percona LLC
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package k8sclient
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+
+	"github.com/percona-platform/dbaas-controller/service/k8sclient/common"
+)
+
+// networkCheckTimeout limits DNS, TCP and TLS checks of connectivity diagnostics.
+const networkCheckTimeout = 5 * time.Second
+
+// CheckStatus is a result of a diagnostic check.
+type CheckStatus string
+
+const (
+	// CheckStatusOK means the check passed.
+	CheckStatusOK CheckStatus = "ok"
+	// CheckStatusWarning means the check passed, but there is something to pay attention to.
+	CheckStatusWarning CheckStatus = "warning"
+	// CheckStatusFailed means the check failed.
+	CheckStatusFailed CheckStatus = "failed"
+	// CheckStatusSkipped means the check wasn't done because a check it depends on failed.
+	CheckStatusSkipped CheckStatus = "skipped"
+)
+
+// Names of connectivity checks in the order they are done.
+const (
+	CheckKubeconfig    = "kubeconfig"
+	CheckDNS           = "dns"
+	CheckTCP           = "tcp"
+	CheckTLS           = "tls"
+	CheckKubectl       = "kubectl"
+	CheckServerVersion = "server version"
+	CheckAuth          = "authentication"
+	CheckRBAC          = "rbac"
+)
+
+// Permission is an action on Kubernetes resources the controller needs to perform.
+type Permission struct {
+	Verb        string `json:"verb"`
+	Group       string `json:"group,omitempty"`
+	Resource    string `json:"resource"`
+	Subresource string `json:"subresource,omitempty"`
+	// ClusterScoped is true for resources which don't belong to a namespace.
+	ClusterScoped bool `json:"clusterScoped,omitempty"`
+}
+
+// resourceVerbs lists verbs used for the resource.
+type resourceVerbs struct {
+	group, resource, subresource string
+	clusterScoped                bool
+	verbs                        []string
+}
+
+var (
+	// applyVerbs are used by kubectl apply: it gets the object and then creates or patches it.
+	applyVerbs = []string{"get", "create", "patch"} //nolint:gochecknoglobals
+	// applyDeleteVerbs are used for objects of manifests deleted on removal of the operator or monitoring.
+	applyDeleteVerbs = []string{"get", "create", "patch", "delete"} //nolint:gochecknoglobals
+)
+
+// requiredPermissions lists actions the controller performs in Kubernetes clusters, including ones
+// needed to apply and delete operator and monitoring manifests.
+var requiredPermissions = expandPermissions([]resourceVerbs{ //nolint:gochecknoglobals
+	{resource: "pods", verbs: []string{"get", "list"}},
+	{resource: "pods", subresource: "log", verbs: []string{"get"}},
+	{resource: "services", verbs: []string{"get", "list"}},
+	{resource: "secrets", verbs: applyDeleteVerbs},
+	{resource: "configmaps", verbs: applyDeleteVerbs},
+	{resource: "events", verbs: []string{"list"}},
+	{resource: "persistentvolumeclaims", verbs: []string{"list"}},
+	{resource: "serviceaccounts", verbs: applyDeleteVerbs},
+	{resource: "namespaces", clusterScoped: true, verbs: applyDeleteVerbs},
+	{resource: "nodes", clusterScoped: true, verbs: []string{"list"}},
+	{resource: "persistentvolumes", clusterScoped: true, verbs: []string{"list"}},
+	{group: "storage.k8s.io", resource: "storageclasses", clusterScoped: true, verbs: []string{"list"}},
+	{group: "storage.k8s.io", resource: "csinodes", clusterScoped: true, verbs: []string{"list"}},
+	// kubectl rollout status watches the deployment.
+	{group: "apps", resource: "deployments", verbs: []string{"get", "list", "watch", "create", "patch", "delete"}},
+	{group: "apps", resource: "statefulsets", verbs: []string{"get", "list", "patch"}},
+	{group: "apiextensions.k8s.io", resource: "customresourcedefinitions", clusterScoped: true, verbs: applyDeleteVerbs},
+	{group: "rbac.authorization.k8s.io", resource: "roles", verbs: applyDeleteVerbs},
+	{group: "rbac.authorization.k8s.io", resource: "rolebindings", verbs: applyDeleteVerbs},
+	{group: "rbac.authorization.k8s.io", resource: "clusterroles", clusterScoped: true, verbs: applyDeleteVerbs},
+	{group: "rbac.authorization.k8s.io", resource: "clusterrolebindings", clusterScoped: true, verbs: applyDeleteVerbs},
+	{group: "policy", resource: "podsecuritypolicies", clusterScoped: true, verbs: applyDeleteVerbs},
+	{group: "pxc.percona.com", resource: "perconaxtradbclusters", verbs: []string{"get", "list", "create", "patch", "delete"}},
+	{group: "pxc.percona.com", resource: "perconaxtradbclusterbackups", verbs: []string{"get", "list"}},
+	{group: "pxc.percona.com", resource: "perconaxtradbclusterrestores", verbs: applyVerbs},
+	{group: "psmdb.percona.com", resource: "perconaservermongodbs", verbs: []string{"get", "list", "create", "patch", "delete"}},
+	{group: "psmdb.percona.com", resource: "perconaservermongodbbackups", verbs: []string{"get", "list"}},
+	{group: "psmdb.percona.com", resource: "perconaservermongodbrestores", verbs: applyVerbs},
+	{group: "operator.victoriametrics.com", resource: "vmagents", verbs: []string{"get", "list", "create", "patch", "delete"}},
+	{group: "operator.victoriametrics.com", resource: "vmnodescrapes", verbs: applyDeleteVerbs},
+	{group: "operator.victoriametrics.com", resource: "vmpodscrapes", verbs: applyDeleteVerbs},
+})
+
+// expandPermissions returns a permission for every verb of given resources.
+func expandPermissions(resources []resourceVerbs) []Permission {
+	var res []Permission
+	for _, r := range resources {
+		for _, verb := range r.verbs {
+			res = append(res, Permission{
+				Verb:          verb,
+				Group:         r.group,
+				Resource:      r.resource,
+				Subresource:   r.subresource,
+				ClusterScoped: r.clusterScoped,
+			})
+		}
+	}
+	return res
+}
+
+// String returns permission in kubectl auth can-i form, like "get pods/log" or "create roles.rbac.authorization.k8s.io".
+func (p Permission) String() string {
+	resource := p.Resource
+	if p.Group != "" {
+		resource += "." + p.Group
+	}
+	if p.Subresource != "" {
+		resource += "/" + p.Subresource
+	}
+	return p.Verb + " " + resource
+}
+
+// DiagnosticCheck is a result of a single connectivity check.
+type DiagnosticCheck struct {
+	Name    string      `json:"name"`
+	Status  CheckStatus `json:"status"`
+	Message string      `json:"message"`
+}
+
+// PermissionCheck is a result of a check of the permission.
+type PermissionCheck struct {
+	Permission
+	Allowed bool   `json:"allowed"`
+	Reason  string `json:"reason,omitempty"`
+}
+
+// KubeconfigContext describes context of kubeconfig.
+type KubeconfigContext struct {
+	Name      string `json:"name"`
+	Cluster   string `json:"cluster"`
+	User      string `json:"user"`
+	Namespace string `json:"namespace,omitempty"`
+	Current   bool   `json:"current,omitempty"`
+}
+
+// KubeconfigCluster describes cluster of kubeconfig.
+type KubeconfigCluster struct {
+	Name                    string `json:"name"`
+	Server                  string `json:"server"`
+	HasCertificateAuthority bool   `json:"hasCertificateAuthority"`
+	InsecureSkipTLSVerify   bool   `json:"insecureSkipTLSVerify"`
+}
+
+// KubeconfigUser describes user of kubeconfig. Credentials are never exposed, only methods of authentication.
+type KubeconfigUser struct {
+	Name        string   `json:"name"`
+	AuthMethods []string `json:"authMethods"`
+}
+
+// ConnectionDiagnostics is a checklist of connectivity to Kubernetes cluster.
+type ConnectionDiagnostics struct {
+	Contexts       []KubeconfigContext `json:"contexts"`
+	Clusters       []KubeconfigCluster `json:"clusters"`
+	Users          []KubeconfigUser    `json:"users"`
+	CurrentContext string              `json:"currentContext"`
+	Namespace      string              `json:"namespace"`
+	Server         string              `json:"server"`
+	ServerVersion  string              `json:"serverVersion"`
+	KubectlCommand string              `json:"kubectlCommand"`
+	KubectlVersion string              `json:"kubectlVersion"`
+	Checks         []DiagnosticCheck   `json:"checks"`
+	Permissions    []PermissionCheck   `json:"permissions"`
+}
+
+// add adds the result of the check.
+func (d *ConnectionDiagnostics) add(name string, status CheckStatus, format string, args ...interface{}) {
+	d.Checks = append(d.Checks, DiagnosticCheck{Name: name, Status: status, Message: fmt.Sprintf(format, args...)})
+}
+
+// skip marks given checks as skipped because of failed check.
+func (d *ConnectionDiagnostics) skip(failed string, names ...string) {
+	for _, name := range names {
+		d.add(name, CheckStatusSkipped, "%s check failed", failed)
+	}
+}
+
+// FirstFailure returns the first failed check or nil if no check failed.
+func (d *ConnectionDiagnostics) FirstFailure() *DiagnosticCheck {
+	for i := range d.Checks {
+		if d.Checks[i].Status == CheckStatusFailed {
+			return &d.Checks[i]
+		}
+	}
+	return nil
+}
+
+// authMethods returns methods of authentication configured for the user.
+func authMethods(user *clientcmdapi.AuthInfo) []string {
+	var res []string
+	if len(user.ClientCertificateData) != 0 || user.ClientCertificate != "" {
+		res = append(res, "client certificate")
+	}
+	if user.Token != "" || user.TokenFile != "" {
+		res = append(res, "token")
+	}
+	if user.Username != "" || user.Password != "" {
+		res = append(res, "basic")
+	}
+	if user.Exec != nil {
+		res = append(res, "exec: "+user.Exec.Command)
+	}
+	if user.AuthProvider != nil {
+		res = append(res, "auth provider: "+user.AuthProvider.Name)
+	}
+	return res
+}
+
+// describeKubeconfig fills contexts, clusters and users of kubeconfig sorted by their names.
+func (d *ConnectionDiagnostics) describeKubeconfig(config *clientcmdapi.Config) {
+	d.CurrentContext = config.CurrentContext
+	for name, c := range config.Contexts {
+		d.Contexts = append(d.Contexts, KubeconfigContext{
+			Name:      name,
+			Cluster:   c.Cluster,
+			User:      c.AuthInfo,
+			Namespace: c.Namespace,
+			Current:   name == config.CurrentContext,
+		})
+	}
+	for name, c := range config.Clusters {
+		d.Clusters = append(d.Clusters, KubeconfigCluster{
+			Name:                    name,
+			Server:                  c.Server,
+			HasCertificateAuthority: len(c.CertificateAuthorityData) != 0 || c.CertificateAuthority != "",
+			InsecureSkipTLSVerify:   c.InsecureSkipTLSVerify,
+		})
+	}
+	for name, u := range config.AuthInfos {
+		d.Users = append(d.Users, KubeconfigUser{Name: name, AuthMethods: authMethods(u)})
+	}
+	sort.Slice(d.Contexts, func(i, j int) bool { return d.Contexts[i].Name < d.Contexts[j].Name })
+	sort.Slice(d.Clusters, func(i, j int) bool { return d.Clusters[i].Name < d.Clusters[j].Name })
+	sort.Slice(d.Users, func(i, j int) bool { return d.Users[i].Name < d.Users[j].Name })
+}
+
+// parseKubeconfig parses kubeconfig and returns REST client config of the current context.
+func (d *ConnectionDiagnostics) parseKubeconfig(kubeconfig string) (*rest.Config, error) {
+	config, err := clientcmd.Load([]byte(kubeconfig))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to parse kubeconfig")
+	}
+	d.describeKubeconfig(config)
+
+	clientConfig := clientcmd.NewDefaultClientConfig(*config, nil)
+	restConfig, err := clientConfig.ClientConfig()
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid kubeconfig")
+	}
+	d.Namespace, _, err = clientConfig.Namespace()
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid kubeconfig")
+	}
+	d.Server = restConfig.Host
+	return restConfig, nil
+}
+
+// serverAddress returns scheme and host:port of Kubernetes API server.
+func serverAddress(server string) (scheme, host, port string, err error) {
+	if !strings.Contains(server, "://") {
+		server = "https://" + server
+	}
+	u, err := url.Parse(server)
+	if err != nil {
+		return "", "", "", errors.Wrap(err, "invalid server URL")
+	}
+	if u.Hostname() == "" {
+		return "", "", "", errors.Errorf("invalid server URL %q: host is empty", server)
+	}
+	port = u.Port()
+	if port == "" {
+		port = "443"
+		if u.Scheme == "http" {
+			port = "80"
+		}
+	}
+	return u.Scheme, u.Hostname(), port, nil
+}
+
+// checkNetwork checks DNS resolution, TCP connection and TLS handshake with the API server.
+// It returns false if any of them failed.
+func (d *ConnectionDiagnostics) checkNetwork(ctx context.Context, restConfig *rest.Config) bool {
+	scheme, host, port, err := serverAddress(restConfig.Host)
+	if err != nil {
+		d.add(CheckDNS, CheckStatusFailed, "%s", err)
+		d.skip(CheckDNS, CheckTCP, CheckTLS)
+		return false
+	}
+
+	if net.ParseIP(host) != nil {
+		d.add(CheckDNS, CheckStatusOK, "%s is an IP address", host)
+	} else {
+		dnsCtx, cancel := context.WithTimeout(ctx, networkCheckTimeout)
+		addrs, err := net.DefaultResolver.LookupHost(dnsCtx, host)
+		cancel()
+		if err != nil {
+			d.add(CheckDNS, CheckStatusFailed, "failed to resolve %s: %s", host, err)
+			d.skip(CheckDNS, CheckTCP, CheckTLS)
+			return false
+		}
+		d.add(CheckDNS, CheckStatusOK, "%s resolved to %s", host, strings.Join(addrs, ", "))
+	}
+
+	addr := net.JoinHostPort(host, port)
+	dialer := &net.Dialer{Timeout: networkCheckTimeout}
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		d.add(CheckTCP, CheckStatusFailed, "failed to connect to %s: %s", addr, err)
+		d.skip(CheckTCP, CheckTLS)
+		return false
+	}
+	conn.Close() //nolint:errcheck
+	d.add(CheckTCP, CheckStatusOK, "connected to %s", addr)
+
+	if scheme != "https" {
+		d.add(CheckTLS, CheckStatusWarning, "API server is accessed without TLS")
+		return true
+	}
+	tlsConfig, err := rest.TLSConfigFor(restConfig)
+	if err != nil {
+		d.add(CheckTLS, CheckStatusFailed, "invalid TLS configuration: %s", err)
+		return false
+	}
+	if tlsConfig == nil {
+		tlsConfig = new(tls.Config) //nolint:gosec
+	}
+	tlsDialer := &tls.Dialer{NetDialer: dialer, Config: tlsConfig}
+	tlsConn, err := tlsDialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		d.add(CheckTLS, CheckStatusFailed, "TLS handshake with %s failed: %s", addr, err)
+		return false
+	}
+	state := tlsConn.(*tls.Conn).ConnectionState()
+	tlsConn.Close() //nolint:errcheck
+
+	var certInfo string
+	if len(state.PeerCertificates) != 0 {
+		cert := state.PeerCertificates[0]
+		certInfo = fmt.Sprintf(", server certificate %q expires at %s", cert.Subject.CommonName, cert.NotAfter.UTC().Format(time.RFC3339))
+	}
+	if tlsConfig.InsecureSkipVerify {
+		d.add(CheckTLS, CheckStatusWarning, "server certificate is not verified%s", certInfo)
+		return true
+	}
+	d.add(CheckTLS, CheckStatusOK, "server certificate is verified%s", certInfo)
+	return true
+}
+
+// kubectlVersions is the output of `kubectl version -o json`.
+type kubectlVersions struct {
+	ClientVersion *struct {
+		GitVersion string `json:"gitVersion"`
+	} `json:"clientVersion"`
+	ServerVersion *struct {
+		GitVersion string `json:"gitVersion"`
+	} `json:"serverVersion"`
+}
+
+// checkServerVersion gets versions of kubectl and Kubernetes API server.
+func (d *ConnectionDiagnostics) checkServerVersion(ctx context.Context, c *K8sClient) {
+	out, err := c.kubeCtl.Run(ctx, []string{"version", "-o", "json"}, nil)
+	if err != nil {
+		d.add(CheckServerVersion, CheckStatusFailed, "failed to get server version: %s", err)
+		return
+	}
+	var versions kubectlVersions
+	if err := json.Unmarshal(out, &versions); err != nil {
+		d.add(CheckServerVersion, CheckStatusFailed, "failed to decode versions: %s", err)
+		return
+	}
+	if versions.ClientVersion != nil {
+		d.KubectlVersion = versions.ClientVersion.GitVersion
+	}
+	if versions.ServerVersion == nil {
+		d.add(CheckServerVersion, CheckStatusFailed, "server version is unknown")
+		return
+	}
+	d.ServerVersion = versions.ServerVersion.GitVersion
+	d.add(CheckServerVersion, CheckStatusOK, "Kubernetes %s, kubectl %s", d.ServerVersion, d.KubectlVersion)
+}
+
+// checkAuth checks that the API server accepts credentials. Anonymous access to API discovery is disabled
+// in most clusters, so it requires authentication.
+func (d *ConnectionDiagnostics) checkAuth(ctx context.Context, c *K8sClient) bool {
+	if _, err := c.kubeCtl.Run(ctx, []string{"get", "--raw", "/api"}, nil); err != nil {
+		msg := err.Error()
+		if strings.Contains(msg, "Unauthorized") || strings.Contains(msg, "You must be logged in") {
+			d.add(CheckAuth, CheckStatusFailed, "credentials were rejected by the API server: %s", msg)
+		} else {
+			d.add(CheckAuth, CheckStatusFailed, "%s", msg)
+		}
+		return false
+	}
+	d.add(CheckAuth, CheckStatusOK, "credentials were accepted by the API server")
+	return true
+}
+
+// selfSubjectAccessReviewList is a list of access reviews created at once.
+type selfSubjectAccessReviewList struct {
+	common.TypeMeta
+	Items []common.SelfSubjectAccessReview `json:"items"`
+}
+
+// decodeAccessReviews decodes access reviews created by kubectl. It returns
+// a single object instead of a list if only one review was created.
+func decodeAccessReviews(out []byte) ([]common.SelfSubjectAccessReview, error) {
+	var list selfSubjectAccessReviewList
+	if err := json.Unmarshal(out, &list); err != nil {
+		return nil, errors.Wrap(err, "failed to decode access reviews")
+	}
+	if list.Kind != common.SelfSubjectAccessReviewKind {
+		return list.Items, nil
+	}
+	var review common.SelfSubjectAccessReview
+	if err := json.Unmarshal(out, &review); err != nil {
+		return nil, errors.Wrap(err, "failed to decode access review")
+	}
+	return []common.SelfSubjectAccessReview{review}, nil
+}
+
+// CheckPermissions checks whether the current user has given permissions in the namespace
+// using SelfSubjectAccessReview.
+func (c *K8sClient) CheckPermissions(ctx context.Context, namespace string, permissions []Permission) ([]PermissionCheck, error) {
+	reviews := make([]common.SelfSubjectAccessReview, len(permissions))
+	for i, p := range permissions {
+		attributes := &common.ResourceAttributes{
+			Verb:        p.Verb,
+			Group:       p.Group,
+			Resource:    p.Resource,
+			Subresource: p.Subresource,
+		}
+		if !p.ClusterScoped {
+			attributes.Namespace = namespace
+		}
+		reviews[i] = common.SelfSubjectAccessReview{
+			TypeMeta: common.TypeMeta{Kind: common.SelfSubjectAccessReviewKind, APIVersion: common.AuthorizationAPIVersion},
+			Spec:     common.SelfSubjectAccessReviewSpec{ResourceAttributes: attributes},
+		}
+	}
+
+	out, err := c.kubeCtl.Run(ctx, []string{"create", "-f", "-", "-o", "json"}, &selfSubjectAccessReviewList{
+		TypeMeta: common.TypeMeta{Kind: "List", APIVersion: "v1"},
+		Items:    reviews,
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to review access")
+	}
+	reviews, err = decodeAccessReviews(out)
+	if err != nil {
+		return nil, err
+	}
+	if len(reviews) != len(permissions) {
+		return nil, errors.Errorf("expected %d access reviews, got %d", len(permissions), len(reviews))
+	}
+
+	res := make([]PermissionCheck, len(permissions))
+	for i, review := range reviews {
+		reason := review.Status.Reason
+		if review.Status.EvaluationError != "" {
+			reason = strings.TrimSpace(reason + " " + review.Status.EvaluationError)
+		}
+		res[i] = PermissionCheck{Permission: permissions[i], Allowed: review.Status.Allowed, Reason: reason}
+	}
+	return res, nil
+}
+
+// checkRBAC checks permissions required by the controller.
+func (d *ConnectionDiagnostics) checkRBAC(ctx context.Context, c *K8sClient) {
+	checks, err := c.CheckPermissions(ctx, d.Namespace, requiredPermissions)
+	if err != nil {
+		d.add(CheckRBAC, CheckStatusFailed, "%s", err)
+		return
+	}
+	d.Permissions = checks
+
+	var missing []string
+	for _, check := range checks {
+		if !check.Allowed {
+			missing = append(missing, check.Permission.String())
+		}
+	}
+	if len(missing) != 0 {
+		d.add(CheckRBAC, CheckStatusFailed, "%d of %d required permissions are missing: %s", len(missing), len(checks), strings.Join(missing, ", "))
+		return
+	}
+	d.add(CheckRBAC, CheckStatusOK, "all %d required permissions are granted", len(checks))
+}
+
+// DiagnoseConnection checks kubeconfig and connectivity to Kubernetes cluster step by step:
+// kubeconfig parsing, DNS resolution, TCP connection, TLS handshake, kubectl selection,
+// server version, authentication and permissions. Checks depending on failed ones are skipped.
+func DiagnoseConnection(ctx context.Context, kubeconfig string) *ConnectionDiagnostics {
+	d := new(ConnectionDiagnostics)
+	restConfig, err := d.parseKubeconfig(kubeconfig)
+	if err != nil {
+		d.add(CheckKubeconfig, CheckStatusFailed, "%s", err)
+		d.skip(CheckKubeconfig, CheckDNS, CheckTCP, CheckTLS, CheckKubectl, CheckServerVersion, CheckAuth, CheckRBAC)
+		return d
+	}
+	d.add(CheckKubeconfig, CheckStatusOK, "using context %q, server %s, namespace %q", d.CurrentContext, d.Server, d.Namespace)
+
+	if !d.checkNetwork(ctx, restConfig) {
+		d.skip("network", CheckKubectl, CheckServerVersion, CheckAuth, CheckRBAC)
+		return d
+	}
+
+	c, err := New(ctx, kubeconfig)
+	if err != nil {
+		d.add(CheckKubectl, CheckStatusFailed, "failed to select kubectl: %s", err)
+		d.skip(CheckKubectl, CheckServerVersion, CheckAuth, CheckRBAC)
+		return d
+	}
+	defer c.Cleanup() //nolint:errcheck
+	d.KubectlCommand = c.kubeCtl.Command()
+	d.add(CheckKubectl, CheckStatusOK, "using %s", d.KubectlCommand)
+
+	d.checkServerVersion(ctx, c)
+	if !d.checkAuth(ctx, c) {
+		d.skip(CheckAuth, CheckRBAC)
+		return d
+	}
+	d.checkRBAC(ctx, c)
+	return d
+}
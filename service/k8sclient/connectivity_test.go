@@ -0,0 +1,182 @@
+// dbaas-controller
+// Copyright (C) 2020 Percona LLC
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package k8sclient
+
+import (
+	"context"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/client-go/rest"
+)
+
+const diagnosticsKubeconfig = `apiVersion: v1
+kind: Config
+current-context: prod
+contexts:
+- name: prod
+  context:
+    cluster: prod
+    user: admin
+    namespace: dbaas
+- name: dev
+  context:
+    cluster: dev
+    user: eks
+clusters:
+- name: prod
+  cluster:
+    server: https://127.0.0.1:1
+    insecure-skip-tls-verify: true
+- name: dev
+  cluster:
+    server: https://dev.example.com
+users:
+- name: admin
+  user:
+    token: secret-token
+- name: eks
+  user:
+    exec:
+      apiVersion: client.authentication.k8s.io/v1beta1
+      command: aws-iam-authenticator
+`
+
+func TestDiagnoseConnection(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+
+	t.Run("InvalidKubeconfig", func(t *testing.T) {
+		t.Parallel()
+		d := DiagnoseConnection(ctx, "clusters: [")
+		require.Len(t, d.Checks, 8)
+		assert.Equal(t, CheckKubeconfig, d.FirstFailure().Name)
+		for _, check := range d.Checks[1:] {
+			assert.Equal(t, CheckStatusSkipped, check.Status, check.Name)
+		}
+	})
+
+	t.Run("Unreachable", func(t *testing.T) {
+		t.Parallel()
+		d := DiagnoseConnection(ctx, diagnosticsKubeconfig)
+		assert.Equal(t, "prod", d.CurrentContext)
+		assert.Equal(t, "dbaas", d.Namespace)
+		assert.Equal(t, "https://127.0.0.1:1", d.Server)
+		assert.Equal(t, []KubeconfigContext{
+			{Name: "dev", Cluster: "dev", User: "eks"},
+			{Name: "prod", Cluster: "prod", User: "admin", Namespace: "dbaas", Current: true},
+		}, d.Contexts)
+		assert.Equal(t, []KubeconfigCluster{
+			{Name: "dev", Server: "https://dev.example.com"},
+			{Name: "prod", Server: "https://127.0.0.1:1", InsecureSkipTLSVerify: true},
+		}, d.Clusters)
+		assert.Equal(t, []KubeconfigUser{
+			{Name: "admin", AuthMethods: []string{"token"}},
+			{Name: "eks", AuthMethods: []string{"exec: aws-iam-authenticator"}},
+		}, d.Users)
+
+		statuses := make(map[string]CheckStatus)
+		for _, check := range d.Checks {
+			statuses[check.Name] = check.Status
+			assert.NotContains(t, check.Message, "secret-token")
+		}
+		assert.Equal(t, map[string]CheckStatus{
+			CheckKubeconfig:    CheckStatusOK,
+			CheckDNS:           CheckStatusOK,
+			CheckTCP:           CheckStatusFailed,
+			CheckTLS:           CheckStatusSkipped,
+			CheckKubectl:       CheckStatusSkipped,
+			CheckServerVersion: CheckStatusSkipped,
+			CheckAuth:          CheckStatusSkipped,
+			CheckRBAC:          CheckStatusSkipped,
+		}, statuses)
+		assert.Equal(t, CheckTCP, d.FirstFailure().Name)
+	})
+}
+
+func TestCheckNetworkTLS(t *testing.T) {
+	t.Parallel()
+	server := httptest.NewTLSServer(http.NotFoundHandler())
+	t.Cleanup(server.Close)
+	ca := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+
+	for _, tt := range []struct {
+		name   string
+		config rest.TLSClientConfig
+		status CheckStatus
+	}{
+		{name: "verified", config: rest.TLSClientConfig{CAData: ca}, status: CheckStatusOK},
+		{name: "unknown authority", status: CheckStatusFailed},
+		{name: "insecure", config: rest.TLSClientConfig{Insecure: true}, status: CheckStatusWarning},
+	} {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			d := new(ConnectionDiagnostics)
+			ok := d.checkNetwork(context.Background(), &rest.Config{Host: server.URL, TLSClientConfig: tt.config})
+			assert.Equal(t, tt.status != CheckStatusFailed, ok)
+			require.Len(t, d.Checks, 3)
+			assert.Equal(t, CheckTLS, d.Checks[2].Name)
+			assert.Equal(t, tt.status, d.Checks[2].Status, d.Checks[2].Message)
+		})
+	}
+}
+
+func TestServerAddress(t *testing.T) {
+	t.Parallel()
+	for _, tt := range []struct {
+		server, scheme, host, port string
+	}{
+		{"https://10.0.0.1:6443", "https", "10.0.0.1", "6443"},
+		{"https://api.example.com", "https", "api.example.com", "443"},
+		{"http://localhost", "http", "localhost", "80"},
+		{"api.example.com:8443", "https", "api.example.com", "8443"},
+		{"https://[::1]:6443/prefix", "https", "::1", "6443"},
+	} {
+		scheme, host, port, err := serverAddress(tt.server)
+		require.NoError(t, err, tt.server)
+		assert.Equal(t, []string{tt.scheme, tt.host, tt.port}, []string{scheme, host, port}, tt.server)
+	}
+	_, _, _, err := serverAddress("https://")
+	assert.Error(t, err)
+}
+
+func TestAccessReviews(t *testing.T) {
+	t.Parallel()
+	const list = `{"apiVersion": "v1", "kind": "List", "items": [
+		{"kind": "SelfSubjectAccessReview", "status": {"allowed": true, "reason": "RBAC: allowed by RoleBinding"}},
+		{"kind": "SelfSubjectAccessReview", "status": {"allowed": false}}
+	]}`
+	reviews, err := decodeAccessReviews([]byte(list))
+	require.NoError(t, err)
+	require.Len(t, reviews, 2)
+	assert.True(t, reviews[0].Status.Allowed)
+	assert.False(t, reviews[1].Status.Allowed)
+
+	const single = `{"kind": "SelfSubjectAccessReview", "spec": {"resourceAttributes": {"verb": "get"}}, "status": {"allowed": true}}`
+	reviews, err = decodeAccessReviews([]byte(single))
+	require.NoError(t, err)
+	require.Len(t, reviews, 1)
+	assert.Equal(t, "get", reviews[0].Spec.ResourceAttributes.Verb)
+
+	assert.Equal(t, "get pods/log", Permission{Verb: "get", Resource: "pods", Subresource: "log"}.String())
+	assert.Equal(t, "create roles.rbac.authorization.k8s.io", Permission{Verb: "create", Group: "rbac.authorization.k8s.io", Resource: "roles"}.String())
+}
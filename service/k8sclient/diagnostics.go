@@ -0,0 +1,91 @@
+// dbaas-controller
+// Copyright (C) 2020 Percona LLC
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package k8sclient
+
+import (
+	"context"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/percona-platform/dbaas-controller/service/k8sclient/internal/kubectl"
+	"github.com/percona-platform/dbaas-controller/service/k8sclient/internal/psmdb"
+	"github.com/percona-platform/dbaas-controller/service/k8sclient/internal/pxc"
+)
+
+// clusterKindsByEngine maps engines to kinds of their database cluster custom resources.
+var clusterKindsByEngine = []struct { //nolint:gochecknoglobals
+	engine Engine
+	kind   string
+}{
+	{engine: EnginePXC, kind: pxc.PerconaXtraDBClusterKind},
+	{engine: EnginePSMDB, kind: psmdb.PerconaServerMongoDBKind},
+}
+
+// GetObjectsJSON returns objects of given comma separated kinds as JSON, exactly as kubectl returns them.
+// Filters, like names or label selectors, are passed to `kubectl get` as is.
+func (c *K8sClient) GetObjectsJSON(ctx context.Context, kinds string, filters ...string) ([]byte, error) {
+	args := append([]string{"get", kinds}, filters...)
+	args = append(args, "-ojson")
+	out, err := c.kubeCtl.Run(ctx, args, nil)
+	if err != nil {
+		return nil, errors.Wrapf(err, "couldn't get %s", kinds)
+	}
+	return out, nil
+}
+
+// GetClusterResource returns the database cluster custom resource with given name as JSON
+// along with the engine of the cluster. It returns ErrNotFound if there is no such cluster.
+func (c *K8sClient) GetClusterResource(ctx context.Context, name string) (Engine, []byte, error) {
+	for _, k := range clusterKindsByEngine {
+		out, err := c.kubeCtl.Run(ctx, []string{"get", k.kind, name, "-ojson"}, nil)
+		if err == nil {
+			return k.engine, out, nil
+		}
+		// Missing CRD is reported the same way as missing object.
+		if !errors.Is(err, kubectl.ErrNotFound) && !strings.Contains(err.Error(), "the server doesn't have a resource type") {
+			return "", nil, errors.Wrapf(err, "couldn't get %s", k.kind)
+		}
+	}
+	return "", nil, errors.Wrapf(ErrNotFound, "database cluster %q", name)
+}
+
+// GetOperatorLogs returns the last lines of logs of given operator.
+// It returns no lines if the operator is not installed. Opts may be nil.
+func (c *K8sClient) GetOperatorLogs(ctx context.Context, operator Engine, tailLines int, opts *LogsOptions) ([]string, error) {
+	deploymentName, err := operatorDeploymentName(operator)
+	if err != nil {
+		return nil, err
+	}
+	args := []string{"logs", "deployment/" + deploymentName, "--all-containers"}
+	if tailLines > 0 {
+		args = append(args, "--tail", strconv.Itoa(tailLines))
+	}
+	args = append(args, opts.args()...)
+	stdout, err := c.kubeCtl.Run(ctx, args, nil)
+	if err != nil {
+		if errors.Is(err, kubectl.ErrNotFound) {
+			return []string{}, nil
+		}
+		return nil, errors.Wrap(err, "couldn't get operator logs")
+	}
+	if len(stdout) == 0 {
+		return []string{}, nil
+	}
+	return strings.Split(strings.TrimSuffix(string(stdout), "\n"), "\n"), nil
+}
@@ -0,0 +1,71 @@
+// dbaas-controller
+// Copyright (C) 2020 Percona LLC
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package k8sclient
+
+import (
+	_ "embed" // for go:embed
+	"encoding/json"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+//go:embed ebs_attach_limits.json
+var ebsAttachLimitsJSON []byte //nolint:gochecknoglobals
+
+// defaultEBSAttachLimits is EBS attach limits table embedded into the binary.
+var defaultEBSAttachLimits = mustParseEBSAttachLimits(ebsAttachLimitsJSON) //nolint:gochecknoglobals
+
+// ebsAttachLimits holds EBS volume attach limits of EC2 instance families.
+type ebsAttachLimits struct {
+	// Version of the table, it's a date of the last update.
+	Version string `json:"version"`
+	// Default is used for instance families missing in the table.
+	Default  uint64            `json:"default"`
+	Families map[string]uint64 `json:"families"`
+}
+
+// parseEBSAttachLimits parses EBS attach limits table.
+func parseEBSAttachLimits(b []byte) (*ebsAttachLimits, error) {
+	var limits ebsAttachLimits
+	if err := json.Unmarshal(b, &limits); err != nil {
+		return nil, errors.Wrap(err, "failed to parse EBS attach limits")
+	}
+	if limits.Default == 0 {
+		return nil, errors.New("EBS attach limits table doesn't have default limit")
+	}
+	return &limits, nil
+}
+
+// mustParseEBSAttachLimits is like parseEBSAttachLimits but panics on error.
+func mustParseEBSAttachLimits(b []byte) *ebsAttachLimits {
+	limits, err := parseEBSAttachLimits(b)
+	if err != nil {
+		panic(err)
+	}
+	return limits
+}
+
+// limit returns EBS volume attach limit of given instance type, e.g. m5.large.
+// Found is false if default limit was returned because instance family is not in the table.
+func (l *ebsAttachLimits) limit(instanceType string) (limit uint64, found bool) {
+	family := strings.SplitN(strings.ToLower(instanceType), ".", 2)[0]
+	if limit, ok := l.Families[family]; ok {
+		return limit, true
+	}
+	return l.Default, false
+}
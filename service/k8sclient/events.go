@@ -0,0 +1,128 @@
+// dbaas-controller
+// Copyright (C) 2020 Percona LLC
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package k8sclient
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/percona-platform/dbaas-controller/service/k8sclient/common"
+	"github.com/percona-platform/dbaas-controller/service/k8sclient/internal/psmdb"
+	"github.com/percona-platform/dbaas-controller/service/k8sclient/internal/pxc"
+)
+
+const (
+	eventKind = "events"
+	podKind   = "Pod"
+	// clusterObjectKinds lists cluster's objects which events are returned by GetClusterEvents.
+	clusterObjectKinds = "pods,statefulsets,persistentvolumeclaims"
+)
+
+// clusterKinds lists kinds of custom resources that represent database clusters.
+var clusterKinds = []string{pxc.PerconaXtraDBClusterKind, psmdb.PerconaServerMongoDBKind} //nolint:gochecknoglobals
+
+// objectList is a list of any objects, it's used where only kinds and names are needed.
+type objectList struct {
+	common.TypeMeta
+
+	Items []struct {
+		common.TypeMeta
+		common.ObjectMeta `json:"metadata,omitempty"`
+	} `json:"items"`
+}
+
+// objectKey returns a key that identifies an object of given kind in the namespace.
+func objectKey(kind, name string) string {
+	return kind + "/" + name
+}
+
+// SortEvents sorts events by the time of their most recent occurrence, the oldest first.
+func SortEvents(events []common.Event) {
+	sort.SliceStable(events, func(i, j int) bool {
+		return events[i].LastSeen().Before(events[j].LastSeen())
+	})
+}
+
+// listEvents returns events matching given field selectors sorted by the time they were last seen.
+func (c *K8sClient) listEvents(ctx context.Context, fieldSelectors ...string) ([]common.Event, error) {
+	args := []string{"get", eventKind, "-ojson"}
+	if len(fieldSelectors) != 0 {
+		args = append(args, "--field-selector", strings.Join(fieldSelectors, ","))
+	}
+	out, err := c.kubeCtl.Run(ctx, args, nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "couldn't get events")
+	}
+	var list common.EventList
+	if err := json.Unmarshal(out, &list); err != nil {
+		return nil, errors.Wrap(err, "couldn't get events")
+	}
+	SortEvents(list.Items)
+	return list.Items, nil
+}
+
+// GetEvents returns events of the object of given kind and name sorted by the time they were last seen.
+func (c *K8sClient) GetEvents(ctx context.Context, kind, name string) ([]common.Event, error) {
+	return c.listEvents(ctx, "involvedObject.kind="+kind, "involvedObject.name="+name)
+}
+
+// GetPodEvents returns events of given pod sorted by the time they were last seen.
+func (c *K8sClient) GetPodEvents(ctx context.Context, pod string) ([]common.Event, error) {
+	return c.GetEvents(ctx, podKind, pod)
+}
+
+// GetClusterEvents returns events of the database cluster custom resource and of its pods,
+// stateful sets and persistent volume claims, sorted by the time they were last seen.
+func (c *K8sClient) GetClusterEvents(ctx context.Context, clusterName string) ([]common.Event, error) {
+	out, err := c.kubeCtl.Run(ctx, []string{"get", clusterObjectKinds, "-lapp.kubernetes.io/instance=" + clusterName, "-ojson"}, nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "couldn't get cluster objects")
+	}
+	var objects objectList
+	if err := json.Unmarshal(out, &objects); err != nil {
+		return nil, errors.Wrap(err, "couldn't get cluster objects")
+	}
+
+	keys := make(map[string]struct{}, len(objects.Items)+len(clusterKinds))
+	for _, kind := range clusterKinds {
+		keys[objectKey(kind, clusterName)] = struct{}{}
+	}
+	for _, object := range objects.Items {
+		keys[objectKey(object.Kind, object.Name)] = struct{}{}
+	}
+
+	events, err := c.listEvents(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return filterEvents(events, keys), nil
+}
+
+// filterEvents returns events of the objects with given keys preserving their order.
+func filterEvents(events []common.Event, keys map[string]struct{}) []common.Event {
+	res := make([]common.Event, 0, len(events))
+	for _, event := range events {
+		if _, ok := keys[objectKey(event.InvolvedObject.Kind, event.InvolvedObject.Name)]; ok {
+			res = append(res, event)
+		}
+	}
+	return res
+}
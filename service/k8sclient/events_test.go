@@ -0,0 +1,70 @@
+// dbaas-controller
+// Copyright (C) 2020 Percona LLC
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package k8sclient
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/percona-platform/dbaas-controller/service/k8sclient/common"
+)
+
+func TestEvents(t *testing.T) {
+	t.Parallel()
+	const list = `{
+		"kind": "List",
+		"items": [{
+			"involvedObject": {"kind": "Pod", "name": "cluster-pxc-1"},
+			"reason": "Pulled",
+			"firstTimestamp": "2021-06-01T10:00:00Z",
+			"lastTimestamp": "2021-06-01T12:00:00Z",
+			"count": 2,
+			"type": "Normal"
+		}, {
+			"involvedObject": {"kind": "Pod", "name": "other-pxc-0"},
+			"reason": "Pulled",
+			"firstTimestamp": "2021-06-01T09:00:00Z",
+			"lastTimestamp": "2021-06-01T09:00:00Z",
+			"type": "Normal"
+		}, {
+			"involvedObject": {"kind": "PerconaXtraDBCluster", "name": "cluster"},
+			"reason": "Created",
+			"firstTimestamp": null,
+			"lastTimestamp": null,
+			"eventTime": "2021-06-01T11:00:00.123456Z",
+			"type": "Normal"
+		}]
+	}`
+	var events common.EventList
+	require.NoError(t, json.Unmarshal([]byte(list), &events))
+	SortEvents(events.Items)
+
+	keysOf := func(events []common.Event) []string {
+		res := make([]string, len(events))
+		for i, e := range events {
+			res[i] = objectKey(e.InvolvedObject.Kind, e.InvolvedObject.Name)
+		}
+		return res
+	}
+	assert.Equal(t, []string{"Pod/other-pxc-0", "PerconaXtraDBCluster/cluster", "Pod/cluster-pxc-1"}, keysOf(events.Items))
+
+	keys := map[string]struct{}{"Pod/cluster-pxc-1": {}, "PerconaXtraDBCluster/cluster": {}}
+	assert.Equal(t, []string{"PerconaXtraDBCluster/cluster", "Pod/cluster-pxc-1"}, keysOf(filterEvents(events.Items, keys)))
+}
@@ -22,6 +22,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"os"
 	"os/exec"
@@ -217,6 +218,17 @@ func (k *KubeCtl) Cleanup() error {
 	return os.RemoveAll(k.kubeconfigPath)
 }
 
+// Command returns kubectl command selected for Kubernetes cluster without kubeconfig argument.
+func (k *KubeCtl) Command() string {
+	cmd := make([]string, 0, len(k.cmd))
+	for _, arg := range k.cmd {
+		if !strings.HasPrefix(arg, "--kubeconfig=") {
+			cmd = append(cmd, arg)
+		}
+	}
+	return strings.Join(cmd, " ")
+}
+
 // Get executes `kubectl get` with given object kind and optional name,
 // and decodes resource into `res`.
 func (k *KubeCtl) Get(ctx context.Context, kind string, name string, res interface{}) error {
@@ -300,29 +312,64 @@ func run(ctx context.Context, kubectlCmd []string, args []string, stdin interfac
 	cmd.Stdin = &inBuf
 	cmd.Stdout = &outBuf
 	cmd.Stderr = &errBuf
-	envs := os.Environ()
-	for _, env := range envs {
-		if strings.HasPrefix(env, "PATH=") {
-			env = fmt.Sprintf("PATH=%s:%s", dbaasToolPath, os.Getenv("PATH"))
-		}
-		cmd.Env = append(cmd.Env, env)
-	}
+	cmd.Env = kubectlEnv()
 	err := cmd.Run()
 	errOutput := errBuf.String()
 	if err != nil {
-		if strings.Contains(errOutput, "NotFound") {
-			l.Warn(errOutput)
-			err = ErrNotFound
-		} else {
-			err = &kubeCtlError{
-				err:    errors.WithStack(err),
-				cmd:    argsString,
-				stderr: errOutput,
-			}
-		}
+		err = runError(l, err, argsString, errOutput)
 	}
 
 	l.Debug(outBuf.String())
 	l.Debug(errOutput)
 	return outBuf.Bytes(), err
 }
+
+// Stream executes kubectl with given arguments writing its stdout to the writer as it goes.
+// It's used for long running commands like `kubectl logs --follow` and stops when ctx is canceled.
+func (k *KubeCtl) Stream(ctx context.Context, args []string, stdout io.Writer) error {
+	l := logger.Get(ctx)
+	l = l.WithField("component", "kubectl")
+	args = append(append([]string{}, k.cmd...), args...)
+	argsString := strings.Join(args, " ")
+	l.Debugf("Streaming %s", argsString)
+
+	var errBuf bytes.Buffer
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...) //nolint:gosec
+	pdeathsig.Set(cmd, unix.SIGKILL)
+	cmd.Stdout = stdout
+	cmd.Stderr = &errBuf
+	cmd.Env = kubectlEnv()
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return runError(l, err, argsString, errBuf.String())
+	}
+	return nil
+}
+
+// kubectlEnv returns environment of kubectl process with dbaas tools in PATH.
+func kubectlEnv() []string {
+	envs := os.Environ()
+	res := make([]string, 0, len(envs))
+	for _, env := range envs {
+		if strings.HasPrefix(env, "PATH=") {
+			env = fmt.Sprintf("PATH=%s:%s", dbaasToolPath, os.Getenv("PATH"))
+		}
+		res = append(res, env)
+	}
+	return res
+}
+
+// runError converts error of kubectl execution to ErrNotFound or error containing command and its stderr.
+func runError(l logger.Logger, err error, argsString, errOutput string) error {
+	if strings.Contains(errOutput, "NotFound") {
+		l.Warn(errOutput)
+		return ErrNotFound
+	}
+	return &kubeCtlError{
+		err:    errors.WithStack(err),
+		cmd:    argsString,
+		stderr: errOutput,
+	}
+}
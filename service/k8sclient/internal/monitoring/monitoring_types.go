@@ -52,6 +52,7 @@ type VMAgentSpec struct {
 	StaticScrapeSelector           *common.LabelSelector    `json:"staticScrapeSelector"`
 	StaticScrapeNamespaceSelector  *common.LabelSelector    `json:"staticScrapeNamespaceSelector"`
 	ReplicaCount                   int                      `json:"replicaCount"`
+	ScrapeInterval                 string                   `json:"scrapeInterval,omitempty"`
 	Resources                      *common.PodResources     `json:"resources"`
 	ExtraArgs                      map[string]string        `json:"extraArgs"`
 	RemoteWrite                    []VMAgentRemoteWriteSpec `json:"remoteWrite"`
@@ -65,7 +66,27 @@ type VMAgent struct {
 	// More info: https://git.k8s.io/community/contributors/devel/sig-architecture/api-conventions.md#metadata
 	common.ObjectMeta `json:"metadata,omitempty"`
 
-	Spec VMAgentSpec `json:"spec"`
+	Spec   VMAgentSpec    `json:"spec"`
+	Status *VMAgentStatus `json:"status,omitempty"`
+}
+
+// VMAgentStatus defines the observed state of VM Agent.
+type VMAgentStatus struct {
+	// Replicas is the total number of pods targeted by this VMAgent.
+	Replicas int32 `json:"replicas,omitempty"`
+	// UpdatedReplicas is the total number of pods targeted by this VMAgent that have the desired version spec.
+	UpdatedReplicas int32 `json:"updatedReplicas,omitempty"`
+	// AvailableReplicas is the total number of available pods (ready for at least minReadySeconds) targeted by this VMAgent.
+	AvailableReplicas int32 `json:"availableReplicas,omitempty"`
+	// UnavailableReplicas is the total number of unavailable pods targeted by this VMAgent.
+	UnavailableReplicas int32 `json:"unavailableReplicas,omitempty"`
+}
+
+// VMAgentList holds a list of VM Agents.
+type VMAgentList struct {
+	common.TypeMeta // anonymous for embedding
+
+	Items []VMAgent `json:"items"`
 }
 
 // TLSConfig specifies TLSConfig configuration parameters.
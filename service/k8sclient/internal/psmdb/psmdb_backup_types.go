@@ -0,0 +1,71 @@
+// dbaas-controller
+// Copyright (C) 2020 Percona LLC
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package psmdb
+
+import (
+	"time"
+
+	"github.com/percona-platform/dbaas-controller/service/k8sclient/common"
+)
+
+const (
+	// PerconaServerMongoDBBackupKind is a name of CRD for mongodb cluster backups.
+	PerconaServerMongoDBBackupKind = "PerconaServerMongoDBBackup"
+)
+
+// PerconaServerMongoDBBackup represents a PSMDB cluster backup.
+type PerconaServerMongoDBBackup struct {
+	common.TypeMeta   // anonymous for embedding
+	common.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   PerconaServerMongoDBBackupSpec   `json:"spec,omitempty"`
+	Status PerconaServerMongoDBBackupStatus `json:"status,omitempty"`
+}
+
+// PerconaServerMongoDBBackupList holds a list of PSMDB backups.
+type PerconaServerMongoDBBackupList struct {
+	common.TypeMeta // anonymous for embedding
+
+	Items []PerconaServerMongoDBBackup `json:"items"`
+}
+
+// PerconaServerMongoDBBackupSpec defines the desired state of PerconaServerMongoDBBackup.
+type PerconaServerMongoDBBackupSpec struct {
+	PSMDBCluster string `json:"psmdbCluster,omitempty"`
+	StorageName  string `json:"storageName,omitempty"`
+}
+
+// BackupState PSMDB backup state string.
+type BackupState string
+
+const (
+	// BackupStateReady means backup was successfully created.
+	BackupStateReady BackupState = "ready"
+	// BackupStateError means backup failed.
+	BackupStateError BackupState = "error"
+)
+
+// PerconaServerMongoDBBackupStatus defines the observed state of PerconaServerMongoDBBackup.
+type PerconaServerMongoDBBackupStatus struct {
+	State       BackupState          `json:"state,omitempty"`
+	StartAt     *time.Time           `json:"start,omitempty"`
+	CompletedAt *time.Time           `json:"completed,omitempty"`
+	Destination string               `json:"destination,omitempty"`
+	StorageName string               `json:"storageName,omitempty"`
+	S3          *backupStorageS3Spec `json:"s3,omitempty"`
+	Error       string               `json:"error,omitempty"`
+}
@@ -0,0 +1,60 @@
+// dbaas-controller
+// Copyright (C) 2020 Percona LLC
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package psmdb
+
+import (
+	"github.com/percona-platform/dbaas-controller/service/k8sclient/common"
+)
+
+const (
+	// PerconaServerMongoDBRestoreKind is a name of CRD for mongodb cluster restores.
+	PerconaServerMongoDBRestoreKind = "PerconaServerMongoDBRestore"
+)
+
+// PerconaServerMongoDBRestore is the Schema for the perconaservermongodbrestores API.
+type PerconaServerMongoDBRestore struct {
+	common.TypeMeta   // anonymous for embedding
+	common.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   PerconaServerMongoDBRestoreSpec   `json:"spec,omitempty"`
+	Status PerconaServerMongoDBRestoreStatus `json:"status,omitempty"`
+}
+
+// PerconaServerMongoDBRestoreSpec defines the desired state of PerconaServerMongoDBRestore.
+type PerconaServerMongoDBRestoreSpec struct {
+	ClusterName  string                            `json:"clusterName,omitempty"`
+	BackupName   string                            `json:"backupName,omitempty"`
+	BackupSource *PerconaServerMongoDBBackupStatus `json:"backupSource,omitempty"`
+}
+
+// RestoreState PSMDB restore state string.
+type RestoreState string
+
+const (
+	// RestoreStateError means restore failed.
+	RestoreStateError RestoreState = "error"
+	// RestoreStateRejected means restore was rejected by the operator.
+	RestoreStateRejected RestoreState = "rejected"
+	// RestoreStateReady means restore finished successfully.
+	RestoreStateReady RestoreState = "ready"
+)
+
+// PerconaServerMongoDBRestoreStatus defines the observed state of PerconaServerMongoDBRestore.
+type PerconaServerMongoDBRestoreStatus struct {
+	State RestoreState `json:"state,omitempty"`
+	Error string       `json:"error,omitempty"`
+}
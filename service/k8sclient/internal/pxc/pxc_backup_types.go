@@ -17,9 +17,16 @@
 package pxc
 
 import (
+	"time"
+
 	"github.com/percona-platform/dbaas-controller/service/k8sclient/common"
 )
 
+const (
+	// PerconaXtraDBClusterBackupKind is a name of CRD for Percona XtraDB Cluster backups.
+	PerconaXtraDBClusterBackupKind = "PerconaXtraDBClusterBackup"
+)
+
 // PerconaXtraDBClusterBackupList holds exported fields representing Percona XtraDB cluster backup list.
 type PerconaXtraDBClusterBackupList struct {
 	common.TypeMeta // anonymous for embedding
@@ -49,7 +56,15 @@ type PXCBackupStatus struct {
 	Destination string               `json:"destination,omitempty"`
 	StorageName string               `json:"storageName,omitempty"`
 	S3          *BackupStorageS3Spec `json:"s3,omitempty"`
+	Completed   *time.Time           `json:"completed,omitempty"`
 }
 
 // PXCBackupState PXC backup state string.
 type PXCBackupState string
+
+const (
+	// PXCBackupStateSucceeded means backup was successfully created.
+	PXCBackupStateSucceeded PXCBackupState = "Succeeded"
+	// PXCBackupStateFailed means backup failed.
+	PXCBackupStateFailed PXCBackupState = "Failed"
+)
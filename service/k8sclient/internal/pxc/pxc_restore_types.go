@@ -20,11 +20,16 @@ import (
 	"github.com/percona-platform/dbaas-controller/service/k8sclient/common"
 )
 
+const (
+	// PerconaXtraDBClusterRestoreKind is a name of CRD for Percona XtraDB Cluster restores.
+	PerconaXtraDBClusterRestoreKind = "PerconaXtraDBClusterRestore"
+)
+
 // PerconaXtraDBClusterRestoreSpec defines the desired state of PerconaXtraDBClusterRestore.
 type PerconaXtraDBClusterRestoreSpec struct {
 	PXCCluster   string           `json:"pxcCluster"`
-	BackupName   string           `json:"backupName"`
-	BackupSource *PXCBackupStatus `json:"backupSource"`
+	BackupName   string           `json:"backupName,omitempty"`
+	BackupSource *PXCBackupStatus `json:"backupSource,omitempty"`
 }
 
 // PerconaXtraDBClusterRestoreStatus defines the observed state of PerconaXtraDBClusterRestore.
@@ -51,3 +56,10 @@ type PerconaXtraDBClusterRestoreList struct {
 
 // BcpRestoreStates backup restore states.
 type BcpRestoreStates string
+
+const (
+	// RestoreStateFailed means restore failed.
+	RestoreStateFailed BcpRestoreStates = "Failed"
+	// RestoreStateSucceeded means restore finished successfully.
+	RestoreStateSucceeded BcpRestoreStates = "Succeeded"
+)
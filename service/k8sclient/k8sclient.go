@@ -19,13 +19,11 @@ package k8sclient
 
 import (
 	"context"
-	"crypto/rand"
 	"encoding/json"
 	"fmt"
-	"io/ioutil"
-	"net/http"
 	"os"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/AlekSi/pointer"
@@ -35,10 +33,8 @@ import (
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/tools/clientcmd"
 
-	dbaascontroller "github.com/percona-platform/dbaas-controller"
 	"github.com/percona-platform/dbaas-controller/service/k8sclient/common"
 	"github.com/percona-platform/dbaas-controller/service/k8sclient/internal/kubectl"
-	"github.com/percona-platform/dbaas-controller/service/k8sclient/internal/monitoring"
 	"github.com/percona-platform/dbaas-controller/service/k8sclient/internal/psmdb"
 	"github.com/percona-platform/dbaas-controller/service/k8sclient/internal/pxc"
 	"github.com/percona-platform/dbaas-controller/utils/convertors"
@@ -66,8 +62,9 @@ const (
 )
 
 const (
-	k8sAPIVersion     = "v1"
-	k8sMetaKindSecret = "Secret"
+	k8sAPIVersion        = "v1"
+	k8sMetaKindSecret    = "Secret"
+	k8sMetaKindConfigMap = "ConfigMap"
 
 	pxcBackupImageTemplate          = "percona/percona-xtradb-cluster-operator:%s-pxc8.0-backup"
 	pxcDefaultImage                 = "percona/percona-xtradb-cluster:8.0.20-11.1"
@@ -85,20 +82,7 @@ const (
 	psmdbAPIVersionTemplate  = psmdbAPINamespace + "/v%s"
 	psmdbSecretNameTmpl      = "dbaas-%s-psmdb-secrets"
 
-	// Max size of volume for AWS Elastic Block Storage service is 16TiB.
-	maxVolumeSizeEBS uint64 = 16 * 1024 * 1024 * 1024 * 1024
-	pullPolicy              = common.PullIfNotPresent
-)
-
-// KubernetesClusterType represents kubernetes cluster type(eg: EKS, Minikube).
-type KubernetesClusterType uint8
-
-const (
-	clusterTypeUnknown KubernetesClusterType = iota
-	// AmazonEKSClusterType represents EKS cluster type.
-	AmazonEKSClusterType
-	// MinikubeClusterType represents minikube Kubernetes cluster.
-	MinikubeClusterType
+	pullPolicy = common.PullIfNotPresent
 )
 
 // ContainerState describes container's state - waiting, running, terminated.
@@ -168,6 +152,12 @@ type PMM struct {
 	Login string
 	// PMM server admin password.
 	Password string
+	// Resources of monitoring agent, defaults are used if not set.
+	Resources *common.PodResources
+	// ScrapeInterval is the interval of metrics scraping, VictoriaMetrics default is used if not set.
+	ScrapeInterval time.Duration
+	// TLSVerify enables verification of PMM server TLS certificate.
+	TLSVerify bool
 }
 
 // PXCParams contains all parameters required to create or update Percona XtraDB cluster.
@@ -182,6 +172,8 @@ type PXCParams struct {
 	HAProxy           *HAProxy
 	Expose            bool
 	VersionServiceURL string
+	// PasswordPolicy is used to generate passwords of system users. Default policy is used if nil.
+	PasswordPolicy *PasswordPolicy
 }
 
 // Cluster contains common information related to cluster.
@@ -200,6 +192,8 @@ type PSMDBParams struct {
 	PMM               *PMM
 	Expose            bool
 	VersionServiceURL string
+	// PasswordPolicy is used to generate passwords of system users. Default policy is used if nil.
+	PasswordPolicy *PasswordPolicy
 }
 
 type appStatus struct {
@@ -315,7 +309,10 @@ type K8sClient struct {
 	kubeCtl    *kubectl.KubeCtl
 	l          logger.Logger
 	kubeconfig string
-	client     *http.Client
+
+	// clusterType caches detected Kubernetes cluster type, detection takes several kubectl calls.
+	clusterTypeM sync.Mutex
+	clusterType  KubernetesClusterType
 }
 
 func init() {
@@ -372,15 +369,8 @@ func New(ctx context.Context, kubeconfig string) (*K8sClient, error) {
 		return nil, err
 	}
 	return &K8sClient{
-		kubeCtl: kubeCtl,
-		l:       l,
-		client: &http.Client{
-			Timeout: time.Second * 5,
-			Transport: &http.Transport{
-				MaxIdleConns:    1,
-				IdleConnTimeout: 10 * time.Second,
-			},
-		},
+		kubeCtl:    kubeCtl,
+		l:          l,
 		kubeconfig: kubeconfig,
 	}, nil
 }
@@ -434,17 +424,62 @@ func (c *K8sClient) CreatePXCCluster(ctx context.Context, params *PXCParams) err
 		return fmt.Errorf(clusterWithSameNameExistsErrTemplate, params.Name)
 	}
 
-	secretName := fmt.Sprintf(pxcSecretNameTmpl, params.Name)
-	secrets, err := generatePXCPasswords()
+	secrets, err := generatePXCPasswords(params.PasswordPolicy)
+	if err != nil {
+		return err
+	}
+	if params.PMM != nil {
+		secrets["pmmserver"] = []byte(params.PMM.Password)
+	}
+
+	res, err := c.newPXCCluster(ctx, params)
+	if err != nil {
+		return err
+	}
+
+	footprint, err := pxcFootprint(res)
+	if err != nil {
+		return err
+	}
+	err = c.checkCapacity(ctx, footprint)
+	if err != nil {
+		return err
+	}
+
+	err = c.CreateSecret(ctx, res.Spec.SecretsName, secrets)
+	if err != nil {
+		return errors.Wrap(err, "cannot create secret for PXC")
+	}
+
+	return c.kubeCtl.Apply(ctx, res)
+}
+
+// CheckPXCClusterCapacity checks that Kubernetes cluster has enough resources to run Percona XtraDB
+// cluster with provided parameters. Nothing is created. Returned error wraps ErrInsufficientCapacity
+// with the reason if the cluster won't fit.
+func (c *K8sClient) CheckPXCClusterCapacity(ctx context.Context, params *PXCParams) error {
+	if (params.ProxySQL != nil) == (params.HAProxy != nil) {
+		return errors.New("pxc cluster must have one and only one proxy type defined")
+	}
+	res, err := c.newPXCCluster(ctx, params)
 	if err != nil {
 		return err
 	}
+	footprint, err := pxcFootprint(res)
+	if err != nil {
+		return err
+	}
+	return c.checkCapacity(ctx, footprint)
+}
 
+// newPXCCluster returns Percona XtraDB cluster custom resource for provided parameters.
+func (c *K8sClient) newPXCCluster(ctx context.Context, params *PXCParams) (*pxc.PerconaXtraDBCluster, error) {
+	secretName := fmt.Sprintf(pxcSecretNameTmpl, params.Name)
 	storageName := fmt.Sprintf(pxcBackupStorageName, params.Name)
 
 	operators, err := c.CheckOperators(ctx)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	pxcImage := pxcDefaultImage
@@ -517,7 +552,6 @@ func (c *K8sClient) CreatePXCCluster(ctx context.Context, params *PXCParams) err
 				},
 			},
 		}
-		secrets["pmmserver"] = []byte(params.PMM.Password)
 	}
 
 	var podSpec *pxc.PodSpec
@@ -543,7 +577,7 @@ func (c *K8sClient) CreatePXCCluster(ctx context.Context, params *PXCParams) err
 	// This enables ingress for the cluster and exposes the cluster to the world.
 	// The cluster will have an internal IP and a world accessible hostname.
 	// This feature cannot be tested with minikube. Please use EKS for testing.
-	if clusterType := c.GetKubernetesClusterType(ctx); clusterType != MinikubeClusterType && params.Expose {
+	if clusterType := c.GetKubernetesClusterType(ctx); !clusterType.IsLocal() && params.Expose {
 		podSpec.ServiceType = common.ServiceTypeLoadBalancer
 	}
 
@@ -554,12 +588,7 @@ func (c *K8sClient) CreatePXCCluster(ctx context.Context, params *PXCParams) err
 		TopologyKey: pointer.ToString(pxc.AffinityTopologyKeyOff),
 	}
 
-	err = c.CreateSecret(ctx, secretName, secrets)
-	if err != nil {
-		return errors.Wrap(err, "cannot create secret for PXC")
-	}
-
-	return c.kubeCtl.Apply(ctx, res)
+	return res, nil
 }
 
 // UpdatePXCCluster changes size of provided Percona XtraDB cluster.
@@ -578,7 +607,19 @@ func (c *K8sClient) UpdatePXCCluster(ctx context.Context, params *PXCParams) err
 
 	// Only if cluster is paused, allow resuming it. All other modifications are forbinden.
 	if params.Resume && clusterState == ClusterStatePaused {
+		// Paused cluster runs no pods, so all of them have to fit when it's resumed.
+		pausedFootprint, err := pxcFootprint(&cluster)
+		if err != nil {
+			return err
+		}
 		cluster.Spec.Pause = false
+		footprint, err := pxcFootprint(&cluster)
+		if err != nil {
+			return err
+		}
+		if err = c.checkCapacity(ctx, footprint.growth(pausedFootprint)); err != nil {
+			return err
+		}
 		return c.kubeCtl.Apply(ctx, &cluster)
 	}
 
@@ -587,6 +628,11 @@ func (c *K8sClient) UpdatePXCCluster(ctx context.Context, params *PXCParams) err
 		return errors.Wrapf(ErrPXCClusterStateUnexpected, "state is %v", cluster.Status.Status) //nolint:wrapcheck
 	}
 
+	oldFootprint, err := pxcFootprint(&cluster)
+	if err != nil {
+		return err
+	}
+
 	if params.Suspend {
 		cluster.Spec.Pause = true
 	}
@@ -619,6 +665,16 @@ func (c *K8sClient) UpdatePXCCluster(ctx context.Context, params *PXCParams) err
 		cluster.Spec.HAProxy.Resources = c.updateComputeResources(params.HAProxy.ComputeResources, cluster.Spec.HAProxy.Resources)
 	}
 
+	// Make sure added pods fit into Kubernetes cluster when scaling up.
+	newFootprint, err := pxcFootprint(&cluster)
+	if err != nil {
+		return err
+	}
+	err = c.checkCapacity(ctx, newFootprint.growth(oldFootprint))
+	if err != nil {
+		return err
+	}
+
 	return c.kubeCtl.Patch(ctx, kubectl.PatchTypeMerge, common.DatabaseCluster(&cluster).CRDName(), common.DatabaseCluster(&cluster).GetName(), cluster)
 }
 
@@ -717,30 +773,6 @@ func (c *K8sClient) getStorageClass(ctx context.Context) (*StorageClass, error)
 	return storageClass, nil
 }
 
-// GetKubernetesClusterType returns k8s cluster type based on storage class.
-func (c *K8sClient) GetKubernetesClusterType(ctx context.Context) KubernetesClusterType {
-	sc, err := c.getStorageClass(ctx)
-	if err != nil {
-		c.l.Error(errors.Wrap(err, "failed to get k8s cluster type"))
-		return clusterTypeUnknown
-	}
-
-	if len(sc.Items) == 0 {
-		return clusterTypeUnknown
-	}
-
-	for _, class := range sc.Items {
-		if strings.Contains(class.Provisioner, "aws") {
-			return AmazonEKSClusterType
-		}
-		if strings.Contains(class.Provisioner, "minikube") {
-			return MinikubeClusterType
-		}
-	}
-
-	return clusterTypeUnknown
-}
-
 func (c *K8sClient) restartDBClusterCmd(name, kind string) []string {
 	return []string{"rollout", "restart", "StatefulSets", fmt.Sprintf("%s-%s", name, kind)}
 }
@@ -931,15 +963,59 @@ func (c *K8sClient) CreatePSMDBCluster(ctx context.Context, params *PSMDBParams)
 		return fmt.Errorf(clusterWithSameNameExistsErrTemplate, params.Name)
 	}
 
-	secretName := fmt.Sprintf(psmdbSecretNameTmpl, params.Name)
-	secrets, err := generatePSMDBPasswords()
+	secrets, err := generatePSMDBPasswords(params.PasswordPolicy)
 	if err != nil {
 		return err
 	}
+	if params.PMM != nil {
+		secrets["PMM_SERVER_USER"] = []byte(params.PMM.Login)
+		secrets["PMM_SERVER_PASSWORD"] = []byte(params.PMM.Password)
+	}
+
+	res, err := c.newPSMDBCluster(ctx, params)
+	if err != nil {
+		return err
+	}
+
+	footprint, err := psmdbFootprint(res)
+	if err != nil {
+		return err
+	}
+	err = c.checkCapacity(ctx, footprint)
+	if err != nil {
+		return err
+	}
+
+	err = c.CreateSecret(ctx, res.Spec.Secrets.Users, secrets)
+	if err != nil {
+		return errors.Wrap(err, "cannot create secret for PXC")
+	}
+
+	return c.kubeCtl.Apply(ctx, res)
+}
+
+// CheckPSMDBClusterCapacity checks that Kubernetes cluster has enough resources to run percona server
+// for mongodb cluster with provided parameters. Nothing is created. Returned error wraps
+// ErrInsufficientCapacity with the reason if the cluster won't fit.
+func (c *K8sClient) CheckPSMDBClusterCapacity(ctx context.Context, params *PSMDBParams) error {
+	res, err := c.newPSMDBCluster(ctx, params)
+	if err != nil {
+		return err
+	}
+	footprint, err := psmdbFootprint(res)
+	if err != nil {
+		return err
+	}
+	return c.checkCapacity(ctx, footprint)
+}
+
+// newPSMDBCluster returns percona server for mongodb custom resource for provided parameters.
+func (c *K8sClient) newPSMDBCluster(ctx context.Context, params *PSMDBParams) (*psmdb.PerconaServerMongoDB, error) {
+	secretName := fmt.Sprintf(psmdbSecretNameTmpl, params.Name)
 
 	affinity := new(psmdb.PodAffinity)
 	var expose psmdb.Expose
-	if clusterType := c.GetKubernetesClusterType(ctx); clusterType != MinikubeClusterType {
+	if clusterType := c.GetKubernetesClusterType(ctx); !clusterType.IsLocal() {
 		affinity.TopologyKey = pointer.ToString("kubernetes.io/hostname")
 
 		if params.Expose {
@@ -962,7 +1038,7 @@ func (c *K8sClient) CreatePSMDBCluster(ctx context.Context, params *PSMDBParams)
 
 	operators, err := c.CheckOperators(ctx)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	psmdbImage := psmdbDefaultImage
@@ -1105,16 +1181,9 @@ func (c *K8sClient) CreatePSMDBCluster(ctx context.Context, params *PSMDBParams)
 				},
 			},
 		}
-		secrets["PMM_SERVER_USER"] = []byte(params.PMM.Login)
-		secrets["PMM_SERVER_PASSWORD"] = []byte(params.PMM.Password)
-	}
-
-	err = c.CreateSecret(ctx, secretName, secrets)
-	if err != nil {
-		return errors.Wrap(err, "cannot create secret for PXC")
 	}
 
-	return c.kubeCtl.Apply(ctx, res)
+	return res, nil
 }
 
 // UpdatePSMDBCluster changes size, stops, resumes or upgrades provided percona server for mongodb cluster.
@@ -1127,7 +1196,19 @@ func (c *K8sClient) UpdatePSMDBCluster(ctx context.Context, params *PSMDBParams)
 
 	clusterState := c.getClusterState(ctx, &cluster, c.crVersionMatchesPodsVersion)
 	if params.Resume && clusterState == ClusterStatePaused {
+		// Paused cluster runs no pods, so all of them have to fit when it's resumed.
+		pausedFootprint, err := psmdbFootprint(&cluster)
+		if err != nil {
+			return err
+		}
 		cluster.Spec.Pause = false
+		footprint, err := psmdbFootprint(&cluster)
+		if err != nil {
+			return err
+		}
+		if err = c.checkCapacity(ctx, footprint.growth(pausedFootprint)); err != nil {
+			return err
+		}
 		return c.kubeCtl.Apply(ctx, &cluster)
 	}
 
@@ -1135,6 +1216,12 @@ func (c *K8sClient) UpdatePSMDBCluster(ctx context.Context, params *PSMDBParams)
 	if clusterState != ClusterStateReady {
 		return errors.Wrap(ErrPSMDBClusterNotReady, "cluster is not in ready state") //nolint:wrapcheck
 	}
+
+	oldFootprint, err := psmdbFootprint(&cluster)
+	if err != nil {
+		return err
+	}
+
 	if params.Size > 0 {
 		cluster.Spec.Replsets[0].Size = params.Size
 	}
@@ -1153,6 +1240,17 @@ func (c *K8sClient) UpdatePSMDBCluster(ctx context.Context, params *PSMDBParams)
 			return err
 		}
 	}
+
+	// Make sure added pods fit into Kubernetes cluster when scaling up.
+	newFootprint, err := psmdbFootprint(&cluster)
+	if err != nil {
+		return err
+	}
+	err = c.checkCapacity(ctx, newFootprint.growth(oldFootprint))
+	if err != nil {
+		return err
+	}
+
 	return c.kubeCtl.Patch(ctx, kubectl.PatchTypeMerge, common.DatabaseCluster(&cluster).CRDName(), common.DatabaseCluster(&cluster).GetName(), cluster)
 }
 
@@ -1511,17 +1609,42 @@ func (c *K8sClient) GetPods(ctx context.Context, filters ...string) (*common.Pod
 	return list, nil
 }
 
+// LogsOptions contains options of logs requests.
+type LogsOptions struct {
+	// Since limits logs to the ones newer than given time if set.
+	Since time.Time
+	// Timestamps prefixes log lines with timestamps.
+	Timestamps bool
+}
+
+// args returns kubectl logs arguments for options, opts may be nil.
+func (opts *LogsOptions) args() []string {
+	var args []string
+	if opts == nil {
+		return args
+	}
+	if !opts.Since.IsZero() {
+		args = append(args, "--since-time", opts.Since.UTC().Format(time.RFC3339))
+	}
+	if opts.Timestamps {
+		args = append(args, "--timestamps")
+	}
+	return args
+}
+
 // GetLogs returns logs as slice of log lines - strings - for given pod's container.
+// Opts may be nil.
 func (c *K8sClient) GetLogs(
 	ctx context.Context,
 	containerStatuses []common.ContainerStatus,
 	pod,
 	container string,
+	opts *LogsOptions,
 ) ([]string, error) {
 	if common.IsContainerInState(containerStatuses, common.ContainerStateWaiting, container) {
 		return []string{}, nil
 	}
-	stdout, err := c.kubeCtl.Run(ctx, []string{"logs", pod, container}, nil)
+	stdout, err := c.kubeCtl.Run(ctx, append([]string{"logs", pod, container}, opts.args()...), nil)
 	if err != nil {
 		return nil, errors.Wrap(err, "couldn't get logs")
 	}
@@ -1531,23 +1654,27 @@ func (c *K8sClient) GetLogs(
 	return strings.Split(string(stdout), "\n"), nil
 }
 
-// GetEvents returns pod's events as a slice of strings.
-func (c *K8sClient) GetEvents(ctx context.Context, pod string) ([]string, error) {
-	stdout, err := c.kubeCtl.Run(ctx, []string{"describe", "pod", pod}, nil)
+// previousContainerMissing returns true if previous logs are not available because the previous instance
+// of the container is gone. Kubernetes reports it as BadRequest, not as NotFound.
+func previousContainerMissing(err error) bool {
+	msg := err.Error()
+	return strings.Contains(msg, "previous terminated container") && strings.Contains(msg, "not found")
+}
+
+// GetPreviousLogs returns logs of the previous instance of given pod's container.
+// It returns no lines if the container was never restarted or its previous instance is gone. Opts may be nil.
+func (c *K8sClient) GetPreviousLogs(ctx context.Context, pod, container string, opts *LogsOptions) ([]string, error) {
+	stdout, err := c.kubeCtl.Run(ctx, append([]string{"logs", pod, container, "--previous"}, opts.args()...), nil)
 	if err != nil {
-		return nil, errors.Wrap(err, "couldn't describe pod")
-	}
-	lines := strings.Split(string(stdout), "\n")
-	var line string
-	var i int
-	for i, line = range lines {
-		if strings.Contains(line, "Events") {
-			break
+		if errors.Is(err, kubectl.ErrNotFound) || previousContainerMissing(err) {
+			return []string{}, nil
 		}
+		return nil, errors.Wrap(err, "couldn't get previous logs")
 	}
-	// Add name of the pod to the Events line so it's clear what pod events we got.
-	lines[i] = pod + " " + lines[i]
-	return lines[i:], nil
+	if string(stdout) == "" {
+		return []string{}, nil
+	}
+	return strings.Split(string(stdout), "\n"), nil
 }
 
 // getWorkerNodes returns list of cluster workers nodes.
@@ -1561,13 +1688,19 @@ func (c *K8sClient) getWorkerNodes(ctx context.Context) ([]common.Node, error) {
 	if err != nil {
 		return nil, errors.Wrap(err, "could not get nodes of Kubernetes cluster")
 	}
+	return workerNodes(nodes.Items), nil
+}
+
+// workerNodes returns nodes which have no taints or have at least one taint
+// other than the ones set on control plane and not ready nodes.
+func workerNodes(nodes []common.Node) []common.Node {
 	forbidenTaints := map[string]string{
 		"node.cloudprovider.kubernetes.io/uninitialized": "NoSchedule",
 		"node.kubernetes.io/unschedulable":               "NoSchedule",
 		"node-role.kubernetes.io/master":                 "NoSchedule",
 	}
-	workers := make([]common.Node, 0, len(nodes.Items))
-	for _, node := range nodes.Items {
+	workers := make([]common.Node, 0, len(nodes))
+	for _, node := range nodes {
 		if len(node.Spec.Taints) == 0 {
 			workers = append(workers, node)
 			continue
@@ -1576,13 +1709,29 @@ func (c *K8sClient) getWorkerNodes(ctx context.Context) ([]common.Node, error) {
 			effect, keyFound := forbidenTaints[taint.Key]
 			if !keyFound || effect != taint.Effect {
 				workers = append(workers, node)
+				break
 			}
 		}
 	}
-	return workers, nil
+	return workers
+}
+
+// getCSINodes returns CSI nodes by node names.
+func (c *K8sClient) getCSINodes(ctx context.Context) (map[string]*common.CSINode, error) {
+	var list common.CSINodeList
+	if err := c.kubeCtl.Get(ctx, "csinodes", "", &list); err != nil {
+		return nil, errors.Wrap(err, "cannot get CSI nodes")
+	}
+	csiNodes := make(map[string]*common.CSINode, len(list.Items))
+	for i := range list.Items {
+		csiNodes[list.Items[i].Name] = &list.Items[i]
+	}
+	return csiNodes, nil
 }
 
 // GetAllClusterResources goes through all cluster nodes and sums their allocatable resources.
+// Disk size is computed only for cluster types with known disk capacity and for clusters whose
+// nodes report volume attach limits, it's zero otherwise.
 func (c *K8sClient) GetAllClusterResources(ctx context.Context, clusterType KubernetesClusterType, volumes *common.PersistentVolumeList) (
 	cpuMillis uint64, memoryBytes uint64, diskSizeBytes uint64, err error,
 ) {
@@ -1590,7 +1739,23 @@ func (c *K8sClient) GetAllClusterResources(ctx context.Context, clusterType Kube
 	if err != nil {
 		return 0, 0, 0, errors.Wrap(err, "could not get a list of nodes")
 	}
-	var volumeCountEKS uint64
+	return c.allClusterResources(ctx, nodes, clusterType, volumes)
+}
+
+// allClusterResources returns all resources of given worker nodes, see GetAllClusterResources.
+func (c *K8sClient) allClusterResources(
+	ctx context.Context, nodes []common.Node, clusterType KubernetesClusterType, volumes *common.PersistentVolumeList,
+) (cpuMillis uint64, memoryBytes uint64, diskSizeBytes uint64, err error) {
+	var csiNodes map[string]*common.CSINode
+	if clusterType.UsesAttachableVolumes() {
+		csiNodes, err = c.getCSINodes(ctx)
+		if err != nil {
+			// CSI drivers are optional, fall back to limits reported by nodes or known for instance types.
+			c.l.Warnf("failed to get CSI nodes: %v", err)
+		}
+	}
+	var volumeCount uint64
+	var attachLimitUnknown bool
 	for _, node := range nodes {
 		cpu, memory, err := getResources(node.Status.Allocatable)
 		if err != nil {
@@ -1599,8 +1764,8 @@ func (c *K8sClient) GetAllClusterResources(ctx context.Context, clusterType Kube
 		cpuMillis += cpu
 		memoryBytes += memory
 
-		switch clusterType {
-		case MinikubeClusterType:
+		switch {
+		case clusterType.IsLocal():
 			storage, ok := node.Status.Allocatable[common.ResourceEphemeralStorage]
 			if !ok {
 				return 0, 0, 0, errors.Errorf("could not get storage size of the node")
@@ -1610,46 +1775,33 @@ func (c *K8sClient) GetAllClusterResources(ctx context.Context, clusterType Kube
 				return 0, 0, 0, errors.Wrapf(err, "could not convert storage size '%s' to bytes", storage)
 			}
 			diskSizeBytes += bytes
-		case AmazonEKSClusterType:
+		case clusterType.UsesAttachableVolumes():
 			// See https://kubernetes.io/docs/tasks/administer-cluster/out-of-resource/#scheduler.
 			if common.IsNodeInCondition(node, common.NodeConditionDiskPressure) {
 				continue
 			}
-
-			// Get nodes's type.
-			nodeType, ok := node.Labels["beta.kubernetes.io/instance-type"]
-			if !ok {
-				return 0, 0, 0, errors.New("dealing with AWS EKS cluster but the node does not have label 'beta.kubernetes.io/instance-type'")
-			}
-			// 39 is a default limit for EKS cluster nodes ...
-			var volumeLimitPerNode uint64 = 39
-			typeAndSize := strings.Split(strings.ToLower(nodeType), ".")
-			if len(typeAndSize) < 2 {
-				return 0, 0, 0, errors.Errorf("failed to parse EKS node type '%s', it's not in expected format 'type.size'", nodeType)
+			volumeLimitPerNode, exact, err := volumeAttachLimit(clusterType, node, csiNodes[node.Name])
+			if errors.Is(err, errVolumeAttachLimitUnknown) {
+				c.l.Debugf("%s, disk size can't be computed", err)
+				attachLimitUnknown = true
+				continue
 			}
-			// ... however, if the node type is one of M5, C5, R5, T3, Z1D it's 25.
-			limitedVolumesSet := map[string]struct{}{
-				"m5": {}, "c5": {}, "r5": {}, "t3": {}, "t1d": {},
+			if err != nil {
+				return 0, 0, 0, err
 			}
-			if _, ok := limitedVolumesSet[typeAndSize[0]]; ok {
-				volumeLimitPerNode = 25
+			if !exact {
+				c.l.Warnf("volume attach limit of node %q is not known, using %d", node.Name, volumeLimitPerNode)
 			}
-			volumeCountEKS += volumeLimitPerNode
+			volumeCount += volumeLimitPerNode
 		}
 	}
-	if clusterType == AmazonEKSClusterType {
-		volumeCountEKSBackup := volumeCountEKS
-		volumeCountEKS -= uint64(len(volumes.Items))
-		if volumeCountEKS > volumeCountEKSBackup {
-			// handle uint underflow
-			volumeCountEKS = 0
-		}
-
+	if clusterType.UsesAttachableVolumes() && !attachLimitUnknown {
+		volumeCount = subtractFloor(volumeCount, uint64(len(volumes.Items)))
 		consumedBytes, err := sumVolumesSize(volumes)
 		if err != nil {
 			return 0, 0, 0, errors.Wrap(err, "failed to sum persistent volumes storage sizes")
 		}
-		diskSizeBytes = (volumeCountEKS * maxVolumeSizeEBS) + consumedBytes
+		diskSizeBytes = (volumeCount * maxVolumeSize(clusterType)) + consumedBytes
 	}
 	return cpuMillis, memoryBytes, diskSizeBytes, nil
 }
@@ -1694,22 +1846,12 @@ func (c *K8sClient) GetConsumedCPUAndMemory(ctx context.Context, namespace strin
 		if ppod.Status.Phase != common.PodPhaseRunning {
 			continue
 		}
-		nonTerminatedInitContainers := make([]common.ContainerSpec, 0, len(ppod.Spec.InitContainers))
-		for _, container := range ppod.Spec.InitContainers {
-			if !common.IsContainerInState(
-				ppod.Status.InitContainerStatuses, common.ContainerStateTerminated, container.Name,
-			) {
-				nonTerminatedInitContainers = append(nonTerminatedInitContainers, container)
-			}
-		}
-		for _, container := range append(ppod.Spec.Containers, nonTerminatedInitContainers...) {
-			cpu, memory, err := getResources(container.Resources.Requests)
-			if err != nil {
-				return 0, 0, errors.Wrap(err, "failed to sum all consumed resources")
-			}
-			cpuMillis += cpu
-			memoryBytes += memory
+		cpu, memory, err := podRequests(ppod)
+		if err != nil {
+			return 0, 0, errors.Wrap(err, "failed to sum all consumed resources")
 		}
+		cpuMillis += cpu
+		memoryBytes += memory
 	}
 
 	return cpuMillis, memoryBytes, nil
@@ -1717,8 +1859,8 @@ func (c *K8sClient) GetConsumedCPUAndMemory(ctx context.Context, namespace strin
 
 // GetConsumedDiskBytes returns consumed bytes. The strategy differs based on k8s cluster type.
 func (c *K8sClient) GetConsumedDiskBytes(ctx context.Context, clusterType KubernetesClusterType, volumes *common.PersistentVolumeList) (consumedBytes uint64, err error) {
-	switch clusterType {
-	case MinikubeClusterType:
+	switch {
+	case clusterType.IsLocal():
 		nodes, err := c.getWorkerNodes(ctx)
 		if err != nil {
 			return 0, errors.Wrap(err, "can't compute consumed disk size: failed to get worker nodes")
@@ -1748,7 +1890,7 @@ func (c *K8sClient) GetConsumedDiskBytes(ctx context.Context, clusterType Kubern
 			consumedBytes += summary.Node.FileSystem.UsedBytes
 		}
 		return consumedBytes, nil
-	case AmazonEKSClusterType:
+	case clusterType.UsesAttachableVolumes():
 		consumedBytes, err := sumVolumesSize(volumes)
 		if err != nil {
 			return 0, errors.Wrap(err, "failed to sum persistent volumes storage sizes")
@@ -1767,117 +1909,19 @@ func (c *K8sClient) getAPIVersionForPXCOperator(version string) string {
 	return fmt.Sprintf(pxcAPIVersionTemplate, strings.ReplaceAll(version, ".", "-"))
 }
 
-func (c *K8sClient) fetchOperatorManifest(ctx context.Context, manifestURL string) ([]byte, error) {
-	req, err := http.NewRequestWithContext(ctx, "GET", manifestURL, nil)
-	if err != nil {
-		return nil, errors.Wrap(err, "failed to fetch operator manifests")
-	}
-	resp, err := c.client.Do(req)
-	if err != nil {
-		return nil, errors.Wrap(err, "failed to fetch operator manifests")
-	}
-	defer func() {
-		err := resp.Body.Close()
-		if err != nil {
-			c.l.Errorf("failed to close response's body: %v", err)
-		}
-	}()
-	if resp.StatusCode != http.StatusOK {
-		return nil, errors.Errorf("failed to fetch operator manifests, http request ended with status %q", resp.Status)
-	}
-	return ioutil.ReadAll(resp.Body)
-}
-
 // ApplyOperator applies bundle.yaml which installs CRDs, RBAC and operator's deployment.
-func (c *K8sClient) ApplyOperator(ctx context.Context, version string, manifestsURLTemplate string) error {
-	bundleURL := fmt.Sprintf(manifestsURLTemplate, version, "bundle.yaml")
-	bundle, err := c.fetchOperatorManifest(ctx, bundleURL)
+func (c *K8sClient) ApplyOperator(ctx context.Context, version string, manifests ManifestSource) error {
+	bundle, err := manifests.Manifest(ctx, version, "bundle.yaml")
 	if err != nil {
 		return errors.Wrap(err, "failed to install operator")
 	}
 	return c.kubeCtl.Apply(ctx, bundle)
 }
 
-// PatchAllPSMDBClusters replaces images versions and CrVersion after update of the operator to match version
-// of the installed operator.
-func (c *K8sClient) PatchAllPSMDBClusters(ctx context.Context, oldVersion, newVersion string) error {
-	var list psmdb.PerconaServerMongoDBList
-	err := c.kubeCtl.Get(ctx, psmdb.PerconaServerMongoDBKind, "", &list)
-	if err != nil {
-		return errors.Wrap(err, "couldn't get percona server MongoDB clusters")
-	}
-
-	for _, cluster := range list.Items {
-		clusterPatch := &psmdb.PerconaServerMongoDB{
-			Spec: &psmdb.PerconaServerMongoDBSpec{
-				CRVersion: newVersion,
-				Image:     strings.Replace(cluster.Spec.Image, oldVersion, newVersion, 1),
-				Backup: &psmdb.BackupSpec{
-					Image: strings.Replace(cluster.Spec.Backup.Image, oldVersion, newVersion, 1),
-				},
-			},
-		}
-		if err := c.kubeCtl.Patch(ctx, kubectl.PatchTypeMerge, "perconaservermongodb", cluster.Name, clusterPatch); err != nil {
-			return err
-		}
-	}
-	return nil
-}
-
-// PatchAllPXCClusters replaces the image versions and crVersion after update of the operator to match version
-// of the installed operator.
-func (c *K8sClient) PatchAllPXCClusters(ctx context.Context, oldVersion, newVersion string) error {
-	var list pxc.PerconaXtraDBClusterList
-	err := c.kubeCtl.Get(ctx, pxc.PerconaXtraDBClusterKind, "", &list)
-	if err != nil {
-		return errors.Wrap(err, "couldn't get percona XtraDB clusters")
-	}
-
-	for _, cluster := range list.Items {
-		clusterPatch := &pxc.PerconaXtraDBCluster{
-			Spec: &pxc.PerconaXtraDBClusterSpec{
-				CRVersion: newVersion,
-				PXC: &pxc.PodSpec{
-					Image: strings.Replace(cluster.Spec.PXC.Image, oldVersion, newVersion, 1),
-				},
-				Backup: &pxc.PXCScheduledBackup{
-					Image: strings.Replace(cluster.Spec.Backup.Image, oldVersion, newVersion, 1),
-				},
-			},
-		}
-
-		if cluster.Spec.HAProxy != nil {
-			clusterPatch.Spec.HAProxy = &pxc.PodSpec{
-				Image: strings.Replace(cluster.Spec.HAProxy.Image, oldVersion, newVersion, 1),
-			}
-		} else {
-			cluster.Spec.ProxySQL = &pxc.PodSpec{
-				Image: strings.Replace(cluster.Spec.ProxySQL.Image, oldVersion, newVersion, 1),
-			}
-		}
-
-		if err := c.kubeCtl.Patch(ctx, kubectl.PatchTypeMerge, "perconaxtradbcluster", cluster.Name, clusterPatch); err != nil {
-			return err
-		}
-	}
-	return nil
-}
-
 // UpdateOperator updates images inside operator deployment and also applies new CRDs and RBAC.
-func (c *K8sClient) UpdateOperator(ctx context.Context, version, deploymentName, manifestsURLTemplate string) error {
-	files := []string{"crd.yaml", "rbac.yaml"}
-	for _, file := range files {
-		manifestURL := fmt.Sprintf(manifestsURLTemplate, version, file)
-		manifest, err := c.fetchOperatorManifest(ctx, manifestURL)
-		if err != nil {
-			return errors.Wrap(err, "failed to update operator")
-		}
-		err = c.kubeCtl.Apply(ctx, manifest)
-		if err != nil {
-			return errors.Wrap(err, "failed to update operator")
-		}
-	}
-	// Change image inside operator deployment.
+// Operator deployment and database clusters are snapshotted before the update, so it could be rolled back
+// with RollbackOperator. ErrOperatorUnhealthy is returned if the new operator pod does not become available.
+func (c *K8sClient) UpdateOperator(ctx context.Context, operator Engine, version, deploymentName string, manifests ManifestSource) error {
 	var deployment common.Deployment
 	err := c.kubeCtl.Get(ctx, "deployment", deploymentName, &deployment)
 	if err != nil {
@@ -1892,104 +1936,39 @@ func (c *K8sClient) UpdateOperator(ctx context.Context, version, deploymentName,
 	if containerIndex < 0 {
 		return errors.Errorf("container with name %q not found inside operator deployment", deploymentName)
 	}
-	imageAndTag := strings.Split(deployment.Spec.Template.Spec.Containers[containerIndex].Image, ":")
+	image := deployment.Spec.Template.Spec.Containers[containerIndex].Image
+	imageAndTag := strings.Split(image, ":")
 	if len(imageAndTag) != 2 {
-		return errors.Errorf("container image %q does not have any tag", deployment.Spec.Template.Spec.Containers[containerIndex].Image)
+		return errors.Errorf("container image %q does not have any tag", image)
 	}
-	deployment.Spec.Template.Spec.Containers[containerIndex].Image = imageAndTag[0] + ":" + version
-	return c.kubeCtl.Patch(ctx, kubectl.PatchTypeStrategic, "deployment", deploymentName, deployment)
-}
-
-func (c *K8sClient) CreateVMOperator(ctx context.Context, params *PMM) error {
-	files := []string{
-		"deploy/victoriametrics/crds/crd.yaml",
-		"deploy/victoriametrics/operator/manager.yaml",
-		"deploy/victoriametrics/operator/rbac.yaml",
-		"deploy/victoriametrics/crs/vmagent_rbac.yaml",
-		"deploy/victoriametrics/crs/vmnodescrape.yaml",
-		"deploy/victoriametrics/crs/vmpodscrape.yaml",
+	if err := c.snapshotOperator(ctx, operator, &deployment, imageAndTag[1], version, manifests); err != nil {
+		return err
 	}
-	for _, path := range files {
-		file, err := dbaascontroller.DeployDir.ReadFile(path)
+
+	files := []string{"crd.yaml", "rbac.yaml"}
+	for _, file := range files {
+		manifest, err := manifests.Manifest(ctx, version, file)
 		if err != nil {
-			return err
+			return errors.Wrap(err, "failed to update operator")
 		}
-		err = c.kubeCtl.Apply(ctx, file)
+		err = c.kubeCtl.Apply(ctx, manifest)
 		if err != nil {
-			return errors.Wrapf(err, "cannot apply file: %q", path)
+			return errors.Wrap(err, "failed to update operator")
 		}
 	}
-
-	randomCrypto, err := rand.Prime(rand.Reader, 64)
-	if err != nil {
-		return err
-	}
-
-	secretName := fmt.Sprintf("vm-operator-%d", randomCrypto)
-	err = c.CreateSecret(ctx, secretName, map[string][]byte{
-		"username": []byte(params.Login),
-		"password": []byte(params.Password),
-	})
-	if err != nil {
-		return err
-	}
-
-	vmagent := vmAgentSpec(params, secretName)
-	return c.kubeCtl.Apply(ctx, vmagent)
-}
-
-func vmAgentSpec(params *PMM, secretName string) monitoring.VMAgent {
-	return monitoring.VMAgent{
-		TypeMeta: common.TypeMeta{
-			Kind:       "VMAgent",
-			APIVersion: "operator.victoriametrics.com/v1beta1",
-		},
-		ObjectMeta: common.ObjectMeta{
-			Name: "pmm-vmagent-" + secretName,
-		},
-		Spec: monitoring.VMAgentSpec{
-			ServiceScrapeNamespaceSelector: new(common.LabelSelector),
-			ServiceScrapeSelector:          new(common.LabelSelector),
-			PodScrapeNamespaceSelector:     new(common.LabelSelector),
-			PodScrapeSelector:              new(common.LabelSelector),
-			ProbeSelector:                  new(common.LabelSelector),
-			ProbeNamespaceSelector:         new(common.LabelSelector),
-			StaticScrapeSelector:           new(common.LabelSelector),
-			StaticScrapeNamespaceSelector:  new(common.LabelSelector),
-			ReplicaCount:                   1,
-			Resources: &common.PodResources{
-				Requests: &common.ResourcesList{
-					CPU:    "250m",
-					Memory: "350Mi",
-				},
-				Limits: &common.ResourcesList{
-					CPU:    "500m",
-					Memory: "850Mi",
-				},
-			},
-			ExtraArgs: map[string]string{
-				"memory.allowedPercent": "40",
-			},
-			RemoteWrite: []monitoring.VMAgentRemoteWriteSpec{
-				{
-					URL:       fmt.Sprintf("%s/victoriametrics/api/v1/write", params.PublicAddress),
-					TLSConfig: &monitoring.TLSConfig{InsecureSkipVerify: true},
-					BasicAuth: &monitoring.BasicAuth{
-						Username: common.SecretKeySelector{
-							LocalObjectReference: common.LocalObjectReference{
-								Name: secretName,
-							},
-							Key: "username",
-						},
-						Password: common.SecretKeySelector{
-							LocalObjectReference: common.LocalObjectReference{
-								Name: secretName,
-							},
-							Key: "password",
-						},
-					},
+	// Change image inside operator deployment.
+	updated := common.Deployment{
+		Spec: common.DeploymentSpec{
+			Template: common.DeploymentTemplate{
+				Spec: common.PodSpec{
+					Containers: []common.ContainerSpec{{Name: deploymentName, Image: imageAndTag[0] + ":" + version}},
 				},
 			},
 		},
 	}
+	err = c.kubeCtl.Patch(ctx, kubectl.PatchTypeStrategic, "deployment", deploymentName, updated)
+	if err != nil {
+		return errors.Wrap(err, "failed to update operator deployment")
+	}
+	return c.waitOperatorHealthy(ctx, deploymentName)
 }
@@ -221,10 +221,10 @@ func TestK8sClient(t *testing.T) {
 	pxc, psmdb, err := versionService.LatestOperatorVersion(ctx, latestPMMVersion.String())
 	require.NoError(t, err)
 
-	err = client.ApplyOperator(ctx, pxc.String(), app.DefaultPXCOperatorURLTemplate)
+	err = client.ApplyOperator(ctx, pxc.String(), NewURLManifestSource(app.DefaultPXCOperatorURLTemplate))
 	require.NoError(t, err)
 
-	err = client.ApplyOperator(ctx, psmdb.String(), app.DefaultPSMDBOperatorURLTemplate)
+	err = client.ApplyOperator(ctx, psmdb.String(), NewURLManifestSource(app.DefaultPSMDBOperatorURLTemplate))
 	require.NoError(t, err)
 
 	for i := 0; i < 5; i++ {
@@ -367,7 +367,7 @@ func TestK8sClient(t *testing.T) {
 						container.Name,
 					)
 
-					logs, err := client.GetLogs(ctx, ppod.Status.ContainerStatuses, ppod.Name, container.Name)
+					logs, err := client.GetLogs(ctx, ppod.Status.ContainerStatuses, ppod.Name, container.Name, nil)
 					require.NoError(t, err, "failed to get logs")
 					assert.Greater(t, len(logs), 0)
 					for _, l := range logs {
@@ -790,7 +790,7 @@ func TestGetAllClusterResources(t *testing.T) {
 
 	clusterType := client.GetKubernetesClusterType(ctx)
 	var volumes *common.PersistentVolumeList
-	if clusterType == AmazonEKSClusterType {
+	if clusterType.UsesAttachableVolumes() {
 		volumes, err = client.GetPersistentVolumes(ctx)
 		require.NoError(t, err)
 	}
@@ -815,6 +815,30 @@ func TestGetAllClusterResources(t *testing.T) {
 	)
 }
 
+func TestWorkerNodes(t *testing.T) {
+	t.Parallel()
+	node := func(name string, taints ...common.Taint) common.Node {
+		return common.Node{ObjectMeta: common.ObjectMeta{Name: name}, Spec: common.NodeSpec{Taints: taints}}
+	}
+	master := common.Taint{Key: "node-role.kubernetes.io/master", Effect: "NoSchedule"}
+	unschedulable := common.Taint{Key: "node.kubernetes.io/unschedulable", Effect: "NoSchedule"}
+	custom := common.Taint{Key: "dedicated", Effect: "NoSchedule"}
+	spot := common.Taint{Key: "spot", Effect: "PreferNoSchedule"}
+
+	workers := workerNodes([]common.Node{
+		node("untainted"),
+		node("master", master),
+		node("cordoned-master", master, unschedulable),
+		node("tainted", custom, spot),
+		node("master-and-tainted", master, custom, spot),
+	})
+	names := make([]string, 0, len(workers))
+	for _, worker := range workers {
+		names = append(names, worker.Name)
+	}
+	assert.Equal(t, []string{"untainted", "tainted", "master-and-tainted"}, names)
+}
+
 func TestVMAgentSpec(t *testing.T) {
 	t.Parallel()
 	expected := `{
@@ -0,0 +1,259 @@
+// dbaas-controller
+// Copyright (C) 2020 Percona LLC
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package k8sclient
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/percona-platform/dbaas-controller/service/k8sclient/common"
+	"github.com/percona-platform/dbaas-controller/service/k8sclient/internal/kubectl"
+)
+
+const (
+	// maxLogLineSize is the maximal size of a log line.
+	maxLogLineSize = 1024 * 1024
+	// logStreamReopenInterval is the delay before reopening followed stream of a restarted container.
+	logStreamReopenInterval = 5 * time.Second
+)
+
+// LogLine is a line of container logs.
+type LogLine struct {
+	Pod       string
+	Container string
+	Line      string
+}
+
+// StreamLogsParams contains parameters of logs streaming.
+type StreamLogsParams struct {
+	// ClusterName is the name of database cluster whose pods logs are streamed.
+	ClusterName string
+	// Pods limits logs to given pods, all cluster pods are used if empty.
+	Pods []string
+	// Containers limits logs to given containers, all containers are used if empty.
+	Containers []string
+	// Since limits logs to the ones newer than given time if set.
+	Since time.Time
+	// TailLines limits logs to the last lines of every container if positive.
+	TailLines int
+	// Timestamps prefixes log lines with timestamps.
+	Timestamps bool
+	// Follow streams new logs until ctx is canceled or pods are deleted, including logs of restarted containers.
+	Follow bool
+}
+
+// logStream is a stream of logs of a single container.
+type logStream struct {
+	pod       string
+	container string
+	// open writes container logs newer than since, or all selected logs if since is zero, to w
+	// until they end or ctx is canceled.
+	open func(ctx context.Context, w io.Writer, since time.Time) error
+	// follow is true if the stream is reopened after it ends, because the container restarted.
+	follow bool
+	// reopenInterval is the delay before reopening the stream.
+	reopenInterval time.Duration
+}
+
+// logsArgs returns kubectl arguments for logs of the pod's container.
+func logsArgs(params *StreamLogsParams, pod, container string) []string {
+	args := []string{"logs", pod, "--container", container}
+	if params.Follow {
+		args = append(args, "--follow")
+	}
+	if !params.Since.IsZero() {
+		args = append(args, "--since-time", params.Since.UTC().Format(time.RFC3339))
+	}
+	if params.TailLines > 0 {
+		args = append(args, "--tail", strconv.Itoa(params.TailLines))
+	}
+	if params.Timestamps {
+		args = append(args, "--timestamps")
+	}
+	return args
+}
+
+// containsOrEmpty returns true if list is empty or it contains s.
+func containsOrEmpty(list []string, s string) bool {
+	if len(list) == 0 {
+		return true
+	}
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+// StreamLogs streams logs of selected containers of the cluster pods multiplexed into send calls.
+// Send is never called concurrently. Streaming stops when ctx is canceled, send returns an error
+// or logs of all containers end. With params.Follow, logs of restarted containers are streamed too,
+// so they end only when pods are deleted; lines logged within a second before a restart may be repeated.
+func (c *K8sClient) StreamLogs(ctx context.Context, params *StreamLogsParams, send func(*LogLine) error) error {
+	pods, err := c.GetPods(ctx, "-lapp.kubernetes.io/instance="+params.ClusterName)
+	if err != nil {
+		return err
+	}
+
+	var streams []logStream
+	for _, pod := range pods.Items {
+		if !containsOrEmpty(params.Pods, pod.Name) {
+			continue
+		}
+		statuses := make([]common.ContainerStatus, 0, len(pod.Status.InitContainerStatuses)+len(pod.Status.ContainerStatuses))
+		statuses = append(statuses, pod.Status.InitContainerStatuses...)
+		statuses = append(statuses, pod.Status.ContainerStatuses...)
+		for i, containers := range [][]common.ContainerSpec{pod.Spec.InitContainers, pod.Spec.Containers} {
+			init := i == 0
+			for _, container := range containers {
+				if !containsOrEmpty(params.Containers, container.Name) {
+					continue
+				}
+				if common.IsContainerInState(statuses, common.ContainerStateWaiting, container.Name) {
+					continue
+				}
+				podName, containerName := pod.Name, container.Name
+				streams = append(streams, logStream{
+					pod:       podName,
+					container: containerName,
+					open: func(ctx context.Context, w io.Writer, since time.Time) error {
+						streamParams := *params
+						if !since.IsZero() {
+							streamParams.Since = since
+							streamParams.TailLines = 0
+						}
+						return c.kubeCtl.Stream(ctx, logsArgs(&streamParams, podName, containerName), w)
+					},
+					// Completed init containers are not restarted.
+					follow:         params.Follow && !init,
+					reopenInterval: logStreamReopenInterval,
+				})
+			}
+		}
+	}
+	return multiplexLogs(ctx, streams, send)
+}
+
+// multiplexLogs reads all streams concurrently and calls send for every line.
+func multiplexLogs(ctx context.Context, streams []logStream, send func(*LogLine) error) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	lines := make(chan *LogLine)
+	errs := make(chan error, len(streams))
+	var wg sync.WaitGroup
+	for _, stream := range streams {
+		wg.Add(1)
+		go func(stream logStream) {
+			defer wg.Done()
+			if err := readLogStream(ctx, stream, lines); err != nil {
+				errs <- errors.Wrapf(err, "failed to stream logs of %s/%s", stream.pod, stream.container)
+			}
+		}(stream)
+	}
+	go func() {
+		wg.Wait()
+		close(lines)
+	}()
+
+	var sendErr error
+	for line := range lines {
+		if sendErr != nil {
+			continue // drain lines until all streams stop
+		}
+		if sendErr = send(line); sendErr != nil {
+			cancel()
+		}
+	}
+	if sendErr != nil {
+		return sendErr
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	select {
+	case err := <-errs:
+		return err
+	default:
+		return nil
+	}
+}
+
+// readLogStream reads lines of the stream and puts them to lines channel until stream ends or ctx is canceled.
+// Followed stream is reopened after it ends, because the container restarted, until the pod is deleted.
+// Errors of reopening are retried, for example, while restarted container is waiting to start.
+func readLogStream(ctx context.Context, stream logStream, lines chan<- *LogLine) error {
+	var since time.Time
+	var opened bool
+	for {
+		read, err := readLogStreamOnce(ctx, stream, since, lines)
+		opened = opened || read || err == nil
+		switch {
+		case ctx.Err() != nil:
+			return nil
+		case !stream.follow:
+			return err
+		case errors.Is(err, kubectl.ErrNotFound):
+			return nil
+		case err != nil && !opened:
+			return err
+		}
+
+		since = time.Now()
+		timer := time.NewTimer(stream.reopenInterval)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil
+		case <-timer.C:
+		}
+	}
+}
+
+// readLogStreamOnce opens the stream once and reads its lines newer than since until the stream ends
+// or ctx is canceled. It returns true if any line was read.
+func readLogStreamOnce(ctx context.Context, stream logStream, since time.Time, lines chan<- *LogLine) (bool, error) {
+	r, w := io.Pipe()
+	go func() {
+		w.CloseWithError(stream.open(ctx, w, since))
+	}()
+	defer r.Close() //nolint:errcheck
+
+	var read bool
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxLogLineSize)
+	for scanner.Scan() {
+		read = true
+		line := &LogLine{Pod: stream.pod, Container: stream.container, Line: scanner.Text()}
+		select {
+		case lines <- line:
+		case <-ctx.Done():
+			return read, nil
+		}
+	}
+	if err := scanner.Err(); err != nil && ctx.Err() == nil {
+		return read, err
+	}
+	return read, nil
+}
@@ -0,0 +1,180 @@
+// dbaas-controller
+// Copyright (C) 2020 Percona LLC
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package k8sclient
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/percona-platform/dbaas-controller/service/k8sclient/internal/kubectl"
+)
+
+func TestLogsArgs(t *testing.T) {
+	t.Parallel()
+	assert.Equal(t, []string{"logs", "pod-0", "--container", "pxc"}, logsArgs(new(StreamLogsParams), "pod-0", "pxc"))
+
+	params := &StreamLogsParams{
+		Since:      time.Date(2021, 6, 1, 12, 0, 0, 0, time.FixedZone("CEST", 2*60*60)),
+		TailLines:  100,
+		Timestamps: true,
+		Follow:     true,
+	}
+	expected := []string{
+		"logs", "pod-0", "--container", "pxc", "--follow", "--since-time", "2021-06-01T10:00:00Z", "--tail", "100", "--timestamps",
+	}
+	assert.Equal(t, expected, logsArgs(params, "pod-0", "pxc"))
+}
+
+func TestLogsOptionsArgs(t *testing.T) {
+	t.Parallel()
+	var opts *LogsOptions
+	assert.Empty(t, opts.args())
+	opts = &LogsOptions{Since: time.Date(2021, 6, 1, 12, 0, 0, 0, time.FixedZone("CEST", 2*60*60)), Timestamps: true}
+	assert.Equal(t, []string{"--since-time", "2021-06-01T10:00:00Z", "--timestamps"}, opts.args())
+}
+
+func TestPreviousContainerMissing(t *testing.T) {
+	t.Parallel()
+	err := errors.New("exit status 1\ncmd: kubectl logs pod-0 pxc --previous\n" +
+		`stderr: Error from server (BadRequest): previous terminated container "pxc" in pod "pod-0" not found`)
+	assert.True(t, previousContainerMissing(err))
+	assert.False(t, previousContainerMissing(errors.New("stderr: Error from server (Forbidden): pods \"pod-0\" is forbidden")))
+}
+
+// fakeLogStream returns stream writing given lines and then blocking until ctx is canceled if follow is true.
+func fakeLogStream(pod, container string, lines int, follow bool) logStream {
+	return logStream{
+		pod:       pod,
+		container: container,
+		open: func(ctx context.Context, w io.Writer, since time.Time) error {
+			for i := 0; i < lines; i++ {
+				if _, err := fmt.Fprintf(w, "line %d\n", i); err != nil {
+					return err
+				}
+			}
+			if follow {
+				<-ctx.Done()
+				return ctx.Err()
+			}
+			return nil
+		},
+	}
+}
+
+func TestMultiplexLogs(t *testing.T) {
+	t.Parallel()
+
+	t.Run("All lines", func(t *testing.T) {
+		t.Parallel()
+		streams := []logStream{fakeLogStream("pod-0", "pxc", 3, false), fakeLogStream("pod-1", "pxc", 2, false)}
+		var lines []string
+		err := multiplexLogs(context.Background(), streams, func(line *LogLine) error {
+			lines = append(lines, line.Pod+"/"+line.Container+": "+line.Line)
+			return nil
+		})
+		require.NoError(t, err)
+		sort.Strings(lines)
+		assert.Equal(t, []string{
+			"pod-0/pxc: line 0", "pod-0/pxc: line 1", "pod-0/pxc: line 2",
+			"pod-1/pxc: line 0", "pod-1/pxc: line 1",
+		}, lines)
+	})
+
+	t.Run("Send error stops following", func(t *testing.T) {
+		t.Parallel()
+		streams := []logStream{fakeLogStream("pod-0", "pxc", 10, true), fakeLogStream("pod-1", "pxc", 10, true)}
+		sendErr := errors.New("client disconnected")
+		var sent int
+		err := multiplexLogs(context.Background(), streams, func(line *LogLine) error {
+			sent++
+			if sent == 3 {
+				return sendErr
+			}
+			return nil
+		})
+		assert.Equal(t, sendErr, err)
+		assert.Equal(t, 3, sent)
+	})
+
+	t.Run("Cancellation stops following", func(t *testing.T) {
+		t.Parallel()
+		ctx, cancel := context.WithCancel(context.Background())
+		streams := []logStream{fakeLogStream("pod-0", "pxc", 1, true)}
+		err := multiplexLogs(ctx, streams, func(line *LogLine) error {
+			cancel()
+			return nil
+		})
+		assert.Equal(t, context.Canceled, err)
+	})
+
+	t.Run("Stream error", func(t *testing.T) {
+		t.Parallel()
+		streams := []logStream{{
+			pod:       "pod-0",
+			container: "pxc",
+			open: func(ctx context.Context, w io.Writer, since time.Time) error {
+				return errors.New("container not found")
+			},
+			follow: true,
+		}}
+		err := multiplexLogs(context.Background(), streams, func(line *LogLine) error { return nil })
+		assert.EqualError(t, err, "failed to stream logs of pod-0/pxc: container not found")
+	})
+
+	t.Run("Restarted container is followed", func(t *testing.T) {
+		t.Parallel()
+		var opens []time.Time
+		streams := []logStream{{
+			pod:       "pod-0",
+			container: "pxc",
+			open: func(ctx context.Context, w io.Writer, since time.Time) error {
+				opens = append(opens, since)
+				switch len(opens) {
+				case 1:
+					_, err := fmt.Fprintln(w, "before restart")
+					return err
+				case 2:
+					return errors.New(`container "pxc" in pod "pod-0" is waiting to start`)
+				case 3:
+					_, err := fmt.Fprintln(w, "after restart")
+					return err
+				default:
+					return kubectl.ErrNotFound
+				}
+			},
+			follow: true,
+		}}
+		var lines []string
+		err := multiplexLogs(context.Background(), streams, func(line *LogLine) error {
+			lines = append(lines, line.Line)
+			return nil
+		})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"before restart", "after restart"}, lines)
+		require.Len(t, opens, 4)
+		assert.True(t, opens[0].IsZero())
+		assert.False(t, opens[1].IsZero())
+	})
+}
@@ -0,0 +1,368 @@
+// dbaas-controller
+// Copyright (C) 2020 Percona LLC
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package k8sclient
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	dbaascontroller "github.com/percona-platform/dbaas-controller"
+	"github.com/percona-platform/dbaas-controller/utils/logger"
+)
+
+// EmbeddedManifests is a manifests location pointing at operator manifests embedded into the binary.
+const EmbeddedManifests = "embedded"
+
+// checksumsFile is a name of the file holding SHA-256 checksums of manifests in sha256sum format.
+const checksumsFile = "SHA256SUMS"
+
+var (
+	// ErrManifestNotFound is returned when manifests source does not have requested manifest.
+	ErrManifestNotFound = errors.New("operator manifest not found")
+	// ErrManifestChecksum is returned when manifest checksum can't be verified.
+	ErrManifestChecksum = errors.New("operator manifest checksum verification failed")
+)
+
+// ManifestSource provides Kubernetes manifests (bundle.yaml, crd.yaml, rbac.yaml) needed to install or upgrade an operator.
+type ManifestSource interface {
+	// Manifest returns content of the manifest file for given operator version.
+	Manifest(ctx context.Context, version, file string) ([]byte, error)
+}
+
+// NewManifestSource returns manifests source of the operator for given location. Empty location means
+// fetching manifests using URL template, EmbeddedManifests uses manifests embedded into the binary
+// and falls back to URL template for operator versions which are not embedded, otherwise location is a path to a directory or to a tarball or OCI image layout archive.
+// Directories and archives hold manifests as <operator>/<version>/<file>.
+// If requireChecksums is true, manifests without checksums in SHA256SUMS file are rejected.
+// Upstream repositories don't publish checksums, so manifests fetched using URL template are
+// verified only if SHA256SUMS file is available next to them.
+func NewManifestSource(location string, operator Engine, urlTemplate string, requireChecksums bool) (ManifestSource, error) {
+	var source ManifestSource
+	switch location {
+	case "":
+		return &verifiedManifestSource{source: NewURLManifestSource(urlTemplate)}, nil
+	case EmbeddedManifests:
+		fsys, err := fs.Sub(dbaascontroller.DeployDir, "deploy/operators")
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to open embedded operator manifests")
+		}
+		return &fallbackManifestSource{
+			source:   &verifiedManifestSource{source: &fsManifestSource{fsys: fsys, operator: operator}, requireChecksums: requireChecksums},
+			fallback: &verifiedManifestSource{source: NewURLManifestSource(urlTemplate)},
+		}, nil
+	default:
+		info, err := os.Stat(location)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to open operator manifests")
+		}
+		if info.IsDir() {
+			source = &fsManifestSource{fsys: os.DirFS(location), operator: operator}
+			break
+		}
+		files, err := readManifestsArchive(location)
+		if err != nil {
+			return nil, err
+		}
+		source = &fsManifestSource{fsys: files, operator: operator}
+	}
+	return &verifiedManifestSource{source: source, requireChecksums: requireChecksums}, nil
+}
+
+// urlManifestSource fetches manifests over HTTP.
+type urlManifestSource struct {
+	urlTemplate string
+	client      *http.Client
+}
+
+// NewURLManifestSource returns manifests source fetching manifests over HTTP. First '%s' in the template
+// is replaced with operator version and second one with the file name.
+func NewURLManifestSource(urlTemplate string) ManifestSource {
+	return &urlManifestSource{
+		urlTemplate: urlTemplate,
+		client: &http.Client{
+			Timeout: time.Second * 5,
+			Transport: &http.Transport{
+				MaxIdleConns:    1,
+				IdleConnTimeout: 10 * time.Second,
+			},
+		},
+	}
+}
+
+// Manifest implements ManifestSource interface.
+func (s *urlManifestSource) Manifest(ctx context.Context, version, file string) ([]byte, error) {
+	manifestURL := fmt.Sprintf(s.urlTemplate, version, file)
+	req, err := http.NewRequestWithContext(ctx, "GET", manifestURL, nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to fetch operator manifests")
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to fetch operator manifests")
+	}
+	defer func() {
+		err := resp.Body.Close()
+		if err != nil {
+			logger.Get(ctx).Errorf("failed to close response's body: %v", err)
+		}
+	}()
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, errors.Wrapf(ErrManifestNotFound, "%s", manifestURL)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.Errorf("failed to fetch operator manifests, http request ended with status %q", resp.Status)
+	}
+	return ioutil.ReadAll(resp.Body)
+}
+
+// fsManifestSource reads manifests from a file system.
+type fsManifestSource struct {
+	fsys     fs.FS
+	operator Engine
+}
+
+// Manifest implements ManifestSource interface.
+func (s *fsManifestSource) Manifest(ctx context.Context, version, file string) ([]byte, error) {
+	name := path.Join(string(s.operator), version, file)
+	b, err := fs.ReadFile(s.fsys, name)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, errors.Wrapf(ErrManifestNotFound, "%s", name)
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read operator manifests")
+	}
+	return b, nil
+}
+
+// fallbackManifestSource reads manifests from the fallback source if the source does not have them.
+type fallbackManifestSource struct {
+	source   ManifestSource
+	fallback ManifestSource
+}
+
+// Manifest implements ManifestSource interface.
+func (s *fallbackManifestSource) Manifest(ctx context.Context, version, file string) ([]byte, error) {
+	manifest, err := s.source.Manifest(ctx, version, file)
+	if !errors.Is(err, ErrManifestNotFound) {
+		return manifest, err
+	}
+	logger.Get(ctx).Warnf("%s, falling back to fetching it: %s", err, file)
+	return s.fallback.Manifest(ctx, version, file)
+}
+
+// verifiedManifestSource verifies manifests against SHA256SUMS file provided by the source.
+type verifiedManifestSource struct {
+	source           ManifestSource
+	requireChecksums bool
+}
+
+// Manifest implements ManifestSource interface.
+func (s *verifiedManifestSource) Manifest(ctx context.Context, version, file string) ([]byte, error) {
+	manifest, err := s.source.Manifest(ctx, version, file)
+	if err != nil {
+		return nil, err
+	}
+	checksums, err := s.source.Manifest(ctx, version, checksumsFile)
+	if errors.Is(err, ErrManifestNotFound) && !s.requireChecksums {
+		return manifest, nil
+	}
+	if err != nil {
+		return nil, errors.Wrapf(ErrManifestChecksum, "failed to get checksums of %s: %s", file, err)
+	}
+	if err := verifyChecksum(checksums, file, manifest); err != nil {
+		return nil, err
+	}
+	return manifest, nil
+}
+
+// verifyChecksum checks that SHA-256 checksum of the manifest matches the one from checksums in sha256sum format.
+func verifyChecksum(checksums []byte, file string, manifest []byte) error {
+	scanner := bufio.NewScanner(bytes.NewReader(checksums))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 2 || strings.TrimPrefix(fields[1], "*") != file {
+			continue
+		}
+		sum := sha256.Sum256(manifest)
+		if !strings.EqualFold(fields[0], hex.EncodeToString(sum[:])) {
+			return errors.Wrapf(ErrManifestChecksum, "checksum of %s does not match", file)
+		}
+		return nil
+	}
+	if err := scanner.Err(); err != nil {
+		return errors.Wrapf(ErrManifestChecksum, "failed to read checksums: %s", err)
+	}
+	return errors.Wrapf(ErrManifestChecksum, "no checksum for %s", file)
+}
+
+// archiveFS is an in-memory file system holding regular files of an archive.
+type archiveFS map[string][]byte
+
+// Open implements fs.FS interface. Only reading whole files with ReadFile is supported.
+func (a archiveFS) Open(name string) (fs.File, error) {
+	return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+}
+
+// ReadFile implements fs.ReadFileFS interface.
+func (a archiveFS) ReadFile(name string) ([]byte, error) {
+	b, ok := a[name]
+	if !ok {
+		return nil, &fs.PathError{Op: "read", Path: name, Err: fs.ErrNotExist}
+	}
+	return b, nil
+}
+
+// readManifestsArchive reads manifests from tarball (optionally gzip compressed). If the archive
+// is OCI image layout, manifests are read from layers of all images in the layout.
+func readManifestsArchive(name string) (archiveFS, error) {
+	b, err := ioutil.ReadFile(name) //nolint:gosec
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read operator manifests archive")
+	}
+	files, err := untar(b)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read operator manifests archive %s", name)
+	}
+	if _, ok := files["oci-layout"]; !ok {
+		return files, nil
+	}
+	files, err = readOCILayout(files)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read OCI image layout %s", name)
+	}
+	return files, nil
+}
+
+// untar returns regular files of tarball, it's decompressed first if it's gzipped.
+func untar(b []byte) (archiveFS, error) {
+	var r io.Reader = bytes.NewReader(b)
+	if len(b) > 2 && b[0] == 0x1f && b[1] == 0x8b {
+		gz, err := gzip.NewReader(r)
+		if err != nil {
+			return nil, err
+		}
+		defer gz.Close() //nolint:errcheck
+		r = gz
+	}
+
+	files := make(archiveFS)
+	tr := tar.NewReader(r)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return files, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+		content, err := ioutil.ReadAll(tr)
+		if err != nil {
+			return nil, err
+		}
+		files[path.Clean(strings.TrimPrefix(header.Name, "/"))] = content
+	}
+}
+
+// ociDescriptor describes content addressable blob of OCI image layout.
+type ociDescriptor struct {
+	Digest string `json:"digest"`
+}
+
+// readOCILayout returns files stored in layers of images referenced by OCI image layout index.
+// See https://github.com/opencontainers/image-spec/blob/main/image-layout.md.
+func readOCILayout(layout archiveFS) (archiveFS, error) {
+	var index struct {
+		Manifests []ociDescriptor `json:"manifests"`
+	}
+	if err := readOCIJSON(layout, "index.json", &index); err != nil {
+		return nil, err
+	}
+
+	files := make(archiveFS)
+	for _, manifestDescriptor := range index.Manifests {
+		var manifest struct {
+			Layers []ociDescriptor `json:"layers"`
+		}
+		blob, err := readOCIBlob(layout, manifestDescriptor)
+		if err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(blob, &manifest); err != nil {
+			return nil, errors.Wrapf(err, "failed to parse image manifest %s", manifestDescriptor.Digest)
+		}
+		for _, layerDescriptor := range manifest.Layers {
+			blob, err := readOCIBlob(layout, layerDescriptor)
+			if err != nil {
+				return nil, err
+			}
+			layer, err := untar(blob)
+			if err != nil {
+				return nil, errors.Wrapf(err, "failed to read layer %s", layerDescriptor.Digest)
+			}
+			for name, content := range layer {
+				files[name] = content
+			}
+		}
+	}
+	return files, nil
+}
+
+// readOCIJSON decodes JSON file of OCI image layout.
+func readOCIJSON(layout archiveFS, name string, v interface{}) error {
+	b, err := layout.ReadFile(name)
+	if err != nil {
+		return err
+	}
+	return errors.Wrapf(json.Unmarshal(b, v), "failed to parse %s", name)
+}
+
+// readOCIBlob returns blob of OCI image layout and verifies its digest.
+func readOCIBlob(layout archiveFS, descriptor ociDescriptor) ([]byte, error) {
+	algorithm, digest, ok := strings.Cut(descriptor.Digest, ":")
+	if !ok || algorithm != "sha256" {
+		return nil, errors.Errorf("unsupported digest %q", descriptor.Digest)
+	}
+	blob, err := layout.ReadFile(path.Join("blobs", algorithm, digest))
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(blob)
+	if hex.EncodeToString(sum[:]) != digest {
+		return nil, errors.Wrapf(ErrManifestChecksum, "digest of blob %s does not match", descriptor.Digest)
+	}
+	return blob, nil
+}
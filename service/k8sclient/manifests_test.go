@@ -0,0 +1,163 @@
+// dbaas-controller
+// Copyright (C) 2020 Percona LLC
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package k8sclient
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func sha256Hex(b []byte) string {
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
+
+func tarGz(t *testing.T, files map[string][]byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	for name, content := range files {
+		require.NoError(t, tw.WriteHeader(&tar.Header{Name: name, Mode: 0o644, Size: int64(len(content)), Typeflag: tar.TypeReg}))
+		_, err := tw.Write(content)
+		require.NoError(t, err)
+	}
+	require.NoError(t, tw.Close())
+	require.NoError(t, gz.Close())
+	return buf.Bytes()
+}
+
+func TestManifestSource(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	bundle := []byte("kind: Deployment\n")
+	checksums := []byte(fmt.Sprintf("%s  bundle.yaml\n%s *crd.yaml\n", sha256Hex(bundle), sha256Hex([]byte("other"))))
+
+	t.Run("Directory", func(t *testing.T) {
+		t.Parallel()
+		dir := t.TempDir()
+		require.NoError(t, os.MkdirAll(filepath.Join(dir, "pxc", "1.10.0"), 0o755))
+		require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "pxc", "1.10.0", "bundle.yaml"), bundle, 0o600))
+		require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "pxc", "1.10.0", "crd.yaml"), bundle, 0o600))
+		require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "pxc", "1.10.0", "rbac.yaml"), bundle, 0o600))
+		require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "pxc", "1.10.0", checksumsFile), checksums, 0o600))
+
+		source, err := NewManifestSource(dir, EnginePXC, "", true)
+		require.NoError(t, err)
+		b, err := source.Manifest(ctx, "1.10.0", "bundle.yaml")
+		require.NoError(t, err)
+		assert.Equal(t, bundle, b)
+
+		_, err = source.Manifest(ctx, "1.10.0", "crd.yaml")
+		assert.ErrorIs(t, err, ErrManifestChecksum)
+
+		_, err = source.Manifest(ctx, "1.10.0", "rbac.yaml")
+		assert.ErrorIs(t, err, ErrManifestChecksum)
+
+		_, err = source.Manifest(ctx, "1.11.0", "bundle.yaml")
+		assert.ErrorIs(t, err, ErrManifestNotFound)
+
+		source, err = NewManifestSource(dir, EnginePSMDB, "", false)
+		require.NoError(t, err)
+		_, err = source.Manifest(ctx, "1.10.0", "bundle.yaml")
+		assert.ErrorIs(t, err, ErrManifestNotFound)
+	})
+
+	t.Run("Tarball", func(t *testing.T) {
+		t.Parallel()
+		archive := filepath.Join(t.TempDir(), "manifests.tar.gz")
+		require.NoError(t, ioutil.WriteFile(archive, tarGz(t, map[string][]byte{
+			"./psmdb/1.11.0/bundle.yaml": bundle,
+		}), 0o600))
+
+		source, err := NewManifestSource(archive, EnginePSMDB, "", false)
+		require.NoError(t, err)
+		b, err := source.Manifest(ctx, "1.11.0", "bundle.yaml")
+		require.NoError(t, err)
+		assert.Equal(t, bundle, b)
+
+		source, err = NewManifestSource(archive, EnginePSMDB, "", true)
+		require.NoError(t, err)
+		_, err = source.Manifest(ctx, "1.11.0", "bundle.yaml")
+		assert.ErrorIs(t, err, ErrManifestChecksum)
+	})
+
+	t.Run("OCI", func(t *testing.T) {
+		t.Parallel()
+		layer := tarGz(t, map[string][]byte{
+			"pxc/1.10.0/bundle.yaml":      bundle,
+			"pxc/1.10.0/" + checksumsFile: checksums,
+		})
+		manifest := []byte(fmt.Sprintf(`{"schemaVersion": 2, "layers": [{"digest": "sha256:%s"}]}`, sha256Hex(layer)))
+		index := []byte(fmt.Sprintf(`{"schemaVersion": 2, "manifests": [{"digest": "sha256:%s"}]}`, sha256Hex(manifest)))
+		archive := filepath.Join(t.TempDir(), "manifests.tar")
+		require.NoError(t, ioutil.WriteFile(archive, tarGz(t, map[string][]byte{
+			"oci-layout":                          []byte(`{"imageLayoutVersion": "1.0.0"}`),
+			"index.json":                          index,
+			"blobs/sha256/" + sha256Hex(manifest): manifest,
+			"blobs/sha256/" + sha256Hex(layer):    layer,
+		}), 0o600))
+
+		source, err := NewManifestSource(archive, EnginePXC, "", true)
+		require.NoError(t, err)
+		b, err := source.Manifest(ctx, "1.10.0", "bundle.yaml")
+		require.NoError(t, err)
+		assert.Equal(t, bundle, b)
+	})
+
+	t.Run("Embedded", func(t *testing.T) {
+		t.Parallel()
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path != "/1.10.0/bundle.yaml" {
+				http.NotFound(w, r)
+				return
+			}
+			w.Write(bundle) //nolint:errcheck
+		}))
+		defer server.Close()
+
+		source, err := NewManifestSource(EmbeddedManifests, EnginePXC, server.URL+"/%s/%s", false)
+		require.NoError(t, err)
+		b, err := source.Manifest(ctx, "1.10.0", "bundle.yaml")
+		require.NoError(t, err, "versions which are not embedded should be fetched")
+		assert.Equal(t, bundle, b)
+		_, err = source.Manifest(ctx, "0.0.0", "bundle.yaml")
+		assert.ErrorIs(t, err, ErrManifestNotFound)
+
+		source, err = NewManifestSource(EmbeddedManifests, EnginePSMDB, "", true)
+		require.NoError(t, err)
+		for _, file := range []string{"bundle.yaml", "crd.yaml", "rbac.yaml"} {
+			b, err := source.Manifest(ctx, "1.9.0", file)
+			require.NoError(t, err, file)
+			assert.Contains(t, string(b), "psmdb.percona.com", file)
+		}
+	})
+}
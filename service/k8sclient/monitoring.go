@@ -0,0 +1,329 @@
+// dbaas-controller
+// Copyright (C) 2020 Percona LLC
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package k8sclient
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	dbaascontroller "github.com/percona-platform/dbaas-controller"
+	"github.com/percona-platform/dbaas-controller/service/k8sclient/common"
+	"github.com/percona-platform/dbaas-controller/service/k8sclient/internal/kubectl"
+	"github.com/percona-platform/dbaas-controller/service/k8sclient/internal/monitoring"
+)
+
+const (
+	vmAgentKind          = "VMAgent"
+	vmAgentNamePrefix    = "pmm-vmagent-"
+	vmSecretNamePrefix   = "vm-operator-"
+	vmOperatorNamespace  = "monitoring-system"
+	vmOperatorDeployment = "vm-operator"
+	// vmRemoteWritePath is the path of PMM server VictoriaMetrics remote write endpoint.
+	vmRemoteWritePath = "/victoriametrics/api/v1/write"
+)
+
+// vmOperatorFiles returns VictoriaMetrics manifests in order of installation.
+func vmOperatorFiles() []string {
+	return []string{
+		"deploy/victoriametrics/crds/crd.yaml",
+		"deploy/victoriametrics/operator/manager.yaml",
+		"deploy/victoriametrics/operator/rbac.yaml",
+		"deploy/victoriametrics/crs/vmagent_rbac.yaml",
+		"deploy/victoriametrics/crs/vmnodescrape.yaml",
+		"deploy/victoriametrics/crs/vmpodscrape.yaml",
+	}
+}
+
+// VMAgentStatus describes VictoriaMetrics agent sending metrics to PMM server.
+type VMAgentStatus struct {
+	Name string `json:"name"`
+	// PMMAddress is the address of PMM server metrics are sent to.
+	PMMAddress        string `json:"pmmAddress"`
+	Replicas          int32  `json:"replicas"`
+	AvailableReplicas int32  `json:"availableReplicas"`
+}
+
+// MonitoringStatus describes VictoriaMetrics monitoring of Kubernetes cluster.
+type MonitoringStatus struct {
+	// OperatorInstalled is true if VictoriaMetrics operator deployment exists.
+	OperatorInstalled bool `json:"operatorInstalled"`
+	// OperatorReady is true if all VictoriaMetrics operator pods are ready.
+	OperatorReady bool            `json:"operatorReady"`
+	Agents        []VMAgentStatus `json:"agents"`
+}
+
+// vmSecretName returns name of the secret with credentials of PMM server.
+// It's derived from PMM server address, so monitoring for the same PMM server is installed once.
+func vmSecretName(pmmAddress string) string {
+	address := strings.TrimRight(strings.ToLower(strings.TrimSpace(pmmAddress)), "/")
+	sum := sha256.Sum256([]byte(address))
+	return vmSecretNamePrefix + hex.EncodeToString(sum[:8])
+}
+
+// CreateVMOperator installs VictoriaMetrics operator and agent sending metrics to PMM server.
+// It's idempotent: installing it for the same PMM server again updates existing agent,
+// installing it for another PMM server replaces existing agent.
+func (c *K8sClient) CreateVMOperator(ctx context.Context, params *PMM) error {
+	for _, path := range vmOperatorFiles() {
+		file, err := dbaascontroller.DeployDir.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		err = c.kubeCtl.Apply(ctx, file)
+		if err != nil {
+			return errors.Wrapf(err, "cannot apply file: %q", path)
+		}
+	}
+	return c.applyVMAgent(ctx, params)
+}
+
+// UpdateVMOperator updates address, credentials and resources of the agent sending metrics to PMM server.
+// It returns ErrNotFound if monitoring is not installed.
+func (c *K8sClient) UpdateVMOperator(ctx context.Context, params *PMM) error {
+	agents, err := c.getVMAgents(ctx)
+	if err != nil {
+		return err
+	}
+	if len(agents) == 0 {
+		return errors.Wrap(ErrNotFound, "monitoring is not installed")
+	}
+	return c.applyVMAgent(ctx, params)
+}
+
+// applyVMAgent creates or updates the agent and its secret for PMM server and removes agents of other PMM servers.
+func (c *K8sClient) applyVMAgent(ctx context.Context, params *PMM) error {
+	secretName := vmSecretName(params.PublicAddress)
+	err := c.CreateSecret(ctx, secretName, map[string][]byte{
+		"username": []byte(params.Login),
+		"password": []byte(params.Password),
+	})
+	if err != nil {
+		return err
+	}
+
+	vmagent := vmAgentSpec(params, secretName)
+	if err := c.kubeCtl.Apply(ctx, vmagent); err != nil {
+		return err
+	}
+
+	agents, err := c.getVMAgents(ctx)
+	if err != nil {
+		return err
+	}
+	for i := range agents {
+		if agents[i].Name == vmagent.Name {
+			continue
+		}
+		if err := c.deleteVMAgent(ctx, &agents[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// getVMAgents returns agents sending metrics to PMM server, including the ones created by previous versions.
+func (c *K8sClient) getVMAgents(ctx context.Context) ([]monitoring.VMAgent, error) {
+	var list monitoring.VMAgentList
+	err := c.kubeCtl.Get(ctx, vmAgentKind, "", &list)
+	if err != nil {
+		// VMAgent CRD is missing if VictoriaMetrics operator was never installed.
+		if errors.Is(err, kubectl.ErrNotFound) || strings.Contains(err.Error(), "the server doesn't have a resource type") {
+			return nil, nil
+		}
+		return nil, errors.Wrap(err, "couldn't get VictoriaMetrics agents")
+	}
+	var agents []monitoring.VMAgent
+	for _, agent := range list.Items {
+		if strings.HasPrefix(agent.Name, vmAgentNamePrefix) {
+			agents = append(agents, agent)
+		}
+	}
+	return agents, nil
+}
+
+// deleteVMAgent deletes the agent and secrets it uses.
+func (c *K8sClient) deleteVMAgent(ctx context.Context, agent *monitoring.VMAgent) error {
+	c.l.Infof("Removing VictoriaMetrics agent %s", agent.Name)
+	_, err := c.kubeCtl.Run(ctx, []string{"delete", vmAgentKind, agent.Name, "--ignore-not-found"}, nil)
+	if err != nil {
+		return errors.Wrapf(err, "failed to delete VictoriaMetrics agent %s", agent.Name)
+	}
+	secrets := make(map[string]struct{})
+	for _, remoteWrite := range agent.Spec.RemoteWrite {
+		if remoteWrite.BasicAuth == nil {
+			continue
+		}
+		secrets[remoteWrite.BasicAuth.Username.Name] = struct{}{}
+		secrets[remoteWrite.BasicAuth.Password.Name] = struct{}{}
+	}
+	for name := range secrets {
+		if !strings.HasPrefix(name, vmSecretNamePrefix) {
+			continue
+		}
+		_, err := c.kubeCtl.Run(ctx, []string{"delete", "secret", name, "--ignore-not-found"}, nil)
+		if err != nil {
+			return errors.Wrapf(err, "failed to delete secret %s", name)
+		}
+	}
+	return nil
+}
+
+// RemoveVMOperator removes agents sending metrics to PMM server, their secrets, scrapes and VictoriaMetrics operator.
+// CRDs are kept as removing them would remove all VictoriaMetrics resources in the cluster.
+func (c *K8sClient) RemoveVMOperator(ctx context.Context) error {
+	agents, err := c.getVMAgents(ctx)
+	if err != nil {
+		return err
+	}
+	for i := range agents {
+		if err := c.deleteVMAgent(ctx, &agents[i]); err != nil {
+			return err
+		}
+	}
+
+	// Files are deleted in reverse order, CRDs are the first file and are kept.
+	files := vmOperatorFiles()
+	for i := len(files) - 1; i > 0; i-- {
+		file, err := dbaascontroller.DeployDir.ReadFile(files[i])
+		if err != nil {
+			return err
+		}
+		_, err = c.kubeCtl.Run(ctx, []string{"delete", "--ignore-not-found", "-f", "-"}, file)
+		if err != nil {
+			return errors.Wrapf(err, "cannot delete resources of file: %q", files[i])
+		}
+	}
+	return nil
+}
+
+// GetMonitoringStatus returns status of VictoriaMetrics operator and agents sending metrics to PMM server.
+func (c *K8sClient) GetMonitoringStatus(ctx context.Context) (*MonitoringStatus, error) {
+	status := new(MonitoringStatus)
+	out, err := c.kubeCtl.Run(ctx, []string{"get", "deployment", vmOperatorDeployment, "-n", vmOperatorNamespace, "-ojson"}, nil)
+	switch {
+	case errors.Is(err, kubectl.ErrNotFound):
+	case err != nil:
+		return nil, errors.Wrap(err, "couldn't get VictoriaMetrics operator deployment")
+	default:
+		var deployment common.Deployment
+		if err := json.Unmarshal(out, &deployment); err != nil {
+			return nil, errors.Wrap(err, "couldn't get VictoriaMetrics operator deployment")
+		}
+		status.OperatorInstalled = true
+		replicas := int32(1)
+		if deployment.Spec.Replicas != nil {
+			replicas = *deployment.Spec.Replicas
+		}
+		status.OperatorReady = deployment.Status != nil && deployment.Status.ReadyReplicas >= replicas
+	}
+
+	agents, err := c.getVMAgents(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, agent := range agents {
+		agentStatus := VMAgentStatus{Name: agent.Name, Replicas: int32(agent.Spec.ReplicaCount)}
+		if len(agent.Spec.RemoteWrite) != 0 {
+			agentStatus.PMMAddress = strings.TrimSuffix(agent.Spec.RemoteWrite[0].URL, vmRemoteWritePath)
+		}
+		if agent.Status != nil {
+			agentStatus.AvailableReplicas = agent.Status.AvailableReplicas
+		}
+		status.Agents = append(status.Agents, agentStatus)
+	}
+	return status, nil
+}
+
+func vmAgentSpec(params *PMM, secretName string) monitoring.VMAgent {
+	resources := params.Resources
+	if resources == nil {
+		resources = &common.PodResources{
+			Requests: &common.ResourcesList{
+				CPU:    "250m",
+				Memory: "350Mi",
+			},
+			Limits: &common.ResourcesList{
+				CPU:    "500m",
+				Memory: "850Mi",
+			},
+		}
+	}
+	var tlsConfig *monitoring.TLSConfig
+	if !params.TLSVerify {
+		tlsConfig = &monitoring.TLSConfig{InsecureSkipVerify: true}
+	}
+	var scrapeInterval string
+	if params.ScrapeInterval > 0 {
+		seconds := int64(params.ScrapeInterval / time.Second)
+		if seconds == 0 {
+			seconds = 1
+		}
+		scrapeInterval = fmt.Sprintf("%ds", seconds)
+	}
+	return monitoring.VMAgent{
+		TypeMeta: common.TypeMeta{
+			Kind:       vmAgentKind,
+			APIVersion: "operator.victoriametrics.com/v1beta1",
+		},
+		ObjectMeta: common.ObjectMeta{
+			Name: vmAgentNamePrefix + secretName,
+		},
+		Spec: monitoring.VMAgentSpec{
+			ServiceScrapeNamespaceSelector: new(common.LabelSelector),
+			ServiceScrapeSelector:          new(common.LabelSelector),
+			PodScrapeNamespaceSelector:     new(common.LabelSelector),
+			PodScrapeSelector:              new(common.LabelSelector),
+			ProbeSelector:                  new(common.LabelSelector),
+			ProbeNamespaceSelector:         new(common.LabelSelector),
+			StaticScrapeSelector:           new(common.LabelSelector),
+			StaticScrapeNamespaceSelector:  new(common.LabelSelector),
+			ReplicaCount:                   1,
+			ScrapeInterval:                 scrapeInterval,
+			Resources:                      resources,
+			ExtraArgs: map[string]string{
+				"memory.allowedPercent": "40",
+			},
+			RemoteWrite: []monitoring.VMAgentRemoteWriteSpec{
+				{
+					URL:       strings.TrimRight(params.PublicAddress, "/") + vmRemoteWritePath,
+					TLSConfig: tlsConfig,
+					BasicAuth: &monitoring.BasicAuth{
+						Username: common.SecretKeySelector{
+							LocalObjectReference: common.LocalObjectReference{
+								Name: secretName,
+							},
+							Key: "username",
+						},
+						Password: common.SecretKeySelector{
+							LocalObjectReference: common.LocalObjectReference{
+								Name: secretName,
+							},
+							Key: "password",
+						},
+					},
+				},
+			},
+		},
+	}
+}
@@ -0,0 +1,59 @@
+// dbaas-controller
+// Copyright (C) 2020 Percona LLC
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package k8sclient
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/percona-platform/dbaas-controller/service/k8sclient/common"
+)
+
+func TestVMSecretName(t *testing.T) {
+	t.Parallel()
+	name := vmSecretName("https://pmm.example.com")
+	assert.Regexp(t, `^vm-operator-[0-9a-f]{16}$`, name)
+	assert.Equal(t, name, vmSecretName("https://PMM.example.com/"))
+	assert.NotEqual(t, name, vmSecretName("https://pmm2.example.com"))
+}
+
+func TestVMAgentSpecResources(t *testing.T) {
+	t.Parallel()
+	resources := &common.PodResources{
+		Requests: &common.ResourcesList{CPU: "100m", Memory: "200Mi"},
+	}
+	spec := vmAgentSpec(&PMM{PublicAddress: "https://pmm.example.com", Resources: resources}, "vm-operator-secret")
+	assert.Equal(t, "pmm-vmagent-vm-operator-secret", spec.Name)
+	assert.Equal(t, resources, spec.Spec.Resources)
+	assert.Equal(t, "https://pmm.example.com/victoriametrics/api/v1/write", spec.Spec.RemoteWrite[0].URL)
+}
+
+func TestVMAgentSpecTLSAndScrapeInterval(t *testing.T) {
+	t.Parallel()
+	spec := vmAgentSpec(&PMM{PublicAddress: "https://pmm.example.com"}, "vm-operator-secret")
+	assert.True(t, spec.Spec.RemoteWrite[0].TLSConfig.InsecureSkipVerify)
+	assert.Empty(t, spec.Spec.ScrapeInterval)
+
+	spec = vmAgentSpec(&PMM{PublicAddress: "https://pmm.example.com", TLSVerify: true, ScrapeInterval: time.Minute}, "vm-operator-secret")
+	assert.Nil(t, spec.Spec.RemoteWrite[0].TLSConfig)
+	assert.Equal(t, "60s", spec.Spec.ScrapeInterval)
+
+	spec = vmAgentSpec(&PMM{PublicAddress: "https://pmm.example.com", ScrapeInterval: 100 * time.Millisecond}, "vm-operator-secret")
+	assert.Equal(t, "1s", spec.Spec.ScrapeInterval)
+}
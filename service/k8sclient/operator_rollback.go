@@ -0,0 +1,217 @@
+// dbaas-controller
+// Copyright (C) 2020 Percona LLC
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package k8sclient
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/percona-platform/dbaas-controller/service/k8sclient/common"
+	"github.com/percona-platform/dbaas-controller/service/k8sclient/internal/kubectl"
+	"github.com/percona-platform/dbaas-controller/service/k8sclient/internal/psmdb"
+	"github.com/percona-platform/dbaas-controller/service/k8sclient/internal/pxc"
+)
+
+const (
+	operatorSnapshotConfigMapTmpl = "dbaas-%s-operator-snapshot"
+	operatorHealthTimeout         = 5 * time.Minute
+)
+
+// ErrOperatorUnhealthy is returned when operator deployment does not become available after update or rollback.
+var ErrOperatorUnhealthy = errors.New("operator is not healthy")
+
+// OperatorSnapshot is a state of the operator and its database clusters taken before operator upgrade.
+// It's stored in Kubernetes cluster and used to roll the upgrade back.
+type OperatorSnapshot struct {
+	Operator   Engine             `json:"operator"`
+	Version    string             `json:"version"`
+	NewVersion string             `json:"newVersion"`
+	Deployment *common.Deployment `json:"deployment"`
+	// RBAC holds RBAC manifest of the previous version if it was available.
+	RBAC string `json:"rbac,omitempty"`
+	// Clusters holds merge patches restoring crVersion and images of database clusters.
+	Clusters  map[string]json.RawMessage `json:"clusters"`
+	CreatedAt time.Time                  `json:"createdAt"`
+}
+
+// pxcRestorePatch returns merge patch restoring crVersion and images of the cluster.
+func pxcRestorePatch(cluster *pxc.PerconaXtraDBCluster) map[string]interface{} {
+	return imagesPatch(cluster.Spec.CRVersion, pxcClusterImages(cluster), pxcImagePaths())
+}
+
+// psmdbRestorePatch returns merge patch restoring crVersion and images of the cluster.
+func psmdbRestorePatch(cluster *psmdb.PerconaServerMongoDB) map[string]interface{} {
+	return imagesPatch(cluster.Spec.CRVersion, psmdbClusterImages(cluster), psmdbImagePaths())
+}
+
+// clusterRestorePatches returns restore patches of all database clusters managed by the operator.
+func (c *K8sClient) clusterRestorePatches(ctx context.Context, operator Engine) (map[string]json.RawMessage, error) {
+	patches := make(map[string]map[string]interface{})
+	switch operator {
+	case EnginePXC:
+		var list pxc.PerconaXtraDBClusterList
+		if err := c.kubeCtl.Get(ctx, pxc.PerconaXtraDBClusterKind, "", &list); err != nil {
+			return nil, errors.Wrap(err, "couldn't get percona XtraDB clusters")
+		}
+		for i := range list.Items {
+			if list.Items[i].Spec != nil {
+				patches[list.Items[i].Name] = pxcRestorePatch(&list.Items[i])
+			}
+		}
+	case EnginePSMDB:
+		var list psmdb.PerconaServerMongoDBList
+		if err := c.kubeCtl.Get(ctx, psmdb.PerconaServerMongoDBKind, "", &list); err != nil {
+			return nil, errors.Wrap(err, "couldn't get percona server MongoDB clusters")
+		}
+		for i := range list.Items {
+			if list.Items[i].Spec != nil {
+				patches[list.Items[i].Name] = psmdbRestorePatch(&list.Items[i])
+			}
+		}
+	default:
+		return nil, errors.Errorf("unknown operator %q", operator)
+	}
+
+	res := make(map[string]json.RawMessage, len(patches))
+	for name, patch := range patches {
+		b, err := json.Marshal(patch)
+		if err != nil {
+			return nil, err
+		}
+		res[name] = b
+	}
+	return res, nil
+}
+
+// snapshotOperator stores state of the operator deployment and its clusters before upgrade to newVersion.
+// Snapshot of the same upgrade is kept, so retried upgrade can still be rolled back to the original version.
+func (c *K8sClient) snapshotOperator(
+	ctx context.Context, operator Engine, deployment *common.Deployment, version, newVersion string, manifests ManifestSource,
+) error {
+	previous, err := c.GetOperatorSnapshot(ctx, operator)
+	switch {
+	case err == nil && previous.NewVersion == newVersion && previous.Version != newVersion:
+		c.l.Infof("Keeping snapshot of %s operator %s taken before upgrade to %s", operator, previous.Version, newVersion)
+		return nil
+	case err != nil && !errors.Is(err, ErrNotFound):
+		return err
+	}
+
+	clusters, err := c.clusterRestorePatches(ctx, operator)
+	if err != nil {
+		return err
+	}
+	snapshot := &OperatorSnapshot{
+		Operator:   operator,
+		Version:    version,
+		NewVersion: newVersion,
+		Deployment: &common.Deployment{TypeMeta: deployment.TypeMeta, ObjectMeta: deployment.ObjectMeta, Spec: deployment.Spec},
+		Clusters:   clusters,
+		CreatedAt:  time.Now(),
+	}
+	rbac, err := manifests.Manifest(ctx, version, "rbac.yaml")
+	if err != nil {
+		c.l.Warnf("RBAC of %s operator %s is not available for rollback: %s", operator, version, err)
+	} else {
+		snapshot.RBAC = string(rbac)
+	}
+	err = c.saveConfigMapJSON(ctx, fmt.Sprintf(operatorSnapshotConfigMapTmpl, operator), snapshot)
+	return errors.Wrap(err, "failed to save operator snapshot")
+}
+
+// GetOperatorSnapshot returns snapshot taken before the last operator upgrade.
+// It returns ErrNotFound if there is no snapshot.
+func (c *K8sClient) GetOperatorSnapshot(ctx context.Context, operator Engine) (*OperatorSnapshot, error) {
+	var snapshot OperatorSnapshot
+	if err := c.getConfigMapJSON(ctx, fmt.Sprintf(operatorSnapshotConfigMapTmpl, operator), &snapshot); err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return nil, errors.Wrapf(ErrNotFound, "no snapshot of %s operator", operator)
+		}
+		return nil, errors.Wrap(err, "failed to get operator snapshot")
+	}
+	return &snapshot, nil
+}
+
+// waitOperatorHealthy waits until the new operator pod of the deployment is rolled out and available.
+func (c *K8sClient) waitOperatorHealthy(ctx context.Context, deploymentName string) error {
+	args := []string{"rollout", "status", "deployment/" + deploymentName, "--timeout", operatorHealthTimeout.String()}
+	if _, err := c.kubeCtl.Run(ctx, args, nil); err != nil {
+		return errors.Wrapf(ErrOperatorUnhealthy, "deployment %s: %s", deploymentName, err)
+	}
+	return nil
+}
+
+// deleteOperatorSnapshot removes snapshot of the operator.
+func (c *K8sClient) deleteOperatorSnapshot(ctx context.Context, operator Engine) error {
+	args := []string{"delete", "configmap", fmt.Sprintf(operatorSnapshotConfigMapTmpl, operator), "--ignore-not-found"}
+	_, err := c.kubeCtl.Run(ctx, args, nil)
+	return errors.Wrap(err, "failed to remove operator snapshot")
+}
+
+// RollbackOperator restores operator deployment, RBAC and versions of database clusters
+// from the snapshot taken before the last upgrade. CRDs are kept as they are backward compatible.
+// Rollback is refused with ErrRolloutInProgress while clusters are patched after the upgrade.
+// The snapshot is removed after successful rollback or rollout, so only unfinished upgrade could be rolled back.
+func (c *K8sClient) RollbackOperator(ctx context.Context, operator Engine, deploymentName string) (*OperatorSnapshot, error) {
+	if err := c.CheckNoRolloutInProgress(ctx, operator); err != nil {
+		return nil, err
+	}
+	snapshot, err := c.GetOperatorSnapshot(ctx, operator)
+	if err != nil {
+		return nil, err
+	}
+	c.l.Infof("Rolling %s operator back from %s to %s", operator, snapshot.NewVersion, snapshot.Version)
+
+	if snapshot.RBAC != "" {
+		if err := c.kubeCtl.Apply(ctx, []byte(snapshot.RBAC)); err != nil {
+			return nil, errors.Wrap(err, "failed to restore operator RBAC")
+		}
+	}
+	if snapshot.Deployment == nil {
+		return nil, errors.Errorf("snapshot of %s operator does not contain deployment", operator)
+	}
+	err = c.kubeCtl.Patch(ctx, kubectl.PatchTypeStrategic, "deployment", deploymentName, snapshot.Deployment)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to restore operator deployment")
+	}
+	if err := c.waitOperatorHealthy(ctx, deploymentName); err != nil {
+		return nil, err
+	}
+
+	kind := pxc.PerconaXtraDBClusterKind
+	if operator == EnginePSMDB {
+		kind = psmdb.PerconaServerMongoDBKind
+	}
+	for name, patch := range snapshot.Clusters {
+		err := c.kubeCtl.Patch(ctx, kubectl.PatchTypeMerge, kind, name, patch)
+		switch {
+		case errors.Is(err, kubectl.ErrNotFound):
+			c.l.Infof("Cluster %s was deleted after upgrade, skipping it", name)
+		case err != nil:
+			return nil, errors.Wrapf(err, "failed to restore cluster %s", name)
+		}
+	}
+
+	if err := c.deleteOperatorSnapshot(ctx, operator); err != nil {
+		return nil, err
+	}
+	return snapshot, nil
+}
@@ -0,0 +1,91 @@
+// dbaas-controller
+// Copyright (C) 2020 Percona LLC
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package k8sclient
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/percona-platform/dbaas-controller/service/k8sclient/internal/psmdb"
+	"github.com/percona-platform/dbaas-controller/service/k8sclient/internal/pxc"
+)
+
+func TestRestorePatches(t *testing.T) {
+	t.Parallel()
+
+	t.Run("PXC with HAProxy", func(t *testing.T) {
+		t.Parallel()
+		cluster := &pxc.PerconaXtraDBCluster{
+			Spec: &pxc.PerconaXtraDBClusterSpec{
+				CRVersion: "1.7.0",
+				PXC:       &pxc.PodSpec{Image: "percona/percona-xtradb-cluster:8.0.20-11.1"},
+				HAProxy:   &pxc.PodSpec{Image: "percona/percona-xtradb-cluster-operator:1.7.0-haproxy"},
+				Backup:    &pxc.PXCScheduledBackup{Image: "percona/percona-xtradb-cluster-operator:1.7.0-pxc8.0-backup"},
+			},
+		}
+		b, err := json.Marshal(pxcRestorePatch(cluster))
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"spec": {
+			"crVersion": "1.7.0",
+			"pxc": {"image": "percona/percona-xtradb-cluster:8.0.20-11.1"},
+			"haproxy": {"image": "percona/percona-xtradb-cluster-operator:1.7.0-haproxy"},
+			"backup": {"image": "percona/percona-xtradb-cluster-operator:1.7.0-pxc8.0-backup"}
+		}}`, string(b))
+	})
+
+	t.Run("PXC with ProxySQL", func(t *testing.T) {
+		t.Parallel()
+		cluster := &pxc.PerconaXtraDBCluster{
+			Spec: &pxc.PerconaXtraDBClusterSpec{
+				CRVersion: "1.7.0",
+				PXC:       &pxc.PodSpec{Image: "percona/percona-xtradb-cluster:8.0.20-11.1"},
+				ProxySQL:  &pxc.PodSpec{Image: "percona/percona-xtradb-cluster-operator:1.7.0-proxysql"},
+			},
+		}
+		b, err := json.Marshal(pxcRestorePatch(cluster))
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"spec": {
+			"crVersion": "1.7.0",
+			"pxc": {"image": "percona/percona-xtradb-cluster:8.0.20-11.1"},
+			"proxysql": {"image": "percona/percona-xtradb-cluster-operator:1.7.0-proxysql"}
+		}}`, string(b))
+	})
+
+	t.Run("PSMDB", func(t *testing.T) {
+		t.Parallel()
+		cluster := &psmdb.PerconaServerMongoDB{
+			Spec: &psmdb.PerconaServerMongoDBSpec{
+				CRVersion: "1.8.0",
+				Image:     "percona/percona-server-mongodb:4.4.5-7",
+				Backup: &psmdb.BackupSpec{
+					Enabled: true,
+					Image:   "percona/percona-server-mongodb-operator:1.8.0-backup",
+				},
+			},
+		}
+		b, err := json.Marshal(psmdbRestorePatch(cluster))
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"spec": {
+			"crVersion": "1.8.0",
+			"image": "percona/percona-server-mongodb:4.4.5-7",
+			"backup": {"image": "percona/percona-server-mongodb-operator:1.8.0-backup"}
+		}}`, string(b))
+	})
+}
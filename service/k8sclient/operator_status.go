@@ -0,0 +1,204 @@
+// dbaas-controller
+// Copyright (C) 2020 Percona LLC
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package k8sclient
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	goversion "github.com/hashicorp/go-version"
+	"github.com/pkg/errors"
+
+	"github.com/percona-platform/dbaas-controller/service/k8sclient/common"
+	"github.com/percona-platform/dbaas-controller/service/k8sclient/internal/kubectl"
+)
+
+const (
+	// PXCOperatorDeploymentName is the name of PXC operator deployment and its container.
+	PXCOperatorDeploymentName = "percona-xtradb-cluster-operator"
+	// PSMDBOperatorDeploymentName is the name of PSMDB operator deployment and its container.
+	PSMDBOperatorDeploymentName = "percona-server-mongodb-operator"
+
+	watchNamespaceEnv = "WATCH_NAMESPACE"
+)
+
+// OperatorStatus describes real state of the operator in Kubernetes cluster.
+type OperatorStatus struct {
+	Operator Engine `json:"operator"`
+	// CRDVersions lists operator versions with API installed, from the oldest to the newest.
+	CRDVersions []string `json:"crdVersions"`
+	// DeploymentFound is false if there is no operator deployment.
+	DeploymentFound bool `json:"deploymentFound"`
+	// Replicas is the number of desired operator pods.
+	Replicas int32 `json:"replicas"`
+	// ReadyReplicas is the number of ready operator pods.
+	ReadyReplicas int32 `json:"readyReplicas"`
+	// Image is the image of the operator container.
+	Image string `json:"image,omitempty"`
+	// Version is the operator version from the image tag.
+	Version string `json:"version,omitempty"`
+	// TotalRestarts is the number of restarts of operator containers over the lifetime of current pods,
+	// it is not reset when a restarted container recovers.
+	TotalRestarts int32 `json:"totalRestarts"`
+	// WatchedNamespaces lists namespaces watched by the operator, empty if it watches all namespaces.
+	WatchedNamespaces []string `json:"watchedNamespaces,omitempty"`
+}
+
+// Installed returns true if both operator API and deployment exist.
+func (s *OperatorStatus) Installed() bool {
+	return s.DeploymentFound && len(s.CRDVersions) != 0
+}
+
+// Healthy returns true if the operator is installed and all its pods are ready.
+func (s *OperatorStatus) Healthy() bool {
+	return s.Installed() && s.Replicas > 0 && s.ReadyReplicas >= s.Replicas
+}
+
+// operatorAPIVersions returns operator versions with API installed sorted from the oldest to the newest.
+func operatorAPIVersions(apiVersions []string, apiPrefix string) []string {
+	var versions []*goversion.Version
+	for _, apiVersion := range apiVersions {
+		parts := strings.Split(strings.TrimSpace(apiVersion), "/")
+		if len(parts) != 2 || parts[0] != apiPrefix {
+			continue
+		}
+		versionParts := strings.Split(strings.TrimPrefix(parts[1], "v"), "-")
+		if len(versionParts) != 3 {
+			continue
+		}
+		v, err := goversion.NewVersion(strings.Join(versionParts, "."))
+		if err != nil {
+			continue
+		}
+		versions = append(versions, v)
+	}
+	sort.Sort(goversion.Collection(versions))
+	res := make([]string, len(versions))
+	for i, v := range versions {
+		res[i] = v.String()
+	}
+	return res
+}
+
+// imageTag returns tag of the image or empty string if it has none.
+func imageTag(image string) string {
+	i := strings.LastIndex(image, ":")
+	if i < 0 || strings.Contains(image[i:], "/") {
+		return ""
+	}
+	return image[i+1:]
+}
+
+// watchedNamespaces returns namespaces watched by the operator container of the deployment.
+func watchedNamespaces(deployment *common.Deployment, container *common.ContainerSpec) []string {
+	for _, env := range container.Env {
+		if env.Name != watchNamespaceEnv {
+			continue
+		}
+		if env.ValueFrom != nil && env.ValueFrom.FieldRef != nil && env.ValueFrom.FieldRef.FieldPath == "metadata.namespace" {
+			namespace := deployment.Namespace
+			if namespace == "" {
+				namespace = "default"
+			}
+			return []string{namespace}
+		}
+		var namespaces []string
+		for _, namespace := range strings.Split(env.Value, ",") {
+			if namespace = strings.TrimSpace(namespace); namespace != "" {
+				namespaces = append(namespaces, namespace)
+			}
+		}
+		return namespaces
+	}
+	return nil
+}
+
+// operatorDeploymentName returns name of the operator deployment.
+func operatorDeploymentName(operator Engine) (string, error) {
+	switch operator {
+	case EnginePXC:
+		return PXCOperatorDeploymentName, nil
+	case EnginePSMDB:
+		return PSMDBOperatorDeploymentName, nil
+	default:
+		return "", errors.Errorf("unknown operator %q", operator)
+	}
+}
+
+// GetOperatorStatus returns status of the operator based on its API, deployment and pods.
+func (c *K8sClient) GetOperatorStatus(ctx context.Context, operator Engine) (*OperatorStatus, error) {
+	deploymentName, err := operatorDeploymentName(operator)
+	if err != nil {
+		return nil, err
+	}
+	apiPrefix := pxcAPINamespace
+	if operator == EnginePSMDB {
+		apiPrefix = psmdbAPINamespace
+	}
+	output, err := c.kubeCtl.Run(ctx, []string{"api-versions"}, "")
+	if err != nil {
+		return nil, errors.Wrap(err, "can't get api versions list")
+	}
+	status := &OperatorStatus{
+		Operator:    operator,
+		CRDVersions: operatorAPIVersions(strings.Split(string(output), "\n"), apiPrefix),
+	}
+
+	var deployment common.Deployment
+	err = c.kubeCtl.Get(ctx, "deployment", deploymentName, &deployment)
+	if errors.Is(err, kubectl.ErrNotFound) {
+		return status, nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get operator deployment")
+	}
+	status.DeploymentFound = true
+	status.Replicas = 1
+	if deployment.Spec.Replicas != nil {
+		status.Replicas = *deployment.Spec.Replicas
+	}
+	if deployment.Status != nil {
+		status.ReadyReplicas = deployment.Status.ReadyReplicas
+	}
+	for i, container := range deployment.Spec.Template.Spec.Containers {
+		if container.Name == deploymentName {
+			status.Image = container.Image
+			status.Version = imageTag(container.Image)
+			status.WatchedNamespaces = watchedNamespaces(&deployment, &deployment.Spec.Template.Spec.Containers[i])
+		}
+	}
+
+	selector := make([]string, 0, len(deployment.Spec.Selector.MatchLabels))
+	for k, v := range deployment.Spec.Selector.MatchLabels {
+		selector = append(selector, k+"="+v)
+	}
+	sort.Strings(selector)
+	if len(selector) == 0 {
+		return status, nil
+	}
+	pods, err := c.GetPods(ctx, "-l", strings.Join(selector, ","))
+	if err != nil {
+		return nil, err
+	}
+	for _, pod := range pods.Items {
+		for _, container := range pod.Status.ContainerStatuses {
+			status.TotalRestarts += container.RestartCount
+		}
+	}
+	return status, nil
+}
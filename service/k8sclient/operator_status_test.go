@@ -0,0 +1,100 @@
+// dbaas-controller
+// Copyright (C) 2020 Percona LLC
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package k8sclient
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/percona-platform/dbaas-controller/service/k8sclient/common"
+)
+
+func TestOperatorAPIVersions(t *testing.T) {
+	t.Parallel()
+	apiVersions := []string{
+		"apps/v1",
+		"pxc.percona.com/v1",
+		"pxc.percona.com/v1-10-0",
+		"pxc.percona.com/v1-8-0",
+		"pxc.percona.com/v1-9-0",
+		"psmdb.percona.com/v1-11-0",
+		"pxc.percona.com/v1alpha1",
+		"",
+	}
+	assert.Equal(t, []string{"1.8.0", "1.9.0", "1.10.0"}, operatorAPIVersions(apiVersions, pxcAPINamespace))
+	assert.Equal(t, []string{"1.11.0"}, operatorAPIVersions(apiVersions, psmdbAPINamespace))
+	assert.Empty(t, operatorAPIVersions([]string{"apps/v1"}, pxcAPINamespace))
+}
+
+func TestImageTag(t *testing.T) {
+	t.Parallel()
+	assert.Equal(t, "1.8.0", imageTag("percona/percona-xtradb-cluster-operator:1.8.0"))
+	assert.Equal(t, "1.8.0", imageTag("registry:5000/percona/percona-xtradb-cluster-operator:1.8.0"))
+	assert.Equal(t, "", imageTag("registry:5000/percona/percona-xtradb-cluster-operator"))
+	assert.Equal(t, "", imageTag("percona/percona-xtradb-cluster-operator"))
+}
+
+func TestWatchedNamespaces(t *testing.T) {
+	t.Parallel()
+	deployment := &common.Deployment{ObjectMeta: common.ObjectMeta{Name: PXCOperatorDeploymentName, Namespace: "dbaas"}}
+
+	for _, tt := range []struct {
+		name     string
+		env      []common.EnvVar
+		expected []string
+	}{{
+		name:     "not set",
+		expected: nil,
+	}, {
+		name:     "all namespaces",
+		env:      []common.EnvVar{{Name: watchNamespaceEnv, Value: ""}},
+		expected: nil,
+	}, {
+		name:     "list",
+		env:      []common.EnvVar{{Name: "LOG_LEVEL", Value: "debug"}, {Name: watchNamespaceEnv, Value: "db1, db2"}},
+		expected: []string{"db1", "db2"},
+	}, {
+		name: "own namespace",
+		env: []common.EnvVar{{
+			Name:      watchNamespaceEnv,
+			ValueFrom: &common.EnvVarSource{FieldRef: &common.ObjectFieldSelector{FieldPath: "metadata.namespace"}},
+		}},
+		expected: []string{"dbaas"},
+	}} {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			container := &common.ContainerSpec{Name: PXCOperatorDeploymentName, Env: tt.env}
+			assert.Equal(t, tt.expected, watchedNamespaces(deployment, container))
+		})
+	}
+}
+
+func TestOperatorStatusHealthy(t *testing.T) {
+	t.Parallel()
+	leftover := &OperatorStatus{Operator: EnginePXC, CRDVersions: []string{"1.8.0"}}
+	assert.False(t, leftover.Installed())
+	assert.False(t, leftover.Healthy())
+
+	starting := &OperatorStatus{Operator: EnginePXC, CRDVersions: []string{"1.8.0"}, DeploymentFound: true, Replicas: 1}
+	assert.True(t, starting.Installed())
+	assert.False(t, starting.Healthy())
+
+	running := &OperatorStatus{Operator: EnginePXC, CRDVersions: []string{"1.8.0"}, DeploymentFound: true, Replicas: 1, ReadyReplicas: 1}
+	assert.True(t, running.Healthy())
+}
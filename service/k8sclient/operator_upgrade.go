@@ -0,0 +1,450 @@
+// dbaas-controller
+// Copyright (C) 2020 Percona LLC
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package k8sclient
+
+import (
+	"context"
+	_ "embed" // for go:embed
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	goversion "github.com/hashicorp/go-version"
+	"github.com/pkg/errors"
+
+	"github.com/percona-platform/dbaas-controller/service/k8sclient/internal/psmdb"
+	"github.com/percona-platform/dbaas-controller/service/k8sclient/internal/pxc"
+)
+
+//go:embed operator_compatibility.json
+var operatorCompatibilityJSON []byte //nolint:gochecknoglobals
+
+// defaultCompatibilityMatrix is operator compatibility matrix embedded into the binary.
+var defaultCompatibilityMatrix = mustParseCompatibilityMatrix(operatorCompatibilityJSON) //nolint:gochecknoglobals
+
+var (
+	// ErrUnknownOperatorVersion is returned when compatibility matrix does not know the operator version.
+	ErrUnknownOperatorVersion = errors.New("operator version is not in compatibility matrix")
+	// ErrUnsafeOperatorUpgrade is returned when operator upgrade would break database clusters or skip required versions.
+	ErrUnsafeOperatorUpgrade = errors.New("operator upgrade is not safe")
+	// ErrUnverifiedOperatorUpgrade is returned when operator upgrade can't be checked against compatibility matrix.
+	ErrUnverifiedOperatorUpgrade = errors.New("operator upgrade can't be verified")
+)
+
+// versionConstraint is a list of alternative version constraints, like ">= 5.7.26, < 5.8 || >= 8.0.18, < 8.1".
+type versionConstraint []goversion.Constraints
+
+// parseVersionConstraint parses alternative version constraints separated by "||".
+func parseVersionConstraint(s string) (versionConstraint, error) {
+	alternatives := strings.Split(s, "||")
+	res := make(versionConstraint, 0, len(alternatives))
+	for _, alternative := range alternatives {
+		c, err := goversion.NewConstraint(strings.TrimSpace(alternative))
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, c)
+	}
+	return res, nil
+}
+
+// Check returns true if the version satisfies any of alternative constraints.
+func (c versionConstraint) Check(v *goversion.Version) bool {
+	for _, alternative := range c {
+		if alternative.Check(v) {
+			return true
+		}
+	}
+	return false
+}
+
+// operatorRelease describes a release of an operator in compatibility matrix.
+type operatorRelease struct {
+	Version string `json:"version"`
+	// UpgradeFrom lists operator versions that can be upgraded to this release directly.
+	UpgradeFrom []string `json:"upgradeFrom,omitempty"`
+	// Database is a version constraint of database images supported by the release.
+	Database string `json:"database"`
+	// Images maps cluster components to version constraints of their images supported by the release.
+	// Images built with the operator are tagged with the operator version.
+	Images map[string]string `json:"images,omitempty"`
+
+	version  *goversion.Version
+	database versionConstraint
+	images   map[string]versionConstraint
+}
+
+// CompatibilityMatrix describes operator releases, supported database versions and upgrade paths.
+type CompatibilityMatrix struct {
+	// Version of the matrix, it's a date of the last update.
+	Version   string                       `json:"version"`
+	Operators map[Engine][]operatorRelease `json:"operators"`
+}
+
+// parseCompatibilityMatrix parses operator compatibility matrix.
+func parseCompatibilityMatrix(b []byte) (*CompatibilityMatrix, error) {
+	var m CompatibilityMatrix
+	if err := json.Unmarshal(b, &m); err != nil {
+		return nil, errors.Wrap(err, "failed to parse operator compatibility matrix")
+	}
+	for operator, releases := range m.Operators {
+		for i := range releases {
+			release := &releases[i]
+			var err error
+			if release.version, err = goversion.NewVersion(release.Version); err != nil {
+				return nil, errors.Wrapf(err, "invalid %s operator version %q", operator, release.Version)
+			}
+			if release.database, err = parseVersionConstraint(release.Database); err != nil {
+				return nil, errors.Wrapf(err, "invalid database constraint of %s operator %s", operator, release.Version)
+			}
+			release.images = make(map[string]versionConstraint, len(release.Images))
+			for component, constraint := range release.Images {
+				if release.images[component], err = parseVersionConstraint(constraint); err != nil {
+					return nil, errors.Wrapf(err, "invalid %s image constraint of %s operator %s", component, operator, release.Version)
+				}
+			}
+		}
+	}
+	return &m, nil
+}
+
+// mustParseCompatibilityMatrix is like parseCompatibilityMatrix but panics on error.
+func mustParseCompatibilityMatrix(b []byte) *CompatibilityMatrix {
+	m, err := parseCompatibilityMatrix(b)
+	if err != nil {
+		panic(err)
+	}
+	return m
+}
+
+// release returns release of the operator with given version.
+func (m *CompatibilityMatrix) release(operator Engine, version string) (*operatorRelease, error) {
+	// Custom builds may be tagged with anything, they are as unknown to the matrix as future releases.
+	v, err := goversion.NewVersion(version)
+	if err != nil {
+		return nil, errors.Wrapf(ErrUnknownOperatorVersion, "%s operator %q: %s", operator, version, err)
+	}
+	releases := m.Operators[operator]
+	for i := range releases {
+		if releases[i].version.Equal(v) {
+			return &releases[i], nil
+		}
+	}
+	return nil, errors.Wrapf(ErrUnknownOperatorVersion, "%s operator %s", operator, version)
+}
+
+// UpgradePath returns operator versions the operator has to be upgraded to one by one to get
+// from one version to another. The last version of the path is the target version.
+func (m *CompatibilityMatrix) UpgradePath(operator Engine, from, to string) ([]string, error) {
+	source, err := m.release(operator, from)
+	if err != nil {
+		return nil, err
+	}
+	target, err := m.release(operator, to)
+	if err != nil {
+		return nil, err
+	}
+	if source == target {
+		return nil, nil
+	}
+
+	// Breadth-first search finds the shortest path.
+	previous := map[*operatorRelease]*operatorRelease{source: nil}
+	queue := []*operatorRelease{source}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		if current == target {
+			var path []string
+			for r := target; r != source; r = previous[r] {
+				path = append([]string{r.Version}, path...)
+			}
+			return path, nil
+		}
+		releases := m.Operators[operator]
+		for i := range releases {
+			next := &releases[i]
+			if _, seen := previous[next]; seen {
+				continue
+			}
+			for _, from := range next.UpgradeFrom {
+				if v, err := goversion.NewVersion(from); err == nil && v.Equal(current.version) {
+					previous[next] = current
+					queue = append(queue, next)
+					break
+				}
+			}
+		}
+	}
+	return nil, errors.Wrapf(ErrUnsafeOperatorUpgrade, "%s operator can't be upgraded from %s to %s", operator, from, to)
+}
+
+// SupportsDatabase returns true if the operator version supports database image.
+func (m *CompatibilityMatrix) SupportsDatabase(operator Engine, operatorVersion, image string) (bool, error) {
+	release, err := m.release(operator, operatorVersion)
+	if err != nil {
+		return false, err
+	}
+	version, err := imageVersion(image)
+	if err != nil {
+		return false, err
+	}
+	return release.database.Check(version), nil
+}
+
+// SupportsImage returns true if the operator version supports image of the cluster component.
+// Images of components without constraints in compatibility matrix are supported.
+func (m *CompatibilityMatrix) SupportsImage(operator Engine, operatorVersion, component, image string) (bool, error) {
+	release, err := m.release(operator, operatorVersion)
+	if err != nil {
+		return false, err
+	}
+	constraint, ok := release.images[component]
+	if !ok {
+		return true, nil
+	}
+	version, err := imageVersion(image)
+	if err != nil {
+		return false, err
+	}
+	return constraint.Check(version), nil
+}
+
+// imageVersion returns version of the image from its tag, e.g. 8.0.20 for percona/percona-xtradb-cluster:8.0.20-11.1.
+func imageVersion(image string) (*goversion.Version, error) {
+	i := strings.LastIndex(image, ":")
+	if i < 0 || strings.Contains(image[i:], "/") {
+		return nil, errors.Errorf("image %q does not have any tag", image)
+	}
+	tag := image[i+1:]
+	if j := strings.Index(tag, "-"); j >= 0 {
+		tag = tag[:j]
+	}
+	version, err := goversion.NewVersion(tag)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get version of image %q", image)
+	}
+	return version, nil
+}
+
+// ImageChange describes image of cluster component changed by operator upgrade.
+type ImageChange struct {
+	Component string `json:"component"`
+	From      string `json:"from"`
+	To        string `json:"to"`
+}
+
+// ClusterUpgrade describes changes of a database cluster made after operator upgrade.
+type ClusterUpgrade struct {
+	Name      string        `json:"name"`
+	CRVersion string        `json:"crVersion"`
+	Images    []ImageChange `json:"images,omitempty"`
+	// Problems prevent the cluster from working with the new operator.
+	Problems []string `json:"problems,omitempty"`
+}
+
+// UpgradePlan describes operator upgrade and changes of database clusters made after it.
+type UpgradePlan struct {
+	Operator    Engine `json:"operator"`
+	FromVersion string `json:"fromVersion"`
+	ToVersion   string `json:"toVersion"`
+	// Path lists operator versions the operator has to be upgraded to one by one, the last one is ToVersion.
+	Path     []string         `json:"path"`
+	Clusters []ClusterUpgrade `json:"clusters,omitempty"`
+	// Verified is false if compatibility matrix does not know operator versions and the plan was not checked.
+	Verified bool `json:"verified"`
+	// Problems prevent the upgrade.
+	Problems []string `json:"problems,omitempty"`
+}
+
+// Err returns error wrapping ErrUnsafeOperatorUpgrade describing plan problems, or error wrapping
+// ErrUnverifiedOperatorUpgrade if the plan was not checked. It returns nil if the upgrade is safe.
+func (p *UpgradePlan) Err() error {
+	problems := p.Problems
+	for _, cluster := range p.Clusters {
+		for _, problem := range cluster.Problems {
+			problems = append(problems, fmt.Sprintf("cluster %s: %s", cluster.Name, problem))
+		}
+	}
+	if len(problems) > 0 {
+		return errors.Wrap(ErrUnsafeOperatorUpgrade, strings.Join(problems, "; "))
+	}
+	if !p.Verified {
+		return errors.Wrapf(ErrUnverifiedOperatorUpgrade, "%s operator versions %s and %s are not in compatibility matrix %s",
+			p.Operator, p.FromVersion, p.ToVersion, defaultCompatibilityMatrix.Version)
+	}
+	return nil
+}
+
+// clusterImages holds current and new images of cluster components.
+type clusterImages struct {
+	name      string
+	crVersion string
+	// database is a component running the database.
+	database string
+	from     map[string]string
+	to       map[string]string
+}
+
+// planUpgrade returns operator upgrade plan for given clusters checked against the compatibility matrix.
+func planUpgrade(m *CompatibilityMatrix, operator Engine, from, to string, clusters []clusterImages) *UpgradePlan {
+	plan := &UpgradePlan{
+		Operator:    operator,
+		FromVersion: from,
+		ToVersion:   to,
+		Path:        []string{to},
+		Verified:    true,
+	}
+	path, err := m.UpgradePath(operator, from, to)
+	switch {
+	case errors.Is(err, ErrUnknownOperatorVersion):
+		plan.Verified = false
+	case err != nil:
+		plan.Problems = append(plan.Problems, err.Error())
+	case len(path) > 1:
+		plan.Path = path
+		plan.Problems = append(plan.Problems, fmt.Sprintf(
+			"%s operator can't be upgraded from %s to %s directly, upgrade to %s first",
+			operator, from, to, strings.Join(path[:len(path)-1], ", then to "),
+		))
+	}
+
+	for _, cluster := range clusters {
+		upgrade := ClusterUpgrade{Name: cluster.name, CRVersion: cluster.crVersion}
+		for _, component := range sortedKeys(cluster.to) {
+			if cluster.from[component] != cluster.to[component] {
+				upgrade.Images = append(upgrade.Images, ImageChange{
+					Component: component,
+					From:      cluster.from[component],
+					To:        cluster.to[component],
+				})
+			}
+		}
+		if plan.Verified {
+			image := cluster.to[cluster.database]
+			for _, version := range plan.Path {
+				supported, err := m.SupportsDatabase(operator, version, image)
+				if err != nil {
+					upgrade.Problems = append(upgrade.Problems, err.Error())
+					break
+				}
+				if !supported {
+					upgrade.Problems = append(upgrade.Problems, fmt.Sprintf(
+						"database image %s is not supported by %s operator %s", image, operator, version,
+					))
+					break
+				}
+			}
+			for _, component := range sortedKeys(cluster.to) {
+				if component == cluster.database || cluster.to[component] == "" {
+					continue
+				}
+				supported, err := m.SupportsImage(operator, to, component, cluster.to[component])
+				if err != nil {
+					upgrade.Problems = append(upgrade.Problems, err.Error())
+					continue
+				}
+				if !supported {
+					upgrade.Problems = append(upgrade.Problems, fmt.Sprintf(
+						"%s image %s is not supported by %s operator %s", component, cluster.to[component], operator, to,
+					))
+				}
+			}
+		}
+		plan.Clusters = append(plan.Clusters, upgrade)
+	}
+	return plan
+}
+
+// sortedKeys returns sorted keys of the map.
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// mergeImages returns images with the ones from patch applied.
+func mergeImages(images, patch map[string]string) map[string]string {
+	merged := make(map[string]string, len(images))
+	for component, image := range images {
+		merged[component] = image
+	}
+	for component, image := range patch {
+		merged[component] = image
+	}
+	return merged
+}
+
+// PlanPXCOperatorUpgrade returns plan of PXC operator upgrade without applying it.
+func (c *K8sClient) PlanPXCOperatorUpgrade(ctx context.Context, fromVersion, toVersion string) (*UpgradePlan, error) {
+	var list pxc.PerconaXtraDBClusterList
+	err := c.kubeCtl.Get(ctx, pxc.PerconaXtraDBClusterKind, "", &list)
+	if err != nil {
+		return nil, errors.Wrap(err, "couldn't get percona XtraDB clusters")
+	}
+	resolver := newImageResolver(fromVersion, toVersion, pmmClientImage)
+	clusters := make([]clusterImages, 0, len(list.Items))
+	for i := range list.Items {
+		cluster := &list.Items[i]
+		from := pxcClusterImages(cluster)
+		clusters = append(clusters, clusterImages{
+			name:      cluster.Name,
+			crVersion: toVersion,
+			database:  componentPXC,
+			from:      from,
+			to:        mergeImages(from, resolver.resolveAll(from)),
+		})
+	}
+	plan := planUpgrade(defaultCompatibilityMatrix, EnginePXC, fromVersion, toVersion, clusters)
+	if !plan.Verified {
+		c.l.Warnf("PXC operator upgrade from %s to %s can't be verified: versions are not in compatibility matrix %s",
+			fromVersion, toVersion, defaultCompatibilityMatrix.Version)
+	}
+	return plan, nil
+}
+
+// PlanPSMDBOperatorUpgrade returns plan of PSMDB operator upgrade without applying it.
+func (c *K8sClient) PlanPSMDBOperatorUpgrade(ctx context.Context, fromVersion, toVersion string) (*UpgradePlan, error) {
+	var list psmdb.PerconaServerMongoDBList
+	err := c.kubeCtl.Get(ctx, psmdb.PerconaServerMongoDBKind, "", &list)
+	if err != nil {
+		return nil, errors.Wrap(err, "couldn't get percona server MongoDB clusters")
+	}
+	resolver := newImageResolver(fromVersion, toVersion, pmmClientImage)
+	clusters := make([]clusterImages, 0, len(list.Items))
+	for i := range list.Items {
+		cluster := &list.Items[i]
+		from := psmdbClusterImages(cluster)
+		clusters = append(clusters, clusterImages{
+			name:      cluster.Name,
+			crVersion: toVersion,
+			database:  componentMongod,
+			from:      from,
+			to:        mergeImages(from, resolver.resolveAll(from)),
+		})
+	}
+	plan := planUpgrade(defaultCompatibilityMatrix, EnginePSMDB, fromVersion, toVersion, clusters)
+	if !plan.Verified {
+		c.l.Warnf("PSMDB operator upgrade from %s to %s can't be verified: versions are not in compatibility matrix %s",
+			fromVersion, toVersion, defaultCompatibilityMatrix.Version)
+	}
+	return plan, nil
+}
@@ -0,0 +1,161 @@
+// dbaas-controller
+// Copyright (C) 2020 Percona LLC
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package k8sclient
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompatibilityMatrix(t *testing.T) {
+	t.Parallel()
+	m, err := parseCompatibilityMatrix(operatorCompatibilityJSON)
+	require.NoError(t, err)
+
+	path, err := m.UpgradePath(EnginePXC, "1.8.0", "1.9.0")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"1.9.0"}, path)
+
+	path, err = m.UpgradePath(EnginePXC, "1.7.0", "1.10.0")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"1.8.0", "1.9.0", "1.10.0"}, path)
+
+	path, err = m.UpgradePath(EnginePSMDB, "1.10", "1.10.0")
+	require.NoError(t, err)
+	assert.Empty(t, path)
+
+	_, err = m.UpgradePath(EnginePXC, "1.10.0", "1.8.0")
+	assert.ErrorIs(t, err, ErrUnsafeOperatorUpgrade)
+
+	_, err = m.UpgradePath(EnginePXC, "1.10.0", "9.0.0")
+	assert.ErrorIs(t, err, ErrUnknownOperatorVersion)
+
+	_, err = m.UpgradePath(EnginePXC, "1.9.0-custom", "1.10.0")
+	assert.ErrorIs(t, err, ErrUnknownOperatorVersion)
+
+	_, err = m.UpgradePath(EnginePXC, "main", "1.10.0")
+	assert.ErrorIs(t, err, ErrUnknownOperatorVersion)
+
+	supported, err := m.SupportsDatabase(EnginePSMDB, "1.9.0", "percona/percona-server-mongodb:3.6.23-13.0")
+	require.NoError(t, err)
+	assert.False(t, supported)
+
+	supported, err = m.SupportsDatabase(EnginePSMDB, "1.10.0", "registry.local:5000/percona-server-mongodb:5.0.7-6")
+	require.NoError(t, err)
+	assert.True(t, supported)
+
+	_, err = m.SupportsDatabase(EnginePXC, "1.10.0", "registry.local:5000/percona-xtradb-cluster")
+	assert.Error(t, err)
+
+	for image, expected := range map[string]bool{
+		"percona/percona-xtradb-cluster:5.7.35-31.53": true,
+		"percona/percona-xtradb-cluster:8.0.25-15.1":  true,
+		"percona/percona-xtradb-cluster:6.0.1":        false,
+		"percona/percona-xtradb-cluster:8.0.17":       false,
+	} {
+		supported, err = m.SupportsDatabase(EnginePXC, "1.10.0", image)
+		require.NoError(t, err)
+		assert.Equal(t, expected, supported, image)
+	}
+
+	supported, err = m.SupportsImage(EnginePXC, "1.10.0", "haproxy", "percona/percona-xtradb-cluster-operator:1.10.0-haproxy")
+	require.NoError(t, err)
+	assert.True(t, supported)
+
+	supported, err = m.SupportsImage(EnginePXC, "1.10.0", "backup", "percona/percona-xtradb-cluster-operator:1.9.0-pxc8.0-backup")
+	require.NoError(t, err)
+	assert.False(t, supported)
+
+	supported, err = m.SupportsImage(EnginePSMDB, "1.12.0", "pmm", "percona/pmm-client:2")
+	require.NoError(t, err)
+	assert.True(t, supported)
+
+	_, err = parseCompatibilityMatrix([]byte(`{"operators": {"pxc": [{"version": "1.0.0", "database": "not a constraint"}]}}`))
+	assert.Error(t, err)
+}
+
+func TestPlanUpgrade(t *testing.T) {
+	t.Parallel()
+	clusters := []clusterImages{
+		{
+			name:      "first",
+			crVersion: "1.10.0",
+			database:  "pxc",
+			from: map[string]string{
+				"pxc":     "percona/percona-xtradb-cluster:8.0.25-15.1",
+				"haproxy": "percona/percona-xtradb-cluster-operator:1.9.0-haproxy",
+			},
+			to: map[string]string{
+				"pxc":     "percona/percona-xtradb-cluster:8.0.25-15.1",
+				"haproxy": "percona/percona-xtradb-cluster-operator:1.10.0-haproxy",
+			},
+		},
+		{
+			name:     "second",
+			database: "pxc",
+			to:       map[string]string{"pxc": "percona/percona-xtradb-cluster:5.6.51"},
+		},
+	}
+
+	t.Run("Direct", func(t *testing.T) {
+		t.Parallel()
+		plan := planUpgrade(defaultCompatibilityMatrix, EnginePXC, "1.9.0", "1.10.0", clusters[:1])
+		assert.True(t, plan.Verified)
+		assert.Equal(t, []string{"1.10.0"}, plan.Path)
+		require.Len(t, plan.Clusters, 1)
+		assert.Equal(t, []ImageChange{{
+			Component: "haproxy",
+			From:      "percona/percona-xtradb-cluster-operator:1.9.0-haproxy",
+			To:        "percona/percona-xtradb-cluster-operator:1.10.0-haproxy",
+		}}, plan.Clusters[0].Images)
+		assert.NoError(t, plan.Err())
+	})
+
+	t.Run("Problems", func(t *testing.T) {
+		t.Parallel()
+		plan := planUpgrade(defaultCompatibilityMatrix, EnginePXC, "1.8.0", "1.10.0", clusters)
+		assert.Equal(t, []string{"1.9.0", "1.10.0"}, plan.Path)
+		err := plan.Err()
+		assert.ErrorIs(t, err, ErrUnsafeOperatorUpgrade)
+		assert.Contains(t, err.Error(), "upgrade to 1.9.0 first")
+		assert.Contains(t, err.Error(), "cluster second: database image percona/percona-xtradb-cluster:5.6.51 is not supported by pxc operator 1.9.0")
+	})
+
+	t.Run("Component image", func(t *testing.T) {
+		t.Parallel()
+		plan := planUpgrade(defaultCompatibilityMatrix, EnginePXC, "1.9.0", "1.10.0", []clusterImages{{
+			name:     "third",
+			database: "pxc",
+			to: map[string]string{
+				"pxc":      "percona/percona-xtradb-cluster:8.0.25-15.1",
+				"proxysql": "percona/percona-xtradb-cluster-operator:1.9.0-proxysql",
+			},
+		}})
+		err := plan.Err()
+		assert.ErrorIs(t, err, ErrUnsafeOperatorUpgrade)
+		assert.Contains(t, err.Error(), "proxysql image percona/percona-xtradb-cluster-operator:1.9.0-proxysql is not supported by pxc operator 1.10.0")
+	})
+
+	t.Run("Unknown version", func(t *testing.T) {
+		t.Parallel()
+		plan := planUpgrade(defaultCompatibilityMatrix, EnginePXC, "1.10.0", "9.0.0", clusters)
+		assert.False(t, plan.Verified)
+		assert.ErrorIs(t, plan.Err(), ErrUnverifiedOperatorUpgrade)
+	})
+}
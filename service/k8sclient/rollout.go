@@ -0,0 +1,485 @@
+// dbaas-controller
+// Copyright (C) 2020 Percona LLC
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package k8sclient
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/percona-platform/dbaas-controller/service/k8sclient/common"
+	"github.com/percona-platform/dbaas-controller/service/k8sclient/internal/kubectl"
+	"github.com/percona-platform/dbaas-controller/service/k8sclient/internal/psmdb"
+	"github.com/percona-platform/dbaas-controller/service/k8sclient/internal/pxc"
+	"github.com/percona-platform/dbaas-controller/utils/logger"
+)
+
+const (
+	rolloutConfigMapTmpl = "dbaas-%s-operator-rollout"
+	configMapJSONKey     = "data.json"
+
+	defaultRolloutReadyTimeout = 30 * time.Minute
+	defaultRolloutPollInterval = 10 * time.Second
+
+	// rolloutHeartbeatInterval is the interval of running rollout heartbeats.
+	rolloutHeartbeatInterval = time.Minute
+	// rolloutHeartbeatTimeout is the time after which running rollout without heartbeats is considered abandoned,
+	// for example, because the controller running it was killed.
+	rolloutHeartbeatTimeout = 5 * time.Minute
+	// rolloutSaveTimeout is the timeout of the final rollout save made after rollout context is canceled.
+	rolloutSaveTimeout = 30 * time.Second
+)
+
+var (
+	// ErrRolloutPaused is returned when rollout of cluster patches is paused because a cluster failed to upgrade.
+	ErrRolloutPaused = errors.New("rollout of cluster patches is paused")
+	// ErrRolloutInProgress is returned when rollout of cluster patches is run by another controller.
+	ErrRolloutInProgress = errors.New("rollout of cluster patches is in progress")
+)
+
+// ClusterRolloutState represents state of a cluster in rollout.
+type ClusterRolloutState string
+
+const (
+	// ClusterRolloutPending represents a cluster that is not patched yet.
+	ClusterRolloutPending ClusterRolloutState = "pending"
+	// ClusterRolloutPatched represents a patched cluster which is not ready yet.
+	ClusterRolloutPatched ClusterRolloutState = "patched"
+	// ClusterRolloutReady represents a patched cluster which became ready.
+	ClusterRolloutReady ClusterRolloutState = "ready"
+	// ClusterRolloutFailed represents a cluster which failed to be patched or to become ready.
+	ClusterRolloutFailed ClusterRolloutState = "failed"
+)
+
+// ClusterRolloutResult holds result of the rollout for a single cluster.
+type ClusterRolloutResult struct {
+	Name       string              `json:"name"`
+	State      ClusterRolloutState `json:"state"`
+	Error      string              `json:"error,omitempty"`
+	StartedAt  *time.Time          `json:"startedAt,omitempty"`
+	FinishedAt *time.Time          `json:"finishedAt,omitempty"`
+}
+
+// Rollout is a record of cluster patches made after operator upgrade. It's stored in Kubernetes cluster,
+// so paused rollout could be resumed.
+type Rollout struct {
+	Operator   Engine                 `json:"operator"`
+	OldVersion string                 `json:"oldVersion"`
+	NewVersion string                 `json:"newVersion"`
+	Paused     bool                   `json:"paused"`
+	Clusters   []ClusterRolloutResult `json:"clusters"`
+	// Running is true while a controller runs the rollout. HeartbeatAt is updated periodically while it runs,
+	// so rollout abandoned by a killed controller isn't considered in progress forever.
+	Running     bool       `json:"running"`
+	HeartbeatAt *time.Time `json:"heartbeatAt,omitempty"`
+}
+
+// InProgress returns true if the rollout is run by a controller at the given time.
+func (r *Rollout) InProgress(now time.Time) bool {
+	return r.Running && r.HeartbeatAt != nil && now.Sub(*r.HeartbeatAt) < rolloutHeartbeatTimeout
+}
+
+// Finished returns true if all clusters are patched and ready.
+func (r *Rollout) Finished() bool {
+	for _, cluster := range r.Clusters {
+		if cluster.State != ClusterRolloutReady {
+			return false
+		}
+	}
+	return true
+}
+
+// RolloutParams contains parameters of cluster patches rollout.
+type RolloutParams struct {
+	// BatchSize is a number of clusters patched at once, 1 if not set.
+	BatchSize int
+	// ReadyTimeout is the time a cluster has to become ready after patching.
+	ReadyTimeout time.Duration
+	// PollInterval is the interval of cluster state checks.
+	PollInterval time.Duration
+}
+
+// withDefaults returns params with defaults set for missing values.
+func (p RolloutParams) withDefaults() RolloutParams {
+	if p.BatchSize <= 0 {
+		p.BatchSize = 1
+	}
+	if p.ReadyTimeout <= 0 {
+		p.ReadyTimeout = defaultRolloutReadyTimeout
+	}
+	if p.PollInterval <= 0 {
+		p.PollInterval = defaultRolloutPollInterval
+	}
+	return p
+}
+
+// rolloutTarget holds operations on clusters managed by an operator.
+type rolloutTarget struct {
+	operator Engine
+	// list returns names of all clusters.
+	list func(ctx context.Context) ([]string, error)
+	// patch patches the cluster to match new version of the operator.
+	patch func(ctx context.Context, name, oldVersion, newVersion string) error
+	// ready returns true if the cluster is ready and error if the cluster failed.
+	ready func(ctx context.Context, name string) (bool, error)
+}
+
+// rolloutStore stores rollouts.
+type rolloutStore interface {
+	GetRollout(ctx context.Context, operator Engine) (*Rollout, error)
+	saveRollout(ctx context.Context, rollout *Rollout) error
+}
+
+// GetRollout returns the last rollout of cluster patches for the operator. It returns ErrNotFound if there was none.
+func (c *K8sClient) GetRollout(ctx context.Context, operator Engine) (*Rollout, error) {
+	var rollout Rollout
+	if err := c.getConfigMapJSON(ctx, fmt.Sprintf(rolloutConfigMapTmpl, operator), &rollout); err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return nil, errors.Wrapf(ErrNotFound, "no rollout of %s cluster patches", operator)
+		}
+		return nil, errors.Wrap(err, "failed to get rollout")
+	}
+	return &rollout, nil
+}
+
+// saveRollout stores the rollout in Kubernetes cluster.
+func (c *K8sClient) saveRollout(ctx context.Context, rollout *Rollout) error {
+	err := c.saveConfigMapJSON(ctx, fmt.Sprintf(rolloutConfigMapTmpl, rollout.Operator), rollout)
+	return errors.Wrap(err, "failed to save rollout")
+}
+
+// CheckNoRolloutInProgress returns ErrRolloutInProgress if rollout of cluster patches for the operator is in progress.
+func (c *K8sClient) CheckNoRolloutInProgress(ctx context.Context, operator Engine) error {
+	rollout, err := c.GetRollout(ctx, operator)
+	switch {
+	case errors.Is(err, ErrNotFound):
+		return nil
+	case err != nil:
+		return err
+	case rollout.InProgress(time.Now()):
+		return errors.Wrapf(ErrRolloutInProgress, "%s cluster patches from %s to %s", operator, rollout.OldVersion, rollout.NewVersion)
+	default:
+		return nil
+	}
+}
+
+// getConfigMapJSON decodes JSON value stored in the config map.
+func (c *K8sClient) getConfigMapJSON(ctx context.Context, name string, v interface{}) error {
+	var configMap common.ConfigMap
+	err := c.kubeCtl.Get(ctx, "configmap", name, &configMap)
+	if err != nil {
+		if errors.Is(err, kubectl.ErrNotFound) {
+			return errors.Wrapf(ErrNotFound, "config map %q not found", name)
+		}
+		return err
+	}
+	return json.Unmarshal([]byte(configMap.Data[configMapJSONKey]), v)
+}
+
+// saveConfigMapJSON stores value encoded as JSON in the config map.
+func (c *K8sClient) saveConfigMapJSON(ctx context.Context, name string, v interface{}) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	configMap := common.ConfigMap{
+		TypeMeta: common.TypeMeta{
+			APIVersion: k8sAPIVersion,
+			Kind:       k8sMetaKindConfigMap,
+		},
+		ObjectMeta: common.ObjectMeta{
+			Name: name,
+		},
+		Data: map[string]string{configMapJSONKey: string(b)},
+	}
+	return c.kubeCtl.Apply(ctx, configMap)
+}
+
+// startRollout returns unfinished rollout to the same operator version to resume it or a new rollout.
+func startRollout(ctx context.Context, l logger.Logger, store rolloutStore, target *rolloutTarget, oldVersion, newVersion string) (*Rollout, error) {
+	rollout, err := store.GetRollout(ctx, target.operator)
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		return nil, err
+	}
+	if err == nil && rollout.InProgress(time.Now()) {
+		return nil, errors.Wrapf(ErrRolloutInProgress, "%s cluster patches from %s to %s", target.operator, rollout.OldVersion, rollout.NewVersion)
+	}
+	if err == nil && !rollout.Finished() && rollout.NewVersion == newVersion {
+		l.Infof("Resuming rollout of %s cluster patches from %s to %s", target.operator, rollout.OldVersion, newVersion)
+		rollout.Paused = false
+		for i := range rollout.Clusters {
+			if rollout.Clusters[i].State == ClusterRolloutFailed {
+				rollout.Clusters[i].State = ClusterRolloutPending
+				rollout.Clusters[i].Error = ""
+			}
+		}
+		return rollout, nil
+	}
+
+	names, err := target.list(ctx)
+	if err != nil {
+		return nil, err
+	}
+	rollout = &Rollout{
+		Operator:   target.operator,
+		OldVersion: oldVersion,
+		NewVersion: newVersion,
+		Clusters:   make([]ClusterRolloutResult, len(names)),
+	}
+	for i, name := range names {
+		rollout.Clusters[i] = ClusterRolloutResult{Name: name, State: ClusterRolloutPending}
+	}
+	return rollout, nil
+}
+
+// runRollout patches clusters in batches, waiting for clusters of the batch to become ready before patching
+// the next batch. Rollout is paused and ErrRolloutPaused is returned when a cluster fails.
+// Rollout is paused too when ctx is canceled, a cluster which was patched stays patched then.
+// Rollout state is saved after every change, so it could be resumed by running it again.
+func runRollout(
+	ctx context.Context, l logger.Logger, store rolloutStore, target *rolloutTarget, oldVersion, newVersion string, params RolloutParams,
+) (*Rollout, error) {
+	params = params.withDefaults()
+	rollout, err := startRollout(ctx, l, store, target, oldVersion, newVersion)
+	if err != nil {
+		return nil, err
+	}
+
+	save := func(ctx context.Context) error {
+		now := time.Now()
+		rollout.HeartbeatAt = &now
+		return store.saveRollout(ctx, rollout)
+	}
+	heartbeat := func(ctx context.Context) error {
+		if time.Since(*rollout.HeartbeatAt) < rolloutHeartbeatInterval {
+			return nil
+		}
+		return save(ctx)
+	}
+	rollout.Running = true
+	if err := save(ctx); err != nil {
+		return nil, err
+	}
+	defer func() {
+		// Final state is saved even if ctx is canceled on controller shutdown, so the rollout could be resumed.
+		saveCtx, cancel := context.WithTimeout(logger.GetCtxWithLogger(context.Background(), l), rolloutSaveTimeout)
+		defer cancel()
+		rollout.Running = false
+		if err := save(saveCtx); err != nil {
+			l.Error(err)
+		}
+	}()
+
+	fail := func(cluster *ClusterRolloutResult, err error) (*Rollout, error) {
+		rollout.Paused = true
+		if ctx.Err() != nil {
+			return rollout, errors.Wrap(ErrRolloutPaused, ctx.Err().Error())
+		}
+		now := time.Now()
+		cluster.State = ClusterRolloutFailed
+		cluster.Error = err.Error()
+		cluster.FinishedAt = &now
+		return rollout, errors.Wrapf(ErrRolloutPaused, "cluster %s: %s", cluster.Name, err)
+	}
+
+	for {
+		var batch []*ClusterRolloutResult
+		for i := range rollout.Clusters {
+			state := rollout.Clusters[i].State
+			if (state == ClusterRolloutPending || state == ClusterRolloutPatched) && len(batch) < params.BatchSize {
+				batch = append(batch, &rollout.Clusters[i])
+			}
+		}
+		if len(batch) == 0 {
+			return rollout, nil
+		}
+
+		for _, cluster := range batch {
+			if cluster.State != ClusterRolloutPending {
+				continue
+			}
+			now := time.Now()
+			cluster.StartedAt = &now
+			if err := target.patch(ctx, cluster.Name, rollout.OldVersion, rollout.NewVersion); err != nil {
+				return fail(cluster, errors.Wrap(err, "failed to patch cluster"))
+			}
+			cluster.State = ClusterRolloutPatched
+			if err := save(ctx); err != nil {
+				return rollout, err
+			}
+		}
+
+		for _, cluster := range batch {
+			if err := waitRolloutReady(ctx, target, cluster.Name, params, heartbeat); err != nil {
+				return fail(cluster, err)
+			}
+			now := time.Now()
+			cluster.State = ClusterRolloutReady
+			cluster.FinishedAt = &now
+			if err := save(ctx); err != nil {
+				return rollout, err
+			}
+		}
+	}
+}
+
+// waitRolloutReady waits until the cluster becomes ready. Heartbeat is called on every check.
+func waitRolloutReady(
+	ctx context.Context, target *rolloutTarget, name string, params RolloutParams, heartbeat func(context.Context) error,
+) error {
+	timeout := time.NewTimer(params.ReadyTimeout)
+	defer timeout.Stop()
+	ticker := time.NewTicker(params.PollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-timeout.C:
+			return errors.Errorf("cluster did not become ready in %s", params.ReadyTimeout)
+		case <-ticker.C:
+			if err := heartbeat(ctx); err != nil {
+				return err
+			}
+			ready, err := target.ready(ctx, name)
+			if err != nil {
+				return err
+			}
+			if ready {
+				return nil
+			}
+		}
+	}
+}
+
+// clusterReady returns true if the cluster is ready and the operator observed its latest changes.
+// Paused cluster is done as soon as it's patched: it won't become ready until resumed, and patched
+// spec is applied then. It returns error if the cluster failed.
+func (c *K8sClient) clusterReady(ctx context.Context, cluster common.DatabaseCluster, generation, observedGeneration int64, messages []string) (bool, error) {
+	if cluster.Pause() {
+		return true, nil
+	}
+	switch c.getClusterState(ctx, cluster, c.crVersionMatchesPodsVersion) {
+	case ClusterStatePaused:
+		return true, nil
+	case ClusterStateFailed:
+		return false, errors.Errorf("cluster failed: %s", strings.Join(messages, "; "))
+	case ClusterStateReady:
+		// Old operators don't report observed generation.
+		return observedGeneration == 0 || observedGeneration >= generation, nil
+	default:
+		return false, nil
+	}
+}
+
+// finishRollout removes operator snapshot after the rollout finished successfully, because the upgrade
+// is complete then and restoring clusters to the snapshot would revert changes made after the upgrade.
+func (c *K8sClient) finishRollout(ctx context.Context, rollout *Rollout, err error) (*Rollout, error) {
+	if err != nil {
+		return rollout, err
+	}
+	if err := c.deleteOperatorSnapshot(ctx, rollout.Operator); err != nil {
+		c.l.Warnf("Rollout of %s cluster patches to %s finished: %s", rollout.Operator, rollout.NewVersion, err)
+	}
+	return rollout, nil
+}
+
+// PatchAllPSMDBClusters replaces images versions and CrVersion after update of the operator to match version
+// of the installed operator. Clusters are patched in batches as a rollout, see runRollout.
+func (c *K8sClient) PatchAllPSMDBClusters(ctx context.Context, oldVersion, newVersion string, params RolloutParams) (*Rollout, error) {
+	get := func(ctx context.Context, name string) (*psmdb.PerconaServerMongoDB, error) {
+		var cluster psmdb.PerconaServerMongoDB
+		err := c.kubeCtl.Get(ctx, psmdb.PerconaServerMongoDBKind, name, &cluster)
+		return &cluster, errors.Wrapf(err, "couldn't get percona server MongoDB cluster %s", name)
+	}
+	target := &rolloutTarget{
+		operator: EnginePSMDB,
+		list: func(ctx context.Context) ([]string, error) {
+			var list psmdb.PerconaServerMongoDBList
+			if err := c.kubeCtl.Get(ctx, psmdb.PerconaServerMongoDBKind, "", &list); err != nil {
+				return nil, errors.Wrap(err, "couldn't get percona server MongoDB clusters")
+			}
+			names := make([]string, len(list.Items))
+			for i, cluster := range list.Items {
+				names[i] = cluster.Name
+			}
+			return names, nil
+		},
+		patch: func(ctx context.Context, name, oldVersion, newVersion string) error {
+			cluster, err := get(ctx, name)
+			if err != nil {
+				return err
+			}
+			clusterPatch := psmdbUpgradePatch(cluster, newImageResolver(oldVersion, newVersion, pmmClientImage))
+			return c.kubeCtl.Patch(ctx, kubectl.PatchTypeMerge, "perconaservermongodb", name, clusterPatch)
+		},
+		ready: func(ctx context.Context, name string) (bool, error) {
+			cluster, err := get(ctx, name)
+			if err != nil || cluster.Status == nil {
+				return false, err
+			}
+			return c.clusterReady(ctx, cluster, cluster.Generation, cluster.Status.ObservedGeneration, []string{cluster.Status.Message})
+		},
+	}
+	rollout, err := runRollout(ctx, c.l, c, target, oldVersion, newVersion, params)
+	return c.finishRollout(ctx, rollout, err)
+}
+
+// PatchAllPXCClusters replaces the image versions and crVersion after update of the operator to match version
+// of the installed operator. Clusters are patched in batches as a rollout, see runRollout.
+func (c *K8sClient) PatchAllPXCClusters(ctx context.Context, oldVersion, newVersion string, params RolloutParams) (*Rollout, error) {
+	get := func(ctx context.Context, name string) (*pxc.PerconaXtraDBCluster, error) {
+		var cluster pxc.PerconaXtraDBCluster
+		err := c.kubeCtl.Get(ctx, pxc.PerconaXtraDBClusterKind, name, &cluster)
+		return &cluster, errors.Wrapf(err, "couldn't get percona XtraDB cluster %s", name)
+	}
+	target := &rolloutTarget{
+		operator: EnginePXC,
+		list: func(ctx context.Context) ([]string, error) {
+			var list pxc.PerconaXtraDBClusterList
+			if err := c.kubeCtl.Get(ctx, pxc.PerconaXtraDBClusterKind, "", &list); err != nil {
+				return nil, errors.Wrap(err, "couldn't get percona XtraDB clusters")
+			}
+			names := make([]string, len(list.Items))
+			for i, cluster := range list.Items {
+				names[i] = cluster.Name
+			}
+			return names, nil
+		},
+		patch: func(ctx context.Context, name, oldVersion, newVersion string) error {
+			cluster, err := get(ctx, name)
+			if err != nil {
+				return err
+			}
+			clusterPatch := pxcUpgradePatch(cluster, newImageResolver(oldVersion, newVersion, pmmClientImage))
+			return c.kubeCtl.Patch(ctx, kubectl.PatchTypeMerge, "perconaxtradbcluster", name, clusterPatch)
+		},
+		ready: func(ctx context.Context, name string) (bool, error) {
+			cluster, err := get(ctx, name)
+			if err != nil || cluster.Status == nil {
+				return false, err
+			}
+			return c.clusterReady(ctx, cluster, cluster.Generation, cluster.Status.ObservedGeneration, cluster.Status.Messages)
+		},
+	}
+	rollout, err := runRollout(ctx, c.l, c, target, oldVersion, newVersion, params)
+	return c.finishRollout(ctx, rollout, err)
+}
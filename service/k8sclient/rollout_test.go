@@ -0,0 +1,218 @@
+// dbaas-controller
+// Copyright (C) 2020 Percona LLC
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package k8sclient
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/percona-platform/dbaas-controller/service/k8sclient/common"
+	"github.com/percona-platform/dbaas-controller/service/k8sclient/internal/pxc"
+	"github.com/percona-platform/dbaas-controller/utils/logger"
+)
+
+// memoryRolloutStore is rolloutStore keeping a copy of the last saved rollout.
+type memoryRolloutStore struct {
+	rollout *Rollout
+	saves   int
+}
+
+func (s *memoryRolloutStore) GetRollout(ctx context.Context, operator Engine) (*Rollout, error) {
+	if s.rollout == nil {
+		return nil, ErrNotFound
+	}
+	rollout := *s.rollout
+	rollout.Clusters = append([]ClusterRolloutResult(nil), s.rollout.Clusters...)
+	return &rollout, nil
+}
+
+func (s *memoryRolloutStore) saveRollout(ctx context.Context, rollout *Rollout) error {
+	s.saves++
+	saved := *rollout
+	saved.Clusters = append([]ClusterRolloutResult(nil), rollout.Clusters...)
+	s.rollout = &saved
+	return nil
+}
+
+func TestRollout(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	l := logger.Get(ctx)
+	params := RolloutParams{BatchSize: 2, ReadyTimeout: time.Second, PollInterval: time.Millisecond}
+
+	var patched []string
+	failing := map[string]bool{"c": true}
+	target := &rolloutTarget{
+		operator: EnginePXC,
+		list: func(ctx context.Context) ([]string, error) {
+			return []string{"a", "b", "c", "d"}, nil
+		},
+		patch: func(ctx context.Context, name, oldVersion, newVersion string) error {
+			assert.Equal(t, "1.9.0", oldVersion)
+			assert.Equal(t, "1.10.0", newVersion)
+			patched = append(patched, name)
+			return nil
+		},
+		ready: func(ctx context.Context, name string) (bool, error) {
+			if failing[name] {
+				return false, errors.New("cluster failed")
+			}
+			return true, nil
+		},
+	}
+
+	store := new(memoryRolloutStore)
+	rollout, err := runRollout(ctx, l, store, target, "1.9.0", "1.10.0", params)
+	assert.ErrorIs(t, err, ErrRolloutPaused)
+	assert.True(t, rollout.Paused)
+	assert.Equal(t, []string{"a", "b", "c", "d"}, patched)
+	states := func(r *Rollout) []ClusterRolloutState {
+		res := make([]ClusterRolloutState, len(r.Clusters))
+		for i, cluster := range r.Clusters {
+			res[i] = cluster.State
+		}
+		return res
+	}
+	assert.Equal(t, []ClusterRolloutState{
+		ClusterRolloutReady, ClusterRolloutReady, ClusterRolloutFailed, ClusterRolloutPatched,
+	}, states(store.rollout))
+	assert.Equal(t, "cluster failed", store.rollout.Clusters[2].Error)
+
+	// Resume after the cluster was fixed. Already patched cluster is not patched again,
+	// the new operator version is kept even though the operator reports it as the old one now.
+	patched = nil
+	failing["c"] = false
+	rollout, err = runRollout(ctx, l, store, target, "1.10.0", "1.10.0", params)
+	require.NoError(t, err)
+	assert.False(t, rollout.Paused)
+	assert.True(t, rollout.Finished())
+	assert.Equal(t, "1.9.0", rollout.OldVersion)
+	assert.Equal(t, []string{"c"}, patched)
+
+	// Finished rollout is not resumed.
+	patched = nil
+	params.BatchSize = 0
+	_, err = runRollout(ctx, l, store, &rolloutTarget{
+		operator: EnginePXC,
+		list:     func(ctx context.Context) ([]string, error) { return []string{"a"}, nil },
+		patch:    target.patch,
+		ready:    func(ctx context.Context, name string) (bool, error) { return false, nil },
+	}, "1.9.0", "1.10.0", params)
+	assert.ErrorIs(t, err, ErrRolloutPaused)
+	assert.Contains(t, err.Error(), "did not become ready")
+	assert.Equal(t, []string{"a"}, patched)
+}
+
+func TestRolloutInProgress(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	l := logger.Get(ctx)
+	params := RolloutParams{ReadyTimeout: time.Second, PollInterval: time.Millisecond}
+
+	var patched []string
+	target := &rolloutTarget{
+		operator: EnginePSMDB,
+		list:     func(ctx context.Context) ([]string, error) { return []string{"a"}, nil },
+		patch: func(ctx context.Context, name, oldVersion, newVersion string) error {
+			patched = append(patched, name)
+			return nil
+		},
+		ready: func(ctx context.Context, name string) (bool, error) { return true, nil },
+	}
+
+	now := time.Now()
+	store := &memoryRolloutStore{rollout: &Rollout{
+		Operator:    EnginePSMDB,
+		OldVersion:  "1.9.0",
+		NewVersion:  "1.10.0",
+		Clusters:    []ClusterRolloutResult{{Name: "a", State: ClusterRolloutPending}},
+		Running:     true,
+		HeartbeatAt: &now,
+	}}
+	_, err := runRollout(ctx, l, store, target, "1.9.0", "1.10.0", params)
+	assert.ErrorIs(t, err, ErrRolloutInProgress)
+	assert.Empty(t, patched)
+
+	// Rollout abandoned by a killed controller is resumed.
+	abandoned := now.Add(-rolloutHeartbeatTimeout)
+	store.rollout.HeartbeatAt = &abandoned
+	rollout, err := runRollout(ctx, l, store, target, "1.9.0", "1.10.0", params)
+	require.NoError(t, err)
+	assert.True(t, rollout.Finished())
+	assert.Equal(t, []string{"a"}, patched)
+	assert.False(t, store.rollout.Running)
+	assert.False(t, store.rollout.InProgress(time.Now()))
+}
+
+func TestRolloutCanceled(t *testing.T) {
+	t.Parallel()
+	ctx, cancel := context.WithCancel(context.Background())
+	l := logger.Get(ctx)
+	params := RolloutParams{ReadyTimeout: time.Second, PollInterval: time.Millisecond}
+
+	target := &rolloutTarget{
+		operator: EnginePXC,
+		list:     func(ctx context.Context) ([]string, error) { return []string{"a"}, nil },
+		patch: func(ctx context.Context, name, oldVersion, newVersion string) error {
+			cancel()
+			return nil
+		},
+		ready: func(ctx context.Context, name string) (bool, error) { return false, nil },
+	}
+	store := new(memoryRolloutStore)
+	rollout, err := runRollout(ctx, l, store, target, "1.9.0", "1.10.0", params)
+	assert.ErrorIs(t, err, ErrRolloutPaused)
+	assert.True(t, rollout.Paused)
+
+	// The cluster isn't marked as failed and the state is saved despite canceled context.
+	assert.Equal(t, ClusterRolloutPatched, store.rollout.Clusters[0].State)
+	assert.True(t, store.rollout.Paused)
+	assert.False(t, store.rollout.Running)
+}
+
+func TestClusterReady(t *testing.T) {
+	t.Parallel()
+	ctx := context.Background()
+	c := &K8sClient{l: logger.Get(ctx)}
+
+	paused := &pxc.PerconaXtraDBCluster{
+		Spec:   &pxc.PerconaXtraDBClusterSpec{Pause: true},
+		Status: &pxc.PerconaXtraDBClusterStatus{Status: common.AppStateInit},
+	}
+	ready, err := c.clusterReady(ctx, paused, 2, 1, nil)
+	require.NoError(t, err)
+	assert.True(t, ready, "paused cluster should not hold the rollout")
+
+	failed := &pxc.PerconaXtraDBCluster{
+		Spec:   new(pxc.PerconaXtraDBClusterSpec),
+		Status: &pxc.PerconaXtraDBClusterStatus{Status: common.AppStateError, Messages: []string{"no space left"}},
+	}
+	_, err = c.clusterReady(ctx, failed, 2, 2, failed.Status.Messages)
+	assert.EqualError(t, err, "cluster failed: no space left")
+
+	ready, err = c.clusterReady(ctx, &pxc.PerconaXtraDBCluster{
+		Spec:   new(pxc.PerconaXtraDBClusterSpec),
+		Status: &pxc.PerconaXtraDBClusterStatus{Status: common.AppStateReady},
+	}, 2, 1, nil)
+	require.NoError(t, err)
+	assert.False(t, ready, "operator has not observed the patch yet")
+}
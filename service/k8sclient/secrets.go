@@ -19,17 +19,145 @@ package k8sclient
 import (
 	"crypto/rand"
 	"math/big"
+	"strings"
+	"unicode/utf8"
 
 	"github.com/pkg/errors"
 )
 
+// Engine represents a database engine the controller manages.
+type Engine string
+
 const (
-	passwordLength = 24
+	// EnginePXC represents Percona XtraDB Cluster.
+	EnginePXC Engine = "pxc"
+	// EnginePSMDB represents Percona Server for MongoDB.
+	EnginePSMDB Engine = "psmdb"
+)
+
+const (
+	defaultPasswordLength = 24
+	// minPasswordLength is the shortest password length a policy may define.
+	minPasswordLength = 8
+
+	lowercaseLetters = "abcdefghijklmnopqrstuvwxyz"
+	uppercaseLetters = "ABCDEFGHIJKLMNOPQRSTUVWXYZ"
+	digits           = "0123456789"
+
+	// maxPasswordGenerationAttempts limits how many times we try to generate a
+	// password that contains all required character classes.
+	maxPasswordGenerationAttempts = 100
 )
 
-func generatePasswords(secrets map[string][]byte) (map[string][]byte, error) {
+// ErrPasswordPolicyViolated is returned when password does not satisfy the password policy.
+var ErrPasswordPolicyViolated = errors.New("password does not satisfy the password policy")
+
+// PasswordPolicy describes how passwords of database system users are generated.
+type PasswordPolicy struct {
+	// Length is the length of generated passwords.
+	Length int
+	// Lowercase, Uppercase and Digits enable corresponding character classes.
+	// Every enabled class has to be present in a password.
+	Lowercase bool
+	Uppercase bool
+	Digits    bool
+	// Symbols contains special characters allowed for the given engine. When
+	// not empty, at least one of them has to be present in a password.
+	Symbols map[Engine]string
+}
+
+// DefaultPasswordPolicy returns password policy used when none is configured.
+// PSMDB does not support all special characters in password https://jira.percona.com/browse/K8SPSMDB-364,
+// so no symbols are allowed by default.
+func DefaultPasswordPolicy() *PasswordPolicy {
+	return &PasswordPolicy{
+		Length:    defaultPasswordLength,
+		Lowercase: true,
+		Uppercase: true,
+		Digits:    true,
+		Symbols:   map[Engine]string{},
+	}
+}
+
+// Check returns an error if the policy can't be used to generate passwords.
+func (p *PasswordPolicy) Check() error {
+	if p.Length < minPasswordLength {
+		return errors.Errorf("password length has to be at least %d, %d was given", minPasswordLength, p.Length)
+	}
+	for engine, symbols := range p.Symbols {
+		if strings.ContainsAny(symbols, lowercaseLetters+uppercaseLetters+digits) {
+			return errors.Errorf("symbols allowed for %s can't contain letters or digits", engine)
+		}
+		if strings.ContainsAny(symbols, " \t\n\r") {
+			return errors.Errorf("symbols allowed for %s can't contain whitespace characters", engine)
+		}
+	}
+	if len(p.classes(EnginePXC)) == 0 || len(p.classes(EnginePSMDB)) == 0 {
+		return errors.New("at least one character class has to be enabled")
+	}
+	return nil
+}
+
+// classes returns character classes enabled for the given engine.
+func (p *PasswordPolicy) classes(engine Engine) []string {
+	var classes []string
+	if p.Lowercase {
+		classes = append(classes, lowercaseLetters)
+	}
+	if p.Uppercase {
+		classes = append(classes, uppercaseLetters)
+	}
+	if p.Digits {
+		classes = append(classes, digits)
+	}
+	if symbols := p.Symbols[engine]; symbols != "" {
+		classes = append(classes, symbols)
+	}
+	return classes
+}
+
+// Validate checks that the password satisfies the policy for the given engine.
+func (p *PasswordPolicy) Validate(engine Engine, password string) error {
+	if utf8.RuneCountInString(password) < p.Length {
+		return errors.Wrapf(ErrPasswordPolicyViolated, "password has to be at least %d characters long", p.Length)
+	}
+	classes := p.classes(engine)
+	alphabet := strings.Join(classes, "")
+	for _, r := range password {
+		if !strings.ContainsRune(alphabet, r) {
+			return errors.Wrapf(ErrPasswordPolicyViolated, "character %q is not allowed for %s", r, engine)
+		}
+	}
+	for _, class := range classes {
+		if !strings.ContainsAny(password, class) {
+			return errors.Wrapf(ErrPasswordPolicyViolated, "password has to contain at least one of %q", class)
+		}
+	}
+	return nil
+}
+
+// Generate generates a random password satisfying the policy for the given engine.
+func (p *PasswordPolicy) Generate(engine Engine) (string, error) {
+	alphabet := []rune(strings.Join(p.classes(engine), ""))
+	for i := 0; i < maxPasswordGenerationAttempts; i++ {
+		password, err := generatePassword(alphabet, p.Length)
+		if err != nil {
+			return "", err
+		}
+		if p.Validate(engine, password) == nil {
+			return password, nil
+		}
+	}
+	return "", errors.Errorf("failed to generate password for %s satisfying the password policy", engine)
+}
+
+// generatePasswords sets generated passwords for all keys of secrets.
+func generatePasswords(policy *PasswordPolicy, engine Engine, secrets map[string][]byte) (map[string][]byte, error) {
+	if policy == nil {
+		policy = DefaultPasswordPolicy()
+	}
 	for key := range secrets {
-		password, err := generatePassword(passwordLength)
+		password, err := policy.Generate(engine)
 		if err != nil {
 			return nil, errors.Wrapf(err, "failed to generate password for  %s", key)
 		}
@@ -38,9 +166,7 @@ func generatePasswords(secrets map[string][]byte) (map[string][]byte, error) {
 	return secrets, nil
 }
 
-func generatePassword(n int) (string, error) {
-	// PSMDB do not support all special characters in password https://jira.percona.com/browse/K8SPSMDB-364
-	symbols := []rune("abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789")
+func generatePassword(symbols []rune, n int) (string, error) {
 	symbolsLen := len(symbols)
 	b := make([]rune, n)
 	for i := range b {
@@ -53,7 +179,8 @@ func generatePassword(n int) (string, error) {
 	return string(b), nil
 }
 
-func generatePXCPasswords() (map[string][]byte, error) {
+// generatePXCPasswords generates passwords of PXC system users.
+func generatePXCPasswords(policy *PasswordPolicy) (map[string][]byte, error) {
 	// secrets represents stringData part of
 	// https://github.com/percona/percona-xtradb-cluster-operator/blob/main/deploy/secrets.yaml.
 	secrets := map[string][]byte{
@@ -66,10 +193,11 @@ func generatePXCPasswords() (map[string][]byte, error) {
 		"replication":  {},
 	}
 
-	return generatePasswords(secrets)
+	return generatePasswords(policy, EnginePXC, secrets)
 }
 
-func generatePSMDBPasswords() (map[string][]byte, error) {
+// generatePSMDBPasswords generates passwords of PSMDB system users.
+func generatePSMDBPasswords(policy *PasswordPolicy) (map[string][]byte, error) {
 	// secrets represents stringData part of
 	// https://github.com/percona/percona-server-mongodb-operator/blob/main/deploy/secrets.yaml.
 	secrets := map[string][]byte{
@@ -78,7 +206,7 @@ func generatePSMDBPasswords() (map[string][]byte, error) {
 		"MONGODB_CLUSTER_MONITOR_USER": []byte("clusterMonitor"),
 		"MONGODB_USER_ADMIN_USER":      []byte("userAdmin"),
 	}
-	passwords, err := generatePasswords(map[string][]byte{
+	passwords, err := generatePasswords(policy, EnginePSMDB, map[string][]byte{
 		"MONGODB_BACKUP_PASSWORD":          {},
 		"MONGODB_CLUSTER_ADMIN_PASSWORD":   {},
 		"MONGODB_CLUSTER_MONITOR_PASSWORD": {},
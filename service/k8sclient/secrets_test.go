@@ -0,0 +1,114 @@
+// dbaas-controller
+// Copyright (C) 2020 Percona LLC
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package k8sclient
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPasswordPolicy(t *testing.T) {
+	t.Parallel()
+
+	t.Run("Default policy generates alphanumeric passwords", func(t *testing.T) {
+		t.Parallel()
+		policy := DefaultPasswordPolicy()
+		require.NoError(t, policy.Check())
+		for _, engine := range []Engine{EnginePXC, EnginePSMDB} {
+			password, err := policy.Generate(engine)
+			require.NoError(t, err)
+			assert.Len(t, password, defaultPasswordLength)
+			assert.NoError(t, policy.Validate(engine, password))
+			assert.False(t, strings.ContainsAny(password, "!#$%&()*+,-./:;<=>?@[]^_{|}~"))
+		}
+	})
+
+	t.Run("Symbols are allowed per engine", func(t *testing.T) {
+		t.Parallel()
+		policy := DefaultPasswordPolicy()
+		policy.Symbols[EnginePXC] = "#!"
+		require.NoError(t, policy.Check())
+
+		password, err := policy.Generate(EnginePXC)
+		require.NoError(t, err)
+		assert.True(t, strings.ContainsAny(password, "#!"))
+
+		err = policy.Validate(EnginePSMDB, password)
+		assert.True(t, errors.Is(err, ErrPasswordPolicyViolated))
+	})
+
+	t.Run("Invalid policies", func(t *testing.T) {
+		t.Parallel()
+		policy := DefaultPasswordPolicy()
+		policy.Length = 4
+		assert.Error(t, policy.Check())
+
+		policy = DefaultPasswordPolicy()
+		policy.Lowercase, policy.Uppercase, policy.Digits = false, false, false
+		assert.Error(t, policy.Check())
+
+		policy = DefaultPasswordPolicy()
+		policy.Symbols[EnginePSMDB] = "#a"
+		assert.Error(t, policy.Check())
+	})
+
+	t.Run("Validate", func(t *testing.T) {
+		t.Parallel()
+		policy := DefaultPasswordPolicy()
+		policy.Length = 10
+		for password, valid := range map[string]bool{
+			"abcDEF1234":  true,
+			"abcDEF123":   false, // too short
+			"abcdef1234":  false, // no uppercase letter
+			"abcDEFGHIJ":  false, // no digit
+			"abcDEF1234#": false, // symbol is not allowed
+		} {
+			err := policy.Validate(EnginePXC, password)
+			assert.Equal(t, valid, err == nil, password)
+		}
+
+		policy.Symbols[EnginePXC] = "€"
+		assert.Error(t, policy.Validate(EnginePXC, "abcDEF12€"), "length is counted in characters, not bytes")
+		assert.NoError(t, policy.Validate(EnginePXC, "abcDEF123€"))
+	})
+}
+
+func TestGeneratePasswords(t *testing.T) {
+	t.Parallel()
+
+	t.Run("All PXC passwords are generated", func(t *testing.T) {
+		t.Parallel()
+		secrets, err := generatePXCPasswords(nil)
+		require.NoError(t, err)
+		assert.Len(t, secrets, 7)
+		for key, password := range secrets {
+			assert.Len(t, password, defaultPasswordLength, key)
+		}
+	})
+
+	t.Run("PSMDB user names are kept", func(t *testing.T) {
+		t.Parallel()
+		secrets, err := generatePSMDBPasswords(DefaultPasswordPolicy())
+		require.NoError(t, err)
+		assert.Equal(t, "userAdmin", string(secrets["MONGODB_USER_ADMIN_USER"]))
+		assert.Len(t, secrets["MONGODB_USER_ADMIN_PASSWORD"], defaultPasswordLength)
+	})
+}
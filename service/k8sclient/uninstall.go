@@ -0,0 +1,166 @@
+// dbaas-controller
+// Copyright (C) 2020 Percona LLC
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package k8sclient
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/percona-platform/dbaas-controller/service/k8sclient/internal/psmdb"
+	"github.com/percona-platform/dbaas-controller/service/k8sclient/internal/pxc"
+)
+
+// ErrOperatorInUse is returned when operator can't be uninstalled because database clusters exist.
+var ErrOperatorInUse = errors.New("operator manages database clusters")
+
+var (
+	// documentSeparatorRegexp matches separator of YAML documents.
+	documentSeparatorRegexp = regexp.MustCompile(`(?m)^---\s*$`) //nolint:gochecknoglobals
+	// kindRegexp matches top level kind of Kubernetes manifest.
+	kindRegexp = regexp.MustCompile(`(?m)^kind:\s*["']?(\w+)`) //nolint:gochecknoglobals
+)
+
+// UninstallOperatorParams contains parameters for uninstalling an operator.
+type UninstallOperatorParams struct {
+	Operator  Engine
+	Manifests ManifestSource
+	// Force uninstalls the operator even if database clusters exist.
+	Force bool
+	// RemoveCRDs removes custom resource definitions and so all database clusters of the operator.
+	RemoveCRDs bool
+}
+
+// UninstallReport describes what was removed by operator uninstall.
+type UninstallReport struct {
+	Version string
+	// Removed lists removed objects as kind.group/name.
+	Removed []string
+	// Clusters lists database clusters existing when the operator was uninstalled.
+	Clusters []string
+}
+
+// splitManifests splits multi-document YAML into CRDs and other manifests.
+func splitManifests(bundle []byte) (crds, others []byte) {
+	for _, document := range documentSeparatorRegexp.Split(string(bundle), -1) {
+		match := kindRegexp.FindStringSubmatch(document)
+		if match == nil {
+			continue
+		}
+		document = "---\n" + strings.TrimSpace(document) + "\n"
+		if match[1] == "CustomResourceDefinition" {
+			crds = append(crds, document...)
+		} else {
+			others = append(others, document...)
+		}
+	}
+	return crds, others
+}
+
+// parseDeleted returns objects from `kubectl delete` output lines like `deployment.apps "name" deleted`.
+func parseDeleted(out []byte) []string {
+	var deleted []string
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if !strings.HasSuffix(line, " deleted") {
+			continue
+		}
+		line = strings.TrimSuffix(line, " deleted")
+		deleted = append(deleted, strings.Replace(strings.ReplaceAll(line, `"`, ""), " ", "/", 1))
+	}
+	return deleted
+}
+
+// operatorClusters returns names of database clusters managed by the operator.
+func (c *K8sClient) operatorClusters(ctx context.Context, operator Engine) ([]string, error) {
+	var names []string
+	switch operator {
+	case EnginePXC:
+		var list pxc.PerconaXtraDBClusterList
+		if err := c.kubeCtl.Get(ctx, pxc.PerconaXtraDBClusterKind, "", &list); err != nil {
+			return nil, errors.Wrap(err, "couldn't get percona XtraDB clusters")
+		}
+		for _, cluster := range list.Items {
+			names = append(names, cluster.Name)
+		}
+	case EnginePSMDB:
+		var list psmdb.PerconaServerMongoDBList
+		if err := c.kubeCtl.Get(ctx, psmdb.PerconaServerMongoDBKind, "", &list); err != nil {
+			return nil, errors.Wrap(err, "couldn't get percona server MongoDB clusters")
+		}
+		for _, cluster := range list.Items {
+			names = append(names, cluster.Name)
+		}
+	default:
+		return nil, errors.Errorf("unknown operator %q", operator)
+	}
+	return names, nil
+}
+
+// UninstallOperator removes operator deployment and RBAC applied from the bundle of installed operator version,
+// and optionally CRDs. It refuses to uninstall the operator if database clusters exist unless forced.
+func (c *K8sClient) UninstallOperator(ctx context.Context, params *UninstallOperatorParams) (*UninstallReport, error) {
+	operators, err := c.CheckOperators(ctx)
+	if err != nil {
+		return nil, err
+	}
+	version := operators.PXCOperatorVersion
+	if params.Operator == EnginePSMDB {
+		version = operators.PsmdbOperatorVersion
+	}
+	if version == "" {
+		return nil, errors.Wrapf(ErrNotFound, "%s operator is not installed", params.Operator)
+	}
+	report := &UninstallReport{Version: version}
+
+	report.Clusters, err = c.operatorClusters(ctx, params.Operator)
+	if err != nil {
+		return nil, err
+	}
+	if len(report.Clusters) > 0 && !params.Force {
+		return nil, errors.Wrapf(ErrOperatorInUse, "%s operator can't be uninstalled, delete clusters first: %s",
+			params.Operator, strings.Join(report.Clusters, ", "))
+	}
+
+	bundle, err := params.Manifests.Manifest(ctx, version, "bundle.yaml")
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to uninstall operator")
+	}
+	crds, others := splitManifests(bundle)
+
+	// CRDs are removed first while the operator is still running to handle finalizers of database clusters.
+	manifests := [][]byte{others}
+	if params.RemoveCRDs {
+		manifests = [][]byte{crds, others}
+	}
+	for _, manifest := range manifests {
+		if len(manifest) == 0 {
+			continue
+		}
+		out, err := c.kubeCtl.Run(ctx, []string{"delete", "--ignore-not-found", "-f", "-"}, manifest)
+		if err != nil {
+			return report, errors.Wrap(err, "failed to uninstall operator")
+		}
+		report.Removed = append(report.Removed, parseDeleted(out)...)
+	}
+	return report, nil
+}
@@ -0,0 +1,80 @@
+// dbaas-controller
+// Copyright (C) 2020 Percona LLC
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package k8sclient
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSplitManifests(t *testing.T) {
+	t.Parallel()
+	bundle := []byte(`apiVersion: apiextensions.k8s.io/v1
+kind: CustomResourceDefinition
+metadata:
+  name: perconaxtradbclusters.pxc.percona.com
+spec:
+  names:
+    kind: PerconaXtraDBCluster
+---
+# comment only
+---
+apiVersion: v1
+kind: ServiceAccount
+metadata:
+  name: percona-xtradb-cluster-operator
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: percona-xtradb-cluster-operator
+`)
+	crds, others := splitManifests(bundle)
+	assert.Equal(t, `---
+apiVersion: apiextensions.k8s.io/v1
+kind: CustomResourceDefinition
+metadata:
+  name: perconaxtradbclusters.pxc.percona.com
+spec:
+  names:
+    kind: PerconaXtraDBCluster
+`, string(crds))
+	assert.Equal(t, `---
+apiVersion: v1
+kind: ServiceAccount
+metadata:
+  name: percona-xtradb-cluster-operator
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: percona-xtradb-cluster-operator
+`, string(others))
+}
+
+func TestParseDeleted(t *testing.T) {
+	t.Parallel()
+	out := []byte(`serviceaccount "percona-xtradb-cluster-operator" deleted
+deployment.apps "percona-xtradb-cluster-operator" deleted
+warning: something
+`)
+	assert.Equal(t, []string{
+		"serviceaccount/percona-xtradb-cluster-operator",
+		"deployment.apps/percona-xtradb-cluster-operator",
+	}, parseDeleted(out))
+}
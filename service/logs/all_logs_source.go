@@ -30,17 +30,11 @@ import (
 	"github.com/percona-platform/dbaas-controller/service/k8sclient/common"
 )
 
-// overallLinesLimit defines how many last lines of logs we should return upon
-// calling allLogsSource's method getLogs.
-const overallLinesLimit = 1000
-
 // allLogsSource implements source interface, it gets all logs from all
 // cluster's containers. It also gets events out of all cluster's pods.
-type allLogsSource struct{}
-
-type tuple struct {
-	statuses   []common.ContainerStatus
-	containers []common.ContainerSpec
+type allLogsSource struct {
+	// opts are options of containers logs requests, may be nil.
+	opts *k8sclient.LogsOptions
 }
 
 // getLogs gets all logs from all cluster's containers and events from all pods.
@@ -56,75 +50,34 @@ func (a *allLogsSource) getLogs(
 			errors.Wrap(err, "failed to get pods").Error(),
 		)
 	}
+	events, err := client.GetClusterEvents(ctx, clusterName)
+	if err != nil {
+		return nil, status.Error(codes.Internal, errors.Wrap(err, "failed to get events").Error())
+	}
+	podEvents, otherEvents := eventsLines(events)
+
 	// Every pod has at least one contaier, set cap to that value.
 	response := make([]*controllerv1beta1.Logs, 0, len(pods.Items))
-	for _, pod := range pods.Items {
-		tuples := []tuple{
-			{
-				statuses:   pod.Status.ContainerStatuses,
-				containers: pod.Spec.Containers,
-			},
-			{
-				statuses:   pod.Status.InitContainerStatuses,
-				containers: pod.Spec.InitContainers,
-			},
-		}
-		// Get all logs from all regular containers and all init containers.
-		for _, t := range tuples {
-			for _, container := range t.containers {
-				logs, err := client.GetLogs(
-					ctx, t.statuses, pod.Name, container.Name)
+	for i := range pods.Items {
+		pod := &pods.Items[i]
+		// Get all logs from all regular containers and all init containers,
+		// including logs of previous instances of restarted containers.
+		for _, containers := range [][]common.ContainerSpec{pod.Spec.Containers, pod.Spec.InitContainers} {
+			for _, container := range containers {
+				logs, err := containerLogs(ctx, client, pod, container.Name, a.opts)
 				if err != nil {
-					return nil, status.Error(
-						codes.Internal,
-						errors.Wrap(err, "failed to get logs").Error(),
-					)
+					return nil, status.Error(codes.Internal, err.Error())
 				}
-				response = append(response, &controllerv1beta1.Logs{
-					Pod:       pod.Name,
-					Container: container.Name,
-					Logs:      logs,
-				})
+				response = append(response, logs...)
 			}
 		}
 
-		// Get pod's events.
-		events, err := client.GetEvents(ctx, pod.Name)
-		if err != nil {
-			return nil, status.Error(codes.Internal, errors.Wrap(err, "failed to get events").Error())
-		}
-		response = append(response, &controllerv1beta1.Logs{
-			Pod:       pod.Name,
-			Container: "",
-			Logs:      events,
-		})
+		// Add pod's events.
+		response = append(response, podEventsLogs(pod.Name, podEvents[pod.Name]))
+	}
+	if logs := clusterEventsLogs(otherEvents); logs != nil {
+		response = append(response, logs)
 	}
 
-	// Limit number of overall log lines.
-	limitLines(response, overallLinesLimit)
 	return response, nil
 }
-
-// limitLines limits each entry's logs lines count in the way the overall sum of
-// all log lines is equal to given limit.
-func limitLines(logs []*controllerv1beta1.Logs, limit int) {
-	counts := make([]int, len(logs))
-	lastSum := -1
-	var newSum int
-	for newSum < limit && newSum > lastSum {
-		lastSum = newSum
-		for i, item := range logs {
-			if counts[i] < len(item.Logs) {
-				counts[i]++
-				newSum++
-				if newSum == limit {
-					break
-				}
-			}
-		}
-	}
-	// Do the actual slicing.
-	for i, item := range logs {
-		logs[i].Logs = item.Logs[len(item.Logs)-counts[i]:]
-	}
-}
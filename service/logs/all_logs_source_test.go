@@ -117,7 +117,7 @@ func TestLimitLines(t *testing.T) {
 	}
 
 	for _, tc := range testCases {
-		limitLines(tc.input, tc.limit)
+		limitLines(tc.input, nil, Limits{Lines: tc.limit})
 		assert.Equal(t, tc.expected, tc.input)
 	}
 }
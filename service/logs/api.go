@@ -0,0 +1,176 @@
+// dbaas-controller
+// Copyright (C) 2020 Percona LLC
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package logs
+
+import (
+	"bufio"
+	"context"
+	"io"
+
+	controllerv1beta1 "github.com/percona-platform/dbaas-api/gen/controller"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+
+	"github.com/percona-platform/dbaas-controller/utils/jsonapi"
+)
+
+// APIServiceName is the name of gRPC service with logs methods which are not part of controller API.
+// Its messages are JSON objects, see jsonapi package.
+const APIServiceName = "percona.platform.dbaas.controller.logs.v1.LogsAPI"
+
+// APIServiceDesc describes gRPC service with logs methods which are not part of controller API.
+var APIServiceDesc = grpc.ServiceDesc{ //nolint:gochecknoglobals
+	ServiceName: APIServiceName,
+	HandlerType: (*interface{})(nil), // handlers require *Service
+	Methods: []grpc.MethodDesc{
+		jsonapi.UnaryMethod(APIServiceName, "GetLogsWithLimits", func() interface{} { return new(GetLogsWithLimitsRequest) },
+			func(ctx context.Context, srv interface{}, req interface{}) (interface{}, error) {
+				r := req.(*GetLogsWithLimitsRequest)
+				return srv.(*Service).GetLogsWithLimits(ctx, &controllerv1beta1.GetLogsRequest{
+					KubeAuth:    &controllerv1beta1.KubeAuth{Kubeconfig: r.Kubeconfig},
+					ClusterName: r.ClusterName,
+				}, r.Limits)
+			}),
+		jsonapi.UnaryMethod(APIServiceName, "SearchLogs", func() interface{} { return new(SearchLogsRequest) },
+			func(ctx context.Context, srv interface{}, req interface{}) (interface{}, error) {
+				return srv.(*Service).SearchLogs(ctx, req.(*SearchLogsRequest))
+			}),
+	},
+	Streams: []grpc.StreamDesc{
+		jsonapi.ServerStreamMethod("TailLogs", func() interface{} { return new(TailLogsRequest) },
+			func(srv interface{}, req interface{}, stream *jsonapi.ServerStream) error {
+				return srv.(*Service).TailLogs(req.(*TailLogsRequest), &logsStream{stream})
+			}),
+		jsonapi.ServerStreamMethod("GetSupportBundle", func() interface{} { return new(SupportBundleRequest) },
+			func(srv interface{}, req interface{}, stream *jsonapi.ServerStream) error {
+				w := bufio.NewWriterSize(&bundleStream{stream}, supportBundleChunkSize)
+				if err := srv.(*Service).WriteSupportBundle(stream.Context(), req.(*SupportBundleRequest), w); err != nil {
+					return err
+				}
+				return w.Flush()
+			}),
+	},
+	Metadata: "service/logs/api.go",
+}
+
+// supportBundleChunkSize is the maximal size of support bundle chunks sent by GetSupportBundle.
+const supportBundleChunkSize = 256 * 1024
+
+// logsStream sends logs as proto messages.
+type logsStream struct {
+	*jsonapi.ServerStream
+}
+
+// Send implements LogsStream interface.
+func (s *logsStream) Send(logs *controllerv1beta1.Logs) error {
+	return s.ServerStream.Send(logs)
+}
+
+// bundleStream sends every write of the support bundle as a chunk of bytes.
+type bundleStream struct {
+	*jsonapi.ServerStream
+}
+
+// Write implements io.Writer interface.
+func (s *bundleStream) Write(p []byte) (int, error) {
+	if err := s.Send(wrapperspb.Bytes(p)); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// RegisterAPIServer registers gRPC service with logs methods which are not part of controller API.
+func RegisterAPIServer(s *grpc.Server, srv *Service) {
+	s.RegisterService(&APIServiceDesc, srv)
+}
+
+// APIClient is the client of gRPC service with logs methods which are not part of controller API.
+type APIClient struct {
+	cc grpc.ClientConnInterface
+}
+
+// NewAPIClient returns new APIClient instance.
+func NewAPIClient(cc grpc.ClientConnInterface) *APIClient {
+	return &APIClient{cc: cc}
+}
+
+// TailLogsClient receives logs streamed by TailLogs.
+type TailLogsClient struct {
+	stream *jsonapi.ClientStream
+}
+
+// Recv returns the next log line. It returns io.EOF when logs end.
+func (c *TailLogsClient) Recv() (*controllerv1beta1.Logs, error) {
+	logs := new(controllerv1beta1.Logs)
+	if err := c.stream.Recv(logs); err != nil {
+		return nil, err
+	}
+	return logs, nil
+}
+
+// TailLogs streams logs of the cluster containers.
+func (c *APIClient) TailLogs(ctx context.Context, req *TailLogsRequest, opts ...grpc.CallOption) (*TailLogsClient, error) {
+	stream, err := jsonapi.NewClientStream(ctx, c.cc, &APIServiceDesc.Streams[0], "/"+APIServiceName+"/TailLogs", req, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &TailLogsClient{stream: stream}, nil
+}
+
+// GetSupportBundle writes tar.gz archive with diagnostics of the database cluster to w.
+func (c *APIClient) GetSupportBundle(ctx context.Context, req *SupportBundleRequest, w io.Writer, opts ...grpc.CallOption) error {
+	stream, err := jsonapi.NewClientStream(ctx, c.cc, &APIServiceDesc.Streams[1], "/"+APIServiceName+"/GetSupportBundle", req, opts...)
+	if err != nil {
+		return err
+	}
+	for {
+		chunk := new(wrapperspb.BytesValue)
+		err := stream.Recv(chunk)
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if _, err := w.Write(chunk.Value); err != nil {
+			return err
+		}
+	}
+}
+
+// GetLogsWithLimits returns logs of the cluster limited by given limits instead of the service ones.
+func (c *APIClient) GetLogsWithLimits(
+	ctx context.Context, req *GetLogsWithLimitsRequest, opts ...grpc.CallOption,
+) (*controllerv1beta1.GetLogsResponse, error) {
+	res := new(controllerv1beta1.GetLogsResponse)
+	if err := jsonapi.Invoke(ctx, c.cc, "/"+APIServiceName+"/GetLogsWithLimits", req, res, opts...); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// SearchLogs returns lines of logs and events of the cluster matching the filter.
+func (c *APIClient) SearchLogs(
+	ctx context.Context, req *SearchLogsRequest, opts ...grpc.CallOption,
+) (*controllerv1beta1.GetLogsResponse, error) {
+	res := new(controllerv1beta1.GetLogsResponse)
+	if err := jsonapi.Invoke(ctx, c.cc, "/"+APIServiceName+"/SearchLogs", req, res, opts...); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
@@ -0,0 +1,73 @@
+// dbaas-controller
+// Copyright (C) 2020 Percona LLC
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package logs
+
+import (
+	"bytes"
+	"context"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestAPI(t *testing.T) {
+	t.Parallel()
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	server := grpc.NewServer()
+	RegisterAPIServer(server, NewService(message.NewPrinter(language.English), Limits{}))
+	go server.Serve(lis) //nolint:errcheck
+	defer server.Stop()
+
+	ctx := context.Background()
+	conn, err := grpc.DialContext(ctx, lis.Addr().String(), grpc.WithInsecure())
+	require.NoError(t, err)
+	defer conn.Close() //nolint:errcheck
+
+	client := NewAPIClient(conn)
+	stream, err := client.TailLogs(ctx, &TailLogsRequest{Kubeconfig: "{}", ClusterName: "cluster", TailLines: -1})
+	require.NoError(t, err)
+	_, err = stream.Recv()
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	_, err = client.GetLogsWithLimits(ctx, &GetLogsWithLimitsRequest{
+		Kubeconfig:  "{}",
+		ClusterName: "cluster",
+		Limits:      Limits{Lines: 100},
+	})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err), "all weights are zero")
+
+	_, err = client.SearchLogs(ctx, &SearchLogsRequest{
+		Kubeconfig:  "{}",
+		ClusterName: "cluster",
+		Filter:      Filter{Pattern: "("},
+	})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err), "invalid pattern")
+
+	var bundle bytes.Buffer
+	err = client.GetSupportBundle(ctx, &SupportBundleRequest{Kubeconfig: "{}"}, &bundle)
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	assert.Zero(t, bundle.Len())
+}
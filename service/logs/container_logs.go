@@ -0,0 +1,116 @@
+// dbaas-controller
+// Copyright (C) 2020 Percona LLC
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package logs
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	controllerv1beta1 "github.com/percona-platform/dbaas-api/gen/controller"
+	"github.com/pkg/errors"
+
+	"github.com/percona-platform/dbaas-controller/service/k8sclient"
+	"github.com/percona-platform/dbaas-controller/service/k8sclient/common"
+)
+
+// previousContainerSuffix is appended to container name of logs of the previous container instance.
+const previousContainerSuffix = " (previous)"
+
+// terminationSummary formats details of the terminated container,
+// like "reason: OOMKilled, exit code: 137, finished at: 2021-06-01T12:00:00Z".
+func terminationSummary(details *common.ContainerStateDetails) string {
+	parts := make([]string, 0, 4)
+	if details.Reason != "" {
+		parts = append(parts, "reason: "+details.Reason)
+	}
+	parts = append(parts, fmt.Sprintf("exit code: %d", details.ExitCode))
+	if details.FinishedAt != nil {
+		parts = append(parts, "finished at: "+details.FinishedAt.UTC().Format(time.RFC3339))
+	}
+	if details.Message != "" {
+		parts = append(parts, "message: "+strings.TrimSpace(details.Message))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// containerStatusLine returns a line describing restarts and the last termination of the container.
+// The line is appended to the container's logs, so it survives limiting lines which keeps the last ones.
+func containerStatusLine(status *common.ContainerStatus) string {
+	line := fmt.Sprintf("[container status] restarts: %d", status.RestartCount)
+	if details, ok := status.LastTerminationState[string(common.ContainerStateTerminated)]; ok {
+		line += "; last termination: " + terminationSummary(&details)
+	}
+	return line
+}
+
+// previousTerminationLine returns a line describing how the previous container instance terminated.
+// It returns an empty string if the termination state is unknown.
+func previousTerminationLine(status *common.ContainerStatus) string {
+	details, ok := status.LastTerminationState[string(common.ContainerStateTerminated)]
+	if !ok {
+		return ""
+	}
+	return "[container terminated] " + terminationSummary(&details)
+}
+
+// containerLogs returns logs of the pod's container. If the container was restarted,
+// it also returns logs of the previous container instance. Both entries end with
+// a line describing restarts and the last termination of the container. Opts may be nil.
+func containerLogs(
+	ctx context.Context,
+	client *k8sclient.K8sClient,
+	pod *common.Pod,
+	container string,
+	opts *k8sclient.LogsOptions,
+) ([]*controllerv1beta1.Logs, error) {
+	var statuses []common.ContainerStatus
+	status := containerStatus(pod, container)
+	if status != nil {
+		statuses = []common.ContainerStatus{*status}
+	}
+	logs, err := client.GetLogs(ctx, statuses, pod.Name, container, opts)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get logs")
+	}
+	if status == nil {
+		return []*controllerv1beta1.Logs{{Pod: pod.Name, Container: container, Logs: logs}}, nil
+	}
+
+	res := []*controllerv1beta1.Logs{{
+		Pod:       pod.Name,
+		Container: container,
+		Logs:      append(logs, containerStatusLine(status)),
+	}}
+	if status.RestartCount == 0 {
+		return res, nil
+	}
+
+	previous, err := client.GetPreviousLogs(ctx, pod.Name, container, opts)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get previous logs")
+	}
+	if line := previousTerminationLine(status); line != "" {
+		previous = append(previous, line)
+	}
+	return append(res, &controllerv1beta1.Logs{
+		Pod:       pod.Name,
+		Container: container + previousContainerSuffix,
+		Logs:      previous,
+	}), nil
+}
@@ -0,0 +1,71 @@
+// dbaas-controller
+// Copyright (C) 2020 Percona LLC
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package logs
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/percona-platform/dbaas-controller/service/k8sclient/common"
+)
+
+func TestContainerStatusLines(t *testing.T) {
+	t.Parallel()
+	finished := time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	for _, tt := range []struct {
+		name        string
+		status      common.ContainerStatus
+		statusLine  string
+		previousEnd string
+	}{{
+		name:       "never restarted",
+		status:     common.ContainerStatus{Name: "pxc"},
+		statusLine: "[container status] restarts: 0",
+	}, {
+		name: "OOM killed",
+		status: common.ContainerStatus{
+			Name:         "pxc",
+			RestartCount: 3,
+			LastTerminationState: map[string]common.ContainerStateDetails{
+				"terminated": {Reason: "OOMKilled", ExitCode: 137, FinishedAt: &finished},
+			},
+		},
+		statusLine:  "[container status] restarts: 3; last termination: reason: OOMKilled, exit code: 137, finished at: 2021-06-01T12:00:00Z",
+		previousEnd: "[container terminated] reason: OOMKilled, exit code: 137, finished at: 2021-06-01T12:00:00Z",
+	}, {
+		name: "error with message",
+		status: common.ContainerStatus{
+			Name:         "pxc",
+			RestartCount: 1,
+			LastTerminationState: map[string]common.ContainerStateDetails{
+				"terminated": {Reason: "Error", ExitCode: 1, Message: "failed to start\n"},
+			},
+		},
+		statusLine:  "[container status] restarts: 1; last termination: reason: Error, exit code: 1, message: failed to start",
+		previousEnd: "[container terminated] reason: Error, exit code: 1, message: failed to start",
+	}} {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, tt.statusLine, containerStatusLine(&tt.status))
+			assert.Equal(t, tt.previousEnd, previousTerminationLine(&tt.status))
+		})
+	}
+}
@@ -0,0 +1,82 @@
+// dbaas-controller
+// Copyright (C) 2020 Percona LLC
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package logs
+
+import (
+	"fmt"
+	"time"
+
+	controllerv1beta1 "github.com/percona-platform/dbaas-api/gen/controller"
+
+	"github.com/percona-platform/dbaas-controller/service/k8sclient/common"
+)
+
+// podKind is a kind of events' involved object for pods.
+const podKind = "Pod"
+
+// eventLine formats event as a single log line.
+func eventLine(event *common.Event) string {
+	lastSeen := "<unknown>"
+	if t := event.LastSeen(); !t.IsZero() {
+		lastSeen = t.UTC().Format(time.RFC3339)
+	}
+	line := fmt.Sprintf("%s %s %s %s/%s", lastSeen, event.Type, event.Reason, event.InvolvedObject.Kind, event.InvolvedObject.Name)
+	if event.Count > 1 {
+		line += fmt.Sprintf(" (x%d since %s)", event.Count, event.FirstSeen().UTC().Format(time.RFC3339))
+	}
+	return line + ": " + event.Message
+}
+
+// eventsLines groups events as log lines by pod names. Events of other objects are returned separately.
+func eventsLines(events []common.Event) (pods map[string][]string, other []string) {
+	pods = make(map[string][]string)
+	other = []string{}
+	for i := range events {
+		event := &events[i]
+		if event.InvolvedObject.Kind == podKind {
+			pods[event.InvolvedObject.Name] = append(pods[event.InvolvedObject.Name], eventLine(event))
+			continue
+		}
+		other = append(other, eventLine(event))
+	}
+	return pods, other
+}
+
+// podEventsLogs returns logs entry with given pod's events.
+func podEventsLogs(pod string, lines []string) *controllerv1beta1.Logs {
+	if lines == nil {
+		lines = []string{}
+	}
+	return &controllerv1beta1.Logs{
+		Pod:       pod,
+		Container: "",
+		Logs:      lines,
+	}
+}
+
+// clusterEventsLogs returns logs entry with events of cluster's custom resource, stateful sets and volume claims.
+// Those events don't belong to any pod, so both pod and container are empty. It returns nil if there are no events.
+func clusterEventsLogs(lines []string) *controllerv1beta1.Logs {
+	if len(lines) == 0 {
+		return nil
+	}
+	return &controllerv1beta1.Logs{
+		Pod:       "",
+		Container: "",
+		Logs:      lines,
+	}
+}
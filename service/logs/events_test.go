@@ -0,0 +1,65 @@
+// dbaas-controller
+// Copyright (C) 2020 Percona LLC
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package logs
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/percona-platform/dbaas-controller/service/k8sclient/common"
+)
+
+func TestEventsLines(t *testing.T) {
+	t.Parallel()
+	first := time.Date(2021, 6, 1, 11, 0, 0, 0, time.UTC)
+	last := time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	events := []common.Event{{
+		InvolvedObject: common.ObjectReference{Kind: "PerconaXtraDBCluster", Name: "cluster"},
+		Type:           common.EventTypeNormal,
+		Reason:         "Created",
+		Message:        "cluster created",
+		EventTime:      &first,
+	}, {
+		InvolvedObject: common.ObjectReference{Kind: "Pod", Name: "cluster-pxc-0"},
+		Type:           common.EventTypeWarning,
+		Reason:         "BackOff",
+		Message:        "Back-off restarting failed container",
+		FirstTimestamp: &first,
+		LastTimestamp:  &last,
+		Count:          5,
+	}, {
+		InvolvedObject: common.ObjectReference{Kind: "PersistentVolumeClaim", Name: "datadir-cluster-pxc-0"},
+		Type:           common.EventTypeWarning,
+		Reason:         "ProvisioningFailed",
+		Message:        "no storage class",
+	}}
+
+	pods, other := eventsLines(events)
+	assert.Equal(t, map[string][]string{
+		"cluster-pxc-0": {"2021-06-01T12:00:00Z Warning BackOff Pod/cluster-pxc-0 (x5 since 2021-06-01T11:00:00Z): Back-off restarting failed container"},
+	}, pods)
+	assert.Equal(t, []string{
+		"2021-06-01T11:00:00Z Normal Created PerconaXtraDBCluster/cluster: cluster created",
+		"<unknown> Warning ProvisioningFailed PersistentVolumeClaim/datadir-cluster-pxc-0: no storage class",
+	}, other)
+
+	assert.Equal(t, []string{}, podEventsLogs("cluster-pxc-1", pods["cluster-pxc-1"]).Logs)
+	assert.Nil(t, clusterEventsLogs(nil))
+}
@@ -0,0 +1,191 @@
+// dbaas-controller
+// Copyright (C) 2020 Percona LLC
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package logs
+
+import (
+	"context"
+	"time"
+
+	controllerv1beta1 "github.com/percona-platform/dbaas-api/gen/controller"
+	"github.com/pkg/errors"
+
+	"github.com/percona-platform/dbaas-controller/service/k8sclient"
+	"github.com/percona-platform/dbaas-controller/service/k8sclient/common"
+)
+
+const (
+	// pendingTimeout defines how long a pod can stay pending before it's considered failing.
+	pendingTimeout = 5 * time.Minute
+	// recentTerminationTimeout defines how long a restarted container is considered failing
+	// after its previous instance failed.
+	recentTerminationTimeout = 15 * time.Minute
+)
+
+// failingWaitingReasons are reasons of waiting containers which won't start without intervention.
+var failingWaitingReasons = map[string]struct{}{ //nolint:gochecknoglobals
+	"CrashLoopBackOff":           {},
+	"ErrImagePull":               {},
+	"ImagePullBackOff":           {},
+	"InvalidImageName":           {},
+	"CreateContainerConfigError": {},
+	"CreateContainerError":       {},
+	"RunContainerError":          {},
+}
+
+// failingTerminatedReasons are reasons of container termination indicating a failure.
+var failingTerminatedReasons = map[string]struct{}{ //nolint:gochecknoglobals
+	"Error":              {},
+	"OOMKilled":          {},
+	"ContainerCannotRun": {},
+	"DeadlineExceeded":   {},
+}
+
+// failingPodsSource implements source interface, it gets logs of failing containers
+// and events of failing pods only.
+type failingPodsSource struct {
+	now func() time.Time
+}
+
+// newFailingPodsSource returns new failingPodsSource.
+func newFailingPodsSource() *failingPodsSource {
+	return &failingPodsSource{now: time.Now}
+}
+
+// terminationFailed returns true if the container terminated because of a failure.
+func terminationFailed(terminated common.ContainerStateDetails) bool {
+	_, ok := failingTerminatedReasons[terminated.Reason]
+	return ok || terminated.ExitCode != 0
+}
+
+// containerFailing returns true if the container is crashing, failed to start, is not ready
+// or its previous instance failed recently, for example, was OOM killed.
+func containerFailing(status *common.ContainerStatus, init bool, now time.Time) bool {
+	if waiting, ok := status.State[string(common.ContainerStateWaiting)]; ok {
+		if _, ok := failingWaitingReasons[waiting.Reason]; ok {
+			return true
+		}
+	}
+	if terminated, ok := status.State[string(common.ContainerStateTerminated)]; ok && terminationFailed(terminated) {
+		return true
+	}
+	if terminated, ok := status.LastTerminationState[string(common.ContainerStateTerminated)]; ok && terminationFailed(terminated) {
+		if terminated.FinishedAt != nil && now.Sub(*terminated.FinishedAt) < recentTerminationTimeout {
+			return true
+		}
+	}
+	// Completed init containers are never ready.
+	_, running := status.State[string(common.ContainerStateRunning)]
+	return !init && running && !status.Ready
+}
+
+// podFailure returns true if the pod is failing and names of its failing containers.
+// Pod is failing if it failed, is pending for too long, is not ready or has failing containers.
+func podFailure(pod *common.Pod, now time.Time) (bool, []string) {
+	var containers []string
+	for i := range pod.Status.InitContainerStatuses {
+		if containerFailing(&pod.Status.InitContainerStatuses[i], true, now) {
+			containers = append(containers, pod.Status.InitContainerStatuses[i].Name)
+		}
+	}
+	for i := range pod.Status.ContainerStatuses {
+		if containerFailing(&pod.Status.ContainerStatuses[i], false, now) {
+			containers = append(containers, pod.Status.ContainerStatuses[i].Name)
+		}
+	}
+	if len(containers) != 0 {
+		return true, containers
+	}
+
+	switch pod.Status.Phase {
+	case common.PodPhaseFailed:
+		return true, nil
+	case common.PodPhasePending:
+		since := pod.CreationTimestamp
+		if pod.Status.StartTime != nil {
+			since = pod.Status.StartTime
+		}
+		return since != nil && now.Sub(*since) > pendingTimeout, nil
+	case common.PodPhaseRunning:
+		for _, condition := range pod.Status.Conditions {
+			if condition.Type == common.PodConditionReady && condition.Status != common.ConditionTrue {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
+
+// containerStatus returns status of the pod's container.
+func containerStatus(pod *common.Pod, container string) *common.ContainerStatus {
+	for _, statuses := range [][]common.ContainerStatus{pod.Status.ContainerStatuses, pod.Status.InitContainerStatuses} {
+		for i := range statuses {
+			if statuses[i].Name == container {
+				return &statuses[i]
+			}
+		}
+	}
+	return nil
+}
+
+// getLogs gets current and previous logs of failing containers and events of failing pods.
+// It returns nothing if all pods of the cluster are healthy.
+func (f *failingPodsSource) getLogs(
+	ctx context.Context,
+	client *k8sclient.K8sClient,
+	clusterName string,
+) ([]*controllerv1beta1.Logs, error) {
+	pods, err := client.GetPods(ctx, "-lapp.kubernetes.io/instance="+clusterName)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get pods")
+	}
+
+	now := f.now()
+	var response []*controllerv1beta1.Logs
+	var podEvents map[string][]string
+	var otherEvents []string
+	for i := range pods.Items {
+		pod := &pods.Items[i]
+		failing, containers := podFailure(pod, now)
+		if !failing {
+			continue
+		}
+
+		// Events are fetched once, only if there is at least one failing pod.
+		if podEvents == nil {
+			events, err := client.GetClusterEvents(ctx, clusterName)
+			if err != nil {
+				return nil, errors.Wrap(err, "failed to get events")
+			}
+			podEvents, otherEvents = eventsLines(events)
+		}
+
+		for _, container := range containers {
+			logs, err := containerLogs(ctx, client, pod, container, nil)
+			if err != nil {
+				return nil, err
+			}
+			response = append(response, logs...)
+		}
+
+		response = append(response, podEventsLogs(pod.Name, podEvents[pod.Name]))
+	}
+	if logs := clusterEventsLogs(otherEvents); logs != nil {
+		response = append(response, logs)
+	}
+
+	return response, nil
+}
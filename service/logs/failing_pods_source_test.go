@@ -0,0 +1,158 @@
+// dbaas-controller
+// Copyright (C) 2020 Percona LLC
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package logs
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/percona-platform/dbaas-controller/service/k8sclient/common"
+)
+
+func TestPodFailure(t *testing.T) {
+	t.Parallel()
+	now := time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC)
+	created := func(ago time.Duration) common.ObjectMeta {
+		ts := now.Add(-ago)
+		return common.ObjectMeta{Name: "pod", CreationTimestamp: &ts}
+	}
+	running := map[string]common.ContainerStateDetails{"running": {}}
+	recent, old := now.Add(-time.Minute), now.Add(-time.Hour)
+	ready := []common.PodCondition{{Type: common.PodConditionReady, Status: common.ConditionTrue}}
+
+	for _, tt := range []struct {
+		name       string
+		pod        common.Pod
+		failing    bool
+		containers []string
+	}{{
+		name: "healthy",
+		pod: common.Pod{Status: common.PodStatus{
+			Phase:      common.PodPhaseRunning,
+			Conditions: ready,
+			InitContainerStatuses: []common.ContainerStatus{
+				{Name: "init", State: map[string]common.ContainerStateDetails{"terminated": {Reason: "Completed"}}},
+			},
+			ContainerStatuses: []common.ContainerStatus{{Name: "pxc", Ready: true, State: running, RestartCount: 1}},
+		}},
+	}, {
+		name: "crash loop",
+		pod: common.Pod{Status: common.PodStatus{
+			Phase: common.PodPhaseRunning,
+			ContainerStatuses: []common.ContainerStatus{
+				{Name: "pxc", State: map[string]common.ContainerStateDetails{"waiting": {Reason: "CrashLoopBackOff"}}},
+				{Name: "pmm-client", Ready: true, State: running},
+			},
+		}},
+		failing:    true,
+		containers: []string{"pxc"},
+	}, {
+		name: "failed init container",
+		pod: common.Pod{Status: common.PodStatus{
+			Phase: common.PodPhasePending,
+			InitContainerStatuses: []common.ContainerStatus{
+				{Name: "init", State: map[string]common.ContainerStateDetails{"terminated": {Reason: "Error", ExitCode: 1}}},
+			},
+		}},
+		failing:    true,
+		containers: []string{"init"},
+	}, {
+		name: "OOM killed before",
+		pod: common.Pod{Status: common.PodStatus{
+			Phase:      common.PodPhaseRunning,
+			Conditions: ready,
+			ContainerStatuses: []common.ContainerStatus{{
+				Name:                 "mongod",
+				Ready:                true,
+				State:                running,
+				LastTerminationState: map[string]common.ContainerStateDetails{"terminated": {Reason: "OOMKilled", ExitCode: 137, FinishedAt: &recent}},
+				RestartCount:         3,
+			}},
+		}},
+		failing:    true,
+		containers: []string{"mongod"},
+	}, {
+		name: "OOM killed long ago",
+		pod: common.Pod{Status: common.PodStatus{
+			Phase:      common.PodPhaseRunning,
+			Conditions: ready,
+			ContainerStatuses: []common.ContainerStatus{{
+				Name:                 "mongod",
+				Ready:                true,
+				State:                running,
+				LastTerminationState: map[string]common.ContainerStateDetails{"terminated": {Reason: "OOMKilled", ExitCode: 137, FinishedAt: &old}},
+				RestartCount:         3,
+			}},
+		}},
+	}, {
+		name: "restarted after clean exit",
+		pod: common.Pod{Status: common.PodStatus{
+			Phase:      common.PodPhaseRunning,
+			Conditions: ready,
+			ContainerStatuses: []common.ContainerStatus{{
+				Name:                 "pxc",
+				Ready:                true,
+				State:                running,
+				LastTerminationState: map[string]common.ContainerStateDetails{"terminated": {Reason: "Completed", FinishedAt: &recent}},
+				RestartCount:         1,
+			}},
+		}},
+	}, {
+		name: "not ready container",
+		pod: common.Pod{Status: common.PodStatus{
+			Phase:             common.PodPhaseRunning,
+			ContainerStatuses: []common.ContainerStatus{{Name: "haproxy", State: running}},
+		}},
+		failing:    true,
+		containers: []string{"haproxy"},
+	}, {
+		name: "not ready pod",
+		pod: common.Pod{Status: common.PodStatus{
+			Phase:             common.PodPhaseRunning,
+			Conditions:        []common.PodCondition{{Type: common.PodConditionReady, Status: "False"}},
+			ContainerStatuses: []common.ContainerStatus{{Name: "haproxy", Ready: true, State: running}},
+		}},
+		failing: true,
+	}, {
+		name: "pending for a short time",
+		pod: common.Pod{
+			ObjectMeta: created(time.Minute),
+			Status:     common.PodStatus{Phase: common.PodPhasePending},
+		},
+	}, {
+		name: "pending for too long",
+		pod: common.Pod{
+			ObjectMeta: created(time.Hour),
+			Status:     common.PodStatus{Phase: common.PodPhasePending},
+		},
+		failing: true,
+	}, {
+		name:    "failed",
+		pod:     common.Pod{Status: common.PodStatus{Phase: common.PodPhaseFailed}},
+		failing: true,
+	}} {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			failing, containers := podFailure(&tt.pod, now)
+			assert.Equal(t, tt.failing, failing)
+			assert.Equal(t, tt.containers, containers)
+		})
+	}
+}
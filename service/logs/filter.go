@@ -0,0 +1,295 @@
+// dbaas-controller
+// Copyright (C) 2020 Percona LLC
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package logs
+
+import (
+	"encoding/json"
+	"math"
+	"regexp"
+	"strings"
+	"time"
+
+	controllerv1beta1 "github.com/percona-platform/dbaas-api/gen/controller"
+	"github.com/pkg/errors"
+)
+
+// Severity is a severity level of a log line.
+type Severity int
+
+// Severity levels in ascending order.
+const (
+	SeverityUnknown Severity = iota
+	SeverityDebug
+	SeverityInfo
+	SeverityWarning
+	SeverityError
+	SeverityFatal
+)
+
+// contextSeparator separates non-adjacent groups of matching lines and their context.
+const contextSeparator = "--"
+
+// severityWords maps lowercase words used by mysqld, mongod, operators and events to severity levels.
+var severityWords = map[string]Severity{ //nolint:gochecknoglobals
+	"debug":    SeverityDebug,
+	"trace":    SeverityDebug,
+	"info":     SeverityInfo,
+	"note":     SeverityInfo,
+	"system":   SeverityInfo,
+	"normal":   SeverityInfo,
+	"warn":     SeverityWarning,
+	"warning":  SeverityWarning,
+	"err":      SeverityError,
+	"error":    SeverityError,
+	"fatal":    SeverityFatal,
+	"critical": SeverityFatal,
+	"panic":    SeverityFatal,
+	"dpanic":   SeverityFatal,
+}
+
+// mongodSeverities maps one letter severities of mongod to severity levels.
+var mongodSeverities = map[string]Severity{ //nolint:gochecknoglobals
+	"D": SeverityDebug,
+	"I": SeverityInfo,
+	"W": SeverityWarning,
+	"E": SeverityError,
+	"F": SeverityFatal,
+}
+
+// timeLayouts are layouts of timestamps at the beginning of log lines.
+var timeLayouts = []string{ //nolint:gochecknoglobals
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05.000-0700", // mongod before 4.4
+}
+
+// Filter defines which log lines are returned. Empty filter matches all lines.
+type Filter struct {
+	// Pattern is a regular expression lines should match.
+	Pattern string `json:"pattern,omitempty"`
+	// Substring is a string lines should contain.
+	Substring string `json:"substring,omitempty"`
+	// MinSeverity is the lowest severity of returned lines, a number from SeverityUnknown to SeverityFatal.
+	MinSeverity Severity `json:"minSeverity,omitempty"`
+	// Since and Until limit lines to the ones logged within the time window.
+	Since time.Time `json:"since,omitempty"`
+	Until time.Time `json:"until,omitempty"`
+	// ContextLines is the number of lines returned before and after every matching line.
+	ContextLines int `json:"contextLines,omitempty"`
+}
+
+// lineInfo contains information parsed from a log line.
+type lineInfo struct {
+	severity Severity
+	time     time.Time
+}
+
+// parseSeverity parses severity word, like "Warning", "[ERROR]" or mongod "W".
+func parseSeverity(word string) Severity {
+	word = strings.Trim(word, "[]:")
+	if s, ok := mongodSeverities[word]; ok {
+		return s
+	}
+	// mongod debug levels D1-D5.
+	if len(word) == 2 && word[0] == 'D' && word[1] >= '1' && word[1] <= '5' {
+		return SeverityDebug
+	}
+	return severityWords[strings.ToLower(word)]
+}
+
+// parseTime parses a timestamp in one of timeLayouts.
+func parseTime(s string) time.Time {
+	for _, layout := range timeLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+// parseJSONLine parses JSON log line of mongod 4.4+ or operators.
+func parseJSONLine(fields map[string]interface{}) lineInfo {
+	var info lineInfo
+	for _, key := range []string{"level", "severity", "s"} {
+		if s, ok := fields[key].(string); ok {
+			info.severity = parseSeverity(s)
+			break
+		}
+	}
+	switch ts := fields["ts"].(type) {
+	case float64:
+		sec, frac := math.Modf(ts)
+		info.time = time.Unix(int64(sec), int64(frac*1e9)).UTC()
+	case string:
+		info.time = parseTime(ts)
+	}
+	if t, ok := fields["t"].(map[string]interface{}); ok {
+		if date, ok := t["$date"].(string); ok {
+			info.time = parseTime(date)
+		}
+	}
+	return info
+}
+
+// parseLine parses severity and time of a log line. Text lines are expected to start with a timestamp
+// followed by severity within a few words, like mysqld "2021-06-01T12:00:00.000000Z 0 [Warning] ...".
+// JSON lines may be prefixed with timestamp added by kubectl.
+func parseLine(line string) lineInfo {
+	var info lineInfo
+	rest := line
+	if i := strings.IndexAny(rest, " \t"); i > 0 {
+		if t := parseTime(rest[:i]); !t.IsZero() {
+			info.time = t
+			rest = strings.TrimLeft(rest[i:], " \t")
+		}
+	}
+
+	if strings.HasPrefix(rest, "{") {
+		var fields map[string]interface{}
+		if err := json.Unmarshal([]byte(rest), &fields); err == nil {
+			jsonInfo := parseJSONLine(fields)
+			if jsonInfo.time.IsZero() {
+				jsonInfo.time = info.time
+			}
+			return jsonInfo
+		}
+	}
+	if info.time.IsZero() {
+		return info
+	}
+
+	words := strings.Fields(rest)
+	if len(words) > 3 {
+		words = words[:3]
+	}
+	for _, word := range words {
+		if s := parseSeverity(word); s != SeverityUnknown {
+			info.severity = s
+			break
+		}
+	}
+	return info
+}
+
+// lineMatcher filters log lines.
+type lineMatcher struct {
+	filter *Filter
+	re     *regexp.Regexp
+}
+
+// newLineMatcher validates the filter and creates a new lineMatcher.
+func newLineMatcher(filter *Filter) (*lineMatcher, error) {
+	if filter.ContextLines < 0 {
+		return nil, errors.New("context lines can't be negative")
+	}
+	if filter.MinSeverity < SeverityUnknown || filter.MinSeverity > SeverityFatal {
+		return nil, errors.Errorf("unknown severity %d", filter.MinSeverity)
+	}
+	if !filter.Since.IsZero() && !filter.Until.IsZero() && filter.Until.Before(filter.Since) {
+		return nil, errors.New("time window ends before it starts")
+	}
+	m := &lineMatcher{filter: filter}
+	if filter.Pattern != "" {
+		re, err := regexp.Compile(filter.Pattern)
+		if err != nil {
+			return nil, errors.Wrap(err, "invalid pattern")
+		}
+		m.re = re
+	}
+	return m, nil
+}
+
+// matches returns true if line with given information passes the filter.
+func (m *lineMatcher) matches(line string, info lineInfo) bool {
+	f := m.filter
+	if f.Substring != "" && !strings.Contains(line, f.Substring) {
+		return false
+	}
+	if m.re != nil && !m.re.MatchString(line) {
+		return false
+	}
+	if f.MinSeverity != SeverityUnknown && info.severity < f.MinSeverity {
+		return false
+	}
+	if !f.Since.IsZero() || !f.Until.IsZero() {
+		if info.time.IsZero() {
+			return false
+		}
+		if !f.Since.IsZero() && info.time.Before(f.Since) {
+			return false
+		}
+		if !f.Until.IsZero() && info.time.After(f.Until) {
+			return false
+		}
+	}
+	return true
+}
+
+// filterLines returns matching lines with context lines around them. Non-adjacent groups of lines
+// are separated with contextSeparator. Lines without severity and time, like continuation lines
+// of stack traces, get them from the previous line.
+func (m *lineMatcher) filterLines(lines []string) []string {
+	matched := make([]bool, len(lines))
+	var last lineInfo
+	for i, line := range lines {
+		info := parseLine(line)
+		if info.severity == SeverityUnknown && info.time.IsZero() {
+			info = last
+		}
+		last = info
+		matched[i] = m.matches(line, info)
+	}
+
+	res := []string{}
+	n := m.filter.ContextLines
+	end := -1 // index after the last returned line
+	for i := range lines {
+		if !matched[i] {
+			continue
+		}
+		start := i - n
+		if start < end {
+			start = end
+		}
+		if start < 0 {
+			start = 0
+		}
+		if end >= 0 && start > end {
+			res = append(res, contextSeparator)
+		}
+		stop := i + n + 1
+		if stop > len(lines) {
+			stop = len(lines)
+		}
+		res = append(res, lines[start:stop]...)
+		end = stop
+	}
+	return res
+}
+
+// filterLogs filters lines of logs entries and returns entries with matching lines only.
+func (m *lineMatcher) filterLogs(logs []*controllerv1beta1.Logs) []*controllerv1beta1.Logs {
+	res := make([]*controllerv1beta1.Logs, 0, len(logs))
+	for _, item := range logs {
+		lines := m.filterLines(item.Logs)
+		if len(lines) == 0 {
+			continue
+		}
+		item.Logs = lines
+		res = append(res, item)
+	}
+	return res
+}
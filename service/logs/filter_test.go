@@ -0,0 +1,175 @@
+// dbaas-controller
+// Copyright (C) 2020 Percona LLC
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package logs
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/percona-platform/dbaas-controller/service/k8sclient"
+)
+
+func TestParseLine(t *testing.T) {
+	t.Parallel()
+	ts := time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	for _, tt := range []struct {
+		name     string
+		line     string
+		severity Severity
+		time     time.Time
+	}{{
+		name:     "mysqld",
+		line:     "2021-06-01T12:00:00.000000Z 0 [Warning] [MY-010068] [Server] CA certificate ca.pem is self signed.",
+		severity: SeverityWarning,
+		time:     ts,
+	}, {
+		name:     "mysqld error",
+		line:     "2021-06-01T12:00:00.000000Z 7 [ERROR] [MY-000000] [Galera] failed to open gcomm backend connection",
+		severity: SeverityError,
+		time:     ts,
+	}, {
+		name:     "mongod",
+		line:     `{"t":{"$date":"2021-06-01T12:00:00.000+00:00"},"s":"W","c":"CONTROL","id":22120,"msg":"Access control is not enabled"}`,
+		severity: SeverityWarning,
+		time:     ts,
+	}, {
+		name:     "mongod debug",
+		line:     `{"t":{"$date":"2021-06-01T12:00:00.000+00:00"},"s":"D2","c":"NETWORK","msg":"connection accepted"}`,
+		severity: SeverityDebug,
+		time:     ts,
+	}, {
+		name:     "mongod before 4.4",
+		line:     "2021-06-01T12:00:00.000+0000 E  STORAGE  [initandlisten] exception in initAndListen",
+		severity: SeverityError,
+		time:     ts,
+	}, {
+		name:     "operator JSON",
+		line:     `{"level":"error","ts":1622548800,"logger":"controller","msg":"Reconciler error"}`,
+		severity: SeverityError,
+		time:     ts,
+	}, {
+		name:     "operator console",
+		line:     "2021-06-01T12:00:00.000Z\tINFO\tcontroller.perconaxtradbcluster-controller\tStarting workers",
+		severity: SeverityInfo,
+		time:     ts,
+	}, {
+		name:     "event",
+		line:     "2021-06-01T12:00:00Z Warning BackOff Pod/cluster-pxc-0: Back-off restarting failed container",
+		severity: SeverityWarning,
+		time:     ts,
+	}, {
+		name:     "kubectl timestamps",
+		line:     `2021-06-01T12:00:00.000000000Z {"level":"warn","msg":"slow reconcile"}`,
+		severity: SeverityWarning,
+		time:     ts,
+	}, {
+		name: "plain",
+		line: "WARNING: no timestamp here",
+	}} {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			info := parseLine(tt.line)
+			assert.Equal(t, tt.severity, info.severity)
+			assert.True(t, tt.time.Equal(info.time), "expected %s, got %s", tt.time, info.time)
+		})
+	}
+}
+
+func TestNewLineMatcher(t *testing.T) {
+	t.Parallel()
+	_, err := newLineMatcher(&Filter{Pattern: "("})
+	assert.Error(t, err)
+	_, err = newLineMatcher(&Filter{ContextLines: -1})
+	assert.EqualError(t, err, "context lines can't be negative")
+	_, err = newLineMatcher(&Filter{MinSeverity: SeverityFatal + 1})
+	assert.EqualError(t, err, "unknown severity 6")
+	_, err = newLineMatcher(&Filter{Since: time.Unix(2, 0), Until: time.Unix(1, 0)})
+	assert.EqualError(t, err, "time window ends before it starts")
+}
+
+func TestFilterLines(t *testing.T) {
+	t.Parallel()
+	lines := []string{
+		"2021-06-01T12:00:00.000000Z 0 [Note] [MY-000000] [Server] starting",
+		"2021-06-01T12:01:00.000000Z 0 [Note] [MY-000000] [Server] opening tables",
+		"2021-06-01T12:02:00.000000Z 0 [ERROR] [MY-000000] [Server] cannot open table",
+		"stack frame 1",
+		"2021-06-01T12:03:00.000000Z 0 [Note] [MY-000000] [Server] retrying",
+		"2021-06-01T12:04:00.000000Z 0 [Note] [MY-000000] [Server] retrying",
+		"2021-06-01T12:05:00.000000Z 0 [Note] [MY-000000] [Server] retrying",
+		"2021-06-01T12:06:00.000000Z 0 [Warning] [MY-000000] [Server] table is slow",
+		"2021-06-01T12:07:00.000000Z 0 [Note] [MY-000000] [Server] ready",
+	}
+
+	for _, tt := range []struct {
+		name     string
+		filter   Filter
+		expected []string
+	}{{
+		name:     "empty filter",
+		expected: lines,
+	}, {
+		name:     "severity carried to continuation lines",
+		filter:   Filter{MinSeverity: SeverityError},
+		expected: lines[2:4],
+	}, {
+		name:     "warnings with context",
+		filter:   Filter{MinSeverity: SeverityWarning, ContextLines: 1},
+		expected: append(append(append([]string{}, lines[1:5]...), contextSeparator), lines[6:9]...),
+	}, {
+		name:     "adjacent context",
+		filter:   Filter{Substring: "retrying", ContextLines: 1},
+		expected: lines[3:8],
+	}, {
+		name:     "pattern",
+		filter:   Filter{Pattern: `open(ing)? table`},
+		expected: lines[1:3],
+	}, {
+		name: "time window",
+		filter: Filter{
+			Since: time.Date(2021, 6, 1, 12, 5, 0, 0, time.UTC),
+			Until: time.Date(2021, 6, 1, 12, 6, 0, 0, time.UTC),
+		},
+		expected: lines[6:8],
+	}, {
+		name:     "no matches",
+		filter:   Filter{Substring: "shutdown", ContextLines: 2},
+		expected: []string{},
+	}} {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			m, err := newLineMatcher(&tt.filter)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, m.filterLines(lines))
+		})
+	}
+}
+
+func TestSearchLogsOptions(t *testing.T) {
+	t.Parallel()
+	since := time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC)
+	assert.Nil(t, searchLogsOptions(&Filter{Pattern: "error"}))
+	assert.Equal(t, &k8sclient.LogsOptions{Since: since, Timestamps: true}, searchLogsOptions(&Filter{Since: since}))
+	// Until can't be pushed down, but lines still need timestamps to be matched.
+	assert.Equal(t, &k8sclient.LogsOptions{Timestamps: true}, searchLogsOptions(&Filter{Until: since}))
+}
@@ -0,0 +1,158 @@
+// dbaas-controller
+// Copyright (C) 2020 Percona LLC
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package logs
+
+import (
+	"fmt"
+	"strings"
+
+	controllerv1beta1 "github.com/percona-platform/dbaas-api/gen/controller"
+	"github.com/pkg/errors"
+)
+
+// Default limits of logs returned by GetLogs.
+const (
+	DefaultLinesLimit    = 1000
+	DefaultBytesLimit    = 1 << 20
+	DefaultFailingWeight = 4
+	DefaultEventsWeight  = 2
+	DefaultOtherWeight   = 1
+)
+
+// Limits defines how many logs GetLogs returns and how they are shared between logs entries.
+// Lines are distributed in rounds: in every round each entry gets as many of its last lines
+// as its weight is, until the limits are reached or all lines are taken.
+type Limits struct {
+	// Lines is the overall limit of log lines, zero means no limit.
+	Lines int `json:"lines"`
+	// Bytes is the overall limit of log lines size in bytes, zero means no limit.
+	Bytes int `json:"bytes"`
+	// FailingWeight is the weight of logs of failing containers and of previous container instances.
+	FailingWeight int `json:"failingWeight"`
+	// EventsWeight is the weight of events.
+	EventsWeight int `json:"eventsWeight"`
+	// OtherWeight is the weight of logs of other containers.
+	OtherWeight int `json:"otherWeight"`
+}
+
+// DefaultLimits returns limits used when they are not configured.
+func DefaultLimits() Limits {
+	return Limits{
+		Lines:         DefaultLinesLimit,
+		Bytes:         DefaultBytesLimit,
+		FailingWeight: DefaultFailingWeight,
+		EventsWeight:  DefaultEventsWeight,
+		OtherWeight:   DefaultOtherWeight,
+	}
+}
+
+// Check returns an error if limits are invalid.
+func (l *Limits) Check() error {
+	if l.Lines < 0 || l.Bytes < 0 {
+		return errors.New("limits can't be negative")
+	}
+	if l.FailingWeight < 0 || l.EventsWeight < 0 || l.OtherWeight < 0 {
+		return errors.New("weights can't be negative")
+	}
+	if l.FailingWeight+l.EventsWeight+l.OtherWeight == 0 {
+		return errors.New("at least one weight should be positive")
+	}
+	return nil
+}
+
+// weight returns weight of the logs entry, failing is true for logs of failing containers.
+// Entries without container hold events.
+func (l *Limits) weight(logs *controllerv1beta1.Logs, failing bool) int {
+	switch {
+	case logs.Container == "":
+		return l.EventsWeight
+	case failing, strings.HasSuffix(logs.Container, previousContainerSuffix):
+		return l.FailingWeight
+	default:
+		return l.OtherWeight
+	}
+}
+
+// limitLines keeps the last lines of each entry in the way the overall number and size of lines
+// fit the limits. In every round each entry gets as many lines as its weight is; nil weights mean
+// that every entry has weight 1. It returns numbers of lines truncated from each entry.
+func limitLines(logs []*controllerv1beta1.Logs, weights []int, limits Limits) []int {
+	counts := make([]int, len(logs))
+	done := make([]bool, len(logs))
+	var lines, size int
+	full := func() bool {
+		return limits.Lines > 0 && lines >= limits.Lines
+	}
+
+	for progress := true; progress && !full(); {
+		progress = false
+		for i, item := range logs {
+			weight := 1
+			if weights != nil {
+				weight = weights[i]
+			}
+			for w := 0; w < weight && !done[i] && !full(); w++ {
+				if counts[i] == len(item.Logs) {
+					done[i] = true
+					break
+				}
+				line := item.Logs[len(item.Logs)-counts[i]-1]
+				if limits.Bytes > 0 && size+len(line) > limits.Bytes {
+					// Smaller lines of other entries may still fit.
+					done[i] = true
+					break
+				}
+				counts[i]++
+				lines++
+				size += len(line)
+				progress = true
+			}
+			if full() {
+				break
+			}
+		}
+	}
+
+	// Do the actual slicing.
+	truncated := make([]int, len(logs))
+	for i, item := range logs {
+		truncated[i] = len(item.Logs) - counts[i]
+		logs[i].Logs = item.Logs[truncated[i]:]
+	}
+	return truncated
+}
+
+// markTruncated prepends a line with number of truncated lines to truncated entries.
+func markTruncated(logs []*controllerv1beta1.Logs, truncated []int) {
+	for i, n := range truncated {
+		if n == 0 {
+			continue
+		}
+		logs[i].Logs = append([]string{fmt.Sprintf("[%d earlier lines truncated]", n)}, logs[i].Logs...)
+	}
+}
+
+// applyLimits limits lines of logs entries giving failing ones and events their weights and marks truncated entries.
+// Failing contains entries with logs of failing containers.
+func applyLimits(logs []*controllerv1beta1.Logs, limits Limits, failing map[*controllerv1beta1.Logs]struct{}) {
+	weights := make([]int, len(logs))
+	for i, item := range logs {
+		_, itemFailing := failing[item]
+		weights[i] = limits.weight(item, itemFailing)
+	}
+	markTruncated(logs, limitLines(logs, weights, limits))
+}
@@ -0,0 +1,117 @@
+// dbaas-controller
+// Copyright (C) 2020 Percona LLC
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package logs
+
+import (
+	"testing"
+
+	controllerv1beta1 "github.com/percona-platform/dbaas-api/gen/controller"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLimitsCheck(t *testing.T) {
+	t.Parallel()
+	assert.NoError(t, (&Limits{FailingWeight: 1}).Check())
+	assert.NoError(t, (&Limits{Lines: 10, Bytes: 100, FailingWeight: 1, EventsWeight: 1, OtherWeight: 1}).Check())
+	assert.EqualError(t, (&Limits{Lines: -1, OtherWeight: 1}).Check(), "limits can't be negative")
+	assert.EqualError(t, (&Limits{FailingWeight: -1, OtherWeight: 2}).Check(), "weights can't be negative")
+	assert.EqualError(t, (&Limits{Lines: 10}).Check(), "at least one weight should be positive")
+}
+
+func TestLimitsWeight(t *testing.T) {
+	t.Parallel()
+	limits := Limits{FailingWeight: 4, EventsWeight: 2, OtherWeight: 1}
+	assert.Equal(t, 2, limits.weight(&controllerv1beta1.Logs{Pod: "pod"}, true))
+	assert.Equal(t, 2, limits.weight(&controllerv1beta1.Logs{Pod: "pod"}, false))
+	assert.Equal(t, 4, limits.weight(&controllerv1beta1.Logs{Pod: "pod", Container: "pxc"}, true))
+	assert.Equal(t, 4, limits.weight(&controllerv1beta1.Logs{Pod: "pod", Container: "pxc" + previousContainerSuffix}, false))
+	assert.Equal(t, 1, limits.weight(&controllerv1beta1.Logs{Pod: "pod", Container: "pxc"}, false))
+}
+
+func TestLimitLinesWeighted(t *testing.T) {
+	t.Parallel()
+	for _, tt := range []struct {
+		name      string
+		weights   []int
+		limits    Limits
+		input     [][]string
+		expected  [][]string
+		truncated []int
+	}{{
+		name:      "weights",
+		weights:   []int{1, 3},
+		limits:    Limits{Lines: 8},
+		input:     [][]string{{"a", "b", "c", "d", "e", "f"}, {"g", "h", "i", "j", "k", "l", "m"}},
+		expected:  [][]string{{"e", "f"}, {"h", "i", "j", "k", "l", "m"}},
+		truncated: []int{4, 1},
+	}, {
+		name:      "zero weight",
+		weights:   []int{0, 1},
+		limits:    Limits{Lines: 10},
+		input:     [][]string{{"a", "b"}, {"c", "d"}},
+		expected:  [][]string{{}, {"c", "d"}},
+		truncated: []int{2, 0},
+	}, {
+		name:      "bytes",
+		weights:   []int{1, 1},
+		limits:    Limits{Bytes: 10},
+		input:     [][]string{{"aaaa", "bbbb", "cccc"}, {"dd", "ee", "ff"}},
+		expected:  [][]string{{"bbbb", "cccc"}, {"ff"}},
+		truncated: []int{1, 2},
+	}, {
+		name:      "no limits",
+		weights:   []int{1, 2},
+		input:     [][]string{{"a", "b", "c"}, {"d"}},
+		expected:  [][]string{{"a", "b", "c"}, {"d"}},
+		truncated: []int{0, 0},
+	}} {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			logs := make([]*controllerv1beta1.Logs, len(tt.input))
+			for i, lines := range tt.input {
+				logs[i] = &controllerv1beta1.Logs{Logs: lines}
+			}
+			truncated := limitLines(logs, tt.weights, tt.limits)
+			assert.Equal(t, tt.truncated, truncated)
+			for i, lines := range tt.expected {
+				assert.Equal(t, lines, logs[i].Logs)
+			}
+		})
+	}
+}
+
+func TestMarkTruncated(t *testing.T) {
+	t.Parallel()
+	logs := []*controllerv1beta1.Logs{{Logs: []string{"a"}}, {Logs: []string{"b"}}}
+	markTruncated(logs, []int{0, 5})
+	assert.Equal(t, []string{"a"}, logs[0].Logs)
+	assert.Equal(t, []string{"[5 earlier lines truncated]", "b"}, logs[1].Logs)
+}
+
+func TestApplyLimits(t *testing.T) {
+	t.Parallel()
+	lines := []string{"a", "b", "c", "d", "e", "f"}
+	failingLogs := &controllerv1beta1.Logs{Pod: "pod-0", Container: "pxc", Logs: lines}
+	operatorLogs := &controllerv1beta1.Logs{Pod: "operator", Container: "operator", Logs: lines}
+	logs := []*controllerv1beta1.Logs{failingLogs, operatorLogs}
+
+	// Only logs of the failing container get the failing weight.
+	applyLimits(logs, Limits{Lines: 5, FailingWeight: 4, OtherWeight: 1}, map[*controllerv1beta1.Logs]struct{}{failingLogs: {}})
+	assert.Equal(t, []string{"[2 earlier lines truncated]", "c", "d", "e", "f"}, failingLogs.Logs)
+	assert.Equal(t, []string{"[5 earlier lines truncated]", "f"}, operatorLogs.Logs)
+}
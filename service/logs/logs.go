@@ -34,8 +34,11 @@ import (
 // and pod's events.
 type Service struct {
 	p             *message.Printer
+	limits        Limits
 	defaultSource source
 	sources       []source
+	// operatorSource returns operator logs about the cluster, they are added to logs of other sources.
+	operatorSource source
 }
 
 // Thanks to source interface we can get logs from different sources.
@@ -43,18 +46,46 @@ type source interface {
 	getLogs(ctx context.Context, client *k8sclient.K8sClient, clusterName string) ([]*controllerv1beta1.Logs, error)
 }
 
-// NewService creates a new instance of Service.
-func NewService(p *message.Printer) *Service {
+// NewService creates a new instance of Service returning logs within given limits.
+func NewService(p *message.Printer, limits Limits) *Service {
 	return &Service{
-		p:             p,
-		defaultSource: source(new(allLogsSource)),
-		sources:       []source{},
+		p:              p,
+		limits:         limits,
+		defaultSource:  source(new(allLogsSource)),
+		sources:        []source{newFailingPodsSource()},
+		operatorSource: source(new(operatorLogsSource)),
 	}
 }
 
 // GetLogs first tries to get logs and events only from failing pods/containers.
 // If no such logs/events are found, it returns logs from the defaultSource.
+// In both cases operator logs about the cluster are returned as well.
 func (s *Service) GetLogs(ctx context.Context, req *controllerv1beta1.GetLogsRequest) (*controllerv1beta1.GetLogsResponse, error) {
+	return s.GetLogsWithLimits(ctx, req, s.limits)
+}
+
+// GetLogsWithLimitsRequest contains parameters of getting logs with limits other than the service ones.
+type GetLogsWithLimitsRequest struct {
+	Kubeconfig  string `json:"kubeconfig"`
+	ClusterName string `json:"clusterName"`
+	Limits      Limits `json:"limits"`
+}
+
+// Validate checks that the request has kubeconfig and cluster name and its limits are valid.
+func (req *GetLogsWithLimitsRequest) Validate() error {
+	if req.Kubeconfig == "" || req.ClusterName == "" {
+		return errors.New("kubeconfig and cluster name are required")
+	}
+	return errors.Wrap(req.Limits.Check(), "invalid logs limits")
+}
+
+// GetLogsWithLimits is the same as GetLogs, but it uses given limits instead of the service ones.
+// Truncated entries start with a line containing the number of truncated lines.
+func (s *Service) GetLogsWithLimits(ctx context.Context, req *controllerv1beta1.GetLogsRequest, limits Limits) (*controllerv1beta1.GetLogsResponse, error) {
+	if err := limits.Check(); err != nil {
+		return nil, status.Error(codes.InvalidArgument, errors.Wrap(err, "invalid logs limits").Error())
+	}
+
 	client, err := k8sclient.New(ctx, req.KubeAuth.Kubeconfig)
 	if err != nil {
 		return nil, status.Error(codes.Internal, s.p.Sprintf("Cannot initialize K8s client: %s", err))
@@ -62,11 +93,16 @@ func (s *Service) GetLogs(ctx context.Context, req *controllerv1beta1.GetLogsReq
 	defer client.Cleanup() //nolint:errcheck
 
 	response := []*controllerv1beta1.Logs{}
+	// Logs of sources are returned only for failing pods/containers, see podFailure.
+	failing := make(map[*controllerv1beta1.Logs]struct{})
 	for _, source := range s.sources {
 		logs, err := source.getLogs(ctx, client, req.ClusterName)
 		if err != nil {
 			return nil, status.Error(codes.Internal, errors.Wrap(err, "failed to get logs").Error())
 		}
+		for _, item := range logs {
+			failing[item] = struct{}{}
+		}
 		response = append(response, logs...)
 	}
 	if len(response) == 0 {
@@ -76,6 +112,13 @@ func (s *Service) GetLogs(ctx context.Context, req *controllerv1beta1.GetLogsReq
 		}
 		response = append(response, logs...)
 	}
+	logs, err := s.operatorSource.getLogs(ctx, client, req.ClusterName)
+	if err != nil {
+		return nil, status.Error(codes.Internal, errors.Wrap(err, "failed to get operator logs").Error())
+	}
+	response = append(response, logs...)
+
+	applyLimits(response, limits, failing)
 
 	return &controllerv1beta1.GetLogsResponse{
 		Logs: response,
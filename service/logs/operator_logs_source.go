@@ -0,0 +1,156 @@
+// dbaas-controller
+// Copyright (C) 2020 Percona LLC
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package logs
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+
+	controllerv1beta1 "github.com/percona-platform/dbaas-api/gen/controller"
+	"github.com/pkg/errors"
+
+	"github.com/percona-platform/dbaas-controller/service/k8sclient"
+	"github.com/percona-platform/dbaas-controller/utils/logger"
+)
+
+// operatorClusterFields are fields of operators' structured logs which hold name of the cluster.
+// Request is in namespace/name form.
+var operatorClusterFields = []string{"name", "cluster", "Request.Name", "request"} //nolint:gochecknoglobals
+
+// unknownOperatorName names operator logs entry when the cluster's operator can't be determined.
+const unknownOperatorName = "operator"
+
+// operatorLogsSource implements source interface, it gets lines of operator logs
+// which are about the cluster.
+type operatorLogsSource struct {
+	// opts are options of operator logs requests, may be nil.
+	opts *k8sclient.LogsOptions
+}
+
+// isClusterName returns true if value is cluster name, optionally prefixed with namespace.
+func isClusterName(value interface{}, clusterName string) bool {
+	s, ok := value.(string)
+	if !ok {
+		return false
+	}
+	return s == clusterName || strings.HasSuffix(s, "/"+clusterName)
+}
+
+// hasClusterField returns true if any of cluster fields of the structured log entry
+// or of its nested objects holds cluster name.
+func hasClusterField(fields map[string]interface{}, clusterName string) bool {
+	for _, field := range operatorClusterFields {
+		if isClusterName(fields[field], clusterName) {
+			return true
+		}
+	}
+	for _, value := range fields {
+		if nested, ok := value.(map[string]interface{}); ok && hasClusterField(nested, clusterName) {
+			return true
+		}
+	}
+	return false
+}
+
+// isNameChar returns true if c can be a part of Kubernetes object name. Dots are excluded
+// as they end sentences more often than they are parts of names.
+func isNameChar(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-'
+}
+
+// mentionsName returns true if line contains name which is not a part of a longer name.
+func mentionsName(line, name string) bool {
+	for offset := 0; ; {
+		i := strings.Index(line[offset:], name)
+		if i < 0 {
+			return false
+		}
+		start, end := offset+i, offset+i+len(name)
+		if (start == 0 || !isNameChar(line[start-1])) && (end == len(line) || !isNameChar(line[end])) {
+			return true
+		}
+		offset = start + 1
+	}
+}
+
+// isClusterLogLine returns true if operator log line is about the cluster. Operators log either JSON
+// objects or text with JSON object of fields at the end; those fields are checked if present.
+// Otherwise the line should mention the cluster name.
+func isClusterLogLine(line, clusterName string) bool {
+	if i := strings.Index(line, "{"); i >= 0 {
+		var fields map[string]interface{}
+		if err := json.Unmarshal([]byte(line[i:]), &fields); err == nil {
+			return hasClusterField(fields, clusterName)
+		}
+	}
+	return mentionsName(line, clusterName)
+}
+
+// operatorLogsFailure returns operator logs entry recording that operator logs couldn't be read,
+// for example, because reading pods logs is forbidden.
+func operatorLogsFailure(deploymentName string, err error) *controllerv1beta1.Logs {
+	msg := strings.Join(strings.Fields(err.Error()), " ")
+	return &controllerv1beta1.Logs{
+		Pod:       deploymentName,
+		Container: deploymentName,
+		Logs:      []string{"[failed to get operator logs: " + msg + "]"},
+	}
+}
+
+// getLogs gets lines of logs of the cluster's operator which are about the cluster.
+// It returns nothing if the cluster or its operator doesn't exist. Failure to find the cluster's operator
+// or to read its logs doesn't fail logs of the cluster, it's recorded in the returned entry instead.
+func (o *operatorLogsSource) getLogs(
+	ctx context.Context,
+	client *k8sclient.K8sClient,
+	clusterName string,
+) ([]*controllerv1beta1.Logs, error) {
+	engine, _, err := client.GetClusterResource(ctx, clusterName)
+	if err != nil {
+		if errors.Is(err, k8sclient.ErrNotFound) {
+			return nil, nil
+		}
+		logger.Get(ctx).Warnf("Failed to get cluster %s: %s", clusterName, err)
+		return []*controllerv1beta1.Logs{operatorLogsFailure(unknownOperatorName, errors.Wrap(err, "failed to get cluster"))}, nil
+	}
+
+	deploymentName := k8sclient.PXCOperatorDeploymentName
+	if engine == k8sclient.EnginePSMDB {
+		deploymentName = k8sclient.PSMDBOperatorDeploymentName
+	}
+	lines, err := client.GetOperatorLogs(ctx, engine, operatorLogsTailLines, o.opts)
+	if err != nil {
+		logger.Get(ctx).Warnf("Failed to get %s operator logs: %s", engine, err)
+		return []*controllerv1beta1.Logs{operatorLogsFailure(deploymentName, err)}, nil
+	}
+	logs := make([]string, 0, len(lines))
+	for _, line := range lines {
+		if isClusterLogLine(line, clusterName) {
+			logs = append(logs, line)
+		}
+	}
+	if len(logs) == 0 {
+		return nil, nil
+	}
+
+	return []*controllerv1beta1.Logs{{
+		Pod:       deploymentName,
+		Container: deploymentName,
+		Logs:      logs,
+	}}, nil
+}
@@ -0,0 +1,62 @@
+// dbaas-controller
+// Copyright (C) 2020 Percona LLC
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package logs
+
+import (
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsClusterLogLine(t *testing.T) {
+	t.Parallel()
+	for _, tt := range []struct {
+		line     string
+		expected bool
+	}{
+		{`{"level":"info","ts":1622548800,"msg":"Reconciling","Request.Namespace":"default","Request.Name":"cluster"}`, true},
+		{`{"level":"error","msg":"reconcile failed","controller":"psmdb-controller","name":"cluster","namespace":"default"}`, true},
+		{`{"level":"info","msg":"Reconciling","Request.Name":"cluster-2"}`, false},
+		{`{"level":"info","msg":"backup of cluster created","name":"backup-1"}`, false},
+		{`2021-06-01T12:00:00.000Z	ERROR	controller.perconaxtradbcluster-controller	Reconciler error	{"name": "cluster", "namespace": "default", "error": "wrong PXC options"}`, true},
+		{`2021-06-01T12:00:00.000Z	INFO	controller	Starting workers	{"request": "default/cluster"}`, true},
+		{`2021-06-01T12:00:00.000Z	INFO	controller	Starting workers	{"reconciler group": "pxc.percona.com", "PerconaXtraDBCluster": {"name": "cluster", "namespace": "default"}}`, true},
+		{`2021-06-01T12:00:00.000Z	INFO	controller	Starting workers	{"worker count": 1}`, false},
+		{`I0601 12:00:00.000000 1 main.go:42] PXC cluster is not ready.`, true},
+		{`I0601 12:00:00.000000 1 main.go:42] PXC cluster-2 is not ready`, false},
+		{`I0601 12:00:00.000000 1 main.go:42] PXC mycluster is not ready`, false},
+	} {
+		assert.Equal(t, tt.expected, isClusterLogLine(tt.line, "cluster"), tt.line)
+	}
+}
+
+func TestOperatorLogsFailure(t *testing.T) {
+	t.Parallel()
+	err := errors.New("exit status 1\ncmd: kubectl logs deployment/percona-xtradb-cluster-operator\n" +
+		"stderr: Error from server (Forbidden): pods \"percona-xtradb-cluster-operator-0\" is forbidden")
+	logs := operatorLogsFailure("percona-xtradb-cluster-operator", err)
+	assert.Equal(t, "percona-xtradb-cluster-operator", logs.Container)
+	assert.Equal(t, []string{
+		"[failed to get operator logs: exit status 1 cmd: kubectl logs deployment/percona-xtradb-cluster-operator " +
+			"stderr: Error from server (Forbidden): pods \"percona-xtradb-cluster-operator-0\" is forbidden]",
+	}, logs.Logs)
+
+	logs = operatorLogsFailure(unknownOperatorName, errors.Wrap(errors.New("exit status 1"), "failed to get cluster"))
+	assert.Equal(t, unknownOperatorName, logs.Pod)
+	assert.Equal(t, []string{"[failed to get operator logs: failed to get cluster: exit status 1]"}, logs.Logs)
+}
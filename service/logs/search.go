@@ -0,0 +1,101 @@
+// dbaas-controller
+// Copyright (C) 2020 Percona LLC
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package logs
+
+import (
+	"context"
+
+	controllerv1beta1 "github.com/percona-platform/dbaas-api/gen/controller"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/percona-platform/dbaas-controller/service/k8sclient"
+)
+
+// SearchLogsRequest contains parameters of logs search.
+type SearchLogsRequest struct {
+	Kubeconfig  string `json:"kubeconfig"`
+	ClusterName string `json:"clusterName"`
+	Filter      Filter `json:"filter"`
+	// Limits are used instead of the service ones if set.
+	Limits *Limits `json:"limits,omitempty"`
+}
+
+// Validate checks that the request has kubeconfig and cluster name.
+func (req *SearchLogsRequest) Validate() error {
+	if req.Kubeconfig == "" || req.ClusterName == "" {
+		return errors.New("kubeconfig and cluster name are required")
+	}
+	return nil
+}
+
+// searchLogsOptions returns options of logs requests pushing the filter time window down to kubectl.
+// It returns nil if the filter has no time window.
+func searchLogsOptions(filter *Filter) *k8sclient.LogsOptions {
+	if filter.Since.IsZero() && filter.Until.IsZero() {
+		return nil
+	}
+	return &k8sclient.LogsOptions{Since: filter.Since, Timestamps: true}
+}
+
+// SearchLogs returns lines of logs and events of all cluster's containers and of the operator
+// matching the filter, with context lines around them. The filter is applied before limits,
+// entries without matching lines are not returned. If the filter has a time window, logs are fetched
+// since its start with timestamps prefixing the lines, so lines without their own timestamps match too.
+func (s *Service) SearchLogs(ctx context.Context, req *SearchLogsRequest) (*controllerv1beta1.GetLogsResponse, error) {
+	if err := req.Validate(); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	limits := s.limits
+	if req.Limits != nil {
+		limits = *req.Limits
+	}
+	if err := limits.Check(); err != nil {
+		return nil, status.Error(codes.InvalidArgument, errors.Wrap(err, "invalid logs limits").Error())
+	}
+	matcher, err := newLineMatcher(&req.Filter)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, errors.Wrap(err, "invalid logs filter").Error())
+	}
+
+	client, err := k8sclient.New(ctx, req.Kubeconfig)
+	if err != nil {
+		return nil, status.Error(codes.Internal, s.p.Sprintf("Cannot initialize K8s client: %s", err))
+	}
+	defer client.Cleanup() //nolint:errcheck
+
+	sources := []source{s.defaultSource, s.operatorSource}
+	if opts := searchLogsOptions(&req.Filter); opts != nil {
+		sources = []source{&allLogsSource{opts: opts}, &operatorLogsSource{opts: opts}}
+	}
+	response := []*controllerv1beta1.Logs{}
+	for _, source := range sources {
+		logs, err := source.getLogs(ctx, client, req.ClusterName)
+		if err != nil {
+			return nil, status.Error(codes.Internal, errors.Wrap(err, "failed to get logs").Error())
+		}
+		response = append(response, logs...)
+	}
+
+	response = matcher.filterLogs(response)
+	applyLimits(response, limits, nil)
+
+	return &controllerv1beta1.GetLogsResponse{
+		Logs: response,
+	}, nil
+}
@@ -0,0 +1,332 @@
+// dbaas-controller
+// Copyright (C) 2020 Percona LLC
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package logs
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"sigs.k8s.io/yaml"
+
+	"github.com/percona-platform/dbaas-controller/service/k8sclient"
+	"github.com/percona-platform/dbaas-controller/service/k8sclient/common"
+)
+
+const (
+	// operatorLogsTailLines defines how many last lines of operator logs are added to the support bundle.
+	operatorLogsTailLines = 10000
+	// redactedValue replaces values of secrets in the support bundle.
+	redactedValue = "<redacted>"
+	// lastAppliedConfigAnnotation holds the whole object as applied by kubectl, so it's redacted as well.
+	lastAppliedConfigAnnotation = "kubectl.kubernetes.io/last-applied-configuration"
+)
+
+// bundleObjectKinds lists kinds of cluster's objects added to the support bundle.
+var bundleObjectKinds = []string{"pods", "statefulsets", "services", "persistentvolumeclaims"} //nolint:gochecknoglobals
+
+// sensitiveKeyParts are parts of keys and environment variable names which values are redacted.
+var sensitiveKeyParts = []string{"password", "passwd", "token", "secretkey", "accesskey", "apikey", "privatekey"} //nolint:gochecknoglobals
+
+// SupportBundleRequest contains parameters of the support bundle.
+type SupportBundleRequest struct {
+	Kubeconfig  string `json:"kubeconfig"`
+	ClusterName string `json:"clusterName"`
+}
+
+// Validate checks that the request has kubeconfig and cluster name.
+func (req *SupportBundleRequest) Validate() error {
+	if req.Kubeconfig == "" || req.ClusterName == "" {
+		return errors.New("kubeconfig and cluster name are required")
+	}
+	return nil
+}
+
+// isSensitiveKey returns true if values of the key shouldn't leave the cluster.
+func isSensitiveKey(key string) bool {
+	key = strings.ToLower(strings.NewReplacer("_", "", "-", "").Replace(key))
+	for _, part := range sensitiveKeyParts {
+		if strings.Contains(key, part) {
+			return true
+		}
+	}
+	return false
+}
+
+// redactSecrets replaces values of sensitive keys and of sensitive name/value pairs,
+// like environment variables, with redactedValue. It modifies given object in place.
+func redactSecrets(v interface{}) {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		if name, ok := v["name"].(string); ok && isSensitiveKey(name) {
+			if _, ok := v["value"]; ok {
+				v["value"] = redactedValue
+			}
+		}
+		for key, value := range v {
+			if _, ok := value.(string); ok && (key == lastAppliedConfigAnnotation || isSensitiveKey(key)) {
+				v[key] = redactedValue
+				continue
+			}
+			redactSecrets(value)
+		}
+	case []interface{}:
+		for _, item := range v {
+			redactSecrets(item)
+		}
+	}
+}
+
+// removeNodeImages removes lists of images cached on nodes, they are long and useless for diagnostics.
+func removeNodeImages(v interface{}) {
+	list, _ := v.(map[string]interface{})
+	items, _ := list["items"].([]interface{})
+	for _, item := range items {
+		node, _ := item.(map[string]interface{})
+		if nodeStatus, ok := node["status"].(map[string]interface{}); ok {
+			delete(nodeStatus, "images")
+		}
+	}
+}
+
+// objectsYAML converts objects returned by kubectl as JSON to YAML with secrets redacted.
+func objectsYAML(data []byte, transforms ...func(interface{})) ([]byte, error) {
+	var v interface{}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return nil, errors.Wrap(err, "failed to decode objects")
+	}
+	redactSecrets(v)
+	for _, transform := range transforms {
+		transform(v)
+	}
+	return yaml.Marshal(v)
+}
+
+// bundleWriter writes files of the support bundle to tar.gz archive.
+// Failures of collecting diagnostics are recorded and written to errors.txt, so the bundle
+// contains everything that could be collected.
+type bundleWriter struct {
+	gz      *gzip.Writer
+	tw      *tar.Writer
+	dir     string
+	modTime time.Time
+	errors  []string
+}
+
+// newBundleWriter creates a new bundleWriter placing all files to given directory of the archive.
+func newBundleWriter(w io.Writer, dir string, modTime time.Time) *bundleWriter {
+	gz := gzip.NewWriter(w)
+	return &bundleWriter{
+		gz:      gz,
+		tw:      tar.NewWriter(gz),
+		dir:     dir,
+		modTime: modTime,
+	}
+}
+
+// addFile adds file with given content to the archive.
+func (b *bundleWriter) addFile(name string, data []byte) error {
+	header := &tar.Header{
+		Typeflag: tar.TypeReg,
+		Name:     path.Join(b.dir, name),
+		Mode:     0o644,
+		Size:     int64(len(data)),
+		ModTime:  b.modTime,
+	}
+	if err := b.tw.WriteHeader(header); err != nil {
+		return errors.Wrapf(err, "failed to add %s to support bundle", name)
+	}
+	if _, err := b.tw.Write(data); err != nil {
+		return errors.Wrapf(err, "failed to add %s to support bundle", name)
+	}
+	return nil
+}
+
+// addLines adds file with given lines to the archive.
+func (b *bundleWriter) addLines(name string, lines []string) error {
+	var data string
+	if len(lines) != 0 {
+		data = strings.Join(lines, "\n") + "\n"
+	}
+	return b.addFile(name, []byte(data))
+}
+
+// addObjects adds objects returned by kubectl as JSON to the archive as YAML with secrets redacted.
+func (b *bundleWriter) addObjects(name string, data []byte, transforms ...func(interface{})) error {
+	out, err := objectsYAML(data, transforms...)
+	if err != nil {
+		b.addError(name, err)
+		return nil
+	}
+	return b.addFile(name, out)
+}
+
+// addError records failure of collecting given file.
+func (b *bundleWriter) addError(name string, err error) {
+	b.errors = append(b.errors, fmt.Sprintf("%s: %s", name, err))
+}
+
+// close writes recorded errors and flushes the archive.
+func (b *bundleWriter) close() error {
+	if len(b.errors) != 0 {
+		if err := b.addLines("errors.txt", b.errors); err != nil {
+			return err
+		}
+	}
+	if err := b.tw.Close(); err != nil {
+		return errors.Wrap(err, "failed to write support bundle")
+	}
+	return errors.Wrap(b.gz.Close(), "failed to write support bundle")
+}
+
+// logsFileName returns name of the file with logs of the pod's container.
+func logsFileName(pod, container string) string {
+	container = strings.ReplaceAll(container, previousContainerSuffix, "-previous")
+	return path.Join("logs", pod, container+".log")
+}
+
+// WriteSupportBundle writes a tar.gz archive with diagnostics of the database cluster to w:
+// the cluster custom resource, cluster's pods, stateful sets, services and volume claims,
+// events, container and operator logs, and nodes running cluster's pods.
+// Secrets are redacted from all objects.
+func (s *Service) WriteSupportBundle(ctx context.Context, req *SupportBundleRequest, w io.Writer) error {
+	if err := req.Validate(); err != nil {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	client, err := k8sclient.New(ctx, req.Kubeconfig)
+	if err != nil {
+		return status.Error(codes.Internal, s.p.Sprintf("Cannot initialize K8s client: %s", err))
+	}
+	defer client.Cleanup() //nolint:errcheck
+
+	engine, cluster, err := client.GetClusterResource(ctx, req.ClusterName)
+	if err != nil {
+		if errors.Is(err, k8sclient.ErrNotFound) {
+			return status.Error(codes.NotFound, err.Error())
+		}
+		return status.Error(codes.Internal, errors.Wrap(err, "failed to get cluster").Error())
+	}
+
+	b := newBundleWriter(w, req.ClusterName, time.Now())
+	if err := s.writeSupportBundle(ctx, client, b, engine, req.ClusterName, cluster); err != nil {
+		return status.Error(codes.Internal, err.Error())
+	}
+	if err := b.close(); err != nil {
+		return status.Error(codes.Internal, err.Error())
+	}
+	return nil
+}
+
+// writeSupportBundle collects diagnostics of the cluster to the bundle. It returns only errors
+// of writing the bundle, failures of collecting diagnostics are recorded to the bundle.
+func (s *Service) writeSupportBundle(
+	ctx context.Context,
+	client *k8sclient.K8sClient,
+	b *bundleWriter,
+	engine k8sclient.Engine,
+	clusterName string,
+	cluster []byte,
+) error {
+	if err := b.addObjects("cluster.yaml", cluster); err != nil {
+		return err
+	}
+
+	selector := "-lapp.kubernetes.io/instance=" + clusterName
+	var pods common.PodList
+	for _, kind := range bundleObjectKinds {
+		name := path.Join("objects", kind+".yaml")
+		data, err := client.GetObjectsJSON(ctx, kind, selector)
+		if err != nil {
+			b.addError(name, err)
+			continue
+		}
+		if kind == "pods" {
+			if err := json.Unmarshal(data, &pods); err != nil {
+				b.addError(name, errors.Wrap(err, "failed to decode pods"))
+			}
+		}
+		if err := b.addObjects(name, data); err != nil {
+			return err
+		}
+	}
+
+	events, err := client.GetClusterEvents(ctx, clusterName)
+	if err != nil {
+		b.addError("events.txt", err)
+	} else {
+		lines := make([]string, len(events))
+		for i := range events {
+			lines[i] = eventLine(&events[i])
+		}
+		if err := b.addLines("events.txt", lines); err != nil {
+			return err
+		}
+	}
+
+	nodes := make([]string, 0, len(pods.Items))
+	seenNodes := make(map[string]struct{}, len(pods.Items))
+	for i := range pods.Items {
+		pod := &pods.Items[i]
+		if _, ok := seenNodes[pod.Spec.NodeName]; !ok && pod.Spec.NodeName != "" {
+			seenNodes[pod.Spec.NodeName] = struct{}{}
+			nodes = append(nodes, pod.Spec.NodeName)
+		}
+
+		for _, containers := range [][]common.ContainerSpec{pod.Spec.Containers, pod.Spec.InitContainers} {
+			for _, container := range containers {
+				logs, err := containerLogs(ctx, client, pod, container.Name, nil)
+				if err != nil {
+					b.addError(logsFileName(pod.Name, container.Name), err)
+					continue
+				}
+				for _, l := range logs {
+					if err := b.addLines(logsFileName(l.Pod, l.Container), l.Logs); err != nil {
+						return err
+					}
+				}
+			}
+		}
+	}
+
+	operatorLogsName := path.Join("operator", string(engine)+"-operator.log")
+	operatorLogs, err := client.GetOperatorLogs(ctx, engine, operatorLogsTailLines, nil)
+	if err != nil {
+		b.addError(operatorLogsName, err)
+	} else if err := b.addLines(operatorLogsName, operatorLogs); err != nil {
+		return err
+	}
+
+	if len(nodes) != 0 {
+		data, err := client.GetObjectsJSON(ctx, "nodes", nodes...)
+		if err != nil {
+			b.addError("nodes.yaml", err)
+		} else if err := b.addObjects("nodes.yaml", data, removeNodeImages); err != nil {
+			return err
+		}
+	}
+	return nil
+}
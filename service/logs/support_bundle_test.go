@@ -0,0 +1,107 @@
+// dbaas-controller
+// Copyright (C) 2020 Percona LLC
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package logs
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestObjectsYAML(t *testing.T) {
+	t.Parallel()
+	const cluster = `{
+		"kind": "PerconaXtraDBCluster",
+		"metadata": {
+			"name": "cluster",
+			"annotations": {"kubectl.kubernetes.io/last-applied-configuration": "{\"spec\":{}}"}
+		},
+		"spec": {
+			"secretsName": "cluster-secrets",
+			"pmm": {"serverUser": "admin", "serverPassword": "secret"},
+			"pxc": {
+				"size": 3,
+				"envVarsSecret": "env",
+				"env": [{"name": "MYSQL_ROOT_PASSWORD", "value": "root"}, {"name": "DEBUG", "value": "1"}]
+			}
+		}
+	}`
+	out, err := objectsYAML([]byte(cluster))
+	require.NoError(t, err)
+	expected := `kind: PerconaXtraDBCluster
+metadata:
+  annotations:
+    kubectl.kubernetes.io/last-applied-configuration: <redacted>
+  name: cluster
+spec:
+  pmm:
+    serverPassword: <redacted>
+    serverUser: admin
+  pxc:
+    env:
+    - name: MYSQL_ROOT_PASSWORD
+      value: <redacted>
+    - name: DEBUG
+      value: "1"
+    envVarsSecret: env
+    size: 3
+  secretsName: cluster-secrets
+`
+	assert.Equal(t, expected, string(out))
+
+	const nodes = `{"items": [{"metadata": {"name": "node"}, "status": {"images": [{"names": ["pxc"]}], "nodeInfo": {"kubeletVersion": "v1.21.0"}}}]}`
+	out, err = objectsYAML([]byte(nodes), removeNodeImages)
+	require.NoError(t, err)
+	assert.Equal(t, "items:\n- metadata:\n    name: node\n  status:\n    nodeInfo:\n      kubeletVersion: v1.21.0\n", string(out))
+}
+
+func TestBundleWriter(t *testing.T) {
+	t.Parallel()
+	var buf bytes.Buffer
+	b := newBundleWriter(&buf, "cluster", time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC))
+	require.NoError(t, b.addLines(logsFileName("cluster-pxc-0", "pxc"+previousContainerSuffix), []string{"line 1", "line 2"}))
+	require.NoError(t, b.addObjects("objects/pods.yaml", []byte("not json")))
+	b.addError("events.txt", errors.New("forbidden"))
+	require.NoError(t, b.close())
+
+	gz, err := gzip.NewReader(&buf)
+	require.NoError(t, err)
+	tr := tar.NewReader(gz)
+	files := make(map[string]string)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		data, err := io.ReadAll(tr)
+		require.NoError(t, err)
+		files[header.Name] = string(data)
+	}
+	assert.Equal(t, map[string]string{
+		"cluster/logs/cluster-pxc-0/pxc-previous.log": "line 1\nline 2\n",
+		"cluster/errors.txt": "objects/pods.yaml: failed to decode objects: invalid character 'o' in literal null (expecting 'u')\n" +
+			"events.txt: forbidden\n",
+	}, files)
+}
@@ -0,0 +1,105 @@
+// dbaas-controller
+// Copyright (C) 2020 Percona LLC
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package logs
+
+import (
+	"context"
+	"time"
+
+	controllerv1beta1 "github.com/percona-platform/dbaas-api/gen/controller"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/percona-platform/dbaas-controller/service/k8sclient"
+)
+
+// TailLogsRequest contains parameters of logs streaming.
+type TailLogsRequest struct {
+	Kubeconfig  string `json:"kubeconfig"`
+	ClusterName string `json:"clusterName"`
+	// Pods limits logs to given pods, all cluster pods are used if empty.
+	Pods []string `json:"pods,omitempty"`
+	// Containers limits logs to given containers, all containers are used if empty.
+	Containers []string `json:"containers,omitempty"`
+	// Since limits logs to the ones newer than given time if set.
+	Since time.Time `json:"since,omitempty"`
+	// TailLines limits logs to the last lines of every container if positive.
+	TailLines int `json:"tailLines,omitempty"`
+	// Timestamps prefixes log lines with timestamps.
+	Timestamps bool `json:"timestamps,omitempty"`
+	// Follow streams new logs, including logs of restarted containers, until the client disconnects.
+	Follow bool `json:"follow,omitempty"`
+}
+
+// Validate checks that the request has kubeconfig and cluster name and its limits are valid.
+func (req *TailLogsRequest) Validate() error {
+	if req.Kubeconfig == "" || req.ClusterName == "" {
+		return errors.New("kubeconfig and cluster name are required")
+	}
+	if req.TailLines < 0 {
+		return errors.New("tail lines can't be negative")
+	}
+	return nil
+}
+
+// LogsStream is a server side stream of logs. It matches server streams generated by gRPC.
+type LogsStream interface {
+	Context() context.Context
+	Send(*controllerv1beta1.Logs) error
+}
+
+// TailLogs streams logs of the cluster containers, every message contains a single line tagged
+// with pod and container names. It returns when logs of all containers end; with req.Follow, that happens
+// only when the stream context is canceled or all pods are deleted.
+func (s *Service) TailLogs(req *TailLogsRequest, stream LogsStream) error {
+	ctx := stream.Context()
+	if err := req.Validate(); err != nil {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	client, err := k8sclient.New(ctx, req.Kubeconfig)
+	if err != nil {
+		return status.Error(codes.Internal, s.p.Sprintf("Cannot initialize K8s client: %s", err))
+	}
+	defer client.Cleanup() //nolint:errcheck
+
+	params := &k8sclient.StreamLogsParams{
+		ClusterName: req.ClusterName,
+		Pods:        req.Pods,
+		Containers:  req.Containers,
+		Since:       req.Since,
+		TailLines:   req.TailLines,
+		Timestamps:  req.Timestamps,
+		Follow:      req.Follow,
+	}
+	err = client.StreamLogs(ctx, params, func(line *k8sclient.LogLine) error {
+		return stream.Send(&controllerv1beta1.Logs{
+			Pod:       line.Pod,
+			Container: line.Container,
+			Logs:      []string{line.Line},
+		})
+	})
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		return status.FromContextError(err).Err()
+	default:
+		return status.Error(codes.Internal, errors.Wrap(err, "failed to stream logs").Error())
+	}
+}
@@ -0,0 +1,83 @@
+// dbaas-controller
+// Copyright (C) 2020 Percona LLC
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package monitoring
+
+import (
+	"context"
+
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/types/known/emptypb"
+
+	"github.com/percona-platform/dbaas-controller/service/k8sclient"
+	"github.com/percona-platform/dbaas-controller/utils/jsonapi"
+)
+
+// APIServiceName is the name of gRPC service with monitoring methods which are not part of controller API.
+// StartMonitoring is served by KubernetesClusterAPI. Messages of this service are JSON objects, see jsonapi package.
+const APIServiceName = "percona.platform.dbaas.controller.monitoring.v1.MonitoringAPI"
+
+// APIServiceDesc describes gRPC service with monitoring methods which are not part of controller API.
+var APIServiceDesc = grpc.ServiceDesc{ //nolint:gochecknoglobals
+	ServiceName: APIServiceName,
+	HandlerType: (*interface{})(nil), // handlers require *Service
+	Methods: []grpc.MethodDesc{
+		jsonapi.UnaryMethod(APIServiceName, "StopMonitoring", func() interface{} { return new(StopMonitoringRequest) },
+			func(ctx context.Context, srv interface{}, req interface{}) (interface{}, error) {
+				if err := srv.(*Service).StopMonitoring(ctx, req.(*StopMonitoringRequest)); err != nil {
+					return nil, err
+				}
+				return new(emptypb.Empty), nil
+			}),
+		jsonapi.UnaryMethod(APIServiceName, "GetMonitoringStatus", func() interface{} { return new(MonitoringStatusRequest) },
+			func(ctx context.Context, srv interface{}, req interface{}) (interface{}, error) {
+				return srv.(*Service).GetMonitoringStatus(ctx, req.(*MonitoringStatusRequest))
+			}),
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "service/monitoring/api.go",
+}
+
+// RegisterAPIServer registers gRPC service with monitoring methods which are not part of controller API.
+func RegisterAPIServer(s *grpc.Server, srv *Service) {
+	s.RegisterService(&APIServiceDesc, srv)
+}
+
+// APIClient is the client of gRPC service with monitoring methods which are not part of controller API.
+type APIClient struct {
+	cc grpc.ClientConnInterface
+}
+
+// NewAPIClient returns new APIClient instance.
+func NewAPIClient(cc grpc.ClientConnInterface) *APIClient {
+	return &APIClient{cc: cc}
+}
+
+// StopMonitoring removes monitoring of Kubernetes cluster.
+func (c *APIClient) StopMonitoring(ctx context.Context, req *StopMonitoringRequest, opts ...grpc.CallOption) error {
+	return jsonapi.Invoke(ctx, c.cc, "/"+APIServiceName+"/StopMonitoring", req, new(emptypb.Empty), opts...)
+}
+
+// GetMonitoringStatus returns status of Kubernetes cluster monitoring.
+func (c *APIClient) GetMonitoringStatus(
+	ctx context.Context, req *MonitoringStatusRequest, opts ...grpc.CallOption,
+) (*k8sclient.MonitoringStatus, error) {
+	res := new(k8sclient.MonitoringStatus)
+	if err := jsonapi.Invoke(ctx, c.cc, "/"+APIServiceName+"/GetMonitoringStatus", req, res, opts...); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
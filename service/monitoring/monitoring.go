@@ -0,0 +1,164 @@
+// dbaas-controller
+// Copyright (C) 2020 Percona LLC
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+// Package monitoring contains business logic of Kubernetes cluster monitoring by PMM.
+package monitoring
+
+import (
+	"context"
+	"net/url"
+	"time"
+
+	controllerv1beta1 "github.com/percona-platform/dbaas-api/gen/controller"
+	"github.com/pkg/errors"
+	"golang.org/x/text/message"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/percona-platform/dbaas-controller/service/k8sclient"
+)
+
+// Params contains parameters of monitoring agent installed to Kubernetes clusters.
+type Params struct {
+	// ScrapeInterval is the interval of metrics scraping.
+	ScrapeInterval time.Duration
+	// TLSVerify enables verification of PMM server TLS certificate.
+	TLSVerify bool
+}
+
+// Service sets up VictoriaMetrics operator and agent sending Kubernetes cluster metrics to PMM server.
+type Service struct {
+	p      *message.Printer
+	params Params
+}
+
+// NewService returns new Service instance.
+func NewService(p *message.Printer, params Params) *Service {
+	return &Service{p: p, params: params}
+}
+
+// StopMonitoringRequest contains parameters of monitoring removal.
+type StopMonitoringRequest struct {
+	Kubeconfig string `json:"kubeconfig"`
+}
+
+// Validate checks that the request has kubeconfig.
+func (req *StopMonitoringRequest) Validate() error {
+	if req.Kubeconfig == "" {
+		return errors.New("kubeconfig is required")
+	}
+	return nil
+}
+
+// MonitoringStatusRequest contains parameters of monitoring status request.
+type MonitoringStatusRequest struct {
+	Kubeconfig string `json:"kubeconfig"`
+}
+
+// Validate checks that the request has kubeconfig.
+func (req *MonitoringStatusRequest) Validate() error {
+	if req.Kubeconfig == "" {
+		return errors.New("kubeconfig is required")
+	}
+	return nil
+}
+
+// validatePMMParams checks PMM server parameters are usable by monitoring agent.
+func validatePMMParams(pmm *controllerv1beta1.PMMParams) error {
+	if pmm == nil {
+		return errors.New("PMM parameters are missing")
+	}
+	u, err := url.Parse(pmm.PublicAddress)
+	if err != nil {
+		return errors.Wrap(err, "invalid PMM public address")
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return errors.Errorf("PMM public address %q must start with http:// or https://", pmm.PublicAddress)
+	}
+	if u.Hostname() == "" {
+		return errors.Errorf("PMM public address %q has no host", pmm.PublicAddress)
+	}
+	if (u.Path != "" && u.Path != "/") || u.RawQuery != "" || u.Fragment != "" || u.User != nil {
+		return errors.Errorf("PMM public address %q must contain only scheme, host and port", pmm.PublicAddress)
+	}
+	if pmm.Login == "" || pmm.Password == "" {
+		return errors.New("PMM login and password are required")
+	}
+	return nil
+}
+
+// StartMonitoring sets up VictoriaMetrics operator and agent to monitor Kubernetes cluster.
+// Starting it again for the same PMM server updates credentials and settings of the agent.
+func (s *Service) StartMonitoring(ctx context.Context, req *controllerv1beta1.StartMonitoringRequest) (*controllerv1beta1.StartMonitoringResponse, error) {
+	if err := validatePMMParams(req.Pmm); err != nil {
+		return nil, status.Error(codes.InvalidArgument, s.p.Sprintf("Invalid PMM parameters: %s", err))
+	}
+
+	client, err := k8sclient.New(ctx, req.KubeAuth.Kubeconfig)
+	if err != nil {
+		return nil, status.Error(codes.FailedPrecondition, s.p.Sprintf("Unable to connect to Kubernetes cluster: %s", err))
+	}
+	defer client.Cleanup() //nolint:errcheck
+
+	err = client.CreateVMOperator(ctx, &k8sclient.PMM{
+		PublicAddress:  req.Pmm.PublicAddress,
+		Login:          req.Pmm.Login,
+		Password:       req.Pmm.Password,
+		ScrapeInterval: s.params.ScrapeInterval,
+		TLSVerify:      s.params.TLSVerify,
+	})
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	return new(controllerv1beta1.StartMonitoringResponse), nil
+}
+
+// StopMonitoring removes monitoring agent, its secrets and scrapes and VictoriaMetrics operator.
+func (s *Service) StopMonitoring(ctx context.Context, req *StopMonitoringRequest) error {
+	if err := req.Validate(); err != nil {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	client, err := k8sclient.New(ctx, req.Kubeconfig)
+	if err != nil {
+		return status.Error(codes.FailedPrecondition, s.p.Sprintf("Unable to connect to Kubernetes cluster: %s", err))
+	}
+	defer client.Cleanup() //nolint:errcheck
+
+	if err := client.RemoveVMOperator(ctx); err != nil {
+		return status.Error(codes.Internal, err.Error())
+	}
+	return nil
+}
+
+// GetMonitoringStatus returns status of VictoriaMetrics operator and monitoring agents.
+func (s *Service) GetMonitoringStatus(ctx context.Context, req *MonitoringStatusRequest) (*k8sclient.MonitoringStatus, error) {
+	if err := req.Validate(); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	client, err := k8sclient.New(ctx, req.Kubeconfig)
+	if err != nil {
+		return nil, status.Error(codes.FailedPrecondition, s.p.Sprintf("Unable to connect to Kubernetes cluster: %s", err))
+	}
+	defer client.Cleanup() //nolint:errcheck
+
+	monitoringStatus, err := client.GetMonitoringStatus(ctx)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	return monitoringStatus, nil
+}
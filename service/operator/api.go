@@ -0,0 +1,265 @@
+// dbaas-controller
+// Copyright (C) 2020 Percona LLC
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package operator
+
+import (
+	"context"
+
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/types/known/emptypb"
+
+	"github.com/percona-platform/dbaas-controller/service/k8sclient"
+	"github.com/percona-platform/dbaas-controller/utils/jsonapi"
+)
+
+// APIServiceName is the name of gRPC service with operator methods which are not part of controller API.
+// Its messages are JSON objects, see jsonapi package.
+const APIServiceName = "percona.platform.dbaas.controller.operator.v1.OperatorAPI"
+
+// APIServer serves operator methods which are not part of controller API.
+type APIServer struct {
+	PXC   *PXCOperatorService
+	PSMDB *PSMDBOperatorService
+}
+
+// APIServiceDesc describes gRPC service with operator methods which are not part of controller API.
+var APIServiceDesc = grpc.ServiceDesc{ //nolint:gochecknoglobals
+	ServiceName: APIServiceName,
+	HandlerType: (*interface{})(nil), // handlers require *APIServer
+	Methods: []grpc.MethodDesc{
+		unaryMethod("PlanPXCOperatorUpgrade", func() interface{} { return new(PlanOperatorUpgradeRequest) },
+			func(ctx context.Context, s *APIServer, req interface{}) (interface{}, error) {
+				return s.PXC.PlanPXCOperatorUpgrade(ctx, req.(*PlanOperatorUpgradeRequest))
+			}),
+		unaryMethod("UpgradePXCOperator", func() interface{} { return new(UpgradeOperatorRequest) },
+			func(ctx context.Context, s *APIServer, req interface{}) (interface{}, error) {
+				if err := s.PXC.UpgradePXCOperator(ctx, req.(*UpgradeOperatorRequest)); err != nil {
+					return nil, err
+				}
+				return new(emptypb.Empty), nil
+			}),
+		unaryMethod("PlanPSMDBOperatorUpgrade", func() interface{} { return new(PlanOperatorUpgradeRequest) },
+			func(ctx context.Context, s *APIServer, req interface{}) (interface{}, error) {
+				return s.PSMDB.PlanPSMDBOperatorUpgrade(ctx, req.(*PlanOperatorUpgradeRequest))
+			}),
+		unaryMethod("UpgradePSMDBOperator", func() interface{} { return new(UpgradeOperatorRequest) },
+			func(ctx context.Context, s *APIServer, req interface{}) (interface{}, error) {
+				if err := s.PSMDB.UpgradePSMDBOperator(ctx, req.(*UpgradeOperatorRequest)); err != nil {
+					return nil, err
+				}
+				return new(emptypb.Empty), nil
+			}),
+		unaryMethod("GetPXCRollout", func() interface{} { return new(RolloutRequest) },
+			func(ctx context.Context, s *APIServer, req interface{}) (interface{}, error) {
+				return s.PXC.GetPXCRollout(ctx, req.(*RolloutRequest))
+			}),
+		unaryMethod("ResumePXCRollout", func() interface{} { return new(RolloutRequest) },
+			func(ctx context.Context, s *APIServer, req interface{}) (interface{}, error) {
+				if err := s.PXC.ResumePXCRollout(ctx, req.(*RolloutRequest)); err != nil {
+					return nil, err
+				}
+				return new(emptypb.Empty), nil
+			}),
+		unaryMethod("GetPSMDBRollout", func() interface{} { return new(RolloutRequest) },
+			func(ctx context.Context, s *APIServer, req interface{}) (interface{}, error) {
+				return s.PSMDB.GetPSMDBRollout(ctx, req.(*RolloutRequest))
+			}),
+		unaryMethod("ResumePSMDBRollout", func() interface{} { return new(RolloutRequest) },
+			func(ctx context.Context, s *APIServer, req interface{}) (interface{}, error) {
+				if err := s.PSMDB.ResumePSMDBRollout(ctx, req.(*RolloutRequest)); err != nil {
+					return nil, err
+				}
+				return new(emptypb.Empty), nil
+			}),
+		unaryMethod("UninstallPXCOperator", func() interface{} { return new(UninstallOperatorRequest) },
+			func(ctx context.Context, s *APIServer, req interface{}) (interface{}, error) {
+				return s.PXC.UninstallPXCOperator(ctx, req.(*UninstallOperatorRequest))
+			}),
+		unaryMethod("UninstallPSMDBOperator", func() interface{} { return new(UninstallOperatorRequest) },
+			func(ctx context.Context, s *APIServer, req interface{}) (interface{}, error) {
+				return s.PSMDB.UninstallPSMDBOperator(ctx, req.(*UninstallOperatorRequest))
+			}),
+		unaryMethod("RollbackPXCOperator", func() interface{} { return new(RollbackOperatorRequest) },
+			func(ctx context.Context, s *APIServer, req interface{}) (interface{}, error) {
+				return s.PXC.RollbackPXCOperator(ctx, req.(*RollbackOperatorRequest))
+			}),
+		unaryMethod("RollbackPSMDBOperator", func() interface{} { return new(RollbackOperatorRequest) },
+			func(ctx context.Context, s *APIServer, req interface{}) (interface{}, error) {
+				return s.PSMDB.RollbackPSMDBOperator(ctx, req.(*RollbackOperatorRequest))
+			}),
+		unaryMethod("GetPXCOperatorStatus", func() interface{} { return new(OperatorStatusRequest) },
+			func(ctx context.Context, s *APIServer, req interface{}) (interface{}, error) {
+				return s.PXC.GetPXCOperatorStatus(ctx, req.(*OperatorStatusRequest))
+			}),
+		unaryMethod("GetPSMDBOperatorStatus", func() interface{} { return new(OperatorStatusRequest) },
+			func(ctx context.Context, s *APIServer, req interface{}) (interface{}, error) {
+				return s.PSMDB.GetPSMDBOperatorStatus(ctx, req.(*OperatorStatusRequest))
+			}),
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "service/operator/api.go",
+}
+
+// unaryMethod returns description of the API method.
+func unaryMethod(
+	name string, newRequest func() interface{}, call func(ctx context.Context, s *APIServer, req interface{}) (interface{}, error),
+) grpc.MethodDesc {
+	return jsonapi.UnaryMethod(APIServiceName, name, newRequest, func(ctx context.Context, srv interface{}, req interface{}) (interface{}, error) {
+		return call(ctx, srv.(*APIServer), req)
+	})
+}
+
+// RegisterAPIServer registers gRPC service with operator methods which are not part of controller API.
+func RegisterAPIServer(s *grpc.Server, srv *APIServer) {
+	s.RegisterService(&APIServiceDesc, srv)
+}
+
+// APIClient is the client of gRPC service with operator methods which are not part of controller API.
+type APIClient struct {
+	cc grpc.ClientConnInterface
+}
+
+// NewAPIClient returns new APIClient instance.
+func NewAPIClient(cc grpc.ClientConnInterface) *APIClient {
+	return &APIClient{cc: cc}
+}
+
+// PlanPXCOperatorUpgrade returns plan of PXC operator upgrade without applying it.
+func (c *APIClient) PlanPXCOperatorUpgrade(
+	ctx context.Context, req *PlanOperatorUpgradeRequest, opts ...grpc.CallOption,
+) (*k8sclient.UpgradePlan, error) {
+	res := new(k8sclient.UpgradePlan)
+	if err := jsonapi.Invoke(ctx, c.cc, "/"+APIServiceName+"/PlanPXCOperatorUpgrade", req, res, opts...); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// UpgradePXCOperator upgrades installed PXC operator.
+func (c *APIClient) UpgradePXCOperator(ctx context.Context, req *UpgradeOperatorRequest, opts ...grpc.CallOption) error {
+	return jsonapi.Invoke(ctx, c.cc, "/"+APIServiceName+"/UpgradePXCOperator", req, new(emptypb.Empty), opts...)
+}
+
+// PlanPSMDBOperatorUpgrade returns plan of PSMDB operator upgrade without applying it.
+func (c *APIClient) PlanPSMDBOperatorUpgrade(
+	ctx context.Context, req *PlanOperatorUpgradeRequest, opts ...grpc.CallOption,
+) (*k8sclient.UpgradePlan, error) {
+	res := new(k8sclient.UpgradePlan)
+	if err := jsonapi.Invoke(ctx, c.cc, "/"+APIServiceName+"/PlanPSMDBOperatorUpgrade", req, res, opts...); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// UpgradePSMDBOperator upgrades installed PSMDB operator.
+func (c *APIClient) UpgradePSMDBOperator(ctx context.Context, req *UpgradeOperatorRequest, opts ...grpc.CallOption) error {
+	return jsonapi.Invoke(ctx, c.cc, "/"+APIServiceName+"/UpgradePSMDBOperator", req, new(emptypb.Empty), opts...)
+}
+
+// GetPXCRollout returns the last rollout of PXC cluster patches, e.g. the one paused after operator install.
+func (c *APIClient) GetPXCRollout(ctx context.Context, req *RolloutRequest, opts ...grpc.CallOption) (*k8sclient.Rollout, error) {
+	res := new(k8sclient.Rollout)
+	if err := jsonapi.Invoke(ctx, c.cc, "/"+APIServiceName+"/GetPXCRollout", req, res, opts...); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// ResumePXCRollout resumes paused rollout of PXC cluster patches.
+func (c *APIClient) ResumePXCRollout(ctx context.Context, req *RolloutRequest, opts ...grpc.CallOption) error {
+	return jsonapi.Invoke(ctx, c.cc, "/"+APIServiceName+"/ResumePXCRollout", req, new(emptypb.Empty), opts...)
+}
+
+// GetPSMDBRollout returns the last rollout of PSMDB cluster patches, e.g. the one paused after operator install.
+func (c *APIClient) GetPSMDBRollout(ctx context.Context, req *RolloutRequest, opts ...grpc.CallOption) (*k8sclient.Rollout, error) {
+	res := new(k8sclient.Rollout)
+	if err := jsonapi.Invoke(ctx, c.cc, "/"+APIServiceName+"/GetPSMDBRollout", req, res, opts...); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// ResumePSMDBRollout resumes paused rollout of PSMDB cluster patches.
+func (c *APIClient) ResumePSMDBRollout(ctx context.Context, req *RolloutRequest, opts ...grpc.CallOption) error {
+	return jsonapi.Invoke(ctx, c.cc, "/"+APIServiceName+"/ResumePSMDBRollout", req, new(emptypb.Empty), opts...)
+}
+
+// UninstallPXCOperator removes PXC operator.
+func (c *APIClient) UninstallPXCOperator(
+	ctx context.Context, req *UninstallOperatorRequest, opts ...grpc.CallOption,
+) (*UninstallOperatorResponse, error) {
+	res := new(UninstallOperatorResponse)
+	if err := jsonapi.Invoke(ctx, c.cc, "/"+APIServiceName+"/UninstallPXCOperator", req, res, opts...); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// UninstallPSMDBOperator removes PSMDB operator.
+func (c *APIClient) UninstallPSMDBOperator(
+	ctx context.Context, req *UninstallOperatorRequest, opts ...grpc.CallOption,
+) (*UninstallOperatorResponse, error) {
+	res := new(UninstallOperatorResponse)
+	if err := jsonapi.Invoke(ctx, c.cc, "/"+APIServiceName+"/UninstallPSMDBOperator", req, res, opts...); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// RollbackPXCOperator restores PXC operator and PXC clusters to the state before the last operator upgrade.
+func (c *APIClient) RollbackPXCOperator(
+	ctx context.Context, req *RollbackOperatorRequest, opts ...grpc.CallOption,
+) (*RollbackOperatorResponse, error) {
+	res := new(RollbackOperatorResponse)
+	if err := jsonapi.Invoke(ctx, c.cc, "/"+APIServiceName+"/RollbackPXCOperator", req, res, opts...); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// RollbackPSMDBOperator restores PSMDB operator and PSMDB clusters to the state before the last operator upgrade.
+func (c *APIClient) RollbackPSMDBOperator(
+	ctx context.Context, req *RollbackOperatorRequest, opts ...grpc.CallOption,
+) (*RollbackOperatorResponse, error) {
+	res := new(RollbackOperatorResponse)
+	if err := jsonapi.Invoke(ctx, c.cc, "/"+APIServiceName+"/RollbackPSMDBOperator", req, res, opts...); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// GetPXCOperatorStatus returns state of PXC operator API, deployment and pods.
+func (c *APIClient) GetPXCOperatorStatus(
+	ctx context.Context, req *OperatorStatusRequest, opts ...grpc.CallOption,
+) (*k8sclient.OperatorStatus, error) {
+	res := new(k8sclient.OperatorStatus)
+	if err := jsonapi.Invoke(ctx, c.cc, "/"+APIServiceName+"/GetPXCOperatorStatus", req, res, opts...); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// GetPSMDBOperatorStatus returns state of PSMDB operator API, deployment and pods.
+func (c *APIClient) GetPSMDBOperatorStatus(
+	ctx context.Context, req *OperatorStatusRequest, opts ...grpc.CallOption,
+) (*k8sclient.OperatorStatus, error) {
+	res := new(k8sclient.OperatorStatus)
+	if err := jsonapi.Invoke(ctx, c.cc, "/"+APIServiceName+"/GetPSMDBOperatorStatus", req, res, opts...); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
@@ -0,0 +1,68 @@
+// dbaas-controller
+// Copyright (C) 2020 Percona LLC
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package operator
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/percona-platform/dbaas-controller/service/k8sclient"
+)
+
+func TestAPI(t *testing.T) {
+	t.Parallel()
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	p := message.NewPrinter(language.English)
+	server := grpc.NewServer()
+	RegisterAPIServer(server, &APIServer{
+		PXC:   NewPXCOperatorService(ctx, p, nil, k8sclient.RolloutParams{}),
+		PSMDB: NewPSMDBOperatorService(ctx, p, nil, k8sclient.RolloutParams{}),
+	})
+	go server.Serve(lis) //nolint:errcheck
+	defer server.Stop()
+
+	conn, err := grpc.DialContext(ctx, lis.Addr().String(), grpc.WithInsecure())
+	require.NoError(t, err)
+	defer conn.Close() //nolint:errcheck
+
+	client := NewAPIClient(conn)
+	_, err = client.PlanPXCOperatorUpgrade(ctx, &PlanOperatorUpgradeRequest{Kubeconfig: "{}"})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	err = client.UpgradePSMDBOperator(ctx, &UpgradeOperatorRequest{Version: "1.12.0", Force: true})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	_, err = client.GetPXCRollout(ctx, new(RolloutRequest))
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	_, err = client.UninstallPSMDBOperator(ctx, &UninstallOperatorRequest{RemoveCRDs: true})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	_, err = client.RollbackPXCOperator(ctx, new(RollbackOperatorRequest))
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	_, err = client.GetPSMDBOperatorStatus(ctx, new(OperatorStatusRequest))
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
@@ -28,16 +28,19 @@ import (
 	"github.com/percona-platform/dbaas-controller/service/k8sclient"
 )
 
-const psmdbOperatorDeploymentName = "percona-server-mongodb-operator"
-
 type PSMDBOperatorService struct {
-	p                    *message.Printer
-	manifestsURLTemplate string
+	p             *message.Printer
+	manifests     k8sclient.ManifestSource
+	rolloutParams k8sclient.RolloutParams
+	rollouts      *rollouts
 }
 
 // NewPSMDBOperatorService returns new PSMDBOperatorService instance.
-func NewPSMDBOperatorService(p *message.Printer, url string) *PSMDBOperatorService {
-	return &PSMDBOperatorService{p: p, manifestsURLTemplate: url}
+// Rollouts of cluster patches run in background are canceled when ctx is canceled.
+func NewPSMDBOperatorService(
+	ctx context.Context, p *message.Printer, manifests k8sclient.ManifestSource, rolloutParams k8sclient.RolloutParams,
+) *PSMDBOperatorService {
+	return &PSMDBOperatorService{p: p, manifests: manifests, rolloutParams: rolloutParams, rollouts: newRollouts(ctx)}
 }
 
 func (x PSMDBOperatorService) InstallPSMDBOperator(ctx context.Context, req *controllerv1beta1.InstallPSMDBOperatorRequest) (*controllerv1beta1.InstallPSMDBOperatorResponse, error) {
@@ -47,30 +50,55 @@ func (x PSMDBOperatorService) InstallPSMDBOperator(ctx context.Context, req *con
 	}
 	defer client.Cleanup() //nolint:errcheck
 
-	// Try to get operator versions to see if we should upgrade or install.
-	operators, err := client.CheckOperators(ctx)
+	// Check operator state to see if we should upgrade or install.
+	operatorStatus, err := client.GetOperatorStatus(ctx, k8sclient.EnginePSMDB)
 	if err != nil {
 		return nil, status.Error(codes.Internal, err.Error())
 	}
 
-	// NOTE: This does not handle corner case when user has deployed database clusters and operator is no longer installed.
-	if operators.PsmdbOperatorVersion != "" {
-		err = client.UpdateOperator(ctx, req.Version, psmdbOperatorDeploymentName, x.manifestsURLTemplate)
-		if err != nil {
-			return nil, status.Error(codes.Internal, err.Error())
-		}
-		err = client.PatchAllPSMDBClusters(ctx, operators.PsmdbOperatorVersion, req.Version)
+	// Operator API left behind without the deployment is handled by the install which recreates the deployment.
+	if operatorStatus.Installed() {
+		// Controller API can't force the upgrade, and compatibility matrix may lag behind operator releases,
+		// so upgrade which can't be verified is applied with a warning. Unsafe upgrade is rejected anyway.
+		err = x.upgradePSMDBOperator(ctx, client, req.KubeAuth.Kubeconfig, installedOperatorVersion(operatorStatus), req.Version, true)
 		if err != nil {
 			return nil, err
 		}
-
 		return new(controllerv1beta1.InstallPSMDBOperatorResponse), nil
 	}
 
-	err = client.ApplyOperator(ctx, req.Version, x.manifestsURLTemplate)
+	err = client.ApplyOperator(ctx, req.Version, x.manifests)
 	if err != nil {
 		return nil, err
 	}
 
 	return new(controllerv1beta1.InstallPSMDBOperatorResponse), nil
 }
+
+// upgradePSMDBOperator upgrades PSMDB operator from oldVersion to version and patches PSMDB clusters in background.
+// Upgrade is checked against compatibility matrix first, unverified upgrade is applied only if forced.
+// Upgrade is refused while the previous rollout of cluster patches is in progress.
+func (x PSMDBOperatorService) upgradePSMDBOperator(
+	ctx context.Context, client *k8sclient.K8sClient, kubeconfig, oldVersion, version string, force bool,
+) error {
+	prepare := func() error {
+		if err := checkNoRolloutInProgress(ctx, client, k8sclient.EnginePSMDB); err != nil {
+			return err
+		}
+		plan, err := client.PlanPSMDBOperatorUpgrade(ctx, oldVersion, version)
+		if err != nil {
+			return status.Error(codes.Internal, err.Error())
+		}
+		if err := checkUpgradePlan(ctx, plan, force); err != nil {
+			return err
+		}
+		err = client.UpdateOperator(ctx, k8sclient.EnginePSMDB, version, k8sclient.PSMDBOperatorDeploymentName, x.manifests)
+		if err != nil {
+			return updateOperatorError(err)
+		}
+		return nil
+	}
+	return x.rollouts.run(ctx, kubeconfig, prepare, func(ctx context.Context, client *k8sclient.K8sClient) (*k8sclient.Rollout, error) {
+		return client.PatchAllPSMDBClusters(ctx, oldVersion, version, x.rolloutParams)
+	})
+}
@@ -27,16 +27,19 @@ import (
 	"github.com/percona-platform/dbaas-controller/service/k8sclient"
 )
 
-const pxcOperatorDeploymentName = "percona-xtradb-cluster-operator"
-
 type PXCOperatorService struct {
-	p                    *message.Printer
-	manifestsURLTemplate string
+	p             *message.Printer
+	manifests     k8sclient.ManifestSource
+	rolloutParams k8sclient.RolloutParams
+	rollouts      *rollouts
 }
 
 // NewPXCOperatorService returns new PXCOperatorService instance.
-func NewPXCOperatorService(p *message.Printer, url string) *PXCOperatorService {
-	return &PXCOperatorService{p: p, manifestsURLTemplate: url}
+// Rollouts of cluster patches run in background are canceled when ctx is canceled.
+func NewPXCOperatorService(
+	ctx context.Context, p *message.Printer, manifests k8sclient.ManifestSource, rolloutParams k8sclient.RolloutParams,
+) *PXCOperatorService {
+	return &PXCOperatorService{p: p, manifests: manifests, rolloutParams: rolloutParams, rollouts: newRollouts(ctx)}
 }
 
 func (x PXCOperatorService) InstallPXCOperator(ctx context.Context, req *controllerv1beta1.InstallPXCOperatorRequest) (*controllerv1beta1.InstallPXCOperatorResponse, error) {
@@ -46,30 +49,55 @@ func (x PXCOperatorService) InstallPXCOperator(ctx context.Context, req *control
 	}
 	defer client.Cleanup() //nolint:errcheck
 
-	// Try to get operator versions to see if we should upgrade or install.
-	operators, err := client.CheckOperators(ctx)
+	// Check operator state to see if we should upgrade or install.
+	operatorStatus, err := client.GetOperatorStatus(ctx, k8sclient.EnginePXC)
 	if err != nil {
 		return nil, status.Error(codes.Internal, err.Error())
 	}
 
-	// NOTE: This does not handle corner case when user has deployed database clusters and operator is no longer installed.
-	if operators.PXCOperatorVersion != "" {
-		err = client.UpdateOperator(ctx, req.Version, pxcOperatorDeploymentName, x.manifestsURLTemplate)
-		if err != nil {
-			return nil, status.Error(codes.Internal, err.Error())
-		}
-		err = client.PatchAllPXCClusters(ctx, operators.PXCOperatorVersion, req.Version)
+	// Operator API left behind without the deployment is handled by the install which recreates the deployment.
+	if operatorStatus.Installed() {
+		// Controller API can't force the upgrade, and compatibility matrix may lag behind operator releases,
+		// so upgrade which can't be verified is applied with a warning. Unsafe upgrade is rejected anyway.
+		err = x.upgradePXCOperator(ctx, client, req.KubeAuth.Kubeconfig, installedOperatorVersion(operatorStatus), req.Version, true)
 		if err != nil {
 			return nil, err
 		}
-
 		return new(controllerv1beta1.InstallPXCOperatorResponse), nil
 	}
 
-	err = client.ApplyOperator(ctx, req.Version, x.manifestsURLTemplate)
+	err = client.ApplyOperator(ctx, req.Version, x.manifests)
 	if err != nil {
 		return nil, err
 	}
 
 	return new(controllerv1beta1.InstallPXCOperatorResponse), nil
 }
+
+// upgradePXCOperator upgrades PXC operator from oldVersion to version and patches PXC clusters in background.
+// Upgrade is checked against compatibility matrix first, unverified upgrade is applied only if forced.
+// Upgrade is refused while the previous rollout of cluster patches is in progress.
+func (x PXCOperatorService) upgradePXCOperator(
+	ctx context.Context, client *k8sclient.K8sClient, kubeconfig, oldVersion, version string, force bool,
+) error {
+	prepare := func() error {
+		if err := checkNoRolloutInProgress(ctx, client, k8sclient.EnginePXC); err != nil {
+			return err
+		}
+		plan, err := client.PlanPXCOperatorUpgrade(ctx, oldVersion, version)
+		if err != nil {
+			return status.Error(codes.Internal, err.Error())
+		}
+		if err := checkUpgradePlan(ctx, plan, force); err != nil {
+			return err
+		}
+		err = client.UpdateOperator(ctx, k8sclient.EnginePXC, version, k8sclient.PXCOperatorDeploymentName, x.manifests)
+		if err != nil {
+			return updateOperatorError(err)
+		}
+		return nil
+	}
+	return x.rollouts.run(ctx, kubeconfig, prepare, func(ctx context.Context, client *k8sclient.K8sClient) (*k8sclient.Rollout, error) {
+		return client.PatchAllPXCClusters(ctx, oldVersion, version, x.rolloutParams)
+	})
+}
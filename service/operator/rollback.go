@@ -0,0 +1,114 @@
+// dbaas-controller
+// Copyright (C) 2020 Percona LLC
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package operator
+
+import (
+	"context"
+	"sort"
+
+	"github.com/pkg/errors"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/percona-platform/dbaas-controller/service/k8sclient"
+)
+
+// RollbackOperatorRequest contains parameters of operator rollback.
+type RollbackOperatorRequest struct {
+	Kubeconfig string `json:"kubeconfig"`
+}
+
+// Validate checks that the request has kubeconfig.
+func (req *RollbackOperatorRequest) Validate() error {
+	if req.Kubeconfig == "" {
+		return errors.New("kubeconfig is required")
+	}
+	return nil
+}
+
+// RollbackOperatorResponse describes the rollback.
+type RollbackOperatorResponse struct {
+	// FromVersion is the version of the operator before the rollback.
+	FromVersion string `json:"fromVersion"`
+	// ToVersion is the version of the operator after the rollback.
+	ToVersion string `json:"toVersion"`
+	// Clusters contains names of restored database clusters.
+	Clusters []string `json:"clusters"`
+}
+
+// rollbackOperator rolls back the last upgrade of given operator and converts errors to gRPC statuses.
+// Rollback is refused while operator upgrade or rollout of cluster patches is in progress.
+func (r *rollouts) rollbackOperator(
+	ctx context.Context, operator k8sclient.Engine, deploymentName string, req *RollbackOperatorRequest,
+) (*RollbackOperatorResponse, error) {
+	if err := req.Validate(); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	unlock, err := r.lock(req.Kubeconfig)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	client, err := k8sclient.New(ctx, req.Kubeconfig)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	defer client.Cleanup() //nolint:errcheck
+
+	snapshot, err := client.RollbackOperator(ctx, operator, deploymentName)
+	switch {
+	case errors.Is(err, k8sclient.ErrNotFound):
+		return nil, status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, k8sclient.ErrRolloutInProgress):
+		return nil, status.Error(codes.FailedPrecondition, err.Error())
+	case errors.Is(err, k8sclient.ErrOperatorUnhealthy):
+		return nil, status.Error(codes.Unavailable, err.Error())
+	case err != nil:
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	res := &RollbackOperatorResponse{
+		FromVersion: snapshot.NewVersion,
+		ToVersion:   snapshot.Version,
+		Clusters:    make([]string, 0, len(snapshot.Clusters)),
+	}
+	for name := range snapshot.Clusters {
+		res.Clusters = append(res.Clusters, name)
+	}
+	sort.Strings(res.Clusters)
+	return res, nil
+}
+
+// updateOperatorError converts operator update error to gRPC status.
+func updateOperatorError(err error) error {
+	if errors.Is(err, k8sclient.ErrOperatorUnhealthy) {
+		return status.Errorf(codes.Unavailable, "%s; the previous version could be restored by operator rollback", err)
+	}
+	return status.Error(codes.Internal, err.Error())
+}
+
+// RollbackPXCOperator restores PXC operator and PXC clusters to the state before the last operator upgrade.
+func (x PXCOperatorService) RollbackPXCOperator(ctx context.Context, req *RollbackOperatorRequest) (*RollbackOperatorResponse, error) {
+	return x.rollouts.rollbackOperator(ctx, k8sclient.EnginePXC, k8sclient.PXCOperatorDeploymentName, req)
+}
+
+// RollbackPSMDBOperator restores PSMDB operator and PSMDB clusters to the state before the last operator upgrade.
+func (x PSMDBOperatorService) RollbackPSMDBOperator(ctx context.Context, req *RollbackOperatorRequest) (*RollbackOperatorResponse, error) {
+	return x.rollouts.rollbackOperator(ctx, k8sclient.EnginePSMDB, k8sclient.PSMDBOperatorDeploymentName, req)
+}
@@ -0,0 +1,252 @@
+// dbaas-controller
+// Copyright (C) 2020 Percona LLC
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package operator
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/percona-platform/dbaas-controller/service/k8sclient"
+	"github.com/percona-platform/dbaas-controller/utils/logger"
+)
+
+// RolloutRequest contains parameters of cluster patches rollout requests.
+// Rollout started by operator install or upgrade runs in background, so its state is requested separately.
+type RolloutRequest struct {
+	Kubeconfig string `json:"kubeconfig"`
+}
+
+// Validate checks that the request has kubeconfig.
+func (req *RolloutRequest) Validate() error {
+	if req.Kubeconfig == "" {
+		return errors.New("kubeconfig is required")
+	}
+	return nil
+}
+
+// rolloutPatch patches clusters after operator upgrade, see k8sclient.K8sClient.PatchAllPXCClusters.
+type rolloutPatch func(ctx context.Context, client *k8sclient.K8sClient) (*k8sclient.Rollout, error)
+
+// rollouts runs rollouts of cluster patches after operator upgrade in background,
+// because waiting for every cluster to become ready takes longer than a request.
+// Rollouts are canceled when the controller shuts down, their state is stored in Kubernetes cluster,
+// see k8sclient.Rollout, so they could be resumed.
+type rollouts struct {
+	ctx context.Context
+	wg  sync.WaitGroup
+
+	m sync.Mutex
+	// running holds keys of Kubernetes clusters with operator upgrade, rollback or rollout in progress.
+	running map[string]struct{}
+}
+
+// newRollouts returns rollouts running for the lifetime of ctx.
+func newRollouts(ctx context.Context) *rollouts {
+	return &rollouts{ctx: ctx, running: make(map[string]struct{})}
+}
+
+// rolloutKey returns key of the Kubernetes cluster in running rollouts without keeping kubeconfig around.
+func rolloutKey(kubeconfig string) string {
+	sum := sha256.Sum256([]byte(kubeconfig))
+	return hex.EncodeToString(sum[:])
+}
+
+// lock marks the Kubernetes cluster as changed by this controller until returned unlock function is called.
+// It returns FailedPrecondition status if the cluster is already locked.
+func (r *rollouts) lock(kubeconfig string) (func(), error) {
+	key := rolloutKey(kubeconfig)
+	r.m.Lock()
+	defer r.m.Unlock()
+	if r.ctx.Err() != nil {
+		return nil, status.Error(codes.Unavailable, "controller is shutting down")
+	}
+	if _, ok := r.running[key]; ok {
+		return nil, status.Error(codes.FailedPrecondition, k8sclient.ErrRolloutInProgress.Error())
+	}
+	r.running[key] = struct{}{}
+	r.wg.Add(1)
+	return func() {
+		r.m.Lock()
+		defer r.m.Unlock()
+		delete(r.running, key)
+		r.wg.Done()
+	}, nil
+}
+
+// run calls prepare and then runs rollout of cluster patches in background.
+// Only one rollout per Kubernetes cluster is run or prepared at a time, see lock.
+func (r *rollouts) run(ctx context.Context, kubeconfig string, prepare func() error, patch rolloutPatch) error {
+	unlock, err := r.lock(kubeconfig)
+	if err != nil {
+		return err
+	}
+	if err := prepare(); err != nil {
+		unlock()
+		return err
+	}
+
+	l := logger.Get(ctx)
+	rolloutCtx, cancel := context.WithCancel(logger.GetCtxWithLogger(r.ctx, l))
+	go func() {
+		defer unlock()
+		defer cancel()
+
+		client, err := k8sclient.New(rolloutCtx, kubeconfig)
+		if err != nil {
+			l.Errorf("failed to start rollout of cluster patches: %v", err)
+			return
+		}
+		defer client.Cleanup() //nolint:errcheck
+
+		rollout, err := patch(rolloutCtx, client)
+		if err != nil {
+			l.Errorf("rollout of cluster patches failed: %v", err)
+			return
+		}
+		l.Infof("Rollout of %s cluster patches from %s to %s finished, %d clusters patched",
+			rollout.Operator, rollout.OldVersion, rollout.NewVersion, len(rollout.Clusters))
+	}()
+	return nil
+}
+
+// wait waits for running rollouts to save their state and for other locks to be released
+// after ctx of rollouts is canceled.
+func (r *rollouts) wait() {
+	r.wg.Wait()
+}
+
+// checkNoRolloutInProgress returns FailedPrecondition status if rollout of cluster patches
+// for the operator is run by another controller.
+func checkNoRolloutInProgress(ctx context.Context, client *k8sclient.K8sClient, operator k8sclient.Engine) error {
+	err := client.CheckNoRolloutInProgress(ctx, operator)
+	switch {
+	case errors.Is(err, k8sclient.ErrRolloutInProgress):
+		return status.Error(codes.FailedPrecondition, err.Error())
+	case err != nil:
+		return status.Error(codes.Internal, err.Error())
+	default:
+		return nil
+	}
+}
+
+// getRollout returns the last rollout of cluster patches for the operator.
+func getRollout(ctx context.Context, operator k8sclient.Engine, req *RolloutRequest) (*k8sclient.Rollout, error) {
+	if err := req.Validate(); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	client, err := k8sclient.New(ctx, req.Kubeconfig)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	defer client.Cleanup() //nolint:errcheck
+
+	rollout, err := client.GetRollout(ctx, operator)
+	switch {
+	case errors.Is(err, k8sclient.ErrNotFound):
+		return nil, status.Error(codes.NotFound, err.Error())
+	case err != nil:
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	return rollout, nil
+}
+
+// resumeRollout resumes paused or abandoned rollout of cluster patches for the operator in background.
+func (r *rollouts) resume(
+	ctx context.Context, operator k8sclient.Engine, req *RolloutRequest,
+	patch func(ctx context.Context, client *k8sclient.K8sClient, oldVersion, newVersion string) (*k8sclient.Rollout, error),
+) error {
+	if err := req.Validate(); err != nil {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	client, err := k8sclient.New(ctx, req.Kubeconfig)
+	if err != nil {
+		return status.Error(codes.Internal, err.Error())
+	}
+	defer client.Cleanup() //nolint:errcheck
+
+	var oldVersion, newVersion string
+	prepare := func() error {
+		rollout, err := client.GetRollout(ctx, operator)
+		switch {
+		case errors.Is(err, k8sclient.ErrNotFound):
+			return status.Error(codes.NotFound, err.Error())
+		case err != nil:
+			return status.Error(codes.Internal, err.Error())
+		case rollout.Finished():
+			return status.Errorf(codes.FailedPrecondition, "rollout of %s cluster patches to %s is finished", operator, rollout.NewVersion)
+		case rollout.InProgress(time.Now()):
+			return status.Error(codes.FailedPrecondition, k8sclient.ErrRolloutInProgress.Error())
+		}
+
+		version, err := installedVersion(ctx, client, operator)
+		if err != nil {
+			return err
+		}
+		if version != rollout.NewVersion {
+			return status.Errorf(codes.FailedPrecondition, "%s operator version %s doesn't match rollout version %s",
+				operator, version, rollout.NewVersion)
+		}
+		oldVersion, newVersion = rollout.OldVersion, rollout.NewVersion
+		return nil
+	}
+	return r.run(ctx, req.Kubeconfig, prepare, func(ctx context.Context, client *k8sclient.K8sClient) (*k8sclient.Rollout, error) {
+		return patch(ctx, client, oldVersion, newVersion)
+	})
+}
+
+// GetPXCRollout returns the last rollout of PXC cluster patches.
+func (x PXCOperatorService) GetPXCRollout(ctx context.Context, req *RolloutRequest) (*k8sclient.Rollout, error) {
+	return getRollout(ctx, k8sclient.EnginePXC, req)
+}
+
+// ResumePXCRollout resumes paused rollout of PXC cluster patches in background.
+func (x PXCOperatorService) ResumePXCRollout(ctx context.Context, req *RolloutRequest) error {
+	return x.rollouts.resume(ctx, k8sclient.EnginePXC, req, func(ctx context.Context, client *k8sclient.K8sClient, oldVersion, newVersion string) (*k8sclient.Rollout, error) {
+		return client.PatchAllPXCClusters(ctx, oldVersion, newVersion, x.rolloutParams)
+	})
+}
+
+// WaitRollouts waits for background rollouts of PXC cluster patches to stop after controller shutdown.
+func (x PXCOperatorService) WaitRollouts() {
+	x.rollouts.wait()
+}
+
+// GetPSMDBRollout returns the last rollout of PSMDB cluster patches.
+func (x PSMDBOperatorService) GetPSMDBRollout(ctx context.Context, req *RolloutRequest) (*k8sclient.Rollout, error) {
+	return getRollout(ctx, k8sclient.EnginePSMDB, req)
+}
+
+// ResumePSMDBRollout resumes paused rollout of PSMDB cluster patches in background.
+func (x PSMDBOperatorService) ResumePSMDBRollout(ctx context.Context, req *RolloutRequest) error {
+	return x.rollouts.resume(ctx, k8sclient.EnginePSMDB, req, func(ctx context.Context, client *k8sclient.K8sClient, oldVersion, newVersion string) (*k8sclient.Rollout, error) {
+		return client.PatchAllPSMDBClusters(ctx, oldVersion, newVersion, x.rolloutParams)
+	})
+}
+
+// WaitRollouts waits for background rollouts of PSMDB cluster patches to stop after controller shutdown.
+func (x PSMDBOperatorService) WaitRollouts() {
+	x.rollouts.wait()
+}
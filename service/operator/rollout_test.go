@@ -0,0 +1,57 @@
+// dbaas-controller
+// Copyright (C) 2020 Percona LLC
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package operator
+
+import (
+	"context"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestRolloutsRun(t *testing.T) {
+	t.Parallel()
+	ctx, cancel := context.WithCancel(context.Background())
+	r := newRollouts(ctx)
+	errPrepare := errors.New("prepare failed")
+
+	// Concurrent rollout for the same Kubernetes cluster is refused while the first one is prepared.
+	err := r.run(ctx, "kubeconfig", func() error {
+		err := r.run(ctx, "kubeconfig", func() error { return nil }, nil)
+		assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+		// Rollback is refused too.
+		_, err = r.lock("kubeconfig")
+		assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+		// Other Kubernetes cluster isn't blocked.
+		assert.Equal(t, errPrepare, r.run(ctx, "other", func() error { return errPrepare }, nil))
+		return errPrepare
+	}, nil)
+	assert.Equal(t, errPrepare, err)
+
+	// Rollout which failed to prepare is released.
+	err = r.run(ctx, "kubeconfig", func() error { return errPrepare }, nil)
+	assert.Equal(t, errPrepare, err)
+	assert.Empty(t, r.running)
+
+	cancel()
+	err = r.run(ctx, "kubeconfig", func() error { return nil }, nil)
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+	r.wait()
+}
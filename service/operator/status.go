@@ -0,0 +1,81 @@
+// dbaas-controller
+// Copyright (C) 2020 Percona LLC
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package operator
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/percona-platform/dbaas-controller/service/k8sclient"
+)
+
+// OperatorStatusRequest contains parameters of operator status request.
+type OperatorStatusRequest struct {
+	Kubeconfig string `json:"kubeconfig"`
+}
+
+// Validate checks that the request has kubeconfig.
+func (req *OperatorStatusRequest) Validate() error {
+	if req.Kubeconfig == "" {
+		return errors.New("kubeconfig is required")
+	}
+	return nil
+}
+
+// installedOperatorVersion returns version of the running operator, or the latest version of its API
+// if the version can't be taken from the deployment image.
+func installedOperatorVersion(operatorStatus *k8sclient.OperatorStatus) string {
+	if operatorStatus.Version != "" {
+		return operatorStatus.Version
+	}
+	if len(operatorStatus.CRDVersions) == 0 {
+		return ""
+	}
+	return operatorStatus.CRDVersions[len(operatorStatus.CRDVersions)-1]
+}
+
+// getOperatorStatus returns status of given operator and converts errors to gRPC statuses.
+func getOperatorStatus(ctx context.Context, operator k8sclient.Engine, req *OperatorStatusRequest) (*k8sclient.OperatorStatus, error) {
+	if err := req.Validate(); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	client, err := k8sclient.New(ctx, req.Kubeconfig)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	defer client.Cleanup() //nolint:errcheck
+
+	operatorStatus, err := client.GetOperatorStatus(ctx, operator)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	return operatorStatus, nil
+}
+
+// GetPXCOperatorStatus returns state of PXC operator API, deployment and pods.
+func (x PXCOperatorService) GetPXCOperatorStatus(ctx context.Context, req *OperatorStatusRequest) (*k8sclient.OperatorStatus, error) {
+	return getOperatorStatus(ctx, k8sclient.EnginePXC, req)
+}
+
+// GetPSMDBOperatorStatus returns state of PSMDB operator API, deployment and pods.
+func (x PSMDBOperatorService) GetPSMDBOperatorStatus(ctx context.Context, req *OperatorStatusRequest) (*k8sclient.OperatorStatus, error) {
+	return getOperatorStatus(ctx, k8sclient.EnginePSMDB, req)
+}
@@ -0,0 +1,91 @@
+// dbaas-controller
+// Copyright (C) 2020 Percona LLC
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package operator
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/percona-platform/dbaas-controller/service/k8sclient"
+)
+
+// UninstallOperatorRequest contains parameters of operator uninstall.
+type UninstallOperatorRequest struct {
+	Kubeconfig string `json:"kubeconfig"`
+	// Force uninstalls the operator even if database clusters exist.
+	Force bool `json:"force,omitempty"`
+	// RemoveCRDs removes custom resource definitions and so all database clusters of the operator.
+	RemoveCRDs bool `json:"removeCrds,omitempty"`
+}
+
+// Validate checks that the request has kubeconfig.
+func (req *UninstallOperatorRequest) Validate() error {
+	if req.Kubeconfig == "" {
+		return errors.New("kubeconfig is required")
+	}
+	return nil
+}
+
+// UninstallOperatorResponse describes what was removed by operator uninstall.
+type UninstallOperatorResponse struct {
+	Version string   `json:"version"`
+	Removed []string `json:"removed"`
+}
+
+// uninstallOperator uninstalls given operator and converts errors to gRPC statuses.
+func uninstallOperator(
+	ctx context.Context, operator k8sclient.Engine, manifests k8sclient.ManifestSource, req *UninstallOperatorRequest,
+) (*UninstallOperatorResponse, error) {
+	if err := req.Validate(); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	client, err := k8sclient.New(ctx, req.Kubeconfig)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	defer client.Cleanup() //nolint:errcheck
+
+	report, err := client.UninstallOperator(ctx, &k8sclient.UninstallOperatorParams{
+		Operator:   operator,
+		Manifests:  manifests,
+		Force:      req.Force,
+		RemoveCRDs: req.RemoveCRDs,
+	})
+	switch {
+	case errors.Is(err, k8sclient.ErrOperatorInUse):
+		return nil, status.Error(codes.FailedPrecondition, err.Error())
+	case errors.Is(err, k8sclient.ErrNotFound):
+		return nil, status.Error(codes.NotFound, err.Error())
+	case err != nil:
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	return &UninstallOperatorResponse{Version: report.Version, Removed: report.Removed}, nil
+}
+
+// UninstallPXCOperator removes PXC operator. It refuses to do that while PXC clusters exist unless forced.
+func (x PXCOperatorService) UninstallPXCOperator(ctx context.Context, req *UninstallOperatorRequest) (*UninstallOperatorResponse, error) {
+	return uninstallOperator(ctx, k8sclient.EnginePXC, x.manifests, req)
+}
+
+// UninstallPSMDBOperator removes PSMDB operator. It refuses to do that while PSMDB clusters exist unless forced.
+func (x PSMDBOperatorService) UninstallPSMDBOperator(ctx context.Context, req *UninstallOperatorRequest) (*UninstallOperatorResponse, error) {
+	return uninstallOperator(ctx, k8sclient.EnginePSMDB, x.manifests, req)
+}
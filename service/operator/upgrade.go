@@ -0,0 +1,170 @@
+// dbaas-controller
+// Copyright (C) 2020 Percona LLC
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package operator
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/percona-platform/dbaas-controller/service/k8sclient"
+	"github.com/percona-platform/dbaas-controller/utils/logger"
+)
+
+// PlanOperatorUpgradeRequest contains parameters of operator upgrade planning.
+type PlanOperatorUpgradeRequest struct {
+	Kubeconfig string `json:"kubeconfig"`
+	// Version is the operator version to upgrade to.
+	Version string `json:"version"`
+}
+
+// Validate checks that the request has kubeconfig and version.
+func (req *PlanOperatorUpgradeRequest) Validate() error {
+	if req.Kubeconfig == "" || req.Version == "" {
+		return errors.New("kubeconfig and operator version are required")
+	}
+	return nil
+}
+
+// UpgradeOperatorRequest contains parameters of operator upgrade.
+type UpgradeOperatorRequest struct {
+	Kubeconfig string `json:"kubeconfig"`
+	// Version is the operator version to upgrade to.
+	Version string `json:"version"`
+	// Force upgrades the operator even if the upgrade can't be verified against compatibility matrix.
+	// Unsafe upgrades are rejected anyway.
+	Force bool `json:"force,omitempty"`
+}
+
+// Validate checks that the request has kubeconfig and version.
+func (req *UpgradeOperatorRequest) Validate() error {
+	if req.Kubeconfig == "" || req.Version == "" {
+		return errors.New("kubeconfig and operator version are required")
+	}
+	return nil
+}
+
+// checkUpgradePlan converts plan problems to gRPC status. Unverified plan is accepted with a warning only if forced.
+func checkUpgradePlan(ctx context.Context, plan *k8sclient.UpgradePlan, force bool) error {
+	err := plan.Err()
+	if err == nil {
+		return nil
+	}
+	if force && errors.Is(err, k8sclient.ErrUnverifiedOperatorUpgrade) {
+		logger.Get(ctx).Warnf("upgrading operator anyway: %v", err)
+		return nil
+	}
+	return status.Error(codes.FailedPrecondition, err.Error())
+}
+
+// installedVersion returns version of the installed operator or FailedPrecondition status if it's not installed.
+func installedVersion(ctx context.Context, client *k8sclient.K8sClient, operator k8sclient.Engine) (string, error) {
+	operatorStatus, err := client.GetOperatorStatus(ctx, operator)
+	if err != nil {
+		return "", status.Error(codes.Internal, err.Error())
+	}
+	if !operatorStatus.Installed() {
+		return "", status.Errorf(codes.FailedPrecondition, "%s operator is not installed", operator)
+	}
+	return installedOperatorVersion(operatorStatus), nil
+}
+
+// PlanPXCOperatorUpgrade returns plan of PXC operator upgrade without applying it.
+func (x PXCOperatorService) PlanPXCOperatorUpgrade(ctx context.Context, req *PlanOperatorUpgradeRequest) (*k8sclient.UpgradePlan, error) {
+	if err := req.Validate(); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	client, err := k8sclient.New(ctx, req.Kubeconfig)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	defer client.Cleanup() //nolint:errcheck
+
+	oldVersion, err := installedVersion(ctx, client, k8sclient.EnginePXC)
+	if err != nil {
+		return nil, err
+	}
+	plan, err := client.PlanPXCOperatorUpgrade(ctx, oldVersion, req.Version)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	return plan, nil
+}
+
+// UpgradePXCOperator upgrades installed PXC operator and patches PXC clusters in background.
+func (x PXCOperatorService) UpgradePXCOperator(ctx context.Context, req *UpgradeOperatorRequest) error {
+	if err := req.Validate(); err != nil {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	client, err := k8sclient.New(ctx, req.Kubeconfig)
+	if err != nil {
+		return status.Error(codes.Internal, err.Error())
+	}
+	defer client.Cleanup() //nolint:errcheck
+
+	oldVersion, err := installedVersion(ctx, client, k8sclient.EnginePXC)
+	if err != nil {
+		return err
+	}
+	return x.upgradePXCOperator(ctx, client, req.Kubeconfig, oldVersion, req.Version, req.Force)
+}
+
+// PlanPSMDBOperatorUpgrade returns plan of PSMDB operator upgrade without applying it.
+func (x PSMDBOperatorService) PlanPSMDBOperatorUpgrade(ctx context.Context, req *PlanOperatorUpgradeRequest) (*k8sclient.UpgradePlan, error) {
+	if err := req.Validate(); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	client, err := k8sclient.New(ctx, req.Kubeconfig)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	defer client.Cleanup() //nolint:errcheck
+
+	oldVersion, err := installedVersion(ctx, client, k8sclient.EnginePSMDB)
+	if err != nil {
+		return nil, err
+	}
+	plan, err := client.PlanPSMDBOperatorUpgrade(ctx, oldVersion, req.Version)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	return plan, nil
+}
+
+// UpgradePSMDBOperator upgrades installed PSMDB operator and patches PSMDB clusters in background.
+func (x PSMDBOperatorService) UpgradePSMDBOperator(ctx context.Context, req *UpgradeOperatorRequest) error {
+	if err := req.Validate(); err != nil {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	client, err := k8sclient.New(ctx, req.Kubeconfig)
+	if err != nil {
+		return status.Error(codes.Internal, err.Error())
+	}
+	defer client.Cleanup() //nolint:errcheck
+
+	oldVersion, err := installedVersion(ctx, client, k8sclient.EnginePSMDB)
+	if err != nil {
+		return err
+	}
+	return x.upgradePSMDBOperator(ctx, client, req.Kubeconfig, oldVersion, req.Version, req.Force)
+}
@@ -0,0 +1,93 @@
+// dbaas-controller
+// Copyright (C) 2020 Percona LLC
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package registry
+
+import (
+	"context"
+
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/types/known/emptypb"
+
+	"github.com/percona-platform/dbaas-controller/utils/jsonapi"
+)
+
+// APIServiceName is the name of gRPC service managing Kubernetes clusters registry.
+// Controller API doesn't contain registry messages, so requests and responses are JSON objects, see jsonapi package.
+const APIServiceName = "percona.platform.dbaas.controller.registry.v1.RegistryAPI"
+
+// APIServiceDesc describes gRPC service managing Kubernetes clusters registry.
+var APIServiceDesc = grpc.ServiceDesc{ //nolint:gochecknoglobals
+	ServiceName: APIServiceName,
+	HandlerType: (*interface{})(nil), // handlers require *Service
+	Methods: []grpc.MethodDesc{
+		jsonapi.UnaryMethod(APIServiceName, "RegisterKubernetesCluster", func() interface{} { return new(RegisterKubernetesClusterRequest) },
+			func(ctx context.Context, srv interface{}, req interface{}) (interface{}, error) {
+				if err := srv.(*Service).RegisterKubernetesCluster(ctx, req.(*RegisterKubernetesClusterRequest)); err != nil {
+					return nil, err
+				}
+				return new(emptypb.Empty), nil
+			}),
+		jsonapi.UnaryMethod(APIServiceName, "UnregisterKubernetesCluster", func() interface{} { return new(UnregisterKubernetesClusterRequest) },
+			func(ctx context.Context, srv interface{}, req interface{}) (interface{}, error) {
+				if err := srv.(*Service).UnregisterKubernetesCluster(ctx, req.(*UnregisterKubernetesClusterRequest)); err != nil {
+					return nil, err
+				}
+				return new(emptypb.Empty), nil
+			}),
+		jsonapi.UnaryMethod(APIServiceName, "ListRegisteredKubernetesClusters", func() interface{} { return new(ListRegisteredKubernetesClustersRequest) },
+			func(ctx context.Context, srv interface{}, req interface{}) (interface{}, error) {
+				return srv.(*Service).ListRegisteredKubernetesClusters(ctx)
+			}),
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "service/registry/api.go",
+}
+
+// RegisterAPIServer registers gRPC service managing Kubernetes clusters registry.
+func RegisterAPIServer(s *grpc.Server, srv *Service) {
+	s.RegisterService(&APIServiceDesc, srv)
+}
+
+// APIClient is the client of gRPC service managing Kubernetes clusters registry.
+type APIClient struct {
+	cc grpc.ClientConnInterface
+}
+
+// NewAPIClient returns new APIClient instance.
+func NewAPIClient(cc grpc.ClientConnInterface) *APIClient {
+	return &APIClient{cc: cc}
+}
+
+// RegisterKubernetesCluster registers Kubernetes cluster with given name and kubeconfig.
+func (c *APIClient) RegisterKubernetesCluster(ctx context.Context, req *RegisterKubernetesClusterRequest, opts ...grpc.CallOption) error {
+	return jsonapi.Invoke(ctx, c.cc, "/"+APIServiceName+"/RegisterKubernetesCluster", req, new(emptypb.Empty), opts...)
+}
+
+// UnregisterKubernetesCluster removes Kubernetes cluster from the registry.
+func (c *APIClient) UnregisterKubernetesCluster(ctx context.Context, req *UnregisterKubernetesClusterRequest, opts ...grpc.CallOption) error {
+	return jsonapi.Invoke(ctx, c.cc, "/"+APIServiceName+"/UnregisterKubernetesCluster", req, new(emptypb.Empty), opts...)
+}
+
+// ListRegisteredKubernetesClusters returns registered Kubernetes clusters without their kubeconfigs.
+func (c *APIClient) ListRegisteredKubernetesClusters(ctx context.Context, opts ...grpc.CallOption) (*ListRegisteredKubernetesClustersResponse, error) {
+	res := new(ListRegisteredKubernetesClustersResponse)
+	err := jsonapi.Invoke(ctx, c.cc, "/"+APIServiceName+"/ListRegisteredKubernetesClusters", new(ListRegisteredKubernetesClustersRequest), res, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
@@ -0,0 +1,79 @@
+// dbaas-controller
+// Copyright (C) 2020 Percona LLC
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package registry
+
+import (
+	"context"
+	"net"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+func TestAPI(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	store, err := Open(filepath.Join(dir, "registry.json"), filepath.Join(dir, "registry.key"))
+	require.NoError(t, err)
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	server := grpc.NewServer()
+	RegisterAPIServer(server, NewService(message.NewPrinter(language.English), store))
+	go server.Serve(lis) //nolint:errcheck
+	defer server.Stop()
+
+	ctx := context.Background()
+	conn, err := grpc.DialContext(ctx, lis.Addr().String(), grpc.WithInsecure())
+	require.NoError(t, err)
+	defer conn.Close() //nolint:errcheck
+
+	client := NewAPIClient(conn)
+	require.NoError(t, client.RegisterKubernetesCluster(ctx, &RegisterKubernetesClusterRequest{Name: "prod", Kubeconfig: testKubeconfig}))
+	err = client.RegisterKubernetesCluster(ctx, &RegisterKubernetesClusterRequest{Name: "prod", Kubeconfig: testKubeconfig})
+	assert.Equal(t, codes.AlreadyExists, status.Code(err))
+	err = client.RegisterKubernetesCluster(ctx, &RegisterKubernetesClusterRequest{Name: "dev"})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	// Unknown fields are rejected instead of being ignored.
+	in, err := structpb.NewStruct(map[string]interface{}{"name": "dev", "kubeconfig": testKubeconfig, "namespace": "default"})
+	require.NoError(t, err)
+	err = conn.Invoke(ctx, "/"+APIServiceName+"/RegisterKubernetesCluster", in, new(emptypb.Empty))
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	res, err := client.ListRegisteredKubernetesClusters(ctx)
+	require.NoError(t, err)
+	require.Len(t, res.Clusters, 1)
+	assert.Equal(t, "prod", res.Clusters[0].Name)
+	assert.WithinDuration(t, store.List()[0].RegisteredAt, res.Clusters[0].RegisteredAt, 0)
+
+	require.NoError(t, client.UnregisterKubernetesCluster(ctx, &UnregisterKubernetesClusterRequest{Name: "prod"}))
+	err = client.UnregisterKubernetesCluster(ctx, &UnregisterKubernetesClusterRequest{Name: "prod"})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+	res, err = client.ListRegisteredKubernetesClusters(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, res.Clusters)
+}
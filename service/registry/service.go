@@ -0,0 +1,263 @@
+// dbaas-controller
+// Copyright (C) 2020 Percona LLC
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+// Package registry contains Kubernetes clusters registry which allows requests to refer
+// to registered clusters by name instead of sending kubeconfig every time.
+package registry
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	controllerv1beta1 "github.com/percona-platform/dbaas-api/gen/controller"
+	"github.com/pkg/errors"
+	"golang.org/x/text/message"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/structpb"
+
+	"github.com/percona-platform/dbaas-controller/utils/jsonapi"
+)
+
+// KubeconfigRefPrefix prefixes name of registered Kubernetes cluster in KubeAuth.Kubeconfig,
+// like "registry://prod". Such kubeconfig is replaced with the registered one before the request is handled.
+const KubeconfigRefPrefix = "registry://"
+
+// RegisterKubernetesClusterRequest contains parameters of Kubernetes cluster registration.
+type RegisterKubernetesClusterRequest struct {
+	Name       string `json:"name"`
+	Kubeconfig string `json:"kubeconfig"`
+}
+
+// Validate checks that the request has a valid name and kubeconfig which is not a reference to registered cluster.
+func (req *RegisterKubernetesClusterRequest) Validate() error {
+	if err := CheckName(req.Name); err != nil {
+		return err
+	}
+	if req.Kubeconfig == "" || strings.HasPrefix(req.Kubeconfig, KubeconfigRefPrefix) {
+		return errors.New("kubeconfig is required")
+	}
+	return nil
+}
+
+// UnregisterKubernetesClusterRequest contains parameters of Kubernetes cluster removal from the registry.
+type UnregisterKubernetesClusterRequest struct {
+	Name string `json:"name"`
+}
+
+// Validate checks that the request has a name.
+func (req *UnregisterKubernetesClusterRequest) Validate() error {
+	if req.Name == "" {
+		return errors.New("name is required")
+	}
+	return nil
+}
+
+// ListRegisteredKubernetesClustersRequest is a request of registered Kubernetes clusters, it has no parameters.
+type ListRegisteredKubernetesClustersRequest struct{}
+
+// ListRegisteredKubernetesClustersResponse contains registered Kubernetes clusters.
+type ListRegisteredKubernetesClustersResponse struct {
+	Clusters []Cluster `json:"clusters"`
+}
+
+// Service implements API of Kubernetes clusters registry served by gRPC service described by APIServiceDesc.
+// Store is nil if the registry is disabled.
+type Service struct {
+	p     *message.Printer
+	store *Store
+}
+
+// NewService returns new Service instance. Store may be nil if the registry is disabled.
+func NewService(p *message.Printer, store *Store) *Service {
+	return &Service{p: p, store: store}
+}
+
+// checkEnabled returns an error if the registry is disabled.
+func (s *Service) checkEnabled() error {
+	if s.store == nil {
+		return status.Error(codes.FailedPrecondition, s.p.Sprintf("Kubernetes clusters registry is disabled."))
+	}
+	return nil
+}
+
+// storeError converts store error to gRPC status.
+func storeError(err error) error {
+	switch {
+	case errors.Is(err, ErrNotFound):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, ErrAlreadyExists):
+		return status.Error(codes.AlreadyExists, err.Error())
+	default:
+		return status.Error(codes.Internal, err.Error())
+	}
+}
+
+// RegisterKubernetesCluster registers Kubernetes cluster with given name and kubeconfig.
+func (s *Service) RegisterKubernetesCluster(ctx context.Context, req *RegisterKubernetesClusterRequest) error {
+	if err := s.checkEnabled(); err != nil {
+		return err
+	}
+	if err := req.Validate(); err != nil {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+	if err := s.store.Register(req.Name, req.Kubeconfig, time.Now()); err != nil {
+		return storeError(err)
+	}
+	return nil
+}
+
+// UnregisterKubernetesCluster removes Kubernetes cluster from the registry.
+func (s *Service) UnregisterKubernetesCluster(ctx context.Context, req *UnregisterKubernetesClusterRequest) error {
+	if err := s.checkEnabled(); err != nil {
+		return err
+	}
+	if err := req.Validate(); err != nil {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+	if err := s.store.Unregister(req.Name); err != nil {
+		return storeError(err)
+	}
+	return nil
+}
+
+// ListRegisteredKubernetesClusters returns registered Kubernetes clusters without their kubeconfigs.
+func (s *Service) ListRegisteredKubernetesClusters(ctx context.Context) (*ListRegisteredKubernetesClustersResponse, error) {
+	if err := s.checkEnabled(); err != nil {
+		return nil, err
+	}
+	return &ListRegisteredKubernetesClustersResponse{Clusters: s.store.List()}, nil
+}
+
+// kubeAuthRequest is implemented by requests of controller API which contain KubeAuth.
+type kubeAuthRequest interface {
+	GetKubeAuth() *controllerv1beta1.KubeAuth
+}
+
+// ResolveKubeconfig returns kubeconfig of registered Kubernetes cluster if given kubeconfig refers to it.
+// Other kubeconfigs are returned as is.
+func (s *Service) ResolveKubeconfig(kubeconfig string) (string, error) {
+	if !strings.HasPrefix(kubeconfig, KubeconfigRefPrefix) {
+		return kubeconfig, nil
+	}
+	if err := s.checkEnabled(); err != nil {
+		return "", err
+	}
+	resolved, err := s.store.Kubeconfig(strings.TrimPrefix(kubeconfig, KubeconfigRefPrefix))
+	if err != nil {
+		return "", storeError(err)
+	}
+	return resolved, nil
+}
+
+// requestKubeconfig returns kubeconfig of the request: KubeAuth one of controller API requests
+// or top-level field of JSON object requests, see jsonapi package.
+func requestKubeconfig(req interface{}) string {
+	switch r := req.(type) {
+	case kubeAuthRequest:
+		return r.GetKubeAuth().GetKubeconfig()
+	case *structpb.Struct:
+		return r.GetFields()[jsonapi.KubeconfigField].GetStringValue()
+	default:
+		return ""
+	}
+}
+
+// setRequestKubeconfig replaces kubeconfig of the request returned by requestKubeconfig.
+func setRequestKubeconfig(req interface{}, kubeconfig string) {
+	switch r := req.(type) {
+	case kubeAuthRequest:
+		r.GetKubeAuth().Kubeconfig = kubeconfig
+	case *structpb.Struct:
+		r.Fields[jsonapi.KubeconfigField] = structpb.NewStringValue(kubeconfig)
+	}
+}
+
+// resolveRequestKubeconfig returns kubeconfig of the request resolved by ResolveKubeconfig.
+// It returns false if the request doesn't refer to registered Kubernetes cluster.
+func (s *Service) resolveRequestKubeconfig(req interface{}) (string, bool, error) {
+	ref := requestKubeconfig(req)
+	if !strings.HasPrefix(ref, KubeconfigRefPrefix) {
+		return "", false, nil
+	}
+	kubeconfig, err := s.ResolveKubeconfig(ref)
+	if err != nil {
+		return "", false, err
+	}
+	return kubeconfig, true, nil
+}
+
+// resolveRequest returns a copy of the request with reference to registered Kubernetes cluster replaced
+// with its kubeconfig. The request itself is not changed, as other interceptors may log it.
+func (s *Service) resolveRequest(req interface{}) (interface{}, error) {
+	kubeconfig, ok, err := s.resolveRequestKubeconfig(req)
+	if err != nil || !ok {
+		return req, err
+	}
+	msg, ok := req.(proto.Message)
+	if !ok {
+		return nil, status.Errorf(codes.Internal, "unexpected request type %T", req)
+	}
+	resolved := proto.Clone(msg)
+	setRequestKubeconfig(resolved, kubeconfig)
+	return resolved, nil
+}
+
+// UnaryServerInterceptor returns a new unary server interceptor that resolves references
+// to registered Kubernetes clusters in requests.
+func (s *Service) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		// Registry requests refer to clusters by name, their kubeconfigs are never resolved.
+		if strings.HasPrefix(info.FullMethod, "/"+APIServiceName+"/") {
+			return handler(ctx, req)
+		}
+		resolved, err := s.resolveRequest(req)
+		if err != nil {
+			return nil, err
+		}
+		return handler(ctx, resolved)
+	}
+}
+
+// resolvingServerStream resolves references to registered Kubernetes clusters in received messages.
+type resolvingServerStream struct {
+	grpc.ServerStream
+	s *Service
+}
+
+// RecvMsg implements grpc.ServerStream. Received message is owned by the handler, so it's resolved in place.
+func (ss *resolvingServerStream) RecvMsg(m interface{}) error {
+	if err := ss.ServerStream.RecvMsg(m); err != nil {
+		return err
+	}
+	kubeconfig, ok, err := ss.s.resolveRequestKubeconfig(m)
+	if err != nil || !ok {
+		return err
+	}
+	setRequestKubeconfig(m, kubeconfig)
+	return nil
+}
+
+// StreamServerInterceptor returns a new stream server interceptor that resolves references
+// to registered Kubernetes clusters in received messages.
+func (s *Service) StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		return handler(srv, &resolvingServerStream{ServerStream: ss, s: s})
+	}
+}
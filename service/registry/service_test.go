@@ -0,0 +1,126 @@
+// dbaas-controller
+// Copyright (C) 2020 Percona LLC
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package registry
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	controllerv1beta1 "github.com/percona-platform/dbaas-api/gen/controller"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+func TestServiceResolve(t *testing.T) {
+	t.Parallel()
+	p := message.NewPrinter(language.English)
+	dir := t.TempDir()
+	store, err := Open(filepath.Join(dir, "registry.json"), filepath.Join(dir, "registry.key"))
+	require.NoError(t, err)
+	require.NoError(t, store.Register("prod", testKubeconfig, time.Now()))
+
+	ctx := context.Background()
+	s := NewService(p, store)
+	interceptor := s.UnaryServerInterceptor()
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return req.(*controllerv1beta1.GetLogsRequest).KubeAuth.Kubeconfig, nil
+	}
+
+	for _, tt := range []struct {
+		kubeconfig string
+		expected   string
+		code       codes.Code
+	}{
+		{kubeconfig: KubeconfigRefPrefix + "prod", expected: testKubeconfig},
+		{kubeconfig: "plain kubeconfig", expected: "plain kubeconfig"},
+		{kubeconfig: KubeconfigRefPrefix + "dev", code: codes.NotFound},
+	} {
+		req := &controllerv1beta1.GetLogsRequest{KubeAuth: &controllerv1beta1.KubeAuth{Kubeconfig: tt.kubeconfig}}
+		res, err := interceptor(ctx, req, &grpc.UnaryServerInfo{}, handler)
+		if tt.code != codes.OK {
+			assert.Equal(t, tt.code, status.Code(err), tt.kubeconfig)
+			continue
+		}
+		require.NoError(t, err)
+		assert.Equal(t, tt.expected, res)
+		// The request logged by other interceptors keeps the reference.
+		assert.Equal(t, tt.kubeconfig, req.KubeAuth.Kubeconfig)
+	}
+
+	// JSON object requests refer to registered Kubernetes clusters with top-level kubeconfig field.
+	jsonReq, err := structpb.NewStruct(map[string]interface{}{"kubeconfig": KubeconfigRefPrefix + "prod"})
+	require.NoError(t, err)
+	res, err := interceptor(ctx, jsonReq, &grpc.UnaryServerInfo{}, func(ctx context.Context, req interface{}) (interface{}, error) {
+		return req.(*structpb.Struct).Fields["kubeconfig"].GetStringValue(), nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, testKubeconfig, res)
+	assert.Equal(t, KubeconfigRefPrefix+"prod", jsonReq.Fields["kubeconfig"].GetStringValue())
+
+	// Registry requests are never resolved.
+	info := &grpc.UnaryServerInfo{FullMethod: "/" + APIServiceName + "/RegisterKubernetesCluster"}
+	res, err = interceptor(ctx, jsonReq, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+		return req.(*structpb.Struct).Fields["kubeconfig"].GetStringValue(), nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, KubeconfigRefPrefix+"prod", res)
+
+	// Requests without KubeAuth are passed as is.
+	_, err = interceptor(ctx, &controllerv1beta1.GetLogsRequest{}, &grpc.UnaryServerInfo{}, func(ctx context.Context, req interface{}) (interface{}, error) {
+		return nil, nil
+	})
+	assert.NoError(t, err)
+
+	disabled := NewService(p, nil)
+	_, err = disabled.ResolveKubeconfig(KubeconfigRefPrefix + "prod")
+	assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+	kubeconfig, err := disabled.ResolveKubeconfig("plain kubeconfig")
+	require.NoError(t, err)
+	assert.Equal(t, "plain kubeconfig", kubeconfig)
+}
+
+func TestServiceRegistration(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	store, err := Open(filepath.Join(dir, "registry.json"), filepath.Join(dir, "registry.key"))
+	require.NoError(t, err)
+	s := NewService(message.NewPrinter(language.English), store)
+	ctx := context.Background()
+
+	require.NoError(t, s.RegisterKubernetesCluster(ctx, &RegisterKubernetesClusterRequest{Name: "prod", Kubeconfig: testKubeconfig}))
+	err = s.RegisterKubernetesCluster(ctx, &RegisterKubernetesClusterRequest{Name: "prod", Kubeconfig: testKubeconfig})
+	assert.Equal(t, codes.AlreadyExists, status.Code(err))
+	err = s.RegisterKubernetesCluster(ctx, &RegisterKubernetesClusterRequest{Name: "dev", Kubeconfig: KubeconfigRefPrefix + "prod"})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	res, err := s.ListRegisteredKubernetesClusters(ctx)
+	require.NoError(t, err)
+	require.Len(t, res.Clusters, 1)
+	assert.Equal(t, "prod", res.Clusters[0].Name)
+
+	require.NoError(t, s.UnregisterKubernetesCluster(ctx, &UnregisterKubernetesClusterRequest{Name: "prod"}))
+	err = s.UnregisterKubernetesCluster(ctx, &UnregisterKubernetesClusterRequest{Name: "prod"})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+}
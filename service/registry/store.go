@@ -0,0 +1,311 @@
+// dbaas-controller
+// Copyright (C) 2020 Percona LLC
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package registry
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// storeVersion is the version of the store file format.
+	storeVersion = 1
+	// keySize is the size of AES-256 key in bytes.
+	keySize = 32
+)
+
+var (
+	// ErrNotFound is returned when Kubernetes cluster is not registered.
+	ErrNotFound = errors.New("cluster is not registered")
+	// ErrAlreadyExists is returned when Kubernetes cluster with the same name is already registered.
+	ErrAlreadyExists = errors.New("cluster is already registered")
+)
+
+// nameRe matches valid names of registered Kubernetes clusters.
+var nameRe = regexp.MustCompile(`^[a-z0-9]([-a-z0-9]{0,61}[a-z0-9])?$`) //nolint:gochecknoglobals
+
+// Cluster contains information about registered Kubernetes cluster. Kubeconfig is never exposed this way.
+type Cluster struct {
+	Name         string    `json:"name"`
+	RegisteredAt time.Time `json:"registeredAt"`
+}
+
+// storedCluster is a registered Kubernetes cluster as it's saved to the store file.
+type storedCluster struct {
+	Name string `json:"name"`
+	// Kubeconfig is encrypted kubeconfig prefixed with nonce.
+	Kubeconfig   []byte    `json:"kubeconfig"`
+	RegisteredAt time.Time `json:"registered_at"`
+}
+
+// storeFile is the content of the store file.
+type storeFile struct {
+	Version  int                       `json:"version"`
+	Clusters map[string]*storedCluster `json:"clusters"`
+}
+
+// Store keeps registered Kubernetes clusters in a local file. Kubeconfigs are encrypted
+// with AES-GCM using the key from a separate key file. Store is safe for concurrent use,
+// but the file shouldn't be shared by several processes.
+type Store struct {
+	path string
+	aead cipher.AEAD
+
+	m        sync.RWMutex
+	clusters map[string]*storedCluster
+}
+
+// CheckName returns an error if name can't be used for registered Kubernetes cluster.
+func CheckName(name string) error {
+	if !nameRe.MatchString(name) {
+		return errors.Errorf("invalid name %q: it should contain at most 63 lowercase alphanumeric characters or '-', and start and end with an alphanumeric character", name)
+	}
+	return nil
+}
+
+// loadKey reads encryption key from the hex-encoded key file. If the file doesn't exist
+// and generate is true, a new key is generated and written to it.
+func loadKey(keyPath string, generate bool) ([]byte, error) {
+	b, err := os.ReadFile(keyPath) //nolint:gosec
+	if err == nil {
+		key, err := hex.DecodeString(strings.TrimSpace(string(b)))
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to decode key file %s", keyPath)
+		}
+		if len(key) != keySize {
+			return nil, errors.Errorf("key in %s should be %d bytes long, got %d", keyPath, keySize, len(key))
+		}
+		return key, nil
+	}
+	if !os.IsNotExist(err) {
+		return nil, errors.Wrapf(err, "failed to read key file %s", keyPath)
+	}
+	if !generate {
+		return nil, errors.Errorf("key file %s doesn't exist, registered kubeconfigs can't be decrypted without it", keyPath)
+	}
+
+	key := make([]byte, keySize)
+	if _, err := io.ReadFull(rand.Reader, key); err != nil {
+		return nil, errors.Wrap(err, "failed to generate key")
+	}
+	if err := os.WriteFile(keyPath, []byte(hex.EncodeToString(key)+"\n"), 0o600); err != nil {
+		return nil, errors.Wrapf(err, "failed to write key file %s", keyPath)
+	}
+	return key, nil
+}
+
+// readStoreFile reads registered clusters from the store file. It returns no clusters if the file doesn't exist.
+func readStoreFile(path string) (map[string]*storedCluster, error) {
+	clusters := make(map[string]*storedCluster)
+	b, err := os.ReadFile(path) //nolint:gosec
+	if err != nil {
+		if os.IsNotExist(err) {
+			return clusters, nil
+		}
+		return nil, errors.Wrapf(err, "failed to read registry %s", path)
+	}
+	var f storeFile
+	if err := json.Unmarshal(b, &f); err != nil {
+		return nil, errors.Wrapf(err, "failed to decode registry %s", path)
+	}
+	if f.Version != storeVersion {
+		return nil, errors.Errorf("unsupported version %d of registry %s", f.Version, path)
+	}
+	if f.Clusters != nil {
+		clusters = f.Clusters
+	}
+	return clusters, nil
+}
+
+// Open opens the store file at given path, it's created on the first registration.
+// Key path is required. If the key file doesn't exist, a new key is generated and written to it,
+// but only while there are no registered clusters; otherwise a lost key would be silently replaced.
+func Open(path, keyPath string) (*Store, error) {
+	if keyPath == "" {
+		return nil, errors.New("registry key path is required")
+	}
+	clusters, err := readStoreFile(path)
+	if err != nil {
+		return nil, err
+	}
+	key, err := loadKey(keyPath, len(clusters) == 0)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	s := &Store{
+		path:     path,
+		aead:     aead,
+		clusters: clusters,
+	}
+	// Check the key early instead of failing on the first request.
+	for name := range s.clusters {
+		if _, err := s.decrypt(s.clusters[name]); err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+// encrypt encrypts kubeconfig of the cluster. Name is used as additional data,
+// so encrypted kubeconfig can't be moved to another cluster.
+func (s *Store) encrypt(name, kubeconfig string) ([]byte, error) {
+	nonce := make([]byte, s.aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, errors.Wrap(err, "failed to generate nonce")
+	}
+	return s.aead.Seal(nonce, nonce, []byte(kubeconfig), []byte(name)), nil
+}
+
+// decrypt decrypts kubeconfig of the cluster.
+func (s *Store) decrypt(c *storedCluster) (string, error) {
+	nonceSize := s.aead.NonceSize()
+	if len(c.Kubeconfig) < nonceSize {
+		return "", errors.Errorf("kubeconfig of %q is corrupted", c.Name)
+	}
+	b, err := s.aead.Open(nil, c.Kubeconfig[:nonceSize], c.Kubeconfig[nonceSize:], []byte(c.Name))
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to decrypt kubeconfig of %q, check the registry key", c.Name)
+	}
+	return string(b), nil
+}
+
+// save writes clusters to the store file atomically. It should be called with the lock held.
+func (s *Store) save(clusters map[string]*storedCluster) error {
+	b, err := json.MarshalIndent(&storeFile{Version: storeVersion, Clusters: clusters}, "", "  ")
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".*.tmp")
+	if err != nil {
+		return errors.Wrap(err, "failed to save registry")
+	}
+	defer os.Remove(tmp.Name()) //nolint:errcheck
+
+	if _, err := tmp.Write(b); err != nil {
+		tmp.Close() //nolint:errcheck,gosec
+		return errors.Wrap(err, "failed to save registry")
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close() //nolint:errcheck,gosec
+		return errors.Wrap(err, "failed to save registry")
+	}
+	if err := tmp.Close(); err != nil {
+		return errors.Wrap(err, "failed to save registry")
+	}
+	return errors.Wrap(os.Rename(tmp.Name(), s.path), "failed to save registry")
+}
+
+// Register registers Kubernetes cluster with given name and kubeconfig.
+func (s *Store) Register(name, kubeconfig string, now time.Time) error {
+	if err := CheckName(name); err != nil {
+		return err
+	}
+	if kubeconfig == "" {
+		return errors.New("kubeconfig is empty")
+	}
+
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	if _, ok := s.clusters[name]; ok {
+		return errors.Wrapf(ErrAlreadyExists, "%q", name)
+	}
+	encrypted, err := s.encrypt(name, kubeconfig)
+	if err != nil {
+		return err
+	}
+
+	clusters := make(map[string]*storedCluster, len(s.clusters)+1)
+	for n, c := range s.clusters {
+		clusters[n] = c
+	}
+	clusters[name] = &storedCluster{Name: name, Kubeconfig: encrypted, RegisteredAt: now.UTC()}
+	if err := s.save(clusters); err != nil {
+		return err
+	}
+	s.clusters = clusters
+	return nil
+}
+
+// Unregister removes Kubernetes cluster with given name from the registry.
+func (s *Store) Unregister(name string) error {
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	if _, ok := s.clusters[name]; !ok {
+		return errors.Wrapf(ErrNotFound, "%q", name)
+	}
+	clusters := make(map[string]*storedCluster, len(s.clusters))
+	for n, c := range s.clusters {
+		if n != name {
+			clusters[n] = c
+		}
+	}
+	if err := s.save(clusters); err != nil {
+		return err
+	}
+	s.clusters = clusters
+	return nil
+}
+
+// List returns registered Kubernetes clusters sorted by name.
+func (s *Store) List() []Cluster {
+	s.m.RLock()
+	defer s.m.RUnlock()
+
+	res := make([]Cluster, 0, len(s.clusters))
+	for _, c := range s.clusters {
+		res = append(res, Cluster{Name: c.Name, RegisteredAt: c.RegisteredAt})
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].Name < res[j].Name })
+	return res
+}
+
+// Kubeconfig returns decrypted kubeconfig of the registered Kubernetes cluster.
+func (s *Store) Kubeconfig(name string) (string, error) {
+	s.m.RLock()
+	c, ok := s.clusters[name]
+	s.m.RUnlock()
+
+	if !ok {
+		return "", errors.Wrapf(ErrNotFound, "%q", name)
+	}
+	return s.decrypt(c)
+}
@@ -0,0 +1,96 @@
+// dbaas-controller
+// Copyright (C) 2020 Percona LLC
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package registry
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testKubeconfig = "apiVersion: v1\nkind: Config\nusers:\n- name: admin\n  user:\n    token: secret-token\n"
+
+func TestStore(t *testing.T) {
+	t.Parallel()
+	dir := t.TempDir()
+	path, keyPath := filepath.Join(dir, "registry.json"), filepath.Join(dir, "registry.key")
+	now := time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	s, err := Open(path, keyPath)
+	require.NoError(t, err)
+	assert.Empty(t, s.List())
+
+	require.NoError(t, s.Register("prod", testKubeconfig, now))
+	require.NoError(t, s.Register("dev", "dev kubeconfig", now.Add(time.Hour)))
+	err = s.Register("prod", testKubeconfig, now)
+	assert.True(t, errors.Is(err, ErrAlreadyExists), "%+v", err)
+	assert.Error(t, s.Register("Prod_1", testKubeconfig, now))
+	assert.EqualError(t, s.Register("empty", "", now), "kubeconfig is empty")
+
+	b, err := os.ReadFile(path) //nolint:gosec
+	require.NoError(t, err)
+	assert.False(t, strings.Contains(string(b), "secret-token"), "kubeconfig is not encrypted")
+	info, err := os.Stat(keyPath)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+
+	// Reopen the store to check that it's persisted.
+	s, err = Open(path, keyPath)
+	require.NoError(t, err)
+	assert.Equal(t, []Cluster{{Name: "dev", RegisteredAt: now.Add(time.Hour)}, {Name: "prod", RegisteredAt: now}}, s.List())
+	kubeconfig, err := s.Kubeconfig("prod")
+	require.NoError(t, err)
+	assert.Equal(t, testKubeconfig, kubeconfig)
+
+	require.NoError(t, s.Unregister("dev"))
+	err = s.Unregister("dev")
+	assert.True(t, errors.Is(err, ErrNotFound), "%+v", err)
+	_, err = s.Kubeconfig("dev")
+	assert.True(t, errors.Is(err, ErrNotFound), "%+v", err)
+	assert.Equal(t, []Cluster{{Name: "prod", RegisteredAt: now}}, s.List())
+
+	// Lost key isn't replaced with a new one while there are registered clusters.
+	otherKeyPath := filepath.Join(dir, "other.key")
+	_, err = Open(path, otherKeyPath)
+	assert.Error(t, err)
+	_, err = os.Stat(otherKeyPath)
+	assert.True(t, os.IsNotExist(err), "%+v", err)
+
+	// Another key can't decrypt kubeconfigs.
+	require.NoError(t, os.WriteFile(otherKeyPath, []byte(strings.Repeat("ab", keySize)), 0o600))
+	_, err = Open(path, otherKeyPath)
+	assert.Error(t, err)
+
+	_, err = Open(path, "")
+	assert.EqualError(t, err, "registry key path is required")
+}
+
+func TestCheckName(t *testing.T) {
+	t.Parallel()
+	for _, name := range []string{"prod", "eks-1", "a"} {
+		assert.NoError(t, CheckName(name), name)
+	}
+	for _, name := range []string{"", "-prod", "prod-", "Prod", "prod_1", "prod.1", strings.Repeat("a", 64)} {
+		assert.Error(t, CheckName(name), name)
+	}
+}
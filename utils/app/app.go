@@ -19,6 +19,8 @@ package app
 
 import (
 	"fmt"
+	"strconv"
+	"time"
 
 	"github.com/percona/pmm/version"
 	"gopkg.in/alecthomas/kingpin.v2"
@@ -37,6 +39,41 @@ type Flags struct {
 	PXCOperatorURLTemplate string
 	// PSMDBOperatorURLTemplate exists for user to fetch Kubernetes manifests when running DBaaS on air-gapped cluster.
 	PSMDBOperatorURLTemplate string
+	// OperatorManifests is a location of operator manifests used instead of URL templates: "embedded",
+	// a directory or a tarball/OCI image layout archive.
+	OperatorManifests string
+	// OperatorManifestsRequireChecksums rejects operator manifests from OperatorManifests location without checksums.
+	OperatorManifestsRequireChecksums bool
+	// RolloutBatchSize is a number of clusters patched at once after operator upgrade.
+	RolloutBatchSize int
+	// RolloutReadyTimeout is the time a cluster has to become ready after it's patched during operator upgrade.
+	RolloutReadyTimeout time.Duration
+	// MonitoringScrapeInterval is the interval of metrics scraping by Kubernetes cluster monitoring agent.
+	MonitoringScrapeInterval time.Duration
+	// MonitoringTLSVerify enables verification of PMM server TLS certificate when sending metrics.
+	MonitoringTLSVerify bool
+	// LogsLinesLimit and LogsBytesLimit are overall limits of log lines returned by logs API.
+	LogsLinesLimit int
+	LogsBytesLimit int
+	// LogsFailingWeight, LogsEventsWeight and LogsOtherWeight define shares of logs limits
+	// given to failing containers, events and other containers.
+	LogsFailingWeight int
+	LogsEventsWeight  int
+	LogsOtherWeight   int
+	// RegistryPath is a path of Kubernetes clusters registry file, registry is disabled if it's empty.
+	RegistryPath string
+	// RegistryKeyPath is a path of the file with key used for encryption of registered kubeconfigs.
+	RegistryKeyPath string
+	// PasswordLength is the length of generated passwords of database system users.
+	PasswordLength int
+	// PasswordLowercase, PasswordUppercase and PasswordDigits enable character classes of generated passwords.
+	PasswordLowercase bool
+	PasswordUppercase bool
+	PasswordDigits    bool
+	// PXCPasswordSymbols contains special characters allowed in passwords of PXC system users.
+	PXCPasswordSymbols string
+	// PSMDBPasswordSymbols contains special characters allowed in passwords of PSMDB system users.
+	PSMDBPasswordSymbols string
 	// Debug enabled.
 	LogDebug bool
 }
@@ -44,6 +81,17 @@ type Flags struct {
 // SetupOpts contains options required for app.
 type SetupOpts struct {
 	Name string
+	// DefaultLogsLimits are defaults of logs limits flags.
+	DefaultLogsLimits LogsLimits
+}
+
+// LogsLimits contains limits of logs returned by logs API, it matches logs.Limits.
+type LogsLimits struct {
+	Lines         int
+	Bytes         int
+	FailingWeight int
+	EventsWeight  int
+	OtherWeight   int
 }
 
 const (
@@ -83,6 +131,56 @@ func Setup(opts *SetupOpts) (*Flags, error) {
 	).Default(
 		DefaultPSMDBOperatorURLTemplate,
 	).StringVar(&flags.PSMDBOperatorURLTemplate)
+	kingpin.Flag(
+		"operator.manifests",
+		"Location of operator manifests for air-gapped clusters: 'embedded', a directory or a tarball/OCI image layout archive "+
+			"holding manifests as <pxc|psmdb>/<version>/<file>. URL templates are used if empty.",
+	).Default("").StringVar(&flags.OperatorManifests)
+	kingpin.Flag(
+		"operator.manifests.require-checksums",
+		"Reject operator manifests from --operator.manifests location without SHA-256 checksums listed in SHA256SUMS file next to them. "+
+			"Manifests fetched using URL templates are verified only if SHA256SUMS file is available.",
+	).Default("true").BoolVar(&flags.OperatorManifestsRequireChecksums)
+	kingpin.Flag("operator.upgrade.batch-size", "Number of database clusters patched at once after operator upgrade").Default("1").IntVar(&flags.RolloutBatchSize)
+	kingpin.Flag(
+		"operator.upgrade.ready-timeout",
+		"Time a database cluster has to become ready after it's patched during operator upgrade. Rollout pauses if it doesn't.",
+	).Default("30m").DurationVar(&flags.RolloutReadyTimeout)
+
+	kingpin.Flag("monitoring.scrape-interval", "Interval of metrics scraping by Kubernetes cluster monitoring agent").Default("30s").DurationVar(&flags.MonitoringScrapeInterval)
+	kingpin.Flag(
+		"monitoring.tls-verify",
+		"Verify TLS certificate of PMM server when sending Kubernetes cluster metrics. Leave it disabled for PMM servers with self-signed certificates.",
+	).Default("false").BoolVar(&flags.MonitoringTLSVerify)
+
+	logsLimits := opts.DefaultLogsLimits
+	kingpin.Flag("logs.lines-limit", "Overall limit of log lines returned by logs API, 0 means no limit").
+		Default(strconv.Itoa(logsLimits.Lines)).IntVar(&flags.LogsLinesLimit)
+	kingpin.Flag("logs.bytes-limit", "Overall limit of log lines size in bytes returned by logs API, 0 means no limit").
+		Default(strconv.Itoa(logsLimits.Bytes)).IntVar(&flags.LogsBytesLimit)
+	kingpin.Flag("logs.failing-weight", "Share of logs limits given to failing containers compared to other logs").
+		Default(strconv.Itoa(logsLimits.FailingWeight)).IntVar(&flags.LogsFailingWeight)
+	kingpin.Flag("logs.events-weight", "Share of logs limits given to events compared to other logs").
+		Default(strconv.Itoa(logsLimits.EventsWeight)).IntVar(&flags.LogsEventsWeight)
+	kingpin.Flag("logs.other-weight", "Share of logs limits given to healthy containers compared to other logs").
+		Default(strconv.Itoa(logsLimits.OtherWeight)).IntVar(&flags.LogsOtherWeight)
+
+	kingpin.Flag("registry.path", "Path of Kubernetes clusters registry file. Registry is disabled if it's not set.").Default("").StringVar(&flags.RegistryPath)
+	kingpin.Flag(
+		"registry.key-path",
+		"Path of the file with key used for encryption of registered kubeconfigs, required if registry is enabled. "+
+			"Keep it apart from the registry file. A new key is generated if the file doesn't exist and no clusters are registered.",
+	).Default("").StringVar(&flags.RegistryKeyPath)
+
+	kingpin.Flag("password.length", "Length of generated passwords of database system users").Default("24").IntVar(&flags.PasswordLength)
+	kingpin.Flag("password.lowercase", "Use lowercase letters in passwords of database system users").Default("true").BoolVar(&flags.PasswordLowercase)
+	kingpin.Flag("password.uppercase", "Use uppercase letters in passwords of database system users").Default("true").BoolVar(&flags.PasswordUppercase)
+	kingpin.Flag("password.digits", "Use digits in passwords of database system users").Default("true").BoolVar(&flags.PasswordDigits)
+	kingpin.Flag("password.pxc.symbols", "Special characters allowed in passwords of PXC system users").Default("").StringVar(&flags.PXCPasswordSymbols)
+	kingpin.Flag(
+		"password.psmdb.symbols",
+		"Special characters allowed in passwords of PSMDB system users. Not all of them are supported, see https://jira.percona.com/browse/K8SPSMDB-364.",
+	).Default("").StringVar(&flags.PSMDBPasswordSymbols)
 
 	kingpin.Flag("debug", "Enable debug").Envar("PMM_DEBUG").BoolVar(&flags.LogDebug)
 
@@ -0,0 +1,237 @@
+// dbaas-controller
+// Copyright (C) 2020 Percona LLC
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+// Package jsonapi describes gRPC services which messages are JSON objects.
+// Controller API is defined in dbaas-api, so controller features without controller API messages
+// are served by such services. Requests and responses are google.protobuf.Struct messages on the wire
+// and Go structs on both ends; requests with unknown fields are rejected.
+// Messages which are proto messages, like controller API ones, are sent as is.
+package jsonapi
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// KubeconfigField is the name of request field containing kubeconfig of Kubernetes cluster.
+const KubeconfigField = "kubeconfig"
+
+// Validator is implemented by requests which check their fields, like required ones.
+type Validator interface {
+	Validate() error
+}
+
+// Decode decodes JSON object into v, which should be a pointer to Go struct.
+// It returns InvalidArgument error if the object has unknown fields or it's not valid.
+func Decode(s *structpb.Struct, v interface{}) error {
+	if err := decode(s, v, true); err != nil {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+	if v, ok := v.(Validator); ok {
+		if err := v.Validate(); err != nil {
+			return status.Error(codes.InvalidArgument, err.Error())
+		}
+	}
+	return nil
+}
+
+// decode decodes JSON object into v.
+func decode(s *structpb.Struct, v interface{}, strict bool) error {
+	b, err := protojson.Marshal(s)
+	if err != nil {
+		return err
+	}
+	d := json.NewDecoder(bytes.NewReader(b))
+	if strict {
+		d.DisallowUnknownFields()
+	}
+	return d.Decode(v)
+}
+
+// Encode encodes Go value into JSON object.
+func Encode(v interface{}) (*structpb.Struct, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	s := new(structpb.Struct)
+	if err := protojson.Unmarshal(b, s); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// message returns message sent for Go value: proto messages are sent as is, other values as JSON objects.
+func message(v interface{}) (proto.Message, error) {
+	if m, ok := v.(proto.Message); ok {
+		return m, nil
+	}
+	return Encode(v)
+}
+
+// wireRequest returns message the request returned by newRequest is received as.
+func wireRequest(newRequest func() interface{}) (proto.Message, bool) {
+	if m, ok := newRequest().(proto.Message); ok {
+		return m, true
+	}
+	return new(structpb.Struct), false
+}
+
+// UnaryMethod returns description of unary method of the service with given name.
+// Server interceptors receive *structpb.Struct request, then it's decoded into the value returned by newRequest
+// and passed to call. If newRequest returns a proto message, it's received and passed as is.
+// Response is encoded into JSON object unless it's a proto message.
+func UnaryMethod(
+	serviceName, name string, newRequest func() interface{},
+	call func(ctx context.Context, srv interface{}, req interface{}) (interface{}, error),
+) grpc.MethodDesc {
+	return grpc.MethodDesc{
+		MethodName: name,
+		Handler: func(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+			in, isProto := wireRequest(newRequest)
+			if err := dec(in); err != nil {
+				return nil, err
+			}
+			handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+				if !isProto {
+					s := req.(*structpb.Struct)
+					req = newRequest()
+					if err := Decode(s, req); err != nil {
+						return nil, err
+					}
+				}
+				res, err := call(ctx, srv, req)
+				if err != nil {
+					return nil, err
+				}
+				m, err := message(res)
+				if err != nil {
+					return nil, status.Error(codes.Internal, err.Error())
+				}
+				return m, nil
+			}
+			if interceptor == nil {
+				return handler(ctx, in)
+			}
+			info := &grpc.UnaryServerInfo{
+				Server:     srv,
+				FullMethod: "/" + serviceName + "/" + name,
+			}
+			return interceptor(ctx, in, info, handler)
+		},
+	}
+}
+
+// ServerStreamMethod returns description of server-streaming method with given name.
+// The only request is decoded into the value returned by newRequest and passed to call along with the stream.
+// If newRequest returns a proto message, it's received and passed as is.
+func ServerStreamMethod(name string, newRequest func() interface{}, call func(srv interface{}, req interface{}, stream *ServerStream) error) grpc.StreamDesc {
+	return grpc.StreamDesc{
+		StreamName:    name,
+		ServerStreams: true,
+		Handler: func(srv interface{}, stream grpc.ServerStream) error {
+			in, isProto := wireRequest(newRequest)
+			if err := stream.RecvMsg(in); err != nil {
+				return err
+			}
+			if isProto {
+				return call(srv, in, &ServerStream{ServerStream: stream})
+			}
+			req := newRequest()
+			if err := Decode(in.(*structpb.Struct), req); err != nil {
+				return err
+			}
+			return call(srv, req, &ServerStream{ServerStream: stream})
+		},
+	}
+}
+
+// ServerStream sends responses of server-streaming method.
+type ServerStream struct {
+	grpc.ServerStream
+}
+
+// Send sends the response encoded into JSON object unless it's a proto message.
+func (s *ServerStream) Send(v interface{}) error {
+	m, err := message(v)
+	if err != nil {
+		return status.Error(codes.Internal, err.Error())
+	}
+	return s.SendMsg(m)
+}
+
+// Invoke calls unary method with given full name, like "/service/Method", and decodes response into res.
+// Request and response are encoded into JSON objects unless they are proto messages.
+func Invoke(ctx context.Context, cc grpc.ClientConnInterface, method string, req, res interface{}, opts ...grpc.CallOption) error {
+	in, err := message(req)
+	if err != nil {
+		return err
+	}
+	if out, ok := res.(proto.Message); ok {
+		return cc.Invoke(ctx, method, in, out, opts...)
+	}
+	out := new(structpb.Struct)
+	if err := cc.Invoke(ctx, method, in, out, opts...); err != nil {
+		return err
+	}
+	return decode(out, res, false)
+}
+
+// NewClientStream calls server-streaming method with given full name and sends the request.
+func NewClientStream(
+	ctx context.Context, cc grpc.ClientConnInterface, desc *grpc.StreamDesc, method string, req interface{}, opts ...grpc.CallOption,
+) (*ClientStream, error) {
+	in, err := message(req)
+	if err != nil {
+		return nil, err
+	}
+	stream, err := cc.NewStream(ctx, desc, method, opts...)
+	if err != nil {
+		return nil, err
+	}
+	if err := stream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := stream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return &ClientStream{ClientStream: stream}, nil
+}
+
+// ClientStream receives responses of server-streaming method.
+type ClientStream struct {
+	grpc.ClientStream
+}
+
+// Recv receives the next response into v. It returns io.EOF when the stream ends.
+func (s *ClientStream) Recv(v interface{}) error {
+	if m, ok := v.(proto.Message); ok {
+		return s.RecvMsg(m)
+	}
+	out := new(structpb.Struct)
+	if err := s.RecvMsg(out); err != nil {
+		return err
+	}
+	return decode(out, v, false)
+}
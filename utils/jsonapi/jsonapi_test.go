@@ -0,0 +1,140 @@
+// dbaas-controller
+// Copyright (C) 2020 Percona LLC
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package jsonapi
+
+import (
+	"context"
+	"io"
+	"net"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/structpb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+const testServiceName = "test.EchoAPI"
+
+type echoRequest struct {
+	Message string `json:"message"`
+	Count   int    `json:"count,omitempty"`
+}
+
+func (req *echoRequest) Validate() error {
+	if req.Message == "" {
+		return errors.New("message is required")
+	}
+	return nil
+}
+
+type echoResponse struct {
+	Message string `json:"message"`
+}
+
+var testServiceDesc = grpc.ServiceDesc{ //nolint:gochecknoglobals
+	ServiceName: testServiceName,
+	HandlerType: (*interface{})(nil),
+	Methods: []grpc.MethodDesc{
+		UnaryMethod(testServiceName, "Echo", func() interface{} { return new(echoRequest) },
+			func(ctx context.Context, srv interface{}, req interface{}) (interface{}, error) {
+				return &echoResponse{Message: req.(*echoRequest).Message}, nil
+			}),
+		UnaryMethod(testServiceName, "EchoString", func() interface{} { return new(wrapperspb.StringValue) },
+			func(ctx context.Context, srv interface{}, req interface{}) (interface{}, error) {
+				return wrapperspb.String(req.(*wrapperspb.StringValue).Value), nil
+			}),
+	},
+	Streams: []grpc.StreamDesc{
+		ServerStreamMethod("EchoStream", func() interface{} { return new(echoRequest) },
+			func(srv interface{}, req interface{}, stream *ServerStream) error {
+				r := req.(*echoRequest)
+				for i := 0; i < r.Count; i++ {
+					if err := stream.Send(wrapperspb.String(r.Message)); err != nil {
+						return err
+					}
+				}
+				return nil
+			}),
+	},
+}
+
+func TestService(t *testing.T) {
+	t.Parallel()
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	var requests []interface{}
+	server := grpc.NewServer(grpc.UnaryInterceptor(
+		func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+			requests = append(requests, req)
+			return handler(ctx, req)
+		},
+	))
+	server.RegisterService(&testServiceDesc, struct{}{})
+	go server.Serve(lis) //nolint:errcheck
+	defer server.Stop()
+
+	ctx := context.Background()
+	conn, err := grpc.DialContext(ctx, lis.Addr().String(), grpc.WithInsecure())
+	require.NoError(t, err)
+	defer conn.Close() //nolint:errcheck
+
+	t.Run("Unary", func(t *testing.T) {
+		var res echoResponse
+		require.NoError(t, Invoke(ctx, conn, "/"+testServiceName+"/Echo", &echoRequest{Message: "hello"}, &res))
+		assert.Equal(t, "hello", res.Message)
+		require.Len(t, requests, 1)
+		assert.IsType(t, new(structpb.Struct), requests[0])
+
+		err := Invoke(ctx, conn, "/"+testServiceName+"/Echo", new(echoRequest), &res)
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+		unknown, err := structpb.NewStruct(map[string]interface{}{"message": "hello", "unknown": true})
+		require.NoError(t, err)
+		err = Invoke(ctx, conn, "/"+testServiceName+"/Echo", unknown, &res)
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	})
+
+	t.Run("Proto messages", func(t *testing.T) {
+		res := new(wrapperspb.StringValue)
+		require.NoError(t, Invoke(ctx, conn, "/"+testServiceName+"/EchoString", wrapperspb.String("hello"), res))
+		assert.Equal(t, "hello", res.Value)
+		assert.IsType(t, new(wrapperspb.StringValue), requests[len(requests)-1])
+	})
+
+	t.Run("Server stream", func(t *testing.T) {
+		stream, err := NewClientStream(ctx, conn, &testServiceDesc.Streams[0], "/"+testServiceName+"/EchoStream",
+			&echoRequest{Message: "hello", Count: 2})
+		require.NoError(t, err)
+		var messages []string
+		for {
+			msg := new(wrapperspb.StringValue)
+			err := stream.Recv(msg)
+			if err == io.EOF {
+				break
+			}
+			require.NoError(t, err)
+			messages = append(messages, msg.Value)
+		}
+		assert.Equal(t, []string{"hello", "hello"}, messages)
+	})
+}
@@ -58,6 +58,9 @@ type NewGRPCServerOpts struct {
 	Addr            string
 	WarnDuration    time.Duration
 	ShutdownTimeout time.Duration
+	// UnaryInterceptors and StreamInterceptors are called after the default ones.
+	UnaryInterceptors  []grpc.UnaryServerInterceptor
+	StreamInterceptors []grpc.StreamServerInterceptor
 }
 
 // NewGRPCServer creates new gRPC server with given options.
@@ -78,21 +81,23 @@ func NewGRPCServer(ctx context.Context, opts *NewGRPCServerOpts) GRPCServer {
 		opts.ShutdownTimeout = 3 * time.Second
 	}
 
+	unaryInterceptors := append([]grpc.UnaryServerInterceptor{
+		unaryLoggingInterceptor(opts.WarnDuration),
+		grpc_prometheus.UnaryServerInterceptor,
+		grpc_validator.UnaryServerInterceptor(),
+	}, opts.UnaryInterceptors...)
+	streamInterceptors := append([]grpc.StreamServerInterceptor{
+		streamLoggingInterceptor(opts.WarnDuration),
+		grpc_prometheus.StreamServerInterceptor,
+		grpc_validator.StreamServerInterceptor(),
+	}, opts.StreamInterceptors...)
+
 	serverOpts := []grpc.ServerOption{
 		grpc.ConnectionTimeout(5 * time.Second),
 		grpc.MaxRecvMsgSize(10 * 1024 * 1024), //nolint:gomnd
 
-		grpc.UnaryInterceptor(grpc_middleware.ChainUnaryServer(
-			unaryLoggingInterceptor(opts.WarnDuration),
-			grpc_prometheus.UnaryServerInterceptor,
-			grpc_validator.UnaryServerInterceptor(),
-		)),
-
-		grpc.StreamInterceptor(grpc_middleware.ChainStreamServer(
-			streamLoggingInterceptor(opts.WarnDuration),
-			grpc_prometheus.StreamServerInterceptor,
-			grpc_validator.StreamServerInterceptor(),
-		)),
+		grpc.UnaryInterceptor(grpc_middleware.ChainUnaryServer(unaryInterceptors...)),
+		grpc.StreamInterceptor(grpc_middleware.ChainStreamServer(streamInterceptors...)),
 	}
 
 	return &grpcServer{
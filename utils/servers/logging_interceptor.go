@@ -27,7 +27,10 @@ import (
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/structpb"
 
+	"github.com/percona-platform/dbaas-controller/utils/jsonapi"
 	"github.com/percona-platform/dbaas-controller/utils/logger"
 )
 
@@ -71,6 +74,21 @@ func logGRPCRequest(l logger.Logger, prefix string, warnD time.Duration, f func(
 	return //nolint:nakedret
 }
 
+// redactRequest returns a copy of JSON object request (see jsonapi package) without kubeconfig,
+// so it's never written to logs. Other requests are returned as is.
+func redactRequest(req interface{}) interface{} {
+	s, ok := req.(*structpb.Struct)
+	if !ok {
+		return req
+	}
+	if _, ok = s.GetFields()[jsonapi.KubeconfigField]; !ok {
+		return req
+	}
+	redacted := proto.Clone(s).(*structpb.Struct)
+	redacted.Fields[jsonapi.KubeconfigField] = structpb.NewStringValue("<redacted>")
+	return redacted
+}
+
 // unaryLoggingInterceptor returns a new unary server interceptor that logs incoming requests.
 func unaryLoggingInterceptor(warnDuration time.Duration) grpc.UnaryServerInterceptor {
 	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) { //nolint:lll
@@ -91,7 +109,7 @@ func unaryLoggingInterceptor(warnDuration time.Duration) grpc.UnaryServerInterce
 		})
 
 		// err is already logged by logRequest
-		l.Debugf("\nRequest:\n%s\nResponse:\n%s\n", req, res)
+		l.Debugf("\nRequest:\n%s\nResponse:\n%s\n", redactRequest(req), res)
 
 		return res, err
 	}
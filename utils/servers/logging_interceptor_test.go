@@ -0,0 +1,40 @@
+// dbaas-controller
+// Copyright (C) 2020 Percona LLC
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Affero General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Affero General Public License for more details.
+//
+// You should have received a copy of the GNU Affero General Public License
+// along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+package servers
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+func TestRedactRequest(t *testing.T) {
+	t.Parallel()
+
+	req, err := structpb.NewStruct(map[string]interface{}{"name": "prod", "kubeconfig": "secret"})
+	require.NoError(t, err)
+	redacted := redactRequest(req).(*structpb.Struct)
+	assert.Equal(t, "<redacted>", redacted.Fields["kubeconfig"].GetStringValue())
+	assert.Equal(t, "prod", redacted.Fields["name"].GetStringValue())
+	assert.Equal(t, "secret", req.Fields["kubeconfig"].GetStringValue(), "request passed to handler must not be changed")
+
+	empty := new(emptypb.Empty)
+	assert.Same(t, empty, redactRequest(empty))
+}